@@ -200,6 +200,122 @@ func (client *Client) GetOrders(ctx context.Context) (*model.Orders, error) {
 	return &ods, nil
 }
 
+func (client *Client) RefundOrder(ctx context.Context, reference string, refundReq *model.RefundRequest) (*model.Refund, error) {
+	var refund model.Refund
+	if err := client.executeJSONRequest(ctx, http.MethodPost, fmt.Sprintf("%s/%s%s", orders.OrdersEndPnt, reference, orders.RefundsEndPnt), refundReq, &refund); err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (client *Client) IssueGiftCard(ctx context.Context, issueReq *model.IssueGiftCardRequest) (*model.GiftCard, error) {
+	var card model.GiftCard
+	if err := client.executeJSONRequest(ctx, http.MethodPost, orders.GiftCardsEndPnt, issueReq, &card); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (client *Client) GetGiftCard(ctx context.Context, code string) (*model.GiftCard, error) {
+	var card model.GiftCard
+	if err := client.executeJSONRequest(ctx, http.MethodGet, fmt.Sprintf("%s/%s", orders.GiftCardsEndPnt, code), nil, &card); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (client *Client) ExpireGiftCard(ctx context.Context, code string) (*model.GiftCard, error) {
+	var card model.GiftCard
+	if err := client.executeJSONRequest(ctx, http.MethodPost, fmt.Sprintf("%s/%s%s", orders.GiftCardsEndPnt, code, orders.ExpireEndPnt), nil, &card); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (client *Client) ListPurchaseLimits(ctx context.Context) ([]*model.PurchaseLimit, error) {
+	var ls []*model.PurchaseLimit
+	if err := client.executeJSONRequest(ctx, http.MethodGet, orders.LimitsEndPnt, nil, &ls); err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+func (client *Client) SetPurchaseLimit(ctx context.Context, sku string, limitReq *model.SetPurchaseLimitRequest) (*model.PurchaseLimit, error) {
+	var l model.PurchaseLimit
+	if err := client.executeJSONRequest(ctx, http.MethodPut, fmt.Sprintf("%s/%s", orders.LimitsEndPnt, sku), limitReq, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (client *Client) DeletePurchaseLimit(ctx context.Context, sku string) error {
+	return client.executeJSONRequest(ctx, http.MethodDelete, fmt.Sprintf("%s/%s", orders.LimitsEndPnt, sku), nil, nil)
+}
+
+func (client *Client) ListPromotions(ctx context.Context) ([]*model.PromotionRule, error) {
+	var ps []*model.PromotionRule
+	if err := client.executeJSONRequest(ctx, http.MethodGet, orders.PromotionsEndPnt, nil, &ps); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+func (client *Client) GetPromotion(ctx context.Context, id int) (*model.PromotionRule, error) {
+	var p model.PromotionRule
+	if err := client.executeJSONRequest(ctx, http.MethodGet, fmt.Sprintf("%s/%d", orders.PromotionsEndPnt, id), nil, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (client *Client) CreatePromotion(ctx context.Context, promo *model.PromotionRule) (*model.PromotionRule, error) {
+	var p model.PromotionRule
+	if err := client.executeJSONRequest(ctx, http.MethodPost, orders.PromotionsEndPnt, promo, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (client *Client) UpdatePromotion(ctx context.Context, id int, promo *model.PromotionRule) (*model.PromotionRule, error) {
+	var p model.PromotionRule
+	if err := client.executeJSONRequest(ctx, http.MethodPut, fmt.Sprintf("%s/%d", orders.PromotionsEndPnt, id), promo, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (client *Client) DeletePromotion(ctx context.Context, id int) error {
+	return client.executeJSONRequest(ctx, http.MethodDelete, fmt.Sprintf("%s/%d", orders.PromotionsEndPnt, id), nil, nil)
+}
+
+func (client *Client) ListCoupons(ctx context.Context) ([]*model.Coupon, error) {
+	var cs []*model.Coupon
+	if err := client.executeJSONRequest(ctx, http.MethodGet, orders.CouponsEndPnt, nil, &cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func (client *Client) GetCoupon(ctx context.Context, code string) (*model.Coupon, error) {
+	var c model.Coupon
+	if err := client.executeJSONRequest(ctx, http.MethodGet, orders.CouponsEndPnt+"/"+code, nil, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (client *Client) CreateCoupon(ctx context.Context, coupon *model.Coupon) (*model.Coupon, error) {
+	var c model.Coupon
+	if err := client.executeJSONRequest(ctx, http.MethodPost, orders.CouponsEndPnt, coupon, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (client *Client) DeleteCoupon(ctx context.Context, code string) error {
+	return client.executeJSONRequest(ctx, http.MethodDelete, orders.CouponsEndPnt+"/"+code, nil, nil)
+}
+
 //
 // Notifications service HTTP API
 //
@@ -12,9 +12,11 @@ import (
 
 	"github.com/ATMackay/checkout/database"
 	srverrors "github.com/ATMackay/checkout/errors"
+	"github.com/ATMackay/checkout/event"
 	"github.com/ATMackay/checkout/httpserver"
 	"github.com/ATMackay/checkout/messaging/noop"
 	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments/fake"
 	"github.com/ATMackay/checkout/services/auth"
 	"github.com/ATMackay/checkout/services/orders"
 	"github.com/shopspring/decimal"
@@ -28,9 +30,15 @@ func TestClient(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	relayer := orders.NewOutboxRelayer(db, &noop.Client{})
+	payer := fake.NewProvider()
+	relayer := orders.NewOutboxRelayer(db, &noop.Client{},
+		orders.WithPollInterval(10*time.Millisecond),
+		orders.WithHandler(event.TopicPaymentCapture, orders.NewCaptureHandler(db, payer)),
+		orders.WithHandler(event.TopicPaymentRefund, orders.NewRefundHandler(db, payer)),
+	)
 	authn := auth.NewPasswordAuthenticator(map[string]string{"1234": "test-user"})
-	svc := orders.NewService(db, relayer, authn)
+	admin := auth.NewPasswordAuthenticator(map[string]string{"5678": "test-admin"})
+	svc := orders.NewService(db, relayer, authn, orders.WithPaymentProvider(payer), orders.WithAdminAuthenticator(admin))
 	svr := httpserver.New(8001, svc)
 	if err := svr.Start(context.Background()); err != nil {
 		t.Fatal(err)
@@ -45,6 +53,12 @@ func TestClient(t *testing.T) {
 		t.Fatal(err)
 	}
 	cl.AddAuthorizationHeader("1234")
+	// adm calls the admin routes, which the customer password does not open.
+	adm, err := New(baseUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adm.AddAuthorizationHeader("5678")
 
 	ctx := context.Background()
 
@@ -113,6 +127,143 @@ func TestClient(t *testing.T) {
 		t.Log(*resp)
 	})
 
+	t.Run("payment-captured", func(t *testing.T) {
+		// The relay captures the authorization asynchronously from the outbox.
+		require.Eventually(t, func() bool {
+			resp, err := cl.GetOrders(ctx)
+			return err == nil && len(*resp) == 1 && (*resp)[0].PaymentStatus == model.PaymentCaptured
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("refund-order", func(t *testing.T) {
+		ods, err := cl.GetOrders(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, *ods)
+		ref := (*ods)[0].Reference
+		resp, err := cl.RefundOrder(ctx, ref, &model.RefundRequest{
+			Lines:   []model.RefundLine{{SKU: it1.SKU, Quantity: 1}},
+			Restock: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, ref, resp.OrderReference)
+		require.True(t, it1.Price.Equal(resp.Amount))
+		require.Eventually(t, func() bool {
+			return len(payer.Calls()) > 0 && payer.Calls()[len(payer.Calls())-1].Op == fake.Refund
+		}, 2*time.Second, 10*time.Millisecond)
+
+		// The same line cannot be returned twice.
+		_, err = cl.RefundOrder(ctx, ref, &model.RefundRequest{Lines: []model.RefundLine{{SKU: it1.SKU, Quantity: 1}}})
+		var he *HTTPError
+		require.ErrorAs(t, err, &he)
+		require.Equal(t, http.StatusBadRequest, he.Status)
+	})
+
+	t.Run("admin-only", func(t *testing.T) {
+		_, err := cl.IssueGiftCard(ctx, &model.IssueGiftCardRequest{Amount: decimal.NewFromInt(1000)})
+		var he *HTTPError
+		require.ErrorAs(t, err, &he)
+		require.Equal(t, http.StatusUnauthorized, he.Status)
+	})
+
+	t.Run("gift-card", func(t *testing.T) {
+		card, err := adm.IssueGiftCard(ctx, &model.IssueGiftCardRequest{Amount: decimal.NewFromInt(10)})
+		require.NoError(t, err)
+		resp, err := cl.PurchaseItems(ctx, &model.PurchaseItemsRequest{SKUs: []string{it1.SKU}, GiftCard: card.Code})
+		require.NoError(t, err)
+		require.Equal(t, 10.0, resp.GiftCardAmount)
+
+		card, err = cl.GetGiftCard(ctx, card.Code)
+		require.NoError(t, err)
+		require.True(t, card.Balance.IsZero())
+		require.Len(t, card.Ledger, 2)
+
+		// An expired card is refused.
+		card, err = adm.ExpireGiftCard(ctx, card.Code)
+		require.NoError(t, err)
+		require.NotNil(t, card.ExpiresAt)
+		_, err = cl.PurchaseItems(ctx, &model.PurchaseItemsRequest{SKUs: []string{it1.SKU}, GiftCard: card.Code})
+		var he *HTTPError
+		require.ErrorAs(t, err, &he)
+		require.Equal(t, http.StatusPaymentRequired, he.Status)
+	})
+
+	t.Run("purchase-limits", func(t *testing.T) {
+		_, err := adm.SetPurchaseLimit(ctx, it2.SKU, &model.SetPurchaseLimitRequest{MaxPerOrder: 1})
+		require.NoError(t, err)
+		ls, err := cl.ListPurchaseLimits(ctx)
+		require.NoError(t, err)
+		require.Len(t, ls, 1)
+
+		_, err = cl.PurchaseItems(ctx, &model.PurchaseItemsRequest{SKUs: []string{it2.SKU, it2.SKU}})
+		var he *HTTPError
+		require.ErrorAs(t, err, &he)
+		require.Equal(t, http.StatusUnprocessableEntity, he.Status)
+
+		require.NoError(t, adm.DeletePurchaseLimit(ctx, it2.SKU))
+	})
+
+	t.Run("promotions", func(t *testing.T) {
+		cable := &model.Item{Name: "HDMI Cable", SKU: "HDMI01", Price: decimal.NewFromFloat(9.99), InventoryQuantity: 10}
+		require.NoError(t, cl.AddItems(ctx, &model.AddItemsRequest{Items: []*model.Item{cable}}))
+		promo, err := adm.CreatePromotion(ctx, &model.PromotionRule{
+			Name: "3 for 2", Type: model.PromotionBuyNGetMFree, Active: true,
+			SKU: cable.SKU, BuyQuantity: 2, FreeQuantity: 1,
+		})
+		require.NoError(t, err)
+
+		// The rule applies to the next checkout, without a restart.
+		basket := &model.ItemsPriceRequest{SKUs: []string{cable.SKU, cable.SKU, cable.SKU}}
+		resp, err := cl.GetItemsPrice(ctx, basket)
+		require.NoError(t, err)
+		require.Equal(t, cable.Price.InexactFloat64(), resp.Promotions.Deduction)
+
+		promo.Active = false
+		_, err = adm.UpdatePromotion(ctx, promo.ID, promo)
+		require.NoError(t, err)
+		resp, err = cl.GetItemsPrice(ctx, basket)
+		require.NoError(t, err)
+		require.Zero(t, resp.Promotions.Deduction)
+
+		require.NoError(t, adm.DeletePromotion(ctx, promo.ID))
+		_, err = adm.GetPromotion(ctx, promo.ID)
+		var he *HTTPError
+		require.ErrorAs(t, err, &he)
+		require.Equal(t, http.StatusNotFound, he.Status)
+	})
+
+	t.Run("coupons", func(t *testing.T) {
+		promo, err := adm.CreatePromotion(ctx, &model.PromotionRule{
+			Name: "half price cable", Type: model.PromotionPercentOffOverQuantity, Active: true, CouponOnly: true,
+			SKU: "HDMI01", MinQuantity: 1, PercentOff: decimal.NewFromInt(50),
+		})
+		require.NoError(t, err)
+		c, err := adm.CreateCoupon(ctx, &model.Coupon{Code: "half", PromotionID: promo.ID, MaxPerCustomer: 1})
+		require.NoError(t, err)
+		require.Equal(t, "HALF", c.Code)
+
+		// Coupon-only promotions never apply on their own.
+		resp, err := cl.GetItemsPrice(ctx, &model.ItemsPriceRequest{SKUs: []string{"HDMI01"}})
+		require.NoError(t, err)
+		require.Zero(t, resp.Promotions.Deduction)
+		resp, err = cl.GetItemsPrice(ctx, &model.ItemsPriceRequest{SKUs: []string{"HDMI01"}, CouponCodes: []string{"HALF"}})
+		require.NoError(t, err)
+		require.Equal(t, 4.995, resp.Promotions.Deduction)
+
+		_, err = cl.PurchaseItems(ctx, &model.PurchaseItemsRequest{SKUs: []string{"HDMI01"}, CouponCodes: []string{"HALF"}})
+		require.NoError(t, err)
+		c, err = adm.GetCoupon(ctx, "HALF")
+		require.NoError(t, err)
+		require.Equal(t, 1, c.Redemptions)
+
+		_, err = cl.PurchaseItems(ctx, &model.PurchaseItemsRequest{SKUs: []string{"HDMI01"}, CouponCodes: []string{"HALF"}})
+		var he *HTTPError
+		require.ErrorAs(t, err, &he)
+		require.Equal(t, http.StatusUnprocessableEntity, he.Status)
+
+		require.NoError(t, adm.DeleteCoupon(ctx, "HALF"))
+		require.NoError(t, adm.DeletePromotion(ctx, promo.ID))
+	})
+
 	// errors
 	t.Run("context-cancelled", func(t *testing.T) {
 		ctxCancelled, cancelFunc := context.WithCancel(ctx)
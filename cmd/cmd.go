@@ -20,6 +20,8 @@ func NewCheckoutCmd() *cobra.Command {
 	cmd.AddCommand(NewRunCmd())
 	cmd.AddCommand(VersionCmd())
 	cmd.AddCommand(HealthCmd())
+	cmd.AddCommand(PromotionsCmd())
+	cmd.AddCommand(OutboxCmd())
 	return cmd
 }
 
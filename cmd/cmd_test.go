@@ -5,6 +5,7 @@ package cmd
 import (
 	"testing"
 
+	"github.com/ATMackay/checkout/services/orders"
 	"github.com/stretchr/testify/require"
 )
 
@@ -17,7 +18,7 @@ func Test_CheckoutCMD(t *testing.T) {
 	// Named rather than counted: a count says nothing about which command went
 	// missing, and "health" in particular is depended on by the container
 	// HEALTHCHECK, which has no shell to fall back to.
-	require.ElementsMatch(t, []string{"run", "version", "health"}, names)
+	require.ElementsMatch(t, []string{"run", "version", "health", "promotions", "outbox"}, names)
 }
 
 func Test_BuildDirty(t *testing.T) {
@@ -26,3 +27,15 @@ func Test_BuildDirty(t *testing.T) {
 	// just exercise it. The parsing itself is covered by constants.Test_parseVCS.
 	_ = isBuildDirty()
 }
+
+// No real payment processor is integrated, so the orders service refuses to
+// start unless the fake is asked for explicitly.
+func Test_RunOrdersRequiresPaymentProvider(t *testing.T) {
+	// Every service command binds the shared flags to the one Viper instance,
+	// the last registered winning, so select the in-memory database through
+	// the environment to keep the test from creating data/db.
+	t.Setenv(EnvPrefix+"_MEMORY_DB", "true")
+	c := NewCheckoutCmd()
+	c.SetArgs([]string{"run", "orders"})
+	require.ErrorIs(t, c.Execute(), orders.ErrNoPaymentProvider)
+}
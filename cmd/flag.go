@@ -47,6 +47,10 @@ const (
 	// FlagPassword is the shared secret guarding authenticated endpoints.
 	FlagPassword = "password"
 
+	// FlagAdminPassword is the secret guarding the administrative endpoints.
+	// Empty closes them. Orders only.
+	FlagAdminPassword = "admin-password"
+
 	// FlagEventBroker is the address of the Kafka broker to publish domain
 	// events to. Empty disables publishing: the service falls back to the no-op
 	// publisher, so events are opt-in rather than required to boot.
@@ -56,4 +60,42 @@ const (
 	// notifications to (as JSON lines), in addition to the terminal. Notifier
 	// only.
 	FlagNotificationFile = "notification-file"
+
+	// FlagFXRatesFile is an optional path to a JSON exchange-rate table used to
+	// quote prices in a currency other than the catalog's. Unset, only the
+	// catalog currency is accepted. Orders only.
+	FlagFXRatesFile = "fx-rates-file"
+
+	// FlagPromotionsFile is an optional path to a YAML promotion rule file
+	// applied alongside the built-in and stored promotions. The file is
+	// validated at startup. Orders only.
+	FlagPromotionsFile = "promotions-file"
+
+	// FlagLoyaltyEarnRate is the loyalty points a customer earns per unit of
+	// catalog currency paid. Zero earns nothing. Orders only.
+	FlagLoyaltyEarnRate = "loyalty-earn-rate"
+
+	// FlagLoyaltyPointValue is the catalog currency one loyalty point takes
+	// off a purchase. Zero refuses redemptions. Orders only.
+	FlagLoyaltyPointValue = "loyalty-point-value"
+
+	// FlagOutboxRetention is how long published and delivered outbox rows are
+	// kept before the purger removes them. Zero keeps them forever. Orders
+	// only.
+	FlagOutboxRetention = "outbox-retention"
+
+	// FlagOutboxArchive makes the outbox purger move rows to the
+	// outbox_archive table rather than delete them. Orders only.
+	FlagOutboxArchive = "outbox-archive"
+
+	// FlagOutboxMaxAge is how long the oldest unpublished outbox row may wait
+	// before /health reports the service unhealthy. Zero never does. Orders
+	// only.
+	FlagOutboxMaxAge = "outbox-max-age"
+
+	// FlagFakePayments charges purchases through the in-memory fake payment
+	// provider, which approves everything and forgets its authorizations on
+	// restart. For local development only: no real processor is integrated
+	// yet, so without it the orders service refuses to start. Orders only.
+	FlagFakePayments = "fake-payments"
 )
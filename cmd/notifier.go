@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/ATMackay/checkout/services/notifier"
-	"github.com/ATMackay/checkout/services/orders"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -24,9 +23,9 @@ func NewNotifierCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			// Subscribe to the orders service's event topic — the cross-service
+			// Subscribe to the orders service's event topics — the cross-service
 			// contract, owned by the producer (orders).
-			consumer, err := openConsumer(cfg, notifier.ConsumerGroup, orders.TopicOrderCreated)
+			consumer, err := openConsumer(cfg, notifier.ConsumerGroup, notifier.Topics...)
 			if err != nil {
 				return fmt.Errorf("could not connect to event broker %q: %w", cfg.eventBroker, err)
 			}
@@ -2,9 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
 
+	"github.com/ATMackay/checkout/event"
+	"github.com/ATMackay/checkout/fx"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments/fake"
+	"github.com/ATMackay/checkout/promotions"
 	"github.com/ATMackay/checkout/services/orders"
+	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // NewOrdersCmd runs the orders API server: inventory + purchase orders over REST,
@@ -26,11 +34,89 @@ func NewOrdersCmd() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("could not connect to event broker %q: %w", cfg.eventBroker, err)
 			}
-			relay := orders.NewOutboxRelayer(db, publisher)
-			svc := orders.NewService(db, relay, newAuthenticator(cfg))
+			var opts []orders.ServiceOption
+			if path := viper.GetString(FlagFXRatesFile); path != "" {
+				rates, err := fx.LoadStaticProvider(path)
+				if err != nil {
+					return fmt.Errorf("could not load exchange rates: %w", err)
+				}
+				opts = append(opts, orders.WithRateProvider(rates))
+			}
+			if path := viper.GetString(FlagPromotionsFile); path != "" {
+				rules, err := promotions.LoadRuleFile(path, db)
+				if err != nil {
+					return fmt.Errorf("could not load promotions: %w", err)
+				}
+				ps := make([]promotions.Promotion, len(rules))
+				for i, r := range rules {
+					ps[i] = r
+				}
+				opts = append(opts, orders.WithPromotions(ps...))
+			}
+			loyalty, err := readLoyaltyProgram()
+			if err != nil {
+				return err
+			}
+			opts = append(opts, orders.WithLoyalty(loyalty))
+			// No real processor is integrated yet, so the only provider is the
+			// local fake, which approves every charge. It must be asked for.
+			// The purchase path authorizes through it and the relay captures
+			// through it, so both must share the one instance.
+			if !viper.GetBool(FlagFakePayments) {
+				return fmt.Errorf("%w: pass --%s to approve every payment in memory (development only)", orders.ErrNoPaymentProvider, FlagFakePayments)
+			}
+			slog.Warn("using the fake payment provider: every payment is approved and authorizations are lost on restart")
+			payer := fake.NewProvider()
+			opts = append(opts, orders.WithPaymentProvider(payer))
+			relay := orders.NewOutboxRelayer(db, publisher,
+				orders.WithHandler(event.TopicPaymentCapture, orders.NewCaptureHandler(db, payer)),
+				orders.WithDeadLetter(event.TopicPaymentCapture, orders.NewCaptureFailedHandler(db)),
+				orders.WithHandler(event.TopicPaymentRefund, orders.NewRefundHandler(db, payer)),
+				orders.WithHook(event.TopicOrderCreated, orders.NewLoyaltyHandler(db, loyalty)),
+				orders.WithMaxBacklogAge(viper.GetDuration(FlagOutboxMaxAge)),
+			)
+			if retention := viper.GetDuration(FlagOutboxRetention); retention > 0 {
+				var popts []orders.PurgerOption
+				if viper.GetBool(FlagOutboxArchive) {
+					popts = append(popts, orders.WithArchive())
+				}
+				opts = append(opts, orders.WithOutboxPurger(orders.NewOutboxPurger(db, retention, popts...)))
+			}
+			opts = append(opts, orders.WithAdminAuthenticator(newAdminAuthenticator(viper.GetString(FlagAdminPassword))))
+			svc := orders.NewService(db, relay, newAuthenticator(cfg), opts...)
 			return serve(cmd, orders.ServiceName, cfg.port, svc)
 		},
 	}
+	// Register the orders-only flags before the shared flags so BindPFlags picks
+	// them up in one pass.
+	cmd.Flags().String(FlagFXRatesFile, "", "Optional JSON exchange-rate table for quoting prices in other currencies")
+	cmd.Flags().String(FlagPromotionsFile, "", "Optional YAML promotion rule file, validated at startup")
+	cmd.Flags().String(FlagLoyaltyEarnRate, "0", "Loyalty points earned per unit of catalog currency paid (0 disables earning)")
+	cmd.Flags().String(FlagLoyaltyPointValue, "0", "Catalog currency one loyalty point is worth at checkout (0 disables redemption)")
+	cmd.Flags().Duration(FlagOutboxRetention, 0, "How long published and delivered outbox rows are kept, e.g. 720h (0 keeps them forever)")
+	cmd.Flags().Bool(FlagOutboxArchive, false, "Archive purged outbox rows to the outbox_archive table instead of deleting them")
+	cmd.Flags().Bool(FlagFakePayments, false, "Development only: approve every payment with an in-memory fake provider")
+	cmd.Flags().Duration(FlagOutboxMaxAge, 0, "Report unhealthy when the oldest unpublished outbox row is older than this, e.g. 5m (0 disables the check)")
+	cmd.Flags().String(FlagAdminPassword, "", "Password for the admin endpoints; empty disables them")
 	registerServiceFlags(cmd)
 	return cmd
 }
+
+// readLoyaltyProgram reads the loyalty program from the loyalty flags.
+func readLoyaltyProgram() (model.LoyaltyProgram, error) {
+	var p model.LoyaltyProgram
+	for _, f := range []struct {
+		name string
+		v    *decimal.Decimal
+	}{
+		{FlagLoyaltyEarnRate, &p.EarnRate},
+		{FlagLoyaltyPointValue, &p.PointValue},
+	} {
+		d, err := decimal.NewFromString(viper.GetString(f.name))
+		if err != nil || d.IsNegative() {
+			return p, fmt.Errorf("invalid --%s %q, want a non-negative number", f.name, viper.GetString(f.name))
+		}
+		*f.v = d
+	}
+	return p, nil
+}
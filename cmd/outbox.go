@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/services/orders"
+	"github.com/spf13/cobra"
+)
+
+// OutboxCmd groups maintenance tools for the transactional outbox.
+func OutboxCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "outbox",
+		Short: "Maintenance tools for the orders outbox",
+		RunE:  runHelp,
+	}
+	cmd.AddCommand(PurgeCmd())
+	cmd.AddCommand(OutboxListCmd())
+	cmd.AddCommand(OutboxShowCmd())
+	cmd.AddCommand(OutboxResetCmd())
+	cmd.AddCommand(OutboxAuditCmd())
+	return cmd
+}
+
+// PurgeCmd runs the outbox retention purge once, as the orders service does
+// on its --outbox-retention schedule, e.g. to clear a backlog before turning
+// retention on.
+func PurgeCmd() *cobra.Command {
+	var (
+		olderThan time.Duration
+		batchSize int
+		archive   bool
+		cfg       serviceConfig
+	)
+	cmd := &cobra.Command{
+		Use:          "purge",
+		Short:        "Delete or archive published and delivered outbox rows older than --older-than",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if olderThan <= 0 {
+				return fmt.Errorf("invalid --older-than %s, want a positive duration", olderThan)
+			}
+			if batchSize < 1 {
+				return fmt.Errorf("invalid --batch-size %d, want at least 1", batchSize)
+			}
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return err
+			}
+			opts := []orders.PurgerOption{orders.WithPurgeBatchSize(batchSize)}
+			action := "deleted"
+			if archive {
+				opts = append(opts, orders.WithArchive())
+				action = "archived"
+			}
+			n, err := orders.NewOutboxPurger(db, olderThan, opts...).Purge(cmd.Context())
+			// Report what was removed even if a later batch failed.
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %d outbox rows\n", action, n)
+			return err
+		},
+	}
+	cmd.Flags().DurationVar(&olderThan, "older-than", 0, "Purge rows created longer ago than this, e.g. 720h")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 500, "Rows removed per transaction")
+	cmd.Flags().BoolVar(&archive, "archive", false, "Move rows to the outbox_archive table instead of deleting them")
+	addDatabaseFlags(cmd, &cfg)
+	if err := cmd.MarkFlagRequired("older-than"); err != nil {
+		panic(err)
+	}
+	return cmd
+}
+
+// OutboxListCmd lists outbox rows, as GET /v1/outbox does.
+func OutboxListCmd() *cobra.Command {
+	var (
+		state        string
+		topics       []string
+		since, until string
+		limit        int
+		cfg          serviceConfig
+	)
+	cmd := &cobra.Command{
+		Use:          "list",
+		Short:        "List outbox rows by state, topic and time, as JSON",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var from, to time.Time
+			for _, t := range []struct {
+				name, s string
+				v       *time.Time
+			}{{"since", since, &from}, {"until", until, &to}} {
+				if t.s == "" {
+					continue
+				}
+				var err error
+				if *t.v, err = time.Parse(time.RFC3339, t.s); err != nil {
+					return fmt.Errorf("invalid --%s %q, want RFC 3339: %w", t.name, t.s, err)
+				}
+			}
+			q, err := orders.NewOutboxQuery(state, topics, from, to, limit)
+			if err != nil {
+				return err
+			}
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return err
+			}
+			items, err := db.GetOutboxItems(cmd.Context(), q)
+			if err != nil {
+				return fmt.Errorf("could not get outbox rows: %w", err)
+			}
+			return writeJSON(cmd.OutOrStdout(), items)
+		},
+	}
+	cmd.Flags().StringVar(&state, "state", "", "Only rows that are pending, published, delivered or dead")
+	cmd.Flags().StringSliceVar(&topics, "topic", nil, "Only rows on these topics")
+	cmd.Flags().StringVar(&since, "since", "", "Only rows enqueued at or after this time, RFC 3339")
+	cmd.Flags().StringVar(&until, "until", "", "Only rows enqueued before this time, RFC 3339")
+	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum rows listed (0 for all)")
+	addDatabaseFlags(cmd, &cfg)
+	return cmd
+}
+
+// OutboxShowCmd prints an outbox row and its decoded event.
+func OutboxShowCmd() *cobra.Command {
+	var cfg serviceConfig
+	cmd := &cobra.Command{
+		Use:          "show <event-id>",
+		Short:        "Show an outbox row and its decoded event, as JSON",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return err
+			}
+			e, err := orders.InspectOutboxEvent(cmd.Context(), db, args[0])
+			if err != nil {
+				return err
+			}
+			return writeJSON(cmd.OutOrStdout(), e)
+		},
+	}
+	addDatabaseFlags(cmd, &cfg)
+	return cmd
+}
+
+// OutboxResetCmd republishes or redelivers outbox events, as POST
+// /v1/outbox/reset does, recording the reset in the outbox audit log.
+func OutboxResetCmd() *cobra.Command {
+	var (
+		req   model.OutboxResetRequest
+		actor string
+		cfg   serviceConfig
+	)
+	cmd := &cobra.Command{
+		Use:          "reset",
+		Short:        "Clear published_at (republish) or delivered_at on outbox events",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return err
+			}
+			entry, err := orders.ResetOutboxEvents(cmd.Context(), db, actor, &req)
+			if err != nil {
+				return err
+			}
+			return writeJSON(cmd.OutOrStdout(), entry)
+		},
+	}
+	cmd.Flags().StringSliceVar(&req.EventIDs, "event-id", nil, "IDs of the events to reset")
+	cmd.Flags().BoolVar(&req.Published, "published", false, "Clear published_at, so the relay publishes the events again")
+	cmd.Flags().BoolVar(&req.Delivered, "delivered", false, "Clear delivered_at, marking the events undelivered")
+	cmd.Flags().StringVar(&req.Reason, "reason", "", "Why, for the audit log")
+	cmd.Flags().StringVar(&actor, "actor", defaultActor(), "Who is resetting, for the audit log")
+	addDatabaseFlags(cmd, &cfg)
+	if err := cmd.MarkFlagRequired("event-id"); err != nil {
+		panic(err)
+	}
+	return cmd
+}
+
+// OutboxAuditCmd prints the outbox audit log.
+func OutboxAuditCmd() *cobra.Command {
+	var (
+		limit int
+		cfg   serviceConfig
+	)
+	cmd := &cobra.Command{
+		Use:          "audit",
+		Short:        "Show the latest outbox resets, newest first, as JSON",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return err
+			}
+			entries, err := db.GetOutboxAudit(cmd.Context(), limit)
+			if err != nil {
+				return fmt.Errorf("could not get outbox audit log: %w", err)
+			}
+			return writeJSON(cmd.OutOrStdout(), entries)
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum entries shown (0 for all)")
+	addDatabaseFlags(cmd, &cfg)
+	return cmd
+}
+
+// addDatabaseFlags registers the flags selecting the database an offline
+// command works on.
+func addDatabaseFlags(cmd *cobra.Command, cfg *serviceConfig) {
+	cmd.Flags().StringVar(&cfg.sqliteDBPath, FlagSQLite, "data/db", "Path to SQLite database file")
+	cmd.Flags().StringVar(&cfg.dbHost, FlagDBHost, "", "Database host (for non-SQLite databases)")
+	cmd.Flags().StringVar(&cfg.dbUser, FlagDBUser, "", "Database user (for non-SQLite databases)")
+	cmd.Flags().StringVar(&cfg.dbPassword, FlagDBPassword, "", "Database password (for non-SQLite databases)")
+	cmd.Flags().IntVar(&cfg.dbPort, FlagDBPort, DefaultDBPort, "Database port (for non-SQLite databases)")
+}
+
+// defaultActor names the operator running a command: the login user.
+func defaultActor() string {
+	if u := os.Getenv("USER"); u != "" {
+		return u
+	}
+	return "cli"
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,74 @@
+//go:build !integration
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/event"
+	"github.com/ATMackay/checkout/model"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OutboxPurge(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db")
+	db, err := database.NewSQLiteDB(path, false)
+	require.NoError(t, err)
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	require.NoError(t, db.AddOutboxItems(ctx, []*model.OutboxItem{
+		{EventID: "done", Topic: "t", CreatedAt: old},
+		{EventID: "pending", Topic: "t", CreatedAt: old},
+	}))
+	require.NoError(t, db.SetPublishedAt(ctx, 1, old))
+	require.NoError(t, db.SetDeliveredAt(ctx, 1, old))
+
+	var out bytes.Buffer
+	c := NewCheckoutCmd()
+	c.SetOut(&out)
+	c.SetArgs([]string{"outbox", "purge", "--older-than", "24h", "--sqlite", path})
+	require.NoError(t, c.Execute())
+	require.Equal(t, "deleted 1 outbox rows\n", out.String())
+
+	left, err := db.GetOutboxItems(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, left, 1)
+	require.Equal(t, "pending", left[0].EventID)
+}
+
+func Test_OutboxReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db")
+	db, err := database.NewSQLiteDB(path, false)
+	require.NoError(t, err)
+	ev := event.New(event.TopicOrderCreated, "ref-1", map[string]string{"reference": "ref-1"})
+	data, err := ev.Encode()
+	require.NoError(t, err)
+	require.NoError(t, db.AddOutboxItems(ctx, []*model.OutboxItem{{EventID: ev.ID, Topic: ev.Topic, PartitionKey: ev.Key, Data: data}}))
+	now := time.Now().UTC()
+	require.NoError(t, db.SetPublishedAt(ctx, 1, now))
+	require.NoError(t, db.SetDeliveredAt(ctx, 1, now))
+
+	run := func(args ...string) string {
+		var out bytes.Buffer
+		c := NewCheckoutCmd()
+		c.SetOut(&out)
+		c.SetArgs(append(append([]string{"outbox"}, args...), "--sqlite", path))
+		require.NoError(t, c.Execute())
+		return out.String()
+	}
+
+	require.Contains(t, run("list", "--state", "delivered", "--topic", event.TopicOrderCreated), ev.ID)
+	require.Equal(t, "[]\n", run("list", "--state", "pending"))
+	require.Contains(t, run("show", ev.ID), `"reference": "ref-1"`)
+
+	out := run("reset", "--event-id", ev.ID, "--published", "--actor", "ops", "--reason", "lost notifications")
+	require.Contains(t, out, `"affected": 1`)
+	require.Contains(t, run("list", "--state", "pending"), ev.ID)
+	require.Contains(t, run("audit"), `"reason": "lost notifications"`)
+}
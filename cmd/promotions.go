@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/promotions"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+// PromotionsCmd groups offline tools for working with promotions.
+func PromotionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "promotions",
+		Short: "Offline tools for checkout promotions",
+		RunE:  runHelp,
+	}
+	cmd.AddCommand(SimulateCmd())
+	return cmd
+}
+
+// simulatedBasket is the --basket file: the SKUs bought, one entry per unit,
+// and optionally who buys them.
+type simulatedBasket struct {
+	SKUs []string `json:"skus"`
+	// CustomerID, when set, loads that customer's order history from the
+	// database.
+	CustomerID string `json:"customer_id,omitempty"`
+	// Orders, when set, is the number of previous orders the customer has
+	// placed, overriding any loaded history.
+	Orders *int `json:"orders,omitempty"`
+}
+
+// SimulateCmd prices a basket with the orders service's promotions engine,
+// without a running service, and explains the result line by line.
+//
+// Items come from a JSON snapshot (as GET /v1/inventory/items returns) or the
+// database; stored promotions come from the database. Pricing bugs reported
+// from production can so be replayed against the same catalog, rules and time.
+func SimulateCmd() *cobra.Command {
+	var (
+		basketPath     string
+		itemsPath      string
+		promotionsPath string
+		at             string
+		cfg            serviceConfig
+	)
+	cmd := &cobra.Command{
+		Use:          "simulate",
+		Short:        "Explain which promotions a basket gets, and why others do not apply",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			basket, err := readBasket(basketPath)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			if at != "" {
+				if now, err = time.Parse(time.RFC3339, at); err != nil {
+					return fmt.Errorf("invalid --at %q, want RFC 3339: %w", at, err)
+				}
+			}
+			if itemsPath != "" {
+				cfg.useMemoryDB = true
+			}
+			db, err := openDatabase(cfg)
+			if err != nil {
+				return err
+			}
+			if itemsPath != "" {
+				if err := loadItems(cmd.Context(), db, itemsPath); err != nil {
+					return err
+				}
+			}
+			var extra []promotions.Promotion
+			if promotionsPath != "" {
+				rules, err := promotions.LoadRuleFile(promotionsPath, db)
+				if err != nil {
+					return fmt.Errorf("could not load promotions: %w", err)
+				}
+				for _, r := range rules {
+					extra = append(extra, r)
+				}
+			}
+			return simulate(cmd.Context(), cmd.OutOrStdout(), db, basket, extra, now)
+		},
+	}
+	cmd.Flags().StringVar(&basketPath, "basket", "", "JSON basket to price: {\"skus\": [...], \"customer_id\": \"...\", \"orders\": n}")
+	cmd.Flags().StringVar(&itemsPath, "items", "", "Optional JSON item snapshot to price against instead of the database")
+	cmd.Flags().StringVar(&promotionsPath, "promotions-file", "", "Optional YAML promotion rule file, as the orders service takes")
+	cmd.Flags().StringVar(&at, "at", "", "Time to price at, RFC 3339 (default now)")
+	cmd.Flags().StringVar(&cfg.sqliteDBPath, FlagSQLite, "data/db", "Path to SQLite database file")
+	cmd.Flags().StringVar(&cfg.dbHost, FlagDBHost, "", "Database host (for non-SQLite databases)")
+	cmd.Flags().StringVar(&cfg.dbUser, FlagDBUser, "", "Database user (for non-SQLite databases)")
+	cmd.Flags().StringVar(&cfg.dbPassword, FlagDBPassword, "", "Database password (for non-SQLite databases)")
+	cmd.Flags().IntVar(&cfg.dbPort, FlagDBPort, DefaultDBPort, "Database port (for non-SQLite databases)")
+	if err := cmd.MarkFlagRequired("basket"); err != nil {
+		panic(err)
+	}
+	return cmd
+}
+
+func readBasket(path string) (*simulatedBasket, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read basket: %w", err)
+	}
+	var basket simulatedBasket
+	if err := json.Unmarshal(b, &basket); err != nil {
+		return nil, fmt.Errorf("could not parse basket %s: %w", path, err)
+	}
+	if len(basket.SKUs) == 0 {
+		return nil, fmt.Errorf("basket %s has no skus", path)
+	}
+	return &basket, nil
+}
+
+// loadItems stores the item snapshot at path in db.
+func loadItems(ctx context.Context, db database.Database, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read items: %w", err)
+	}
+	var items []*model.Item
+	if err := json.Unmarshal(b, &items); err != nil {
+		return fmt.Errorf("could not parse items %s: %w", path, err)
+	}
+	if _, err := db.UpsertItems(ctx, items); err != nil {
+		return fmt.Errorf("could not load items: %w", err)
+	}
+	return nil
+}
+
+// simulate prices basket as the orders service would at now, with its
+// built-in and stored promotions plus extra, and writes the explanation to w.
+func simulate(ctx context.Context, w io.Writer, db database.Database, b *simulatedBasket, extra []promotions.Promotion, now time.Time) error {
+	var skus []string
+	for _, sku := range b.SKUs {
+		if !slices.Contains(skus, sku) {
+			skus = append(skus, sku)
+		}
+	}
+	items, err := db.GetItemsBySKU(ctx, skus)
+	if err != nil {
+		return fmt.Errorf("could not get items: %w", err)
+	}
+	bySKU := make(map[string]*model.Item, len(items))
+	for _, it := range items {
+		bySKU[it.SKU] = it
+	}
+	units := make([]*model.Item, len(b.SKUs))
+	for i, sku := range b.SKUs {
+		if units[i] = bySKU[sku]; units[i] == nil {
+			return fmt.Errorf("basket item %s is not in the catalog", sku)
+		}
+	}
+	basket := promotions.NewBasket(units)
+	if b.CustomerID != "" || b.Orders != nil {
+		basket.Customer = promotions.NewCustomer(b.CustomerID, func(ctx context.Context) (*model.OrderSummary, error) {
+			if b.Orders != nil {
+				return &model.OrderSummary{Orders: *b.Orders}, nil
+			}
+			return db.GetOrderSummary(ctx, b.CustomerID)
+		})
+	}
+
+	// The same promotions, in the same order, as orders.NewService.
+	rules := promotions.NewStoreRules(db, db)
+	rules.SetClock(func() time.Time { return now })
+	engine := promotions.NewPromotionsEngine(append(append(promotions.Builtin(db), rules), extra...)...)
+	engine.SetClock(func() time.Time { return now })
+	engine.SetOptimizer(promotions.NewOptimizer())
+	applied, decisions, err := engine.Explain(ctx, basket)
+	if err != nil {
+		return fmt.Errorf("could not apply promotions: %w", err)
+	}
+
+	fmt.Fprintf(w, "basket at %s\n", now.Format(time.RFC3339))
+	subtotal := decimal.Zero
+	for _, sku := range skus {
+		it := bySKU[sku]
+		n := int64(countOf(b.SKUs, sku))
+		line := it.Price.Mul(decimal.NewFromInt(n))
+		subtotal = subtotal.Add(line)
+		fmt.Fprintf(w, "  %d x %s %s @ %s = %s\n", n, it.SKU, it.Name, it.Price.StringFixed(2), line.StringFixed(2))
+	}
+	fmt.Fprintln(w, "promotions")
+	for _, d := range decisions {
+		if d.Result == nil {
+			fmt.Fprintf(w, "  skipped  %s: %s\n", d.PromotionID, d.Reason)
+			continue
+		}
+		fmt.Fprintf(w, "  applied  %s: -%.2f\n", d.PromotionID, d.Result.Deduction)
+		for _, a := range d.Result.Adjustments {
+			fmt.Fprintf(w, "    %s\n", a.Reason)
+			for _, l := range a.Lines {
+				fmt.Fprintf(w, "      %d x %s: -%.2f\n", l.Quantity, l.SKU, l.Amount)
+			}
+			for _, sku := range a.AddedSKUs {
+				fmt.Fprintf(w, "      free %s\n", sku)
+			}
+		}
+	}
+	discount := decimal.NewFromFloat(applied.Deduction)
+	fmt.Fprintf(w, "subtotal %s\n", subtotal.StringFixed(2))
+	fmt.Fprintf(w, "discount -%s\n", discount.StringFixed(2))
+	fmt.Fprintf(w, "total    %s\n", subtotal.Sub(discount).StringFixed(2))
+	return nil
+}
+
+func countOf(skus []string, sku string) int {
+	n := 0
+	for _, s := range skus {
+		if s == sku {
+			n++
+		}
+	}
+	return n
+}
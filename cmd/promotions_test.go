@@ -0,0 +1,50 @@
+//go:build !integration
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SimulatePromotions(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	items := write("items.json", `[
+		{"name": "Google TV", "sku": "120P90", "price": "49.99", "inventory_quantity": 10},
+		{"name": "Alexa Speaker", "sku": "A304SD", "price": "109.50", "inventory_quantity": 10}
+	]`)
+	basket := write("basket.json", `{"skus": ["120P90", "120P90", "120P90", "A304SD"], "orders": 0}`)
+	rules := write("rules.yaml", `promotions:
+  - name: Weekend speakers
+    when: { sku: A304SD }
+    then: { percent_off: 20 }
+    schedule: { days: "sat,sun" }
+  - name: Welcome back
+    when: { sku: A304SD, min_orders: 1 }
+    then: { percent_off: 5 }
+`)
+
+	var out bytes.Buffer
+	c := NewCheckoutCmd()
+	c.SetOut(&out)
+	c.SetArgs([]string{"promotions", "simulate", "--basket", basket, "--items", items,
+		"--promotions-file", rules, "--at", "2024-05-06T12:00:00Z"})
+	require.NoError(t, c.Execute())
+
+	got := out.String()
+	require.Contains(t, got, "3 x 120P90 Google TV @ 49.99 = 149.97")
+	require.Contains(t, got, "applied  builtin:google-tv: -49.99")
+	require.Contains(t, got, "skipped  builtin:alexa-speaker: its conditions are not met by this basket")
+	require.Contains(t, got, "skipped  file:Welcome back: customer does not qualify (needs 1 previous orders)")
+	require.Contains(t, got, "skipped  file:Weekend speakers: outside its schedule at 2024-05-06T12:00:00Z")
+	require.Contains(t, got, "total    209.48")
+}
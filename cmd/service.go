@@ -24,6 +24,10 @@ import (
 // to under simple password auth, until per-user token auth (JWT) lands.
 const DefaultUserID = "default-user"
 
+// DefaultAdminID is the identity the admin password resolves to, recorded as
+// the actor of audited admin actions.
+const DefaultAdminID = "admin"
+
 // serviceConfig is the wiring config shared by every `run <service>` command.
 type serviceConfig struct {
 	port           int
@@ -103,6 +107,17 @@ func newAuthenticator(cfg serviceConfig) auth.Authenticator {
 	return auth.NewPasswordAuthenticator(map[string]string{cfg.authPassword: DefaultUserID})
 }
 
+// newAdminAuthenticator builds the authenticator for the orders admin routes:
+// the admin password maps to DefaultAdminID. With no admin password it admits
+// no one.
+func newAdminAuthenticator(password string) auth.Authenticator {
+	if password == "" {
+		slog.Warn("no admin password set: admin endpoints are disabled", "flag", FlagAdminPassword)
+		return auth.NewPasswordAuthenticator(nil)
+	}
+	return auth.NewPasswordAuthenticator(map[string]string{password: DefaultAdminID})
+}
+
 // openPublisher builds the event publisher: a no-op client when no broker is
 // configured (events are opt-in), otherwise a Kafka client.
 func openPublisher(cfg serviceConfig) (messaging.Publisher, error) {
@@ -4,19 +4,29 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
-//go:generate mockgen -destination ./mock/database_mock.go -package mock github.com/ATMackay/checkout/database Database,HealthChecker,InventoryStore,OrderStore,OutboxStore
+//go:generate mockgen -destination ./mock/database_mock.go -package mock github.com/ATMackay/checkout/database Database,HealthChecker,InventoryStore,OrderStore,RefundStore,GiftCardStore,LoyaltyStore,LimitStore,BundleStore,PromotionStore,CouponStore,OutboxStore
 type Database interface {
 	HealthChecker
 	InventoryStore
 	OrderStore
+	RefundStore
+	GiftCardStore
+	LoyaltyStore
+	LimitStore
+	BundleStore
+	PromotionStore
+	CouponStore
 	OutboxStore
 	Transaction(ctx context.Context, fn func(Database) error) error
 }
@@ -29,6 +39,11 @@ var _ Database = (*GormDB)(nil)
 
 type GormDB struct {
 	db *gorm.DB
+	// wake signals this process's outbox watchers where Postgres
+	// LISTEN/NOTIFY is not available. Inside a transaction, outboxAdded
+	// defers the signal until the outermost transaction commits.
+	wake        *wakeup
+	outboxAdded *bool
 }
 
 func NewGormDB(d gorm.Dialector, recreateSchema bool) (*GormDB, error) {
@@ -55,10 +70,31 @@ func newStorage(db *gorm.DB) (*GormDB, error) {
 	if err := db.AutoMigrate(&model.Order{}); err != nil {
 		return nil, fmt.Errorf("failed to auto migrate orders table: %w", err)
 	}
-	if err := db.AutoMigrate(&model.OutboxItem{}); err != nil {
-		return nil, fmt.Errorf("failed to auto migrate outbox table: %w", err)
+	if err := db.AutoMigrate(&model.Refund{}); err != nil {
+		return nil, fmt.Errorf("failed to auto migrate refunds table: %w", err)
 	}
-	return &GormDB{db}, nil
+	if err := db.AutoMigrate(&model.GiftCard{}, &model.GiftCardEntry{}); err != nil {
+		return nil, fmt.Errorf("failed to auto migrate gift card tables: %w", err)
+	}
+	if err := db.AutoMigrate(&model.LoyaltyAccount{}, &model.LoyaltyEntry{}); err != nil {
+		return nil, fmt.Errorf("failed to auto migrate loyalty tables: %w", err)
+	}
+	if err := db.AutoMigrate(&model.PurchaseLimit{}, &model.PurchaseLimitLock{}); err != nil {
+		return nil, fmt.Errorf("failed to auto migrate purchase limit tables: %w", err)
+	}
+	if err := db.AutoMigrate(&model.Bundle{}); err != nil {
+		return nil, fmt.Errorf("failed to auto migrate bundles table: %w", err)
+	}
+	if err := db.AutoMigrate(&model.PromotionRule{}); err != nil {
+		return nil, fmt.Errorf("failed to auto migrate promotions table: %w", err)
+	}
+	if err := db.AutoMigrate(&model.Coupon{}, &model.CouponRedemption{}); err != nil {
+		return nil, fmt.Errorf("failed to auto migrate coupon tables: %w", err)
+	}
+	if err := db.AutoMigrate(&model.OutboxItem{}, &model.ArchivedOutboxItem{}, &model.OutboxAuditEntry{}); err != nil {
+		return nil, fmt.Errorf("failed to auto migrate outbox tables: %w", err)
+	}
+	return &GormDB{db: db, wake: newWakeup()}, nil
 }
 
 func deleteStorage(db *gorm.DB) error {
@@ -68,8 +104,29 @@ func deleteStorage(db *gorm.DB) error {
 	if err := db.Migrator().DropTable(&model.Order{}); err != nil {
 		return fmt.Errorf("failed to drop table orders: %w", err)
 	}
-	if err := db.Migrator().DropTable(&model.OutboxItem{}); err != nil {
-		return fmt.Errorf("failed to drop table outbox: %w", err)
+	if err := db.Migrator().DropTable(&model.Refund{}); err != nil {
+		return fmt.Errorf("failed to drop table refunds: %w", err)
+	}
+	if err := db.Migrator().DropTable(&model.GiftCard{}, &model.GiftCardEntry{}); err != nil {
+		return fmt.Errorf("failed to drop gift card tables: %w", err)
+	}
+	if err := db.Migrator().DropTable(&model.LoyaltyAccount{}, &model.LoyaltyEntry{}); err != nil {
+		return fmt.Errorf("failed to drop loyalty tables: %w", err)
+	}
+	if err := db.Migrator().DropTable(&model.PurchaseLimit{}, &model.PurchaseLimitLock{}); err != nil {
+		return fmt.Errorf("failed to drop purchase limit tables: %w", err)
+	}
+	if err := db.Migrator().DropTable(&model.Bundle{}); err != nil {
+		return fmt.Errorf("failed to drop table bundles: %w", err)
+	}
+	if err := db.Migrator().DropTable(&model.PromotionRule{}); err != nil {
+		return fmt.Errorf("failed to drop table promotions: %w", err)
+	}
+	if err := db.Migrator().DropTable(&model.Coupon{}, &model.CouponRedemption{}); err != nil {
+		return fmt.Errorf("failed to drop coupon tables: %w", err)
+	}
+	if err := db.Migrator().DropTable(&model.OutboxItem{}, &model.ArchivedOutboxItem{}, &model.OutboxAuditEntry{}); err != nil {
+		return fmt.Errorf("failed to drop outbox tables: %w", err)
 	}
 	return nil
 }
@@ -143,6 +200,23 @@ func (g *GormDB) UpsertItems(ctx context.Context, items []*model.Item) ([]*model
 	return items, nil
 }
 
+// ErrItemNotFound is returned when a strict inventory update matches no row.
+var ErrItemNotFound = errors.New("item not found")
+
+func (g *GormDB) AdjustInventory(ctx context.Context, sku string, delta int) error {
+	res := g.db.WithContext(ctx).
+		Model(&model.Item{}).
+		Where("sku = ?", sku).
+		Update("inventory_quantity", gorm.Expr("inventory_quantity + ?", delta))
+	if res.Error != nil {
+		return fmt.Errorf("adjust inventory for item %s: %w", sku, res.Error)
+	}
+	if res.RowsAffected != 1 {
+		return fmt.Errorf("adjust inventory for item %s: %w", sku, ErrItemNotFound)
+	}
+	return nil
+}
+
 // OrderStore Implementation
 
 func (g *GormDB) AddOrder(ctx context.Context, o *model.Order) error {
@@ -160,16 +234,638 @@ func (g *GormDB) GetOrders(ctx context.Context, customerID string) ([]*model.Ord
 	return os, nil
 }
 
+func (g *GormDB) GetOrdersSince(ctx context.Context, customerID string, since time.Time) ([]*model.Order, error) {
+	var os []*model.Order
+	if err := g.db.WithContext(ctx).Order("id DESC").Where("customer_id = ? AND created_at >= ?", customerID, since).Find(&os).Error; err != nil {
+		return nil, err
+	}
+	return os, nil
+}
+
+func (g *GormDB) GetOrderSummary(ctx context.Context, customerID string) (*model.OrderSummary, error) {
+	orders := func() *gorm.DB {
+		return g.db.WithContext(ctx).Model(&model.Order{}).Where("customer_id = ?", customerID)
+	}
+	var n int64
+	if err := orders().Count(&n).Error; err != nil {
+		return nil, err
+	}
+	summary := &model.OrderSummary{Orders: int(n)}
+	if n == 0 {
+		return summary, nil
+	}
+	var first, last model.Order
+	if err := orders().Select("created_at").Order("created_at ASC").Limit(1).Find(&first).Error; err != nil {
+		return nil, err
+	}
+	if err := orders().Select("created_at").Order("created_at DESC").Limit(1).Find(&last).Error; err != nil {
+		return nil, err
+	}
+	summary.FirstOrderAt, summary.LastOrderAt = &first.CreatedAt, &last.CreatedAt
+	return summary, nil
+}
+
+// ErrOrderNotFound is returned when an order lookup or strict update matches no
+// row.
+var ErrOrderNotFound = errors.New("order not found")
+
+func (g *GormDB) GetOrderByReference(ctx context.Context, reference string) (*model.Order, error) {
+	return getOrder(g.db.WithContext(ctx), reference)
+}
+
+// GetOrderForUpdate locks the row with SELECT ... FOR UPDATE on Postgres.
+// SQLite has no row locks and needs none: a write transaction holds the
+// database-wide write lock, so writers already serialize.
+func (g *GormDB) GetOrderForUpdate(ctx context.Context, reference string) (*model.Order, error) {
+	return getOrder(forUpdate(g.db.WithContext(ctx)), reference)
+}
+
+// forUpdate adds FOR UPDATE to db's next query on dialects with row locks.
+func forUpdate(db *gorm.DB) *gorm.DB {
+	if db.Dialector.Name() == "postgres" {
+		return db.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	return db
+}
+
+func getOrder(db *gorm.DB, reference string) (*model.Order, error) {
+	var o model.Order
+	res := db.Where("reference = ?", reference).Limit(1).Find(&o)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("order %s: %w", reference, ErrOrderNotFound)
+	}
+	return &o, nil
+}
+
+func (g *GormDB) SetPaymentStatus(ctx context.Context, reference string, status model.PaymentStatus) error {
+	res := g.db.WithContext(ctx).
+		Model(&model.Order{}).
+		Where("reference = ?", reference).
+		Update("payment_status", status)
+	if res.Error != nil {
+		return fmt.Errorf("set payment_status for order %s: %w", reference, res.Error)
+	}
+	if res.RowsAffected != 1 {
+		return fmt.Errorf("set payment_status for order %s: %w", reference, ErrOrderNotFound)
+	}
+	return nil
+}
+
+// RefundStore Implementation
+
+// ErrRefundNotFound is returned when a strict refund update matches no row.
+var ErrRefundNotFound = errors.New("refund not found")
+
+func (g *GormDB) AddRefund(ctx context.Context, r *model.Refund) error {
+	return g.db.WithContext(ctx).Create(r).Error
+}
+
+func (g *GormDB) GetRefunds(ctx context.Context, orderReference string) ([]*model.Refund, error) {
+	var rs []*model.Refund
+	if err := g.db.WithContext(ctx).Order("id ASC").Where("order_reference = ?", orderReference).Find(&rs).Error; err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func (g *GormDB) SetRefundStatus(ctx context.Context, reference string, status model.RefundStatus) error {
+	res := g.db.WithContext(ctx).
+		Model(&model.Refund{}).
+		Where("reference = ?", reference).
+		Update("status", status)
+	if res.Error != nil {
+		return fmt.Errorf("set status for refund %s: %w", reference, res.Error)
+	}
+	if res.RowsAffected != 1 {
+		return fmt.Errorf("set status for refund %s: %w", reference, ErrRefundNotFound)
+	}
+	return nil
+}
+
+// LimitStore Implementation
+
+// ErrLimitNotFound is returned when no purchase limit is set for a SKU.
+var ErrLimitNotFound = errors.New("purchase limit not found")
+
+func (g *GormDB) SetPurchaseLimit(ctx context.Context, l *model.PurchaseLimit) error {
+	return g.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "sku"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_per_order", "max_per_window", "window_seconds"}),
+	}).Create(l).Error
+}
+
+func (g *GormDB) ListPurchaseLimits(ctx context.Context) ([]*model.PurchaseLimit, error) {
+	var ls []*model.PurchaseLimit
+	if err := g.db.WithContext(ctx).Order("sku ASC").Find(&ls).Error; err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+func (g *GormDB) GetPurchaseLimits(ctx context.Context, skus []string) ([]*model.PurchaseLimit, error) {
+	var ls []*model.PurchaseLimit
+	if err := g.db.WithContext(ctx).Where("sku IN ?", skus).Find(&ls).Error; err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+func (g *GormDB) DeletePurchaseLimit(ctx context.Context, sku string) error {
+	res := g.db.WithContext(ctx).Where("sku = ?", sku).Delete(&model.PurchaseLimit{})
+	if res.Error != nil {
+		return fmt.Errorf("delete purchase limit for item %s: %w", sku, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("purchase limit for item %s: %w", sku, ErrLimitNotFound)
+	}
+	return nil
+}
+
+func (g *GormDB) LockPurchaseLimits(ctx context.Context, customerID string) error {
+	db := g.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.PurchaseLimitLock{CustomerID: customerID}).Error; err != nil {
+		return fmt.Errorf("lock purchase limits for customer %s: %w", customerID, err)
+	}
+	// The increment takes the row lock (the SQLite write lock) and holds it
+	// until the surrounding transaction commits.
+	if err := db.Model(&model.PurchaseLimitLock{}).Where("customer_id = ?", customerID).
+		Update("purchases", gorm.Expr("purchases + 1")).Error; err != nil {
+		return fmt.Errorf("lock purchase limits for customer %s: %w", customerID, err)
+	}
+	return nil
+}
+
+// BundleStore Implementation
+
+// ErrBundleNotFound is returned when no bundle has the given code.
+var ErrBundleNotFound = errors.New("bundle not found")
+
+func (g *GormDB) PutBundle(ctx context.Context, b *model.Bundle) error {
+	return g.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "skus", "price", "currency", "active", "updated_at"}),
+	}).Create(b).Error
+}
+
+func (g *GormDB) GetBundle(ctx context.Context, code string) (*model.Bundle, error) {
+	var b model.Bundle
+	res := g.db.WithContext(ctx).Where("code = ?", code).Limit(1).Find(&b)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("bundle %s: %w", code, ErrBundleNotFound)
+	}
+	return &b, nil
+}
+
+func (g *GormDB) ListBundles(ctx context.Context, activeOnly bool) ([]*model.Bundle, error) {
+	var bs []*model.Bundle
+	q := g.db.WithContext(ctx).Order("code ASC")
+	if activeOnly {
+		q = q.Where("active = ?", true)
+	}
+	if err := q.Find(&bs).Error; err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+func (g *GormDB) DeleteBundle(ctx context.Context, code string) error {
+	res := g.db.WithContext(ctx).Where("code = ?", code).Delete(&model.Bundle{})
+	if res.Error != nil {
+		return fmt.Errorf("delete bundle %s: %w", code, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("bundle %s: %w", code, ErrBundleNotFound)
+	}
+	return nil
+}
+
+// PromotionStore Implementation
+
+var (
+	// ErrPromotionNotFound is returned when no promotion has the given ID.
+	ErrPromotionNotFound = errors.New("promotion not found")
+	// ErrPromotionInactive is returned when redeeming a promotion that has
+	// been deactivated, e.g. by a concurrent purchase exhausting its budget.
+	ErrPromotionInactive = errors.New("promotion no longer active")
+)
+
+func (g *GormDB) AddPromotion(ctx context.Context, p *model.PromotionRule) error {
+	return g.db.WithContext(ctx).Create(p).Error
+}
+
+func (g *GormDB) UpdatePromotion(ctx context.Context, p *model.PromotionRule) error {
+	// Select("*") so zero values (e.g. Active: false) are written too. Usage
+	// is only ever added to, by RedeemPromotion.
+	res := g.db.WithContext(ctx).Model(p).Select("*").
+		Omit("id", "created_at", "redemptions", "discount_granted", "units_granted").Updates(p)
+	if res.Error != nil {
+		return fmt.Errorf("update promotion %d: %w", p.ID, res.Error)
+	}
+	if res.RowsAffected != 1 {
+		return fmt.Errorf("update promotion %d: %w", p.ID, ErrPromotionNotFound)
+	}
+	return nil
+}
+
+func (g *GormDB) GetPromotion(ctx context.Context, id int) (*model.PromotionRule, error) {
+	return getPromotion(g.db.WithContext(ctx), id)
+}
+
+func getPromotion(db *gorm.DB, id int) (*model.PromotionRule, error) {
+	var p model.PromotionRule
+	res := db.Where("id = ?", id).Limit(1).Find(&p)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("promotion %d: %w", id, ErrPromotionNotFound)
+	}
+	return &p, nil
+}
+
+func (g *GormDB) ListPromotions(ctx context.Context, activeOnly bool) ([]*model.PromotionRule, error) {
+	var ps []*model.PromotionRule
+	q := g.db.WithContext(ctx).Order("id ASC")
+	if activeOnly {
+		q = q.Where("active = ?", true)
+	}
+	if err := q.Find(&ps).Error; err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+func (g *GormDB) DeletePromotion(ctx context.Context, id int) error {
+	res := g.db.WithContext(ctx).Delete(&model.PromotionRule{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("delete promotion %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("delete promotion %d: %w", id, ErrPromotionNotFound)
+	}
+	return nil
+}
+
+func (g *GormDB) RedeemPromotion(ctx context.Context, id int, discount decimal.Decimal, units int) (*model.PromotionRule, error) {
+	var p *model.PromotionRule
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// As with coupons, the conditional increment holds the row locked
+		// until commit, so concurrent redemptions serialize and exactly one
+		// of them sees the budget run out.
+		res := tx.Model(&model.PromotionRule{}).Where("id = ? AND active = ?", id, true).
+			Updates(map[string]any{
+				"redemptions":      gorm.Expr("redemptions + 1"),
+				"discount_granted": gorm.Expr("discount_granted + ?", discount),
+				"units_granted":    gorm.Expr("units_granted + ?", units),
+			})
+		if res.Error != nil {
+			return fmt.Errorf("redeem promotion %d: %w", id, res.Error)
+		}
+		if res.RowsAffected != 1 {
+			if _, err := getPromotion(tx, id); err != nil {
+				return err
+			}
+			return fmt.Errorf("promotion %d: %w", id, ErrPromotionInactive)
+		}
+		var err error
+		if p, err = getPromotion(tx, id); err != nil {
+			return err
+		}
+		if p.Exhausted(p.PromotionUsage) {
+			p.Active = false
+			if err := tx.Model(&model.PromotionRule{}).Where("id = ?", id).Update("active", false).Error; err != nil {
+				return fmt.Errorf("deactivate promotion %d: %w", id, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// CouponStore Implementation
+
+var (
+	// ErrCouponNotFound is returned when no coupon has the given code.
+	ErrCouponNotFound = errors.New("coupon not found")
+	// ErrCouponExpired is returned when redeeming an expired coupon.
+	ErrCouponExpired = errors.New("coupon expired")
+	// ErrCouponExhausted is returned when a coupon has reached its
+	// redemption limit.
+	ErrCouponExhausted = errors.New("coupon redemption limit reached")
+	// ErrCouponCustomerLimit is returned when a customer has reached a
+	// coupon's per-customer redemption limit.
+	ErrCouponCustomerLimit = errors.New("coupon already used the maximum number of times by this customer")
+)
+
+func (g *GormDB) AddCoupon(ctx context.Context, c *model.Coupon) error {
+	return g.db.WithContext(ctx).Create(c).Error
+}
+
+func (g *GormDB) GetCoupon(ctx context.Context, code string) (*model.Coupon, error) {
+	return getCoupon(g.db.WithContext(ctx), code)
+}
+
+func getCoupon(db *gorm.DB, code string) (*model.Coupon, error) {
+	var c model.Coupon
+	res := db.Where("code = ?", code).Limit(1).Find(&c)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("coupon %s: %w", code, ErrCouponNotFound)
+	}
+	return &c, nil
+}
+
+func (g *GormDB) ListCoupons(ctx context.Context) ([]*model.Coupon, error) {
+	var cs []*model.Coupon
+	if err := g.db.WithContext(ctx).Order("code ASC").Find(&cs).Error; err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func (g *GormDB) DeleteCoupon(ctx context.Context, code string) error {
+	res := g.db.WithContext(ctx).Where("code = ?", code).Delete(&model.Coupon{})
+	if res.Error != nil {
+		return fmt.Errorf("delete coupon %s: %w", code, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("delete coupon %s: %w", code, ErrCouponNotFound)
+	}
+	return nil
+}
+
+func (g *GormDB) CountCouponRedemptions(ctx context.Context, code, customerID string) (int, error) {
+	var n int64
+	err := g.db.WithContext(ctx).Model(&model.CouponRedemption{}).
+		Where("code = ? AND customer_id = ?", code, customerID).
+		Count(&n).Error
+	return int(n), err
+}
+
+func (g *GormDB) RedeemCoupon(ctx context.Context, r *model.CouponRedemption) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The conditional increment enforces the global limit and expiry in
+		// one statement, and holds the coupon row locked until commit, so
+		// concurrent redemptions of the same code serialize from here on and
+		// the per-customer count below cannot race.
+		now := time.Now().UTC()
+		res := tx.Model(&model.Coupon{}).Where("code = ?", r.Code).
+			Where("max_redemptions = 0 OR redemptions < max_redemptions").
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			Update("redemptions", gorm.Expr("redemptions + 1"))
+		if res.Error != nil {
+			return fmt.Errorf("redeem coupon %s: %w", r.Code, res.Error)
+		}
+		c, err := getCoupon(tx, r.Code)
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected != 1 {
+			// Nothing matched: say why.
+			if c.Expired(now) {
+				return fmt.Errorf("coupon %s: %w", r.Code, ErrCouponExpired)
+			}
+			return fmt.Errorf("coupon %s: %w", r.Code, ErrCouponExhausted)
+		}
+		if c.MaxPerCustomer > 0 {
+			var used int64
+			if err := tx.Model(&model.CouponRedemption{}).
+				Where("code = ? AND customer_id = ?", r.Code, r.CustomerID).
+				Count(&used).Error; err != nil {
+				return err
+			}
+			if int(used) >= c.MaxPerCustomer {
+				return fmt.Errorf("coupon %s: %w", r.Code, ErrCouponCustomerLimit)
+			}
+		}
+		return tx.Create(r).Error
+	})
+}
+
+// GiftCardStore Implementation
+
+var (
+	// ErrGiftCardNotFound is returned when no gift card has the given code.
+	ErrGiftCardNotFound = errors.New("gift card not found")
+	// ErrGiftCardExpired is returned when redeeming an expired gift card.
+	ErrGiftCardExpired = errors.New("gift card expired")
+	// ErrInsufficientBalance is returned when a redemption would overdraw a
+	// gift card.
+	ErrInsufficientBalance = errors.New("insufficient gift card balance")
+)
+
+func (g *GormDB) IssueGiftCard(ctx context.Context, card *model.GiftCard) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(card).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.GiftCardEntry{
+			Code:   card.Code,
+			Amount: card.Balance,
+			Reason: model.GiftCardIssued,
+		}).Error
+	})
+}
+
+func (g *GormDB) GetGiftCard(ctx context.Context, code string) (*model.GiftCard, error) {
+	return getGiftCard(g.db.WithContext(ctx), code)
+}
+
+func getGiftCard(db *gorm.DB, code string) (*model.GiftCard, error) {
+	var c model.GiftCard
+	res := db.Where("code = ?", code).Limit(1).Find(&c)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("gift card %s: %w", code, ErrGiftCardNotFound)
+	}
+	return &c, nil
+}
+
+func (g *GormDB) GetGiftCardLedger(ctx context.Context, code string) ([]model.GiftCardEntry, error) {
+	var es []model.GiftCardEntry
+	if err := g.db.WithContext(ctx).Order("id ASC").Where("code = ?", code).Find(&es).Error; err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+func (g *GormDB) AdjustGiftCard(ctx context.Context, entry *model.GiftCardEntry) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&model.GiftCard{}).Where("code = ?", entry.Code)
+		if entry.Reason == model.GiftCardRedeemed {
+			q = q.Where("balance >= ?", entry.Amount.Neg()).
+				Where("expires_at IS NULL OR expires_at > ?", time.Now().UTC())
+		}
+		res := q.Update("balance", gorm.Expr("balance + ?", entry.Amount))
+		if res.Error != nil {
+			return fmt.Errorf("adjust gift card %s: %w", entry.Code, res.Error)
+		}
+		if res.RowsAffected != 1 {
+			// Nothing matched: say why.
+			card, err := getGiftCard(tx, entry.Code)
+			if err != nil {
+				return err
+			}
+			if card.Expired(time.Now().UTC()) {
+				return fmt.Errorf("gift card %s: %w", entry.Code, ErrGiftCardExpired)
+			}
+			return fmt.Errorf("gift card %s: %w", entry.Code, ErrInsufficientBalance)
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+func (g *GormDB) ExpireGiftCard(ctx context.Context, code string, at time.Time) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		card, err := getGiftCard(forUpdate(tx), code)
+		if err != nil {
+			return err
+		}
+		expiresAt := at
+		if card.ExpiresAt != nil && card.ExpiresAt.Before(at) {
+			expiresAt = *card.ExpiresAt
+		}
+		if err := tx.Model(card).Updates(map[string]any{
+			"balance":    decimal.Zero,
+			"expires_at": expiresAt,
+		}).Error; err != nil {
+			return fmt.Errorf("expire gift card %s: %w", code, err)
+		}
+		if card.Balance.IsZero() {
+			return nil
+		}
+		return tx.Create(&model.GiftCardEntry{
+			Code:   code,
+			Amount: card.Balance.Neg(),
+			Reason: model.GiftCardExpired,
+		}).Error
+	})
+}
+
+// LoyaltyStore Implementation
+
+// ErrInsufficientPoints is returned when a redemption would overdraw a
+// loyalty points balance.
+var ErrInsufficientPoints = errors.New("insufficient loyalty points")
+
+func (g *GormDB) GetLoyaltyAccount(ctx context.Context, customerID string) (*model.LoyaltyAccount, error) {
+	a := model.LoyaltyAccount{CustomerID: customerID}
+	if err := g.db.WithContext(ctx).Where("customer_id = ?", customerID).Limit(1).Find(&a).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (g *GormDB) GetLoyaltyLedger(ctx context.Context, customerID string) ([]model.LoyaltyEntry, error) {
+	var es []model.LoyaltyEntry
+	if err := g.db.WithContext(ctx).Order("id ASC").Where("customer_id = ?", customerID).Find(&es).Error; err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+func (g *GormDB) AdjustLoyalty(ctx context.Context, entry *model.LoyaltyEntry) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The ledger entry goes first: if the order already has one for this
+		// reason, nothing else changes.
+		res := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "reason"}, {Name: "order_reference"}},
+			DoNothing: true,
+		}).Create(entry)
+		if res.Error != nil {
+			return fmt.Errorf("adjust loyalty points of %s: %w", entry.CustomerID, res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.LoyaltyAccount{CustomerID: entry.CustomerID}).Error; err != nil {
+			return fmt.Errorf("adjust loyalty points of %s: %w", entry.CustomerID, err)
+		}
+		q := tx.Model(&model.LoyaltyAccount{}).Where("customer_id = ?", entry.CustomerID)
+		if entry.Points < 0 {
+			q = q.Where("balance >= ?", -entry.Points)
+		}
+		res = q.Update("balance", gorm.Expr("balance + ?", entry.Points))
+		if res.Error != nil {
+			return fmt.Errorf("adjust loyalty points of %s: %w", entry.CustomerID, res.Error)
+		}
+		if res.RowsAffected != 1 {
+			return fmt.Errorf("customer %s: %w", entry.CustomerID, ErrInsufficientPoints)
+		}
+		return nil
+	})
+}
+
 // OutboxStore Implementation
 
-// ErrOutboxItemNotFound is returned when a strict update matches no row.
+// ErrOutboxItemNotFound is returned when a strict update or a lookup matches
+// no row.
 var ErrOutboxItemNotFound = errors.New("outbox item not found")
 
+// AddOutboxItems enqueues items and wakes the relays watching the outbox.
 func (g *GormDB) AddOutboxItems(ctx context.Context, items []*model.OutboxItem) error {
 	if len(items) == 0 {
 		return nil
 	}
-	return g.db.WithContext(ctx).Create(items).Error
+	return g.outboxWrite(ctx, func(tx *gorm.DB) error {
+		return tx.Create(items).Error
+	})
+}
+
+// outboxWrite runs fn, which adds or resets outbox rows, in a transaction and
+// wakes the outbox's watchers. On Postgres the NOTIFY is sent in the same
+// transaction, so it is delivered when, and only if, the rows are committed.
+// Elsewhere watchers in this process are signalled once the rows are
+// committed: inside a Transaction, when the outermost one commits.
+func (g *GormDB) outboxWrite(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if g.isPostgres() {
+			return tx.Exec("SELECT pg_notify(?, '')", outboxChannel).Error
+		}
+		return nil
+	})
+	if err != nil || g.isPostgres() {
+		return err
+	}
+	if g.outboxAdded != nil {
+		*g.outboxAdded = true
+	} else {
+		g.wake.notify()
+	}
+	return nil
+}
+
+func (g *GormDB) WatchOutbox(ctx context.Context) (<-chan struct{}, error) {
+	if !g.isPostgres() {
+		return g.wake.watch(ctx), nil
+	}
+	sqlDB, err := g.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return listen(ctx, sqlDB)
+}
+
+func (g *GormDB) isPostgres() bool {
+	return g.db.Dialector.Name() == "postgres"
 }
 
 func (g *GormDB) GetOutboxItems(ctx context.Context, q *OutboxQuery) ([]*model.OutboxItem, error) {
@@ -178,9 +874,33 @@ func (g *GormDB) GetOutboxItems(ctx context.Context, q *OutboxQuery) ([]*model.O
 		if q.OnlyUnpublished {
 			db = db.Where("published_at IS NULL")
 		}
+		if q.OnlyPublished {
+			db = db.Where("published_at IS NOT NULL")
+		}
 		if q.OnlyUndelivered {
 			db = db.Where("delivered_at IS NULL")
 		}
+		if q.OnlyDelivered {
+			db = db.Where("delivered_at IS NOT NULL")
+		}
+		if q.OnlyDead {
+			db = db.Where("dead_at IS NOT NULL")
+		}
+		if q.OnlyLive {
+			db = db.Where("dead_at IS NULL")
+		}
+		if len(q.Topics) > 0 {
+			db = db.Where("topic IN ?", q.Topics)
+		}
+		if len(q.EventIDs) > 0 {
+			db = db.Where("event_id IN ?", q.EventIDs)
+		}
+		if !q.CreatedAfter.IsZero() {
+			db = db.Where("created_at >= ?", q.CreatedAfter)
+		}
+		if !q.CreatedBefore.IsZero() {
+			db = db.Where("created_at < ?", q.CreatedBefore)
+		}
 		if q.Limit > 0 {
 			db = db.Limit(q.Limit)
 		}
@@ -193,10 +913,257 @@ func (g *GormDB) GetOutboxItems(ctx context.Context, q *OutboxQuery) ([]*model.O
 	return items, nil
 }
 
+func (g *GormDB) GetOutboxBacklog(ctx context.Context) (*model.OutboxBacklog, error) {
+	const pending = "published_at IS NULL AND dead_at IS NULL"
+	items := func() *gorm.DB { return g.db.WithContext(ctx).Model(&model.OutboxItem{}) }
+
+	var b model.OutboxBacklog
+	if err := items().Where(pending).Count(&b.Rows).Error; err != nil {
+		return nil, fmt.Errorf("count outbox backlog: %w", err)
+	}
+	if b.Rows > 0 {
+		var oldest model.OutboxItem
+		res := items().Select("created_at").Where(pending).Order("created_at ASC").Limit(1).Find(&oldest)
+		if res.Error != nil {
+			return nil, fmt.Errorf("find oldest outbox item: %w", res.Error)
+		}
+		if res.RowsAffected == 1 {
+			b.Oldest = &oldest.CreatedAt
+		}
+	}
+	if err := items().Where("dead_at IS NOT NULL").Count(&b.Dead).Error; err != nil {
+		return nil, fmt.Errorf("count dead outbox items: %w", err)
+	}
+	return &b, nil
+}
+
+func (g *GormDB) GetOutboxItem(ctx context.Context, eventID string) (*model.OutboxItem, error) {
+	var item model.OutboxItem
+	res := g.db.WithContext(ctx).Where("event_id = ?", eventID).Limit(1).Find(&item)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("event %s: %w", eventID, ErrOutboxItemNotFound)
+	}
+	return &item, nil
+}
+
+func (g *GormDB) ResetOutboxItems(ctx context.Context, entry *model.OutboxAuditEntry) error {
+	updates := map[string]any{}
+	if entry.Published {
+		updates["published_at"] = nil
+		updates["attempts"] = 0
+		updates["last_error"] = ""
+		updates["next_attempt_at"] = nil
+		updates["dead_at"] = nil
+		updates["claimed_by"] = ""
+		updates["claimed_until"] = nil
+	}
+	if entry.Delivered {
+		updates["delivered_at"] = nil
+	}
+	return g.outboxWrite(ctx, func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			res := tx.Model(&model.OutboxItem{}).Where("event_id IN ?", entry.EventIDs).Updates(updates)
+			if res.Error != nil {
+				return fmt.Errorf("reset outbox items: %w", res.Error)
+			}
+			entry.Affected = int(res.RowsAffected)
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return fmt.Errorf("record outbox audit entry: %w", err)
+		}
+		return nil
+	})
+}
+
+func (g *GormDB) GetOutboxAudit(ctx context.Context, limit int) ([]*model.OutboxAuditEntry, error) {
+	db := g.db.WithContext(ctx).Order("id DESC")
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	var entries []*model.OutboxAuditEntry
+	if err := db.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (g *GormDB) ClaimOutboxItems(ctx context.Context, owner string, lease time.Duration, limit int) ([]*model.OutboxItem, error) {
+	now := time.Now().UTC()
+	claimable := func(db *gorm.DB) *gorm.DB {
+		return db.Model(&model.OutboxItem{}).
+			Where("published_at IS NULL AND dead_at IS NULL").
+			Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+			Where("claimed_until IS NULL OR claimed_until < ?", now)
+	}
+	var items []*model.OutboxItem
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := claimable(tx).Order("id ASC")
+		if limit > 0 {
+			q = q.Limit(limit)
+		}
+		// On Postgres, rows another relay is claiming right now are skipped
+		// rather than waited for.
+		if g.isPostgres() {
+			q = q.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+		var ids []int64
+		if err := q.Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("claim outbox items: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		// Hold back rows queued behind an earlier live row with their key
+		// that this batch does not include: one backing off, leased to
+		// another relay or past the limit.
+		var blocked []int64
+		if err := tx.Table("outbox AS o").
+			Where("o.id IN ? AND o.partition_key <> ''", ids).
+			Where(`EXISTS (SELECT 1 FROM outbox AS e
+				WHERE e.topic = o.topic AND e.partition_key = o.partition_key AND e.id < o.id
+				AND e.published_at IS NULL AND e.dead_at IS NULL AND e.id NOT IN ?)`, ids).
+			Pluck("o.id", &blocked).Error; err != nil {
+			return fmt.Errorf("claim outbox items: %w", err)
+		}
+		ids = slices.DeleteFunc(ids, func(id int64) bool { return slices.Contains(blocked, id) })
+		if len(ids) == 0 {
+			return nil
+		}
+		// The update repeats the claim condition: without row locks (SQLite)
+		// a relay that read the same rows concurrently claims them first or
+		// not at all.
+		if err := claimable(tx).Where("id IN ?", ids).Updates(map[string]any{
+			"claimed_by":    owner,
+			"claimed_until": now.Add(lease),
+		}).Error; err != nil {
+			return fmt.Errorf("claim outbox items: %w", err)
+		}
+		return tx.Where("id IN ? AND claimed_by = ?", ids, owner).Order("id ASC").Find(&items).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (g *GormDB) FailOutboxItem(ctx context.Context, id int64, reason string, retryAt *time.Time) error {
+	updates := map[string]any{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      reason,
+		"next_attempt_at": retryAt,
+		"claimed_by":      "",
+		"claimed_until":   nil,
+	}
+	if retryAt == nil {
+		updates["dead_at"] = time.Now().UTC()
+	}
+	res := g.db.WithContext(ctx).Model(&model.OutboxItem{}).Where("id = ?", id).Updates(updates)
+	if res.Error != nil {
+		return fmt.Errorf("record failure of outbox item %d: %w", id, res.Error)
+	}
+	if res.RowsAffected != 1 {
+		return fmt.Errorf("record failure of outbox item %d: %w", id, ErrOutboxItemNotFound)
+	}
+	return nil
+}
+
+func (g *GormDB) ReleaseOutboxItems(ctx context.Context, owner string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := g.db.WithContext(ctx).
+		Model(&model.OutboxItem{}).
+		Where("id IN ? AND claimed_by = ?", ids, owner).
+		Updates(map[string]any{"claimed_by": "", "claimed_until": nil}).Error
+	if err != nil {
+		return fmt.Errorf("release %d outbox items: %w", len(ids), err)
+	}
+	return nil
+}
+
+func (g *GormDB) PurgeOutboxItems(ctx context.Context, before time.Time, limit int, archive bool) (int, error) {
+	var n int
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&model.OutboxItem{}).
+			Where("published_at IS NOT NULL AND delivered_at IS NOT NULL").
+			Where("created_at < ?", before).
+			Order("id ASC")
+		if limit > 0 {
+			q = q.Limit(limit)
+		}
+		var ids []int64
+		if err := q.Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if archive {
+			var items []*model.OutboxItem
+			if err := tx.Where("id IN ?", ids).Order("id ASC").Find(&items).Error; err != nil {
+				return err
+			}
+			now := time.Now().UTC()
+			archived := make([]*model.ArchivedOutboxItem, len(items))
+			for i, it := range items {
+				archived[i] = &model.ArchivedOutboxItem{OutboxItem: *it, ArchivedAt: now}
+			}
+			if err := tx.Create(archived).Error; err != nil {
+				return err
+			}
+		}
+		res := tx.Where("id IN ?", ids).Delete(&model.OutboxItem{})
+		if res.Error != nil {
+			return res.Error
+		}
+		n = int(res.RowsAffected)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("purge outbox items: %w", err)
+	}
+	return n, nil
+}
+
 func (g *GormDB) SetPublishedAt(ctx context.Context, id int64, t time.Time) error {
 	return g.setOutboxTimestamp(ctx, id, "published_at", t)
 }
 
+func (g *GormDB) SetPublishedAtBulk(ctx context.Context, ids []int64, t time.Time) error {
+	return g.setOutboxTimestampsBulk(ctx, ids, t, "published_at")
+}
+
+func (g *GormDB) SetDeliveredAtBulk(ctx context.Context, ids []int64, t time.Time) error {
+	return g.setOutboxTimestampsBulk(ctx, ids, t, "published_at", "delivered_at")
+}
+
+// setOutboxTimestampsBulk sets columns to t on the rows with the given IDs in
+// one statement, erroring with ErrOutboxItemNotFound if any ID matches no row.
+func (g *GormDB) setOutboxTimestampsBulk(ctx context.Context, ids []int64, t time.Time, columns ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	updates := make(map[string]any, len(columns))
+	for _, c := range columns {
+		updates[c] = t
+	}
+	res := g.db.WithContext(ctx).
+		Model(&model.OutboxItem{}).
+		Where("id IN ?", ids).
+		Updates(updates)
+	what := strings.Join(columns, ", ")
+	if res.Error != nil {
+		return fmt.Errorf("set %s for %d outbox items: %w", what, len(ids), res.Error)
+	}
+	if res.RowsAffected != int64(len(ids)) {
+		return fmt.Errorf("set %s for %d outbox items, %d found: %w", what, len(ids), res.RowsAffected, ErrOutboxItemNotFound)
+	}
+	return nil
+}
+
 func (g *GormDB) SetDeliveredAt(ctx context.Context, id int64, t time.Time) error {
 	return g.setOutboxTimestamp(ctx, id, "delivered_at", t)
 }
@@ -237,7 +1204,21 @@ func (g *GormDB) setOutboxTimestamp(ctx context.Context, id int64, column string
 }
 
 func (g *GormDB) Transaction(ctx context.Context, fn func(Database) error) error {
-	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		return fn(&GormDB{db: tx})
-	})
+	// A nested transaction is a savepoint: only the outermost one commits, so
+	// only it signals outbox watchers.
+	if g.outboxAdded != nil {
+		return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(&GormDB{db: tx, wake: g.wake, outboxAdded: g.outboxAdded})
+		})
+	}
+	var added bool
+	if err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&GormDB{db: tx, wake: g.wake, outboxAdded: &added})
+	}); err != nil {
+		return err
+	}
+	if added {
+		g.wake.notify()
+	}
+	return nil
 }
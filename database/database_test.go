@@ -3,9 +3,15 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,3 +32,582 @@ func Test_SQLite_Database(t *testing.T) {
 		require.NotNil(t, d)
 	})
 }
+
+// Redemptions are checked and debited in one statement, so a card cannot be
+// overdrawn and every balance change lands on the ledger.
+func Test_SQLite_GiftCards(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+
+	card := &model.GiftCard{Code: "CODE", Balance: decimal.RequireFromString("25.50"), Currency: "USD"}
+	require.NoError(t, d.IssueGiftCard(ctx, card))
+
+	redeem := func(amount string) error {
+		return d.AdjustGiftCard(ctx, &model.GiftCardEntry{Code: "CODE", Amount: decimal.RequireFromString(amount).Neg(), Reason: model.GiftCardRedeemed})
+	}
+	require.NoError(t, redeem("20.25"))
+	require.ErrorIs(t, redeem("5.26"), ErrInsufficientBalance)
+	require.NoError(t, redeem("5.25"))
+
+	got, err := d.GetGiftCard(ctx, "CODE")
+	require.NoError(t, err)
+	require.True(t, got.Balance.IsZero(), got.Balance.String())
+
+	ledger, err := d.GetGiftCardLedger(ctx, "CODE")
+	require.NoError(t, err)
+	require.Len(t, ledger, 3)
+	require.Equal(t, model.GiftCardIssued, ledger[0].Reason)
+
+	require.NoError(t, d.AdjustGiftCard(ctx, &model.GiftCardEntry{Code: "CODE", Amount: decimal.NewFromInt(10), Reason: model.GiftCardRefunded}))
+	require.NoError(t, d.ExpireGiftCard(ctx, "CODE", time.Now().UTC()))
+	require.ErrorIs(t, redeem("0.01"), ErrGiftCardExpired)
+	_, err = d.GetGiftCard(ctx, "NOPE")
+	require.ErrorIs(t, err, ErrGiftCardNotFound)
+}
+
+func Test_SQLite_Loyalty(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+
+	a, err := d.GetLoyaltyAccount(ctx, "alice")
+	require.NoError(t, err)
+	require.Zero(t, a.Balance)
+
+	earn := &model.LoyaltyEntry{CustomerID: "alice", Points: 120, Reason: model.LoyaltyEarned, OrderReference: "ref1"}
+	require.NoError(t, d.AdjustLoyalty(ctx, earn))
+	// A replayed earning is ignored.
+	require.NoError(t, d.AdjustLoyalty(ctx, &model.LoyaltyEntry{CustomerID: "alice", Points: 120, Reason: model.LoyaltyEarned, OrderReference: "ref1"}))
+
+	redeem := func(ref string, points int64) error {
+		return d.AdjustLoyalty(ctx, &model.LoyaltyEntry{CustomerID: "alice", Points: -points, Reason: model.LoyaltyRedeemed, OrderReference: ref})
+	}
+	require.ErrorIs(t, redeem("ref2", 121), ErrInsufficientPoints)
+	require.NoError(t, redeem("ref2", 100))
+	require.ErrorIs(t, redeem("ref3", 21), ErrInsufficientPoints)
+	require.ErrorIs(t, d.AdjustLoyalty(ctx, &model.LoyaltyEntry{CustomerID: "bob", Points: -1, Reason: model.LoyaltyRedeemed, OrderReference: "ref4"}), ErrInsufficientPoints)
+
+	a, err = d.GetLoyaltyAccount(ctx, "alice")
+	require.NoError(t, err)
+	require.EqualValues(t, 20, a.Balance)
+	// Refused redemptions leave no trace.
+	ledger, err := d.GetLoyaltyLedger(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, ledger, 2)
+	require.Equal(t, model.LoyaltyEarned, ledger[0].Reason)
+	require.EqualValues(t, -100, ledger[1].Points)
+}
+
+func Test_SQLite_Coupons(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+
+	require.NoError(t, d.AddCoupon(ctx, &model.Coupon{Code: "SAVE10", PromotionID: 1, MaxRedemptions: 3, MaxPerCustomer: 2}))
+	redeem := func(code, customer string) error {
+		return d.RedeemCoupon(ctx, &model.CouponRedemption{Code: code, CustomerID: customer, OrderReference: "ref"})
+	}
+	require.NoError(t, redeem("SAVE10", "alice"))
+	require.NoError(t, redeem("SAVE10", "alice"))
+	require.ErrorIs(t, redeem("SAVE10", "alice"), ErrCouponCustomerLimit)
+	require.NoError(t, redeem("SAVE10", "bob"))
+	require.ErrorIs(t, redeem("SAVE10", "carol"), ErrCouponExhausted)
+
+	// Rejected redemptions leave no trace.
+	got, err := d.GetCoupon(ctx, "SAVE10")
+	require.NoError(t, err)
+	require.Equal(t, 3, got.Redemptions)
+	n, err := d.CountCouponRedemptions(ctx, "SAVE10", "alice")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	past := time.Now().UTC().Add(-time.Minute)
+	require.NoError(t, d.AddCoupon(ctx, &model.Coupon{Code: "OLD", PromotionID: 1, ExpiresAt: &past}))
+	require.ErrorIs(t, redeem("OLD", "alice"), ErrCouponExpired)
+	require.ErrorIs(t, redeem("NOPE", "alice"), ErrCouponNotFound)
+
+	require.NoError(t, d.DeleteCoupon(ctx, "OLD"))
+	require.ErrorIs(t, d.DeleteCoupon(ctx, "OLD"), ErrCouponNotFound)
+}
+
+// Locking a customer's purchase limits creates their lock row on first use
+// and takes it again on every later check.
+func Test_SQLite_LockPurchaseLimits(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+
+	for range 2 {
+		require.NoError(t, d.Transaction(ctx, func(tx Database) error {
+			return tx.LockPurchaseLimits(ctx, "alice")
+		}))
+	}
+	var l model.PurchaseLimitLock
+	require.NoError(t, d.db.Where("customer_id = ?", "alice").First(&l).Error)
+	require.Equal(t, int64(2), l.Purchases)
+}
+
+// A customer's order summary counts their orders and dates the first and
+// latest.
+func Test_SQLite_OrderSummary(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+
+	got, err := d.GetOrderSummary(ctx, "customer")
+	require.NoError(t, err)
+	require.Equal(t, &model.OrderSummary{}, got)
+
+	first := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, at := range []time.Time{first.Add(48 * time.Hour), first, first.Add(time.Hour)} {
+		require.NoError(t, d.AddOrder(ctx, &model.Order{Reference: model.GenerateReference(), CustomerID: "customer", CreatedAt: at}), i)
+	}
+	require.NoError(t, d.AddOrder(ctx, &model.Order{Reference: model.GenerateReference(), CustomerID: "other"}))
+
+	got, err = d.GetOrderSummary(ctx, "customer")
+	require.NoError(t, err)
+	require.Equal(t, 3, got.Orders)
+	require.True(t, first.Equal(*got.FirstOrderAt), got.FirstOrderAt)
+	require.True(t, first.Add(48*time.Hour).Equal(*got.LastOrderAt), got.LastOrderAt)
+}
+
+// Redeeming a promotion adds to its usage and deactivates it once its budget
+// is exhausted; updating the promotion leaves its usage alone.
+func Test_SQLite_RedeemPromotion(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+
+	p := &model.PromotionRule{Name: "budgeted", Type: model.PromotionPercentOffOverQuantity, Active: true, SKU: "120P90",
+		MinQuantity: 1, PercentOff: decimal.NewFromInt(10), PromotionBudget: model.PromotionBudget{MaxDiscount: decimal.NewFromInt(20)}}
+	require.NoError(t, d.AddPromotion(ctx, p))
+
+	got, err := d.RedeemPromotion(ctx, p.ID, decimal.RequireFromString("12.50"), 3)
+	require.NoError(t, err)
+	require.True(t, got.Active)
+	require.Equal(t, 1, got.Redemptions)
+	require.Equal(t, "12.5", got.DiscountGranted.String())
+	require.Equal(t, 3, got.UnitsGranted)
+
+	p.Name = "renamed"
+	require.NoError(t, d.UpdatePromotion(ctx, p))
+
+	got, err = d.RedeemPromotion(ctx, p.ID, decimal.RequireFromString("12.50"), 3)
+	require.NoError(t, err)
+	require.False(t, got.Active)
+	require.Equal(t, "renamed", got.Name)
+	require.Equal(t, 2, got.Redemptions)
+	require.Equal(t, "25", got.DiscountGranted.String())
+
+	stored, err := d.GetPromotion(ctx, p.ID)
+	require.NoError(t, err)
+	require.False(t, stored.Active)
+
+	_, err = d.RedeemPromotion(ctx, p.ID, decimal.NewFromInt(1), 1)
+	require.ErrorIs(t, err, ErrPromotionInactive)
+	_, err = d.RedeemPromotion(ctx, 99, decimal.NewFromInt(1), 1)
+	require.ErrorIs(t, err, ErrPromotionNotFound)
+}
+
+// Price tiers round-trip with their item, and bundles are created, replaced,
+// listed and deleted by code.
+func Test_SQLite_TiersAndBundles(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+
+	tiers := []model.PriceTier{{MinQuantity: 10, UnitPrice: decimal.RequireFromString("45.5")}}
+	_, err = d.UpsertItems(ctx, []*model.Item{{Name: "Google TV", SKU: "120P90", Price: decimal.NewFromInt(50), InventoryQuantity: 20, PriceTiers: tiers}})
+	require.NoError(t, err)
+	it, err := d.GetItemBySKU(ctx, "120P90")
+	require.NoError(t, err)
+	require.Len(t, it.PriceTiers, 1)
+	require.Equal(t, 10, it.PriceTiers[0].MinQuantity)
+	require.Equal(t, "45.5", it.PriceTiers[0].UnitPrice.String())
+
+	b := &model.Bundle{Code: "TV-PAIR", Name: "Two TVs", SKUs: []string{"120P90", "120P90"}, Price: decimal.NewFromInt(90), Active: true}
+	require.NoError(t, d.PutBundle(ctx, b))
+	b.Price = decimal.NewFromInt(85)
+	b.Active = false
+	require.NoError(t, d.PutBundle(ctx, b))
+
+	got, err := d.GetBundle(ctx, "TV-PAIR")
+	require.NoError(t, err)
+	require.Equal(t, []string{"120P90", "120P90"}, got.SKUs)
+	require.Equal(t, "85", got.Price.String())
+	active, err := d.ListBundles(ctx, true)
+	require.NoError(t, err)
+	require.Empty(t, active)
+	all, err := d.ListBundles(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+
+	require.NoError(t, d.DeleteBundle(ctx, "TV-PAIR"))
+	require.ErrorIs(t, d.DeleteBundle(ctx, "TV-PAIR"), ErrBundleNotFound)
+	_, err = d.GetBundle(ctx, "TV-PAIR")
+	require.ErrorIs(t, err, ErrBundleNotFound)
+}
+
+// Relays sharing the outbox claim disjoint batches; a row returns to the pool
+// once its lease runs out, unless it was published.
+func Test_SQLite_ClaimOutboxItems(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+
+	require.NoError(t, d.AddOutboxItems(ctx, []*model.OutboxItem{
+		{EventID: "e1", Topic: "t"}, {EventID: "e2", Topic: "t"}, {EventID: "e3", Topic: "t"},
+	}))
+	ids := func(items []*model.OutboxItem) []int64 {
+		var ids []int64
+		for _, it := range items {
+			ids = append(ids, it.ID)
+		}
+		return ids
+	}
+
+	a, err := d.ClaimOutboxItems(ctx, "a", time.Minute, 2)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, ids(a))
+	require.Equal(t, "a", a[0].ClaimedBy)
+	b, err := d.ClaimOutboxItems(ctx, "b", time.Minute, 2)
+	require.NoError(t, err)
+	require.Equal(t, []int64{3}, ids(b))
+	c, err := d.ClaimOutboxItems(ctx, "c", time.Minute, 2)
+	require.NoError(t, err)
+	require.Empty(t, c)
+
+	// An expired lease is claimed again; a published row is not.
+	require.NoError(t, d.AddOutboxItems(ctx, []*model.OutboxItem{{EventID: "e4", Topic: "t"}, {EventID: "e5", Topic: "t"}}))
+	_, err = d.ClaimOutboxItems(ctx, "d", -time.Second, 0)
+	require.NoError(t, err)
+	require.NoError(t, d.SetPublishedAt(ctx, 4, time.Now().UTC()))
+	e, err := d.ClaimOutboxItems(ctx, "e", time.Minute, 0)
+	require.NoError(t, err)
+	require.Equal(t, []int64{5}, ids(e))
+}
+
+// A row is not claimed while an earlier row with its topic and key is pending
+// outside the batch, so it cannot overtake one backing off; a dead row does
+// not hold its key up.
+func Test_SQLite_ClaimOutboxItemsKeyOrder(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+
+	require.NoError(t, d.AddOutboxItems(ctx, []*model.OutboxItem{
+		{EventID: "e1", Topic: "t", PartitionKey: "ref-1"},
+		{EventID: "e2", Topic: "t", PartitionKey: "ref-1"},
+		{EventID: "e3", Topic: "t", PartitionKey: "ref-2"},
+		{EventID: "e4", Topic: "u", PartitionKey: "ref-1"},
+		{EventID: "e5", Topic: "t"},
+	}))
+	ids := func(items []*model.OutboxItem) []int64 {
+		var ids []int64
+		for _, it := range items {
+			ids = append(ids, it.ID)
+		}
+		return ids
+	}
+
+	// Both ref-1 rows fit in one batch, to be published in order.
+	claimed, err := d.ClaimOutboxItems(ctx, "a", -time.Second, 0)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2, 3, 4, 5}, ids(claimed))
+	// While another relay holds e1, e2 waits for it.
+	claimed, err = d.ClaimOutboxItems(ctx, "b", time.Minute, 1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1}, ids(claimed))
+	claimed, err = d.ClaimOutboxItems(ctx, "c", -time.Second, 0)
+	require.NoError(t, err)
+	require.Equal(t, []int64{3, 4, 5}, ids(claimed))
+
+	// So it does while e1 backs off.
+	later := time.Now().UTC().Add(time.Hour)
+	require.NoError(t, d.FailOutboxItem(ctx, 1, "broker down", &later))
+	claimed, err = d.ClaimOutboxItems(ctx, "d", -time.Second, 0)
+	require.NoError(t, err)
+	require.Equal(t, []int64{3, 4, 5}, ids(claimed))
+
+	// Dead, it no longer holds e2 back.
+	require.NoError(t, d.FailOutboxItem(ctx, 1, "poison", nil))
+	claimed, err = d.ClaimOutboxItems(ctx, "e", time.Minute, 0)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2, 3, 4, 5}, ids(claimed))
+}
+
+// A failed row waits out its backoff before it is claimed again, and a dead
+// row is never claimed again.
+func Test_SQLite_FailOutboxItem(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+	require.NoError(t, d.AddOutboxItems(ctx, []*model.OutboxItem{{EventID: "e1", Topic: "t"}, {EventID: "e2", Topic: "t"}}))
+
+	claimed, err := d.ClaimOutboxItems(ctx, "a", time.Minute, 0)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+
+	later := time.Now().UTC().Add(time.Hour)
+	require.NoError(t, d.FailOutboxItem(ctx, 1, "broker down", &later))
+	require.NoError(t, d.FailOutboxItem(ctx, 2, "malformed", nil))
+	require.ErrorIs(t, d.FailOutboxItem(ctx, 3, "x", nil), ErrOutboxItemNotFound)
+
+	// Both claims are released, but neither row is due.
+	claimed, err = d.ClaimOutboxItems(ctx, "b", time.Minute, 0)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+
+	earlier := time.Now().UTC().Add(-time.Second)
+	require.NoError(t, d.FailOutboxItem(ctx, 1, "broker still down", &earlier))
+	claimed, err = d.ClaimOutboxItems(ctx, "b", time.Minute, 0)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, 2, claimed[0].Attempts)
+	require.Equal(t, "broker still down", claimed[0].LastError)
+
+	dead, err := d.GetOutboxItems(ctx, &OutboxQuery{OnlyDead: true})
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	require.Equal(t, "e2", dead[0].EventID)
+	require.NotNil(t, dead[0].DeadAt)
+	live, err := d.GetOutboxItems(ctx, &OutboxQuery{OnlyLive: true})
+	require.NoError(t, err)
+	require.Len(t, live, 1)
+}
+
+// A released row is claimable again at once with no attempt counted; a row
+// claimed by another relay is not released.
+func Test_SQLite_ReleaseOutboxItems(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+	require.NoError(t, d.AddOutboxItems(ctx, []*model.OutboxItem{{EventID: "e1", Topic: "t"}, {EventID: "e2", Topic: "t"}}))
+
+	claimed, err := d.ClaimOutboxItems(ctx, "a", time.Minute, 1)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	claimed, err = d.ClaimOutboxItems(ctx, "b", time.Minute, 1)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	require.NoError(t, d.ReleaseOutboxItems(ctx, "a", nil))
+	require.NoError(t, d.ReleaseOutboxItems(ctx, "a", []int64{1, 2}))
+
+	claimed, err = d.ClaimOutboxItems(ctx, "c", time.Minute, 0)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.Equal(t, int64(1), claimed[0].ID)
+	require.Zero(t, claimed[0].Attempts)
+}
+
+func Test_SQLite_SetPublishedAtBulk(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+	require.NoError(t, d.AddOutboxItems(ctx, []*model.OutboxItem{
+		{EventID: "e1", Topic: "t"}, {EventID: "e2", Topic: "t"}, {EventID: "e3", Topic: "t"},
+	}))
+
+	now := time.Now().UTC()
+	require.NoError(t, d.SetPublishedAtBulk(ctx, nil, now))
+	require.NoError(t, d.SetPublishedAtBulk(ctx, []int64{1, 3}, now))
+	require.ErrorIs(t, d.SetPublishedAtBulk(ctx, []int64{2, 99}, now), ErrOutboxItemNotFound)
+
+	items, err := d.GetOutboxItems(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	for _, item := range items {
+		require.NotNil(t, item.PublishedAt, "item %d unpublished", item.ID)
+	}
+}
+
+func Test_SQLite_PurgeOutboxItems(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	var items []*model.OutboxItem
+	for i := range 5 {
+		items = append(items, &model.OutboxItem{EventID: "e" + strconv.Itoa(i+1), Topic: "t", CreatedAt: old})
+	}
+	items = append(items, &model.OutboxItem{EventID: "new", Topic: "t"})
+	require.NoError(t, d.AddOutboxItems(ctx, items))
+	// e1-e3 and new are done; e4 is published but undelivered; e5 is pending.
+	for _, id := range []int64{1, 2, 3, 4, 6} {
+		require.NoError(t, d.SetPublishedAt(ctx, id, old))
+	}
+	for _, id := range []int64{1, 2, 3, 6} {
+		require.NoError(t, d.SetDeliveredAt(ctx, id, old))
+	}
+
+	before := time.Now().UTC().Add(-24 * time.Hour)
+	n, err := d.PurgeOutboxItems(ctx, before, 2, true)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	n, err = d.PurgeOutboxItems(ctx, before, 2, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	n, err = d.PurgeOutboxItems(ctx, before, 2, false)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	left, err := d.GetOutboxItems(ctx, nil)
+	require.NoError(t, err)
+	var ids []string
+	for _, it := range left {
+		ids = append(ids, it.EventID)
+	}
+	require.Equal(t, []string{"e4", "e5", "new"}, ids)
+
+	var archived []model.ArchivedOutboxItem
+	require.NoError(t, d.db.Order("id ASC").Find(&archived).Error)
+	require.Len(t, archived, 2)
+	require.Equal(t, "e1", archived[0].EventID)
+	require.NotNil(t, archived[0].DeliveredAt)
+	require.False(t, archived[0].ArchivedAt.IsZero())
+}
+
+// A row the relay handled in-process (a payment capture) is marked delivered
+// with its publish, as nothing else will acknowledge it, so retention purges
+// it like a row a consumer acknowledged.
+func Test_SQLite_PurgeHandledOutboxItems(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+	old := time.Now().UTC().Add(-48 * time.Hour)
+	require.NoError(t, d.AddOutboxItems(ctx, []*model.OutboxItem{
+		{EventID: "capture", Topic: "payments.capture", CreatedAt: old},
+		{EventID: "order", Topic: "orders.created", CreatedAt: old},
+	}))
+	require.NoError(t, d.SetDeliveredAtBulk(ctx, []int64{1}, old))
+	require.NoError(t, d.SetPublishedAtBulk(ctx, []int64{2}, old))
+	require.ErrorIs(t, d.SetDeliveredAtBulk(ctx, []int64{99}, old), ErrOutboxItemNotFound)
+
+	n, err := d.PurgeOutboxItems(ctx, time.Now().UTC().Add(-24*time.Hour), 0, false)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	left, err := d.GetOutboxItems(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, left, 1)
+	require.Equal(t, "order", left[0].EventID)
+}
+
+func Test_SQLite_GetOutboxBacklog(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+	b, err := d.GetOutboxBacklog(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &model.OutboxBacklog{}, b)
+
+	oldest := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, d.AddOutboxItems(ctx, []*model.OutboxItem{
+		{EventID: "published", Topic: "t", CreatedAt: oldest.Add(-time.Hour)},
+		{EventID: "dead", Topic: "t", CreatedAt: oldest.Add(-time.Hour)},
+		{EventID: "e1", Topic: "t", CreatedAt: oldest},
+		{EventID: "e2", Topic: "t"},
+	}))
+	require.NoError(t, d.SetPublishedAt(ctx, 1, time.Now().UTC()))
+	require.NoError(t, d.FailOutboxItem(ctx, 2, "broker down", nil))
+
+	b, err = d.GetOutboxBacklog(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, b.Rows)
+	require.EqualValues(t, 1, b.Dead)
+	require.NotNil(t, b.Oldest)
+	require.True(t, oldest.Equal(*b.Oldest), "oldest %s, want %s", b.Oldest, oldest)
+}
+
+func Test_SQLite_WatchOutbox(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+	wake, err := d.WatchOutbox(ctx)
+	require.NoError(t, err)
+	signalled := func() bool {
+		select {
+		case <-wake:
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}
+
+	require.NoError(t, d.AddOutboxItems(ctx, []*model.OutboxItem{{EventID: "e1", Topic: "t"}}))
+	require.True(t, signalled())
+
+	// Rows added in a transaction signal once it commits, and not if it rolls
+	// back.
+	require.NoError(t, d.Transaction(ctx, func(tx Database) error {
+		require.NoError(t, tx.AddOutboxItems(ctx, []*model.OutboxItem{{EventID: "e2", Topic: "t"}}))
+		require.NoError(t, tx.AddOutboxItems(ctx, []*model.OutboxItem{{EventID: "e3", Topic: "t"}}))
+		require.False(t, signalled())
+		return nil
+	}))
+	require.True(t, signalled())
+	require.False(t, signalled())
+	require.Error(t, d.Transaction(ctx, func(tx Database) error {
+		require.NoError(t, tx.AddOutboxItems(ctx, []*model.OutboxItem{{EventID: "e4", Topic: "t"}}))
+		return errors.New("abort")
+	}))
+	require.False(t, signalled())
+
+	cancel()
+	_, open := <-wake
+	require.False(t, open)
+}
+
+func Test_SQLite_ResetOutboxItems(t *testing.T) {
+	ctx := context.Background()
+	d, err := NewSQLiteDB(InMemoryDSN, false)
+	require.NoError(t, err)
+	require.NoError(t, d.AddOutboxItems(ctx, []*model.OutboxItem{
+		{EventID: "e1", Topic: "orders"}, {EventID: "e2", Topic: "orders"}, {EventID: "e3", Topic: "payments"},
+	}))
+	now := time.Now().UTC()
+	for _, id := range []int64{1, 2, 3} {
+		require.NoError(t, d.SetPublishedAt(ctx, id, now))
+		require.NoError(t, d.SetDeliveredAt(ctx, id, now))
+	}
+	require.NoError(t, d.FailOutboxItem(ctx, 2, "poison", nil))
+
+	got, err := d.GetOutboxItems(ctx, &OutboxQuery{OnlyDelivered: true, Topics: []string{"orders"}, CreatedAfter: now.Add(-time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	got, err = d.GetOutboxItems(ctx, &OutboxQuery{CreatedBefore: now.Add(-time.Minute)})
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	entry := &model.OutboxAuditEntry{Actor: "ops", EventIDs: []string{"e2", "e3", "missing"}, Published: true, Reason: "lost"}
+	require.NoError(t, d.ResetOutboxItems(ctx, entry))
+	require.Equal(t, 2, entry.Affected)
+
+	e2, err := d.GetOutboxItem(ctx, "e2")
+	require.NoError(t, err)
+	require.Nil(t, e2.PublishedAt)
+	require.Nil(t, e2.DeadAt)
+	require.Zero(t, e2.Attempts)
+	require.NotNil(t, e2.DeliveredAt)
+	_, err = d.GetOutboxItem(ctx, "missing")
+	require.ErrorIs(t, err, ErrOutboxItemNotFound)
+
+	// Both rows are claimable again.
+	claimed, err := d.ClaimOutboxItems(ctx, "relay", time.Minute, 0)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+
+	require.NoError(t, d.ResetOutboxItems(ctx, &model.OutboxAuditEntry{Actor: "ops", EventIDs: []string{"e1"}, Delivered: true}))
+	undelivered, err := d.GetOutboxItems(ctx, &OutboxQuery{OnlyPublished: true, OnlyUndelivered: true})
+	require.NoError(t, err)
+	require.Len(t, undelivered, 1)
+	require.Equal(t, "e1", undelivered[0].EventID)
+
+	audit, err := d.GetOutboxAudit(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, audit, 2)
+	require.True(t, audit[0].Delivered)
+	require.Equal(t, []string{"e2", "e3", "missing"}, audit[1].EventIDs)
+	require.Equal(t, "lost", audit[1].Reason)
+}
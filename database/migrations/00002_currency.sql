@@ -0,0 +1,20 @@
+-- Multi-currency pricing (model.Item.Currency, model.Order currency fields).
+--
+-- As with 00001, AutoMigrate is the source of truth; the column defaults let it
+-- add the columns to a populated table, backfilling existing rows as USD.
+
+-- +migrate Up
+ALTER TABLE inventory ADD COLUMN currency TEXT DEFAULT 'USD';
+
+-- currency is what the customer was charged in; base_currency is what the
+-- items were priced in; fx_rate converts base -> charged (price / fx_rate
+-- recovers the catalog amount).
+ALTER TABLE orders ADD COLUMN currency      TEXT          DEFAULT 'USD';
+ALTER TABLE orders ADD COLUMN base_currency TEXT          DEFAULT 'USD';
+ALTER TABLE orders ADD COLUMN fx_rate       NUMERIC(18,8) DEFAULT 1;
+
+-- +migrate Down
+ALTER TABLE orders DROP COLUMN fx_rate;
+ALTER TABLE orders DROP COLUMN base_currency;
+ALTER TABLE orders DROP COLUMN currency;
+ALTER TABLE inventory DROP COLUMN currency;
@@ -0,0 +1,14 @@
+-- Payment authorization and capture (model.Order payment fields).
+
+-- +migrate Up
+-- payment_id is the processor's authorization ID; payment_status moves
+-- authorized -> captured once the relay settles the payments.capture event, or
+-- authorized -> failed if the relay dead-letters it. Orders placed before
+-- payments were recorded owe the processor nothing, so they default to
+-- captured, as an order with nothing to charge is.
+ALTER TABLE orders ADD COLUMN payment_id     TEXT;
+ALTER TABLE orders ADD COLUMN payment_status TEXT NOT NULL DEFAULT 'captured';
+
+-- +migrate Down
+ALTER TABLE orders DROP COLUMN payment_status;
+ALTER TABLE orders DROP COLUMN payment_id;
@@ -0,0 +1,27 @@
+-- Refunds and partial returns (model.Refund, model.Order lines/discount).
+
+-- +migrate Up
+-- lines is the JSON-encoded []OrderLine as charged; discount is the promotion
+-- deduction refunds prorate across those lines.
+ALTER TABLE orders ADD COLUMN lines    TEXT;
+ALTER TABLE orders ADD COLUMN discount NUMERIC(12,2) NOT NULL DEFAULT 0;
+
+CREATE TABLE refunds (
+    id              SERIAL PRIMARY KEY,
+    reference       TEXT NOT NULL,
+    order_reference TEXT NOT NULL,
+    customer_id     TEXT,
+    lines           TEXT,
+    amount          NUMERIC(12,2) NOT NULL,
+    currency        TEXT,
+    restocked       BOOLEAN NOT NULL DEFAULT FALSE,
+    status          TEXT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_refunds_reference ON refunds (reference);
+CREATE INDEX idx_refunds_order_reference ON refunds (order_reference);
+
+-- +migrate Down
+DROP TABLE refunds;
+ALTER TABLE orders DROP COLUMN discount;
+ALTER TABLE orders DROP COLUMN lines;
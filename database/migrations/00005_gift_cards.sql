@@ -0,0 +1,37 @@
+-- Gift cards and store credit (model.GiftCard, model.GiftCardEntry) and the
+-- gift card tender on orders and refunds.
+
+-- +migrate Up
+CREATE TABLE gift_cards (
+    id          SERIAL PRIMARY KEY,
+    code        TEXT NOT NULL,
+    balance     NUMERIC(12,2) NOT NULL,
+    currency    TEXT NOT NULL DEFAULT 'USD',
+    customer_id TEXT,            -- set for store credit; empty for a bearer card
+    expires_at  TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX idx_gift_cards_code ON gift_cards (code);
+
+-- Append-only ledger: balance is the sum of a card's entries. Redemptions are
+-- negative.
+CREATE TABLE gift_card_ledger (
+    id              SERIAL PRIMARY KEY,
+    code            TEXT NOT NULL,
+    amount          NUMERIC(12,2) NOT NULL,
+    reason          TEXT NOT NULL,   -- issued | redeemed | refunded | expired
+    order_reference TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_gift_card_ledger_code ON gift_card_ledger (code);
+
+ALTER TABLE orders  ADD COLUMN gift_card_code   TEXT;
+ALTER TABLE orders  ADD COLUMN gift_card_amount NUMERIC(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE refunds ADD COLUMN gift_card_amount NUMERIC(12,2) NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE refunds DROP COLUMN gift_card_amount;
+ALTER TABLE orders  DROP COLUMN gift_card_amount;
+ALTER TABLE orders  DROP COLUMN gift_card_code;
+DROP TABLE gift_card_ledger;
+DROP TABLE gift_cards;
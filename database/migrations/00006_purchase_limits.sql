@@ -0,0 +1,20 @@
+-- Per-customer purchase limits (model.PurchaseLimit) and order timestamps for
+-- the rolling-window check.
+
+-- +migrate Up
+CREATE TABLE purchase_limits (
+    id             SERIAL PRIMARY KEY,
+    sku            TEXT NOT NULL,
+    max_per_order  INTEGER NOT NULL DEFAULT 0,  -- 0 = no per-order limit
+    max_per_window INTEGER NOT NULL DEFAULT 0,  -- 0 = no window limit
+    window_seconds BIGINT NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX idx_purchase_limits_sku ON purchase_limits (sku);
+
+ALTER TABLE orders ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+CREATE INDEX idx_orders_created_at ON orders (created_at);
+
+-- +migrate Down
+DROP INDEX idx_orders_created_at;
+ALTER TABLE orders DROP COLUMN created_at;
+DROP TABLE purchase_limits;
@@ -0,0 +1,25 @@
+-- Promotions managed as data (model.PromotionRule).
+
+-- +migrate Up
+-- type selects which of the rule columns apply:
+--   buy_n_get_m_free          buy_quantity, free_quantity
+--   percent_off_over_quantity min_quantity, percent_off
+--   free_gift_with_purchase   gift_sku
+CREATE TABLE promotions (
+    id            SERIAL PRIMARY KEY,
+    name          TEXT NOT NULL,
+    type          TEXT NOT NULL,
+    active        BOOLEAN NOT NULL DEFAULT FALSE,
+    sku           TEXT NOT NULL,
+    buy_quantity  INTEGER,
+    free_quantity INTEGER,
+    min_quantity  INTEGER,
+    percent_off   NUMERIC(5,2) DEFAULT 0,
+    gift_sku      TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_promotions_active ON promotions (active);
+
+-- +migrate Down
+DROP TABLE promotions;
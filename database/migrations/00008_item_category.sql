@@ -0,0 +1,12 @@
+-- Item categories (model.Item.Category), matched by promotion rule files.
+--
+-- As with 00001, AutoMigrate is the source of truth. The column is optional, so
+-- existing rows are left uncategorized.
+
+-- +migrate Up
+ALTER TABLE inventory ADD COLUMN category TEXT;
+CREATE INDEX idx_inventory_category ON inventory (category);
+
+-- +migrate Down
+DROP INDEX idx_inventory_category;
+ALTER TABLE inventory DROP COLUMN category;
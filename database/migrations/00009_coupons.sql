@@ -0,0 +1,33 @@
+-- Coupon codes (model.Coupon) unlocking coupon-only promotions, and their
+-- redemptions (model.CouponRedemption).
+
+-- +migrate Up
+ALTER TABLE promotions ADD COLUMN coupon_only BOOLEAN DEFAULT FALSE;
+
+-- max_redemptions and max_per_customer of 0 mean unlimited. redemptions is
+-- incremented conditionally on max_redemptions in the purchase transaction.
+CREATE TABLE coupons (
+    id               SERIAL PRIMARY KEY,
+    code             TEXT NOT NULL UNIQUE,
+    promotion_id     INTEGER NOT NULL,
+    max_redemptions  INTEGER NOT NULL DEFAULT 0,
+    max_per_customer INTEGER NOT NULL DEFAULT 0,
+    redemptions      INTEGER NOT NULL DEFAULT 0,
+    expires_at       TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_coupons_promotion_id ON coupons (promotion_id);
+
+CREATE TABLE coupon_redemptions (
+    id              SERIAL PRIMARY KEY,
+    code            TEXT NOT NULL,
+    customer_id     TEXT NOT NULL,
+    order_reference TEXT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_coupon_redemptions_customer ON coupon_redemptions (code, customer_id);
+
+-- +migrate Down
+DROP TABLE coupon_redemptions;
+DROP TABLE coupons;
+ALTER TABLE promotions DROP COLUMN coupon_only;
@@ -0,0 +1,19 @@
+-- Promotion schedules (model.Schedule, embedded in model.PromotionRule).
+
+-- +migrate Up
+-- days is a comma-separated list of weekday abbreviations ("sat,sun");
+-- time_from and time_to are "HH:MM" in time_zone (UTC when empty).
+ALTER TABLE promotions ADD COLUMN starts_at TIMESTAMPTZ;
+ALTER TABLE promotions ADD COLUMN ends_at   TIMESTAMPTZ;
+ALTER TABLE promotions ADD COLUMN days      TEXT;
+ALTER TABLE promotions ADD COLUMN time_from TEXT;
+ALTER TABLE promotions ADD COLUMN time_to   TEXT;
+ALTER TABLE promotions ADD COLUMN time_zone TEXT;
+
+-- +migrate Down
+ALTER TABLE promotions DROP COLUMN time_zone;
+ALTER TABLE promotions DROP COLUMN time_to;
+ALTER TABLE promotions DROP COLUMN time_from;
+ALTER TABLE promotions DROP COLUMN days;
+ALTER TABLE promotions DROP COLUMN ends_at;
+ALTER TABLE promotions DROP COLUMN starts_at;
@@ -0,0 +1,15 @@
+-- Promotion stacking policy (model.PromotionRule priority, exclusive_group
+-- and stackable).
+
+-- +migrate Up
+-- Higher priority promotions claim basket units first; at most one promotion
+-- per exclusive_group applies; stackable promotions may discount units other
+-- promotions have already claimed.
+ALTER TABLE promotions ADD COLUMN priority        INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE promotions ADD COLUMN exclusive_group TEXT;
+ALTER TABLE promotions ADD COLUMN stackable       BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE promotions DROP COLUMN stackable;
+ALTER TABLE promotions DROP COLUMN exclusive_group;
+ALTER TABLE promotions DROP COLUMN priority;
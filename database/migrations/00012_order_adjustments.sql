@@ -0,0 +1,9 @@
+-- Per-line promotion adjustments (model.Order adjustments).
+
+-- +migrate Up
+-- adjustments is the JSON-encoded []Adjustment: which promotion took what off
+-- which lines. Refunds of orders without it prorate discount by value.
+ALTER TABLE orders ADD COLUMN adjustments TEXT;
+
+-- +migrate Down
+ALTER TABLE orders DROP COLUMN adjustments;
@@ -0,0 +1,16 @@
+-- Customer conditions on promotions (model.CustomerCondition, embedded in
+-- model.PromotionRule).
+
+-- +migrate Up
+-- first_order limits a promotion to a customer's first order; min_orders to
+-- customers with at least that many orders already placed.
+ALTER TABLE promotions ADD COLUMN first_order BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE promotions ADD COLUMN min_orders  INTEGER NOT NULL DEFAULT 0;
+
+-- Order history summaries count a customer's orders.
+CREATE INDEX idx_orders_customer_id ON orders (customer_id);
+
+-- +migrate Down
+DROP INDEX idx_orders_customer_id;
+ALTER TABLE promotions DROP COLUMN min_orders;
+ALTER TABLE promotions DROP COLUMN first_order;
@@ -0,0 +1,20 @@
+-- Promotion budgets and usage (model.PromotionBudget and model.PromotionUsage,
+-- embedded in model.PromotionRule).
+
+-- +migrate Up
+-- Caps on what a promotion gives away; 0 is unlimited. A purchase that reaches
+-- either cap deactivates the promotion.
+ALTER TABLE promotions ADD COLUMN max_discount NUMERIC(18,2) NOT NULL DEFAULT 0;
+ALTER TABLE promotions ADD COLUMN max_units    INTEGER       NOT NULL DEFAULT 0;
+
+-- Usage, added to in the purchase transaction.
+ALTER TABLE promotions ADD COLUMN redemptions      INTEGER       NOT NULL DEFAULT 0;
+ALTER TABLE promotions ADD COLUMN discount_granted NUMERIC(18,2) NOT NULL DEFAULT 0;
+ALTER TABLE promotions ADD COLUMN units_granted    INTEGER       NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE promotions DROP COLUMN units_granted;
+ALTER TABLE promotions DROP COLUMN discount_granted;
+ALTER TABLE promotions DROP COLUMN redemptions;
+ALTER TABLE promotions DROP COLUMN max_units;
+ALTER TABLE promotions DROP COLUMN max_discount;
@@ -0,0 +1,23 @@
+-- Quantity price tiers on items (model.PriceTier) and fixed-price bundles
+-- (model.Bundle).
+
+-- +migrate Up
+-- JSON-encoded array of {min_quantity, unit_price}, ascending; NULL when the
+-- item has a single price.
+ALTER TABLE inventory ADD COLUMN price_tiers TEXT;
+
+CREATE TABLE bundles (
+    code       TEXT PRIMARY KEY,
+    name       TEXT,
+    skus       TEXT,             -- JSON-encoded array of SKUs, one per unit
+    price      NUMERIC(12,2),
+    currency   TEXT NOT NULL DEFAULT 'USD',
+    active     BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX idx_bundles_active ON bundles (active);
+
+-- +migrate Down
+DROP TABLE bundles;
+ALTER TABLE inventory DROP COLUMN price_tiers;
@@ -0,0 +1,29 @@
+-- Loyalty points (model.LoyaltyAccount and its ledger, model.LoyaltyEntry),
+-- and the points redeemed against each order.
+
+-- +migrate Up
+CREATE TABLE loyalty_accounts (
+    customer_id TEXT PRIMARY KEY,
+    balance     BIGINT NOT NULL DEFAULT 0,  -- running total of the ledger
+    updated_at  TIMESTAMPTZ
+);
+
+-- Append-only; points are signed (earnings positive, redemptions negative).
+-- An order has at most one entry per reason, so replayed earnings are ignored.
+CREATE TABLE loyalty_ledger (
+    id              SERIAL PRIMARY KEY,
+    customer_id     TEXT,
+    points          BIGINT,
+    reason          TEXT,
+    order_reference TEXT,
+    created_at      TIMESTAMPTZ
+);
+CREATE INDEX idx_loyalty_ledger_customer_id ON loyalty_ledger (customer_id);
+CREATE UNIQUE INDEX idx_loyalty_ledger_order_reason ON loyalty_ledger (reason, order_reference);
+
+ALTER TABLE orders ADD COLUMN loyalty_points BIGINT NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE orders DROP COLUMN loyalty_points;
+DROP TABLE loyalty_ledger;
+DROP TABLE loyalty_accounts;
@@ -0,0 +1,12 @@
+-- Outbox row leases, so relays on several replicas publish disjoint rows
+-- (model.OutboxItem ClaimedBy and ClaimedUntil).
+
+-- +migrate Up
+ALTER TABLE outbox ADD COLUMN claimed_by    TEXT;         -- relay holding the lease
+ALTER TABLE outbox ADD COLUMN claimed_until TIMESTAMPTZ;  -- NULL or past: claimable
+CREATE INDEX idx_outbox_claimed_until ON outbox (claimed_until);
+
+-- +migrate Down
+DROP INDEX idx_outbox_claimed_until;
+ALTER TABLE outbox DROP COLUMN claimed_until;
+ALTER TABLE outbox DROP COLUMN claimed_by;
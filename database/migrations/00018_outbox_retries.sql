@@ -0,0 +1,16 @@
+-- Outbox publish retries with backoff, and dead-lettering of rows the relay
+-- gives up on (model.OutboxItem Attempts, LastError, NextAttemptAt, DeadAt).
+
+-- +migrate Up
+ALTER TABLE outbox ADD COLUMN attempts        INTEGER NOT NULL DEFAULT 0; -- failed publish attempts
+ALTER TABLE outbox ADD COLUMN last_error      TEXT;                       -- latest failure
+ALTER TABLE outbox ADD COLUMN next_attempt_at TIMESTAMPTZ;                -- NULL or past: claimable
+ALTER TABLE outbox ADD COLUMN dead_at         TIMESTAMPTZ;                -- set: never claimed again
+CREATE INDEX idx_outbox_dead_at ON outbox (dead_at);
+
+-- +migrate Down
+DROP INDEX idx_outbox_dead_at;
+ALTER TABLE outbox DROP COLUMN dead_at;
+ALTER TABLE outbox DROP COLUMN next_attempt_at;
+ALTER TABLE outbox DROP COLUMN last_error;
+ALTER TABLE outbox DROP COLUMN attempts;
@@ -0,0 +1,30 @@
+-- Outbox retention: the purge ages rows by created_at, and may archive them to
+-- outbox_archive rather than delete them (model.ArchivedOutboxItem).
+
+-- +migrate Up
+CREATE INDEX idx_outbox_created_at ON outbox (created_at);
+
+CREATE TABLE outbox_archive (
+    id              BIGINT PRIMARY KEY,  -- the row's outbox id
+    event_id        TEXT NOT NULL,
+    topic           TEXT,
+    partition_key   TEXT,
+    data            BYTEA,
+    occurred_at     TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ,
+    published_at    TIMESTAMPTZ,
+    delivered_at    TIMESTAMPTZ,
+    claimed_by      TEXT,
+    claimed_until   TIMESTAMPTZ,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMPTZ,
+    dead_at         TIMESTAMPTZ,
+    archived_at     TIMESTAMPTZ          -- when the purge moved the row
+);
+CREATE UNIQUE INDEX idx_outbox_archive_event_id ON outbox_archive (event_id);
+CREATE INDEX idx_outbox_archive_created_at ON outbox_archive (created_at);
+
+-- +migrate Down
+DROP TABLE outbox_archive;
+DROP INDEX idx_outbox_created_at;
@@ -0,0 +1,18 @@
+-- Outbox audit log: every reset made through the outbox admin API or CLI
+-- (model.OutboxAuditEntry).
+
+-- +migrate Up
+CREATE TABLE outbox_audit (
+    id         BIGSERIAL PRIMARY KEY,
+    actor      TEXT,                 -- authenticated user or CLI operator
+    event_ids  TEXT,                 -- JSON array of the selected event IDs
+    published  BOOLEAN,              -- published_at cleared (republish)
+    delivered  BOOLEAN,              -- delivered_at cleared
+    reason     TEXT,
+    affected   BIGINT,               -- outbox rows changed
+    created_at TIMESTAMPTZ
+);
+CREATE INDEX idx_outbox_audit_created_at ON outbox_audit (created_at);
+
+-- +migrate Down
+DROP TABLE outbox_audit;
@@ -0,0 +1,11 @@
+-- Per-customer lock row taken before checking rolling-window purchase limits
+-- (model.PurchaseLimitLock).
+
+-- +migrate Up
+CREATE TABLE purchase_limit_locks (
+    customer_id TEXT PRIMARY KEY,
+    purchases   BIGINT NOT NULL DEFAULT 0 -- window checks that took the lock
+);
+
+-- +migrate Down
+DROP TABLE purchase_limit_locks;
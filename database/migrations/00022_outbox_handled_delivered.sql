@@ -0,0 +1,11 @@
+-- Rows the relay handles in-process (payment captures and refunds) are marked
+-- delivered when published, as no consumer acknowledges them. Backfill the
+-- rows published before that, which retention would otherwise never purge.
+
+-- +migrate Up
+UPDATE outbox SET delivered_at = published_at
+WHERE topic IN ('payments.capture', 'payments.refund')
+  AND published_at IS NOT NULL AND delivered_at IS NULL;
+
+-- +migrate Down
+-- Nothing to undo: the relay now sets delivered_at on these rows itself.
@@ -0,0 +1,8 @@
+-- Index for the claim's same-key ordering check: a row is not claimed while an
+-- earlier row with its topic and partition key is still pending.
+
+-- +migrate Up
+CREATE INDEX idx_outbox_topic_key ON outbox (topic, partition_key);
+
+-- +migrate Down
+DROP INDEX idx_outbox_topic_key;
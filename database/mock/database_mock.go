@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/ATMackay/checkout/database (interfaces: Database,HealthChecker,InventoryStore,OrderStore,OutboxStore)
+// Source: github.com/ATMackay/checkout/database (interfaces: Database,HealthChecker,InventoryStore,OrderStore,RefundStore,GiftCardStore,LoyaltyStore,LimitStore,BundleStore,PromotionStore,CouponStore,OutboxStore)
 //
 // Generated by this command:
 //
-//	mockgen -destination ./mock/database_mock.go -package mock github.com/ATMackay/checkout/database Database,HealthChecker,InventoryStore,OrderStore,OutboxStore
+//	mockgen -destination ./mock/database_mock.go -package mock github.com/ATMackay/checkout/database Database,HealthChecker,InventoryStore,OrderStore,RefundStore,GiftCardStore,LoyaltyStore,LimitStore,BundleStore,PromotionStore,CouponStore,OutboxStore
 //
 
 // Package mock is a generated GoMock package.
@@ -16,6 +16,7 @@ import (
 
 	database "github.com/ATMackay/checkout/database"
 	model "github.com/ATMackay/checkout/model"
+	decimal "github.com/shopspring/decimal"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -43,6 +44,20 @@ func (m *MockDatabase) EXPECT() *MockDatabaseMockRecorder {
 	return m.recorder
 }
 
+// AddCoupon mocks base method.
+func (m *MockDatabase) AddCoupon(ctx context.Context, c *model.Coupon) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCoupon", ctx, c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddCoupon indicates an expected call of AddCoupon.
+func (mr *MockDatabaseMockRecorder) AddCoupon(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCoupon", reflect.TypeOf((*MockDatabase)(nil).AddCoupon), ctx, c)
+}
+
 // AddOrder mocks base method.
 func (m *MockDatabase) AddOrder(ctx context.Context, o *model.Order) error {
 	m.ctrl.T.Helper()
@@ -71,245 +86,252 @@ func (mr *MockDatabaseMockRecorder) AddOutboxItems(ctx, items any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOutboxItems", reflect.TypeOf((*MockDatabase)(nil).AddOutboxItems), ctx, items)
 }
 
-// GetItemByName mocks base method.
-func (m *MockDatabase) GetItemByName(ctx context.Context, name string) (*model.Item, error) {
+// AddPromotion mocks base method.
+func (m *MockDatabase) AddPromotion(ctx context.Context, p *model.PromotionRule) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetItemByName", ctx, name)
-	ret0, _ := ret[0].(*model.Item)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "AddPromotion", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetItemByName indicates an expected call of GetItemByName.
-func (mr *MockDatabaseMockRecorder) GetItemByName(ctx, name any) *gomock.Call {
+// AddPromotion indicates an expected call of AddPromotion.
+func (mr *MockDatabaseMockRecorder) AddPromotion(ctx, p any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemByName", reflect.TypeOf((*MockDatabase)(nil).GetItemByName), ctx, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPromotion", reflect.TypeOf((*MockDatabase)(nil).AddPromotion), ctx, p)
 }
 
-// GetItemBySKU mocks base method.
-func (m *MockDatabase) GetItemBySKU(ctx context.Context, sku string) (*model.Item, error) {
+// AddRefund mocks base method.
+func (m *MockDatabase) AddRefund(ctx context.Context, r *model.Refund) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetItemBySKU", ctx, sku)
-	ret0, _ := ret[0].(*model.Item)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "AddRefund", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetItemBySKU indicates an expected call of GetItemBySKU.
-func (mr *MockDatabaseMockRecorder) GetItemBySKU(ctx, sku any) *gomock.Call {
+// AddRefund indicates an expected call of AddRefund.
+func (mr *MockDatabaseMockRecorder) AddRefund(ctx, r any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemBySKU", reflect.TypeOf((*MockDatabase)(nil).GetItemBySKU), ctx, sku)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRefund", reflect.TypeOf((*MockDatabase)(nil).AddRefund), ctx, r)
 }
 
-// GetItemsBySKU mocks base method.
-func (m *MockDatabase) GetItemsBySKU(ctx context.Context, sku []string) ([]*model.Item, error) {
+// AdjustGiftCard mocks base method.
+func (m *MockDatabase) AdjustGiftCard(ctx context.Context, entry *model.GiftCardEntry) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetItemsBySKU", ctx, sku)
-	ret0, _ := ret[0].([]*model.Item)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "AdjustGiftCard", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetItemsBySKU indicates an expected call of GetItemsBySKU.
-func (mr *MockDatabaseMockRecorder) GetItemsBySKU(ctx, sku any) *gomock.Call {
+// AdjustGiftCard indicates an expected call of AdjustGiftCard.
+func (mr *MockDatabaseMockRecorder) AdjustGiftCard(ctx, entry any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemsBySKU", reflect.TypeOf((*MockDatabase)(nil).GetItemsBySKU), ctx, sku)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustGiftCard", reflect.TypeOf((*MockDatabase)(nil).AdjustGiftCard), ctx, entry)
 }
 
-// GetOrders mocks base method.
-func (m *MockDatabase) GetOrders(ctx context.Context, userID string) ([]*model.Order, error) {
+// AdjustInventory mocks base method.
+func (m *MockDatabase) AdjustInventory(ctx context.Context, sku string, delta int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrders", ctx, userID)
-	ret0, _ := ret[0].([]*model.Order)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "AdjustInventory", ctx, sku, delta)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetOrders indicates an expected call of GetOrders.
-func (mr *MockDatabaseMockRecorder) GetOrders(ctx, userID any) *gomock.Call {
+// AdjustInventory indicates an expected call of AdjustInventory.
+func (mr *MockDatabaseMockRecorder) AdjustInventory(ctx, sku, delta any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockDatabase)(nil).GetOrders), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustInventory", reflect.TypeOf((*MockDatabase)(nil).AdjustInventory), ctx, sku, delta)
 }
 
-// GetOutboxItems mocks base method.
-func (m *MockDatabase) GetOutboxItems(ctx context.Context, q *database.OutboxQuery) ([]*model.OutboxItem, error) {
+// AdjustLoyalty mocks base method.
+func (m *MockDatabase) AdjustLoyalty(ctx context.Context, entry *model.LoyaltyEntry) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOutboxItems", ctx, q)
+	ret := m.ctrl.Call(m, "AdjustLoyalty", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AdjustLoyalty indicates an expected call of AdjustLoyalty.
+func (mr *MockDatabaseMockRecorder) AdjustLoyalty(ctx, entry any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustLoyalty", reflect.TypeOf((*MockDatabase)(nil).AdjustLoyalty), ctx, entry)
+}
+
+// ClaimOutboxItems mocks base method.
+func (m *MockDatabase) ClaimOutboxItems(ctx context.Context, owner string, lease time.Duration, limit int) ([]*model.OutboxItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimOutboxItems", ctx, owner, lease, limit)
 	ret0, _ := ret[0].([]*model.OutboxItem)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetOutboxItems indicates an expected call of GetOutboxItems.
-func (mr *MockDatabaseMockRecorder) GetOutboxItems(ctx, q any) *gomock.Call {
+// ClaimOutboxItems indicates an expected call of ClaimOutboxItems.
+func (mr *MockDatabaseMockRecorder) ClaimOutboxItems(ctx, owner, lease, limit any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxItems", reflect.TypeOf((*MockDatabase)(nil).GetOutboxItems), ctx, q)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOutboxItems", reflect.TypeOf((*MockDatabase)(nil).ClaimOutboxItems), ctx, owner, lease, limit)
 }
 
-// ListItems mocks base method.
-func (m *MockDatabase) ListItems(ctx context.Context) ([]*model.Item, error) {
+// CountCouponRedemptions mocks base method.
+func (m *MockDatabase) CountCouponRedemptions(ctx context.Context, code, customerID string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListItems", ctx)
-	ret0, _ := ret[0].([]*model.Item)
+	ret := m.ctrl.Call(m, "CountCouponRedemptions", ctx, code, customerID)
+	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListItems indicates an expected call of ListItems.
-func (mr *MockDatabaseMockRecorder) ListItems(ctx any) *gomock.Call {
+// CountCouponRedemptions indicates an expected call of CountCouponRedemptions.
+func (mr *MockDatabaseMockRecorder) CountCouponRedemptions(ctx, code, customerID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListItems", reflect.TypeOf((*MockDatabase)(nil).ListItems), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountCouponRedemptions", reflect.TypeOf((*MockDatabase)(nil).CountCouponRedemptions), ctx, code, customerID)
 }
 
-// Ping mocks base method.
-func (m *MockDatabase) Ping(ctx context.Context) error {
+// DeleteBundle mocks base method.
+func (m *MockDatabase) DeleteBundle(ctx context.Context, code string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret := m.ctrl.Call(m, "DeleteBundle", ctx, code)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Ping indicates an expected call of Ping.
-func (mr *MockDatabaseMockRecorder) Ping(ctx any) *gomock.Call {
+// DeleteBundle indicates an expected call of DeleteBundle.
+func (mr *MockDatabaseMockRecorder) DeleteBundle(ctx, code any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockDatabase)(nil).Ping), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBundle", reflect.TypeOf((*MockDatabase)(nil).DeleteBundle), ctx, code)
 }
 
-// SetDeliveredAt mocks base method.
-func (m *MockDatabase) SetDeliveredAt(ctx context.Context, id int64, t time.Time) error {
+// DeleteCoupon mocks base method.
+func (m *MockDatabase) DeleteCoupon(ctx context.Context, code string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetDeliveredAt", ctx, id, t)
+	ret := m.ctrl.Call(m, "DeleteCoupon", ctx, code)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetDeliveredAt indicates an expected call of SetDeliveredAt.
-func (mr *MockDatabaseMockRecorder) SetDeliveredAt(ctx, id, t any) *gomock.Call {
+// DeleteCoupon indicates an expected call of DeleteCoupon.
+func (mr *MockDatabaseMockRecorder) DeleteCoupon(ctx, code any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeliveredAt", reflect.TypeOf((*MockDatabase)(nil).SetDeliveredAt), ctx, id, t)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCoupon", reflect.TypeOf((*MockDatabase)(nil).DeleteCoupon), ctx, code)
 }
 
-// SetDeliveredByEventID mocks base method.
-func (m *MockDatabase) SetDeliveredByEventID(ctx context.Context, eventID string, t time.Time) error {
+// DeletePromotion mocks base method.
+func (m *MockDatabase) DeletePromotion(ctx context.Context, id int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetDeliveredByEventID", ctx, eventID, t)
+	ret := m.ctrl.Call(m, "DeletePromotion", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetDeliveredByEventID indicates an expected call of SetDeliveredByEventID.
-func (mr *MockDatabaseMockRecorder) SetDeliveredByEventID(ctx, eventID, t any) *gomock.Call {
+// DeletePromotion indicates an expected call of DeletePromotion.
+func (mr *MockDatabaseMockRecorder) DeletePromotion(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeliveredByEventID", reflect.TypeOf((*MockDatabase)(nil).SetDeliveredByEventID), ctx, eventID, t)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePromotion", reflect.TypeOf((*MockDatabase)(nil).DeletePromotion), ctx, id)
 }
 
-// SetPublishedAt mocks base method.
-func (m *MockDatabase) SetPublishedAt(ctx context.Context, id int64, t time.Time) error {
+// DeletePurchaseLimit mocks base method.
+func (m *MockDatabase) DeletePurchaseLimit(ctx context.Context, sku string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetPublishedAt", ctx, id, t)
+	ret := m.ctrl.Call(m, "DeletePurchaseLimit", ctx, sku)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SetPublishedAt indicates an expected call of SetPublishedAt.
-func (mr *MockDatabaseMockRecorder) SetPublishedAt(ctx, id, t any) *gomock.Call {
+// DeletePurchaseLimit indicates an expected call of DeletePurchaseLimit.
+func (mr *MockDatabaseMockRecorder) DeletePurchaseLimit(ctx, sku any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPublishedAt", reflect.TypeOf((*MockDatabase)(nil).SetPublishedAt), ctx, id, t)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePurchaseLimit", reflect.TypeOf((*MockDatabase)(nil).DeletePurchaseLimit), ctx, sku)
 }
 
-// Transaction mocks base method.
-func (m *MockDatabase) Transaction(ctx context.Context, fn func(database.Database) error) error {
+// ExpireGiftCard mocks base method.
+func (m *MockDatabase) ExpireGiftCard(ctx context.Context, code string, at time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Transaction", ctx, fn)
+	ret := m.ctrl.Call(m, "ExpireGiftCard", ctx, code, at)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Transaction indicates an expected call of Transaction.
-func (mr *MockDatabaseMockRecorder) Transaction(ctx, fn any) *gomock.Call {
+// ExpireGiftCard indicates an expected call of ExpireGiftCard.
+func (mr *MockDatabaseMockRecorder) ExpireGiftCard(ctx, code, at any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockDatabase)(nil).Transaction), ctx, fn)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireGiftCard", reflect.TypeOf((*MockDatabase)(nil).ExpireGiftCard), ctx, code, at)
 }
 
-// UpsertItems mocks base method.
-func (m *MockDatabase) UpsertItems(ctx context.Context, items []*model.Item) ([]*model.Item, error) {
+// FailOutboxItem mocks base method.
+func (m *MockDatabase) FailOutboxItem(ctx context.Context, id int64, reason string, retryAt *time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpsertItems", ctx, items)
-	ret0, _ := ret[0].([]*model.Item)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "FailOutboxItem", ctx, id, reason, retryAt)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// UpsertItems indicates an expected call of UpsertItems.
-func (mr *MockDatabaseMockRecorder) UpsertItems(ctx, items any) *gomock.Call {
+// FailOutboxItem indicates an expected call of FailOutboxItem.
+func (mr *MockDatabaseMockRecorder) FailOutboxItem(ctx, id, reason, retryAt any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertItems", reflect.TypeOf((*MockDatabase)(nil).UpsertItems), ctx, items)
-}
-
-// MockHealthChecker is a mock of HealthChecker interface.
-type MockHealthChecker struct {
-	ctrl     *gomock.Controller
-	recorder *MockHealthCheckerMockRecorder
-	isgomock struct{}
-}
-
-// MockHealthCheckerMockRecorder is the mock recorder for MockHealthChecker.
-type MockHealthCheckerMockRecorder struct {
-	mock *MockHealthChecker
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailOutboxItem", reflect.TypeOf((*MockDatabase)(nil).FailOutboxItem), ctx, id, reason, retryAt)
 }
 
-// NewMockHealthChecker creates a new mock instance.
-func NewMockHealthChecker(ctrl *gomock.Controller) *MockHealthChecker {
-	mock := &MockHealthChecker{ctrl: ctrl}
-	mock.recorder = &MockHealthCheckerMockRecorder{mock}
-	return mock
+// GetBundle mocks base method.
+func (m *MockDatabase) GetBundle(ctx context.Context, code string) (*model.Bundle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBundle", ctx, code)
+	ret0, _ := ret[0].(*model.Bundle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockHealthChecker) EXPECT() *MockHealthCheckerMockRecorder {
-	return m.recorder
+// GetBundle indicates an expected call of GetBundle.
+func (mr *MockDatabaseMockRecorder) GetBundle(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBundle", reflect.TypeOf((*MockDatabase)(nil).GetBundle), ctx, code)
 }
 
-// Ping mocks base method.
-func (m *MockHealthChecker) Ping(ctx context.Context) error {
+// GetCoupon mocks base method.
+func (m *MockDatabase) GetCoupon(ctx context.Context, code string) (*model.Coupon, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping", ctx)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "GetCoupon", ctx, code)
+	ret0, _ := ret[0].(*model.Coupon)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// Ping indicates an expected call of Ping.
-func (mr *MockHealthCheckerMockRecorder) Ping(ctx any) *gomock.Call {
+// GetCoupon indicates an expected call of GetCoupon.
+func (mr *MockDatabaseMockRecorder) GetCoupon(ctx, code any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockHealthChecker)(nil).Ping), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoupon", reflect.TypeOf((*MockDatabase)(nil).GetCoupon), ctx, code)
 }
 
-// MockInventoryStore is a mock of InventoryStore interface.
-type MockInventoryStore struct {
-	ctrl     *gomock.Controller
-	recorder *MockInventoryStoreMockRecorder
-	isgomock struct{}
+// GetGiftCard mocks base method.
+func (m *MockDatabase) GetGiftCard(ctx context.Context, code string) (*model.GiftCard, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGiftCard", ctx, code)
+	ret0, _ := ret[0].(*model.GiftCard)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockInventoryStoreMockRecorder is the mock recorder for MockInventoryStore.
-type MockInventoryStoreMockRecorder struct {
-	mock *MockInventoryStore
+// GetGiftCard indicates an expected call of GetGiftCard.
+func (mr *MockDatabaseMockRecorder) GetGiftCard(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGiftCard", reflect.TypeOf((*MockDatabase)(nil).GetGiftCard), ctx, code)
 }
 
-// NewMockInventoryStore creates a new mock instance.
-func NewMockInventoryStore(ctrl *gomock.Controller) *MockInventoryStore {
-	mock := &MockInventoryStore{ctrl: ctrl}
-	mock.recorder = &MockInventoryStoreMockRecorder{mock}
-	return mock
+// GetGiftCardLedger mocks base method.
+func (m *MockDatabase) GetGiftCardLedger(ctx context.Context, code string) ([]model.GiftCardEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGiftCardLedger", ctx, code)
+	ret0, _ := ret[0].([]model.GiftCardEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockInventoryStore) EXPECT() *MockInventoryStoreMockRecorder {
-	return m.recorder
+// GetGiftCardLedger indicates an expected call of GetGiftCardLedger.
+func (mr *MockDatabaseMockRecorder) GetGiftCardLedger(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGiftCardLedger", reflect.TypeOf((*MockDatabase)(nil).GetGiftCardLedger), ctx, code)
 }
 
 // GetItemByName mocks base method.
-func (m *MockInventoryStore) GetItemByName(ctx context.Context, name string) (*model.Item, error) {
+func (m *MockDatabase) GetItemByName(ctx context.Context, name string) (*model.Item, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetItemByName", ctx, name)
 	ret0, _ := ret[0].(*model.Item)
@@ -318,13 +340,13 @@ func (m *MockInventoryStore) GetItemByName(ctx context.Context, name string) (*m
 }
 
 // GetItemByName indicates an expected call of GetItemByName.
-func (mr *MockInventoryStoreMockRecorder) GetItemByName(ctx, name any) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) GetItemByName(ctx, name any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemByName", reflect.TypeOf((*MockInventoryStore)(nil).GetItemByName), ctx, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemByName", reflect.TypeOf((*MockDatabase)(nil).GetItemByName), ctx, name)
 }
 
 // GetItemBySKU mocks base method.
-func (m *MockInventoryStore) GetItemBySKU(ctx context.Context, sku string) (*model.Item, error) {
+func (m *MockDatabase) GetItemBySKU(ctx context.Context, sku string) (*model.Item, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetItemBySKU", ctx, sku)
 	ret0, _ := ret[0].(*model.Item)
@@ -333,13 +355,13 @@ func (m *MockInventoryStore) GetItemBySKU(ctx context.Context, sku string) (*mod
 }
 
 // GetItemBySKU indicates an expected call of GetItemBySKU.
-func (mr *MockInventoryStoreMockRecorder) GetItemBySKU(ctx, sku any) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) GetItemBySKU(ctx, sku any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemBySKU", reflect.TypeOf((*MockInventoryStore)(nil).GetItemBySKU), ctx, sku)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemBySKU", reflect.TypeOf((*MockDatabase)(nil).GetItemBySKU), ctx, sku)
 }
 
 // GetItemsBySKU mocks base method.
-func (m *MockInventoryStore) GetItemsBySKU(ctx context.Context, sku []string) ([]*model.Item, error) {
+func (m *MockDatabase) GetItemsBySKU(ctx context.Context, sku []string) ([]*model.Item, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetItemsBySKU", ctx, sku)
 	ret0, _ := ret[0].([]*model.Item)
@@ -348,81 +370,88 @@ func (m *MockInventoryStore) GetItemsBySKU(ctx context.Context, sku []string) ([
 }
 
 // GetItemsBySKU indicates an expected call of GetItemsBySKU.
-func (mr *MockInventoryStoreMockRecorder) GetItemsBySKU(ctx, sku any) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) GetItemsBySKU(ctx, sku any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemsBySKU", reflect.TypeOf((*MockInventoryStore)(nil).GetItemsBySKU), ctx, sku)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemsBySKU", reflect.TypeOf((*MockDatabase)(nil).GetItemsBySKU), ctx, sku)
 }
 
-// ListItems mocks base method.
-func (m *MockInventoryStore) ListItems(ctx context.Context) ([]*model.Item, error) {
+// GetLoyaltyAccount mocks base method.
+func (m *MockDatabase) GetLoyaltyAccount(ctx context.Context, customerID string) (*model.LoyaltyAccount, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListItems", ctx)
-	ret0, _ := ret[0].([]*model.Item)
+	ret := m.ctrl.Call(m, "GetLoyaltyAccount", ctx, customerID)
+	ret0, _ := ret[0].(*model.LoyaltyAccount)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListItems indicates an expected call of ListItems.
-func (mr *MockInventoryStoreMockRecorder) ListItems(ctx any) *gomock.Call {
+// GetLoyaltyAccount indicates an expected call of GetLoyaltyAccount.
+func (mr *MockDatabaseMockRecorder) GetLoyaltyAccount(ctx, customerID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListItems", reflect.TypeOf((*MockInventoryStore)(nil).ListItems), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoyaltyAccount", reflect.TypeOf((*MockDatabase)(nil).GetLoyaltyAccount), ctx, customerID)
 }
 
-// UpsertItems mocks base method.
-func (m *MockInventoryStore) UpsertItems(ctx context.Context, items []*model.Item) ([]*model.Item, error) {
+// GetLoyaltyLedger mocks base method.
+func (m *MockDatabase) GetLoyaltyLedger(ctx context.Context, customerID string) ([]model.LoyaltyEntry, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpsertItems", ctx, items)
-	ret0, _ := ret[0].([]*model.Item)
+	ret := m.ctrl.Call(m, "GetLoyaltyLedger", ctx, customerID)
+	ret0, _ := ret[0].([]model.LoyaltyEntry)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// UpsertItems indicates an expected call of UpsertItems.
-func (mr *MockInventoryStoreMockRecorder) UpsertItems(ctx, items any) *gomock.Call {
+// GetLoyaltyLedger indicates an expected call of GetLoyaltyLedger.
+func (mr *MockDatabaseMockRecorder) GetLoyaltyLedger(ctx, customerID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertItems", reflect.TypeOf((*MockInventoryStore)(nil).UpsertItems), ctx, items)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoyaltyLedger", reflect.TypeOf((*MockDatabase)(nil).GetLoyaltyLedger), ctx, customerID)
 }
 
-// MockOrderStore is a mock of OrderStore interface.
-type MockOrderStore struct {
-	ctrl     *gomock.Controller
-	recorder *MockOrderStoreMockRecorder
-	isgomock struct{}
+// GetOrderByReference mocks base method.
+func (m *MockDatabase) GetOrderByReference(ctx context.Context, reference string) (*model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderByReference", ctx, reference)
+	ret0, _ := ret[0].(*model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockOrderStoreMockRecorder is the mock recorder for MockOrderStore.
-type MockOrderStoreMockRecorder struct {
-	mock *MockOrderStore
+// GetOrderByReference indicates an expected call of GetOrderByReference.
+func (mr *MockDatabaseMockRecorder) GetOrderByReference(ctx, reference any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByReference", reflect.TypeOf((*MockDatabase)(nil).GetOrderByReference), ctx, reference)
 }
 
-// NewMockOrderStore creates a new mock instance.
-func NewMockOrderStore(ctrl *gomock.Controller) *MockOrderStore {
-	mock := &MockOrderStore{ctrl: ctrl}
-	mock.recorder = &MockOrderStoreMockRecorder{mock}
-	return mock
+// GetOrderForUpdate mocks base method.
+func (m *MockDatabase) GetOrderForUpdate(ctx context.Context, reference string) (*model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderForUpdate", ctx, reference)
+	ret0, _ := ret[0].(*model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockOrderStore) EXPECT() *MockOrderStoreMockRecorder {
-	return m.recorder
+// GetOrderForUpdate indicates an expected call of GetOrderForUpdate.
+func (mr *MockDatabaseMockRecorder) GetOrderForUpdate(ctx, reference any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderForUpdate", reflect.TypeOf((*MockDatabase)(nil).GetOrderForUpdate), ctx, reference)
 }
 
-// AddOrder mocks base method.
-func (m *MockOrderStore) AddOrder(ctx context.Context, o *model.Order) error {
+// GetOrderSummary mocks base method.
+func (m *MockDatabase) GetOrderSummary(ctx context.Context, userID string) (*model.OrderSummary, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddOrder", ctx, o)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "GetOrderSummary", ctx, userID)
+	ret0, _ := ret[0].(*model.OrderSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// AddOrder indicates an expected call of AddOrder.
-func (mr *MockOrderStoreMockRecorder) AddOrder(ctx, o any) *gomock.Call {
+// GetOrderSummary indicates an expected call of GetOrderSummary.
+func (mr *MockDatabaseMockRecorder) GetOrderSummary(ctx, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOrder", reflect.TypeOf((*MockOrderStore)(nil).AddOrder), ctx, o)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderSummary", reflect.TypeOf((*MockDatabase)(nil).GetOrderSummary), ctx, userID)
 }
 
 // GetOrders mocks base method.
-func (m *MockOrderStore) GetOrders(ctx context.Context, userID string) ([]*model.Order, error) {
+func (m *MockDatabase) GetOrders(ctx context.Context, userID string) ([]*model.Order, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetOrders", ctx, userID)
 	ret0, _ := ret[0].([]*model.Order)
@@ -431,21 +460,1423 @@ func (m *MockOrderStore) GetOrders(ctx context.Context, userID string) ([]*model
 }
 
 // GetOrders indicates an expected call of GetOrders.
-func (mr *MockOrderStoreMockRecorder) GetOrders(ctx, userID any) *gomock.Call {
+func (mr *MockDatabaseMockRecorder) GetOrders(ctx, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockOrderStore)(nil).GetOrders), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockDatabase)(nil).GetOrders), ctx, userID)
 }
 
-// MockOutboxStore is a mock of OutboxStore interface.
-type MockOutboxStore struct {
-	ctrl     *gomock.Controller
-	recorder *MockOutboxStoreMockRecorder
-	isgomock struct{}
+// GetOrdersSince mocks base method.
+func (m *MockDatabase) GetOrdersSince(ctx context.Context, userID string, since time.Time) ([]*model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrdersSince", ctx, userID, since)
+	ret0, _ := ret[0].([]*model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockOutboxStoreMockRecorder is the mock recorder for MockOutboxStore.
-type MockOutboxStoreMockRecorder struct {
-	mock *MockOutboxStore
+// GetOrdersSince indicates an expected call of GetOrdersSince.
+func (mr *MockDatabaseMockRecorder) GetOrdersSince(ctx, userID, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersSince", reflect.TypeOf((*MockDatabase)(nil).GetOrdersSince), ctx, userID, since)
+}
+
+// GetOutboxAudit mocks base method.
+func (m *MockDatabase) GetOutboxAudit(ctx context.Context, limit int) ([]*model.OutboxAuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutboxAudit", ctx, limit)
+	ret0, _ := ret[0].([]*model.OutboxAuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutboxAudit indicates an expected call of GetOutboxAudit.
+func (mr *MockDatabaseMockRecorder) GetOutboxAudit(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxAudit", reflect.TypeOf((*MockDatabase)(nil).GetOutboxAudit), ctx, limit)
+}
+
+// GetOutboxBacklog mocks base method.
+func (m *MockDatabase) GetOutboxBacklog(ctx context.Context) (*model.OutboxBacklog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutboxBacklog", ctx)
+	ret0, _ := ret[0].(*model.OutboxBacklog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutboxBacklog indicates an expected call of GetOutboxBacklog.
+func (mr *MockDatabaseMockRecorder) GetOutboxBacklog(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxBacklog", reflect.TypeOf((*MockDatabase)(nil).GetOutboxBacklog), ctx)
+}
+
+// GetOutboxItem mocks base method.
+func (m *MockDatabase) GetOutboxItem(ctx context.Context, eventID string) (*model.OutboxItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutboxItem", ctx, eventID)
+	ret0, _ := ret[0].(*model.OutboxItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutboxItem indicates an expected call of GetOutboxItem.
+func (mr *MockDatabaseMockRecorder) GetOutboxItem(ctx, eventID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxItem", reflect.TypeOf((*MockDatabase)(nil).GetOutboxItem), ctx, eventID)
+}
+
+// GetOutboxItems mocks base method.
+func (m *MockDatabase) GetOutboxItems(ctx context.Context, q *database.OutboxQuery) ([]*model.OutboxItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutboxItems", ctx, q)
+	ret0, _ := ret[0].([]*model.OutboxItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutboxItems indicates an expected call of GetOutboxItems.
+func (mr *MockDatabaseMockRecorder) GetOutboxItems(ctx, q any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxItems", reflect.TypeOf((*MockDatabase)(nil).GetOutboxItems), ctx, q)
+}
+
+// GetPromotion mocks base method.
+func (m *MockDatabase) GetPromotion(ctx context.Context, id int) (*model.PromotionRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPromotion", ctx, id)
+	ret0, _ := ret[0].(*model.PromotionRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPromotion indicates an expected call of GetPromotion.
+func (mr *MockDatabaseMockRecorder) GetPromotion(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPromotion", reflect.TypeOf((*MockDatabase)(nil).GetPromotion), ctx, id)
+}
+
+// GetPurchaseLimits mocks base method.
+func (m *MockDatabase) GetPurchaseLimits(ctx context.Context, skus []string) ([]*model.PurchaseLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPurchaseLimits", ctx, skus)
+	ret0, _ := ret[0].([]*model.PurchaseLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPurchaseLimits indicates an expected call of GetPurchaseLimits.
+func (mr *MockDatabaseMockRecorder) GetPurchaseLimits(ctx, skus any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPurchaseLimits", reflect.TypeOf((*MockDatabase)(nil).GetPurchaseLimits), ctx, skus)
+}
+
+// GetRefunds mocks base method.
+func (m *MockDatabase) GetRefunds(ctx context.Context, orderReference string) ([]*model.Refund, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefunds", ctx, orderReference)
+	ret0, _ := ret[0].([]*model.Refund)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefunds indicates an expected call of GetRefunds.
+func (mr *MockDatabaseMockRecorder) GetRefunds(ctx, orderReference any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefunds", reflect.TypeOf((*MockDatabase)(nil).GetRefunds), ctx, orderReference)
+}
+
+// IssueGiftCard mocks base method.
+func (m *MockDatabase) IssueGiftCard(ctx context.Context, card *model.GiftCard) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueGiftCard", ctx, card)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IssueGiftCard indicates an expected call of IssueGiftCard.
+func (mr *MockDatabaseMockRecorder) IssueGiftCard(ctx, card any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueGiftCard", reflect.TypeOf((*MockDatabase)(nil).IssueGiftCard), ctx, card)
+}
+
+// ListBundles mocks base method.
+func (m *MockDatabase) ListBundles(ctx context.Context, activeOnly bool) ([]*model.Bundle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBundles", ctx, activeOnly)
+	ret0, _ := ret[0].([]*model.Bundle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListBundles indicates an expected call of ListBundles.
+func (mr *MockDatabaseMockRecorder) ListBundles(ctx, activeOnly any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBundles", reflect.TypeOf((*MockDatabase)(nil).ListBundles), ctx, activeOnly)
+}
+
+// ListCoupons mocks base method.
+func (m *MockDatabase) ListCoupons(ctx context.Context) ([]*model.Coupon, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCoupons", ctx)
+	ret0, _ := ret[0].([]*model.Coupon)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCoupons indicates an expected call of ListCoupons.
+func (mr *MockDatabaseMockRecorder) ListCoupons(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCoupons", reflect.TypeOf((*MockDatabase)(nil).ListCoupons), ctx)
+}
+
+// ListItems mocks base method.
+func (m *MockDatabase) ListItems(ctx context.Context) ([]*model.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListItems", ctx)
+	ret0, _ := ret[0].([]*model.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListItems indicates an expected call of ListItems.
+func (mr *MockDatabaseMockRecorder) ListItems(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListItems", reflect.TypeOf((*MockDatabase)(nil).ListItems), ctx)
+}
+
+// ListPromotions mocks base method.
+func (m *MockDatabase) ListPromotions(ctx context.Context, activeOnly bool) ([]*model.PromotionRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPromotions", ctx, activeOnly)
+	ret0, _ := ret[0].([]*model.PromotionRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPromotions indicates an expected call of ListPromotions.
+func (mr *MockDatabaseMockRecorder) ListPromotions(ctx, activeOnly any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPromotions", reflect.TypeOf((*MockDatabase)(nil).ListPromotions), ctx, activeOnly)
+}
+
+// ListPurchaseLimits mocks base method.
+func (m *MockDatabase) ListPurchaseLimits(ctx context.Context) ([]*model.PurchaseLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPurchaseLimits", ctx)
+	ret0, _ := ret[0].([]*model.PurchaseLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPurchaseLimits indicates an expected call of ListPurchaseLimits.
+func (mr *MockDatabaseMockRecorder) ListPurchaseLimits(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPurchaseLimits", reflect.TypeOf((*MockDatabase)(nil).ListPurchaseLimits), ctx)
+}
+
+// LockPurchaseLimits mocks base method.
+func (m *MockDatabase) LockPurchaseLimits(ctx context.Context, customerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockPurchaseLimits", ctx, customerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockPurchaseLimits indicates an expected call of LockPurchaseLimits.
+func (mr *MockDatabaseMockRecorder) LockPurchaseLimits(ctx, customerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockPurchaseLimits", reflect.TypeOf((*MockDatabase)(nil).LockPurchaseLimits), ctx, customerID)
+}
+
+// Ping mocks base method.
+func (m *MockDatabase) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockDatabaseMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockDatabase)(nil).Ping), ctx)
+}
+
+// PurgeOutboxItems mocks base method.
+func (m *MockDatabase) PurgeOutboxItems(ctx context.Context, before time.Time, limit int, archive bool) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeOutboxItems", ctx, before, limit, archive)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeOutboxItems indicates an expected call of PurgeOutboxItems.
+func (mr *MockDatabaseMockRecorder) PurgeOutboxItems(ctx, before, limit, archive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeOutboxItems", reflect.TypeOf((*MockDatabase)(nil).PurgeOutboxItems), ctx, before, limit, archive)
+}
+
+// PutBundle mocks base method.
+func (m *MockDatabase) PutBundle(ctx context.Context, b *model.Bundle) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutBundle", ctx, b)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutBundle indicates an expected call of PutBundle.
+func (mr *MockDatabaseMockRecorder) PutBundle(ctx, b any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutBundle", reflect.TypeOf((*MockDatabase)(nil).PutBundle), ctx, b)
+}
+
+// RedeemCoupon mocks base method.
+func (m *MockDatabase) RedeemCoupon(ctx context.Context, r *model.CouponRedemption) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeemCoupon", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RedeemCoupon indicates an expected call of RedeemCoupon.
+func (mr *MockDatabaseMockRecorder) RedeemCoupon(ctx, r any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemCoupon", reflect.TypeOf((*MockDatabase)(nil).RedeemCoupon), ctx, r)
+}
+
+// RedeemPromotion mocks base method.
+func (m *MockDatabase) RedeemPromotion(ctx context.Context, id int, discount decimal.Decimal, units int) (*model.PromotionRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeemPromotion", ctx, id, discount, units)
+	ret0, _ := ret[0].(*model.PromotionRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RedeemPromotion indicates an expected call of RedeemPromotion.
+func (mr *MockDatabaseMockRecorder) RedeemPromotion(ctx, id, discount, units any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemPromotion", reflect.TypeOf((*MockDatabase)(nil).RedeemPromotion), ctx, id, discount, units)
+}
+
+// ReleaseOutboxItems mocks base method.
+func (m *MockDatabase) ReleaseOutboxItems(ctx context.Context, owner string, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseOutboxItems", ctx, owner, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseOutboxItems indicates an expected call of ReleaseOutboxItems.
+func (mr *MockDatabaseMockRecorder) ReleaseOutboxItems(ctx, owner, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseOutboxItems", reflect.TypeOf((*MockDatabase)(nil).ReleaseOutboxItems), ctx, owner, ids)
+}
+
+// ResetOutboxItems mocks base method.
+func (m *MockDatabase) ResetOutboxItems(ctx context.Context, entry *model.OutboxAuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetOutboxItems", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetOutboxItems indicates an expected call of ResetOutboxItems.
+func (mr *MockDatabaseMockRecorder) ResetOutboxItems(ctx, entry any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetOutboxItems", reflect.TypeOf((*MockDatabase)(nil).ResetOutboxItems), ctx, entry)
+}
+
+// SetDeliveredAt mocks base method.
+func (m *MockDatabase) SetDeliveredAt(ctx context.Context, id int64, t time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDeliveredAt", ctx, id, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDeliveredAt indicates an expected call of SetDeliveredAt.
+func (mr *MockDatabaseMockRecorder) SetDeliveredAt(ctx, id, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeliveredAt", reflect.TypeOf((*MockDatabase)(nil).SetDeliveredAt), ctx, id, t)
+}
+
+// SetDeliveredAtBulk mocks base method.
+func (m *MockDatabase) SetDeliveredAtBulk(ctx context.Context, ids []int64, t time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDeliveredAtBulk", ctx, ids, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDeliveredAtBulk indicates an expected call of SetDeliveredAtBulk.
+func (mr *MockDatabaseMockRecorder) SetDeliveredAtBulk(ctx, ids, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeliveredAtBulk", reflect.TypeOf((*MockDatabase)(nil).SetDeliveredAtBulk), ctx, ids, t)
+}
+
+// SetDeliveredByEventID mocks base method.
+func (m *MockDatabase) SetDeliveredByEventID(ctx context.Context, eventID string, t time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDeliveredByEventID", ctx, eventID, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDeliveredByEventID indicates an expected call of SetDeliveredByEventID.
+func (mr *MockDatabaseMockRecorder) SetDeliveredByEventID(ctx, eventID, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeliveredByEventID", reflect.TypeOf((*MockDatabase)(nil).SetDeliveredByEventID), ctx, eventID, t)
+}
+
+// SetPaymentStatus mocks base method.
+func (m *MockDatabase) SetPaymentStatus(ctx context.Context, reference string, status model.PaymentStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPaymentStatus", ctx, reference, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPaymentStatus indicates an expected call of SetPaymentStatus.
+func (mr *MockDatabaseMockRecorder) SetPaymentStatus(ctx, reference, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPaymentStatus", reflect.TypeOf((*MockDatabase)(nil).SetPaymentStatus), ctx, reference, status)
+}
+
+// SetPublishedAt mocks base method.
+func (m *MockDatabase) SetPublishedAt(ctx context.Context, id int64, t time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPublishedAt", ctx, id, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPublishedAt indicates an expected call of SetPublishedAt.
+func (mr *MockDatabaseMockRecorder) SetPublishedAt(ctx, id, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPublishedAt", reflect.TypeOf((*MockDatabase)(nil).SetPublishedAt), ctx, id, t)
+}
+
+// SetPublishedAtBulk mocks base method.
+func (m *MockDatabase) SetPublishedAtBulk(ctx context.Context, ids []int64, t time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPublishedAtBulk", ctx, ids, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPublishedAtBulk indicates an expected call of SetPublishedAtBulk.
+func (mr *MockDatabaseMockRecorder) SetPublishedAtBulk(ctx, ids, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPublishedAtBulk", reflect.TypeOf((*MockDatabase)(nil).SetPublishedAtBulk), ctx, ids, t)
+}
+
+// SetPurchaseLimit mocks base method.
+func (m *MockDatabase) SetPurchaseLimit(ctx context.Context, l *model.PurchaseLimit) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPurchaseLimit", ctx, l)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPurchaseLimit indicates an expected call of SetPurchaseLimit.
+func (mr *MockDatabaseMockRecorder) SetPurchaseLimit(ctx, l any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPurchaseLimit", reflect.TypeOf((*MockDatabase)(nil).SetPurchaseLimit), ctx, l)
+}
+
+// SetRefundStatus mocks base method.
+func (m *MockDatabase) SetRefundStatus(ctx context.Context, reference string, status model.RefundStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRefundStatus", ctx, reference, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRefundStatus indicates an expected call of SetRefundStatus.
+func (mr *MockDatabaseMockRecorder) SetRefundStatus(ctx, reference, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRefundStatus", reflect.TypeOf((*MockDatabase)(nil).SetRefundStatus), ctx, reference, status)
+}
+
+// Transaction mocks base method.
+func (m *MockDatabase) Transaction(ctx context.Context, fn func(database.Database) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transaction", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Transaction indicates an expected call of Transaction.
+func (mr *MockDatabaseMockRecorder) Transaction(ctx, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transaction", reflect.TypeOf((*MockDatabase)(nil).Transaction), ctx, fn)
+}
+
+// UpdatePromotion mocks base method.
+func (m *MockDatabase) UpdatePromotion(ctx context.Context, p *model.PromotionRule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePromotion", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePromotion indicates an expected call of UpdatePromotion.
+func (mr *MockDatabaseMockRecorder) UpdatePromotion(ctx, p any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePromotion", reflect.TypeOf((*MockDatabase)(nil).UpdatePromotion), ctx, p)
+}
+
+// UpsertItems mocks base method.
+func (m *MockDatabase) UpsertItems(ctx context.Context, items []*model.Item) ([]*model.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertItems", ctx, items)
+	ret0, _ := ret[0].([]*model.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertItems indicates an expected call of UpsertItems.
+func (mr *MockDatabaseMockRecorder) UpsertItems(ctx, items any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertItems", reflect.TypeOf((*MockDatabase)(nil).UpsertItems), ctx, items)
+}
+
+// WatchOutbox mocks base method.
+func (m *MockDatabase) WatchOutbox(ctx context.Context) (<-chan struct{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchOutbox", ctx)
+	ret0, _ := ret[0].(<-chan struct{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchOutbox indicates an expected call of WatchOutbox.
+func (mr *MockDatabaseMockRecorder) WatchOutbox(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchOutbox", reflect.TypeOf((*MockDatabase)(nil).WatchOutbox), ctx)
+}
+
+// MockHealthChecker is a mock of HealthChecker interface.
+type MockHealthChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockHealthCheckerMockRecorder
+	isgomock struct{}
+}
+
+// MockHealthCheckerMockRecorder is the mock recorder for MockHealthChecker.
+type MockHealthCheckerMockRecorder struct {
+	mock *MockHealthChecker
+}
+
+// NewMockHealthChecker creates a new mock instance.
+func NewMockHealthChecker(ctrl *gomock.Controller) *MockHealthChecker {
+	mock := &MockHealthChecker{ctrl: ctrl}
+	mock.recorder = &MockHealthCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHealthChecker) EXPECT() *MockHealthCheckerMockRecorder {
+	return m.recorder
+}
+
+// Ping mocks base method.
+func (m *MockHealthChecker) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockHealthCheckerMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockHealthChecker)(nil).Ping), ctx)
+}
+
+// MockInventoryStore is a mock of InventoryStore interface.
+type MockInventoryStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockInventoryStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockInventoryStoreMockRecorder is the mock recorder for MockInventoryStore.
+type MockInventoryStoreMockRecorder struct {
+	mock *MockInventoryStore
+}
+
+// NewMockInventoryStore creates a new mock instance.
+func NewMockInventoryStore(ctrl *gomock.Controller) *MockInventoryStore {
+	mock := &MockInventoryStore{ctrl: ctrl}
+	mock.recorder = &MockInventoryStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInventoryStore) EXPECT() *MockInventoryStoreMockRecorder {
+	return m.recorder
+}
+
+// AdjustInventory mocks base method.
+func (m *MockInventoryStore) AdjustInventory(ctx context.Context, sku string, delta int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustInventory", ctx, sku, delta)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AdjustInventory indicates an expected call of AdjustInventory.
+func (mr *MockInventoryStoreMockRecorder) AdjustInventory(ctx, sku, delta any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustInventory", reflect.TypeOf((*MockInventoryStore)(nil).AdjustInventory), ctx, sku, delta)
+}
+
+// GetItemByName mocks base method.
+func (m *MockInventoryStore) GetItemByName(ctx context.Context, name string) (*model.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetItemByName", ctx, name)
+	ret0, _ := ret[0].(*model.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetItemByName indicates an expected call of GetItemByName.
+func (mr *MockInventoryStoreMockRecorder) GetItemByName(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemByName", reflect.TypeOf((*MockInventoryStore)(nil).GetItemByName), ctx, name)
+}
+
+// GetItemBySKU mocks base method.
+func (m *MockInventoryStore) GetItemBySKU(ctx context.Context, sku string) (*model.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetItemBySKU", ctx, sku)
+	ret0, _ := ret[0].(*model.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetItemBySKU indicates an expected call of GetItemBySKU.
+func (mr *MockInventoryStoreMockRecorder) GetItemBySKU(ctx, sku any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemBySKU", reflect.TypeOf((*MockInventoryStore)(nil).GetItemBySKU), ctx, sku)
+}
+
+// GetItemsBySKU mocks base method.
+func (m *MockInventoryStore) GetItemsBySKU(ctx context.Context, sku []string) ([]*model.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetItemsBySKU", ctx, sku)
+	ret0, _ := ret[0].([]*model.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetItemsBySKU indicates an expected call of GetItemsBySKU.
+func (mr *MockInventoryStoreMockRecorder) GetItemsBySKU(ctx, sku any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemsBySKU", reflect.TypeOf((*MockInventoryStore)(nil).GetItemsBySKU), ctx, sku)
+}
+
+// ListItems mocks base method.
+func (m *MockInventoryStore) ListItems(ctx context.Context) ([]*model.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListItems", ctx)
+	ret0, _ := ret[0].([]*model.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListItems indicates an expected call of ListItems.
+func (mr *MockInventoryStoreMockRecorder) ListItems(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListItems", reflect.TypeOf((*MockInventoryStore)(nil).ListItems), ctx)
+}
+
+// UpsertItems mocks base method.
+func (m *MockInventoryStore) UpsertItems(ctx context.Context, items []*model.Item) ([]*model.Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertItems", ctx, items)
+	ret0, _ := ret[0].([]*model.Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertItems indicates an expected call of UpsertItems.
+func (mr *MockInventoryStoreMockRecorder) UpsertItems(ctx, items any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertItems", reflect.TypeOf((*MockInventoryStore)(nil).UpsertItems), ctx, items)
+}
+
+// MockOrderStore is a mock of OrderStore interface.
+type MockOrderStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrderStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockOrderStoreMockRecorder is the mock recorder for MockOrderStore.
+type MockOrderStoreMockRecorder struct {
+	mock *MockOrderStore
+}
+
+// NewMockOrderStore creates a new mock instance.
+func NewMockOrderStore(ctrl *gomock.Controller) *MockOrderStore {
+	mock := &MockOrderStore{ctrl: ctrl}
+	mock.recorder = &MockOrderStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOrderStore) EXPECT() *MockOrderStoreMockRecorder {
+	return m.recorder
+}
+
+// AddOrder mocks base method.
+func (m *MockOrderStore) AddOrder(ctx context.Context, o *model.Order) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddOrder", ctx, o)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddOrder indicates an expected call of AddOrder.
+func (mr *MockOrderStoreMockRecorder) AddOrder(ctx, o any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOrder", reflect.TypeOf((*MockOrderStore)(nil).AddOrder), ctx, o)
+}
+
+// GetOrderByReference mocks base method.
+func (m *MockOrderStore) GetOrderByReference(ctx context.Context, reference string) (*model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderByReference", ctx, reference)
+	ret0, _ := ret[0].(*model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderByReference indicates an expected call of GetOrderByReference.
+func (mr *MockOrderStoreMockRecorder) GetOrderByReference(ctx, reference any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByReference", reflect.TypeOf((*MockOrderStore)(nil).GetOrderByReference), ctx, reference)
+}
+
+// GetOrderForUpdate mocks base method.
+func (m *MockOrderStore) GetOrderForUpdate(ctx context.Context, reference string) (*model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderForUpdate", ctx, reference)
+	ret0, _ := ret[0].(*model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderForUpdate indicates an expected call of GetOrderForUpdate.
+func (mr *MockOrderStoreMockRecorder) GetOrderForUpdate(ctx, reference any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderForUpdate", reflect.TypeOf((*MockOrderStore)(nil).GetOrderForUpdate), ctx, reference)
+}
+
+// GetOrderSummary mocks base method.
+func (m *MockOrderStore) GetOrderSummary(ctx context.Context, userID string) (*model.OrderSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderSummary", ctx, userID)
+	ret0, _ := ret[0].(*model.OrderSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderSummary indicates an expected call of GetOrderSummary.
+func (mr *MockOrderStoreMockRecorder) GetOrderSummary(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderSummary", reflect.TypeOf((*MockOrderStore)(nil).GetOrderSummary), ctx, userID)
+}
+
+// GetOrders mocks base method.
+func (m *MockOrderStore) GetOrders(ctx context.Context, userID string) ([]*model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrders", ctx, userID)
+	ret0, _ := ret[0].([]*model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrders indicates an expected call of GetOrders.
+func (mr *MockOrderStoreMockRecorder) GetOrders(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockOrderStore)(nil).GetOrders), ctx, userID)
+}
+
+// GetOrdersSince mocks base method.
+func (m *MockOrderStore) GetOrdersSince(ctx context.Context, userID string, since time.Time) ([]*model.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrdersSince", ctx, userID, since)
+	ret0, _ := ret[0].([]*model.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrdersSince indicates an expected call of GetOrdersSince.
+func (mr *MockOrderStoreMockRecorder) GetOrdersSince(ctx, userID, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrdersSince", reflect.TypeOf((*MockOrderStore)(nil).GetOrdersSince), ctx, userID, since)
+}
+
+// SetPaymentStatus mocks base method.
+func (m *MockOrderStore) SetPaymentStatus(ctx context.Context, reference string, status model.PaymentStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPaymentStatus", ctx, reference, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPaymentStatus indicates an expected call of SetPaymentStatus.
+func (mr *MockOrderStoreMockRecorder) SetPaymentStatus(ctx, reference, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPaymentStatus", reflect.TypeOf((*MockOrderStore)(nil).SetPaymentStatus), ctx, reference, status)
+}
+
+// MockRefundStore is a mock of RefundStore interface.
+type MockRefundStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockRefundStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockRefundStoreMockRecorder is the mock recorder for MockRefundStore.
+type MockRefundStoreMockRecorder struct {
+	mock *MockRefundStore
+}
+
+// NewMockRefundStore creates a new mock instance.
+func NewMockRefundStore(ctrl *gomock.Controller) *MockRefundStore {
+	mock := &MockRefundStore{ctrl: ctrl}
+	mock.recorder = &MockRefundStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRefundStore) EXPECT() *MockRefundStoreMockRecorder {
+	return m.recorder
+}
+
+// AddRefund mocks base method.
+func (m *MockRefundStore) AddRefund(ctx context.Context, r *model.Refund) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddRefund", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddRefund indicates an expected call of AddRefund.
+func (mr *MockRefundStoreMockRecorder) AddRefund(ctx, r any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRefund", reflect.TypeOf((*MockRefundStore)(nil).AddRefund), ctx, r)
+}
+
+// GetRefunds mocks base method.
+func (m *MockRefundStore) GetRefunds(ctx context.Context, orderReference string) ([]*model.Refund, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefunds", ctx, orderReference)
+	ret0, _ := ret[0].([]*model.Refund)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefunds indicates an expected call of GetRefunds.
+func (mr *MockRefundStoreMockRecorder) GetRefunds(ctx, orderReference any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefunds", reflect.TypeOf((*MockRefundStore)(nil).GetRefunds), ctx, orderReference)
+}
+
+// SetRefundStatus mocks base method.
+func (m *MockRefundStore) SetRefundStatus(ctx context.Context, reference string, status model.RefundStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRefundStatus", ctx, reference, status)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRefundStatus indicates an expected call of SetRefundStatus.
+func (mr *MockRefundStoreMockRecorder) SetRefundStatus(ctx, reference, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRefundStatus", reflect.TypeOf((*MockRefundStore)(nil).SetRefundStatus), ctx, reference, status)
+}
+
+// MockGiftCardStore is a mock of GiftCardStore interface.
+type MockGiftCardStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockGiftCardStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockGiftCardStoreMockRecorder is the mock recorder for MockGiftCardStore.
+type MockGiftCardStoreMockRecorder struct {
+	mock *MockGiftCardStore
+}
+
+// NewMockGiftCardStore creates a new mock instance.
+func NewMockGiftCardStore(ctrl *gomock.Controller) *MockGiftCardStore {
+	mock := &MockGiftCardStore{ctrl: ctrl}
+	mock.recorder = &MockGiftCardStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGiftCardStore) EXPECT() *MockGiftCardStoreMockRecorder {
+	return m.recorder
+}
+
+// AdjustGiftCard mocks base method.
+func (m *MockGiftCardStore) AdjustGiftCard(ctx context.Context, entry *model.GiftCardEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustGiftCard", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AdjustGiftCard indicates an expected call of AdjustGiftCard.
+func (mr *MockGiftCardStoreMockRecorder) AdjustGiftCard(ctx, entry any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustGiftCard", reflect.TypeOf((*MockGiftCardStore)(nil).AdjustGiftCard), ctx, entry)
+}
+
+// ExpireGiftCard mocks base method.
+func (m *MockGiftCardStore) ExpireGiftCard(ctx context.Context, code string, at time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireGiftCard", ctx, code, at)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExpireGiftCard indicates an expected call of ExpireGiftCard.
+func (mr *MockGiftCardStoreMockRecorder) ExpireGiftCard(ctx, code, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireGiftCard", reflect.TypeOf((*MockGiftCardStore)(nil).ExpireGiftCard), ctx, code, at)
+}
+
+// GetGiftCard mocks base method.
+func (m *MockGiftCardStore) GetGiftCard(ctx context.Context, code string) (*model.GiftCard, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGiftCard", ctx, code)
+	ret0, _ := ret[0].(*model.GiftCard)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGiftCard indicates an expected call of GetGiftCard.
+func (mr *MockGiftCardStoreMockRecorder) GetGiftCard(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGiftCard", reflect.TypeOf((*MockGiftCardStore)(nil).GetGiftCard), ctx, code)
+}
+
+// GetGiftCardLedger mocks base method.
+func (m *MockGiftCardStore) GetGiftCardLedger(ctx context.Context, code string) ([]model.GiftCardEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGiftCardLedger", ctx, code)
+	ret0, _ := ret[0].([]model.GiftCardEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGiftCardLedger indicates an expected call of GetGiftCardLedger.
+func (mr *MockGiftCardStoreMockRecorder) GetGiftCardLedger(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGiftCardLedger", reflect.TypeOf((*MockGiftCardStore)(nil).GetGiftCardLedger), ctx, code)
+}
+
+// IssueGiftCard mocks base method.
+func (m *MockGiftCardStore) IssueGiftCard(ctx context.Context, card *model.GiftCard) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueGiftCard", ctx, card)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IssueGiftCard indicates an expected call of IssueGiftCard.
+func (mr *MockGiftCardStoreMockRecorder) IssueGiftCard(ctx, card any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueGiftCard", reflect.TypeOf((*MockGiftCardStore)(nil).IssueGiftCard), ctx, card)
+}
+
+// MockLoyaltyStore is a mock of LoyaltyStore interface.
+type MockLoyaltyStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoyaltyStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockLoyaltyStoreMockRecorder is the mock recorder for MockLoyaltyStore.
+type MockLoyaltyStoreMockRecorder struct {
+	mock *MockLoyaltyStore
+}
+
+// NewMockLoyaltyStore creates a new mock instance.
+func NewMockLoyaltyStore(ctrl *gomock.Controller) *MockLoyaltyStore {
+	mock := &MockLoyaltyStore{ctrl: ctrl}
+	mock.recorder = &MockLoyaltyStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoyaltyStore) EXPECT() *MockLoyaltyStoreMockRecorder {
+	return m.recorder
+}
+
+// AdjustLoyalty mocks base method.
+func (m *MockLoyaltyStore) AdjustLoyalty(ctx context.Context, entry *model.LoyaltyEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustLoyalty", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AdjustLoyalty indicates an expected call of AdjustLoyalty.
+func (mr *MockLoyaltyStoreMockRecorder) AdjustLoyalty(ctx, entry any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustLoyalty", reflect.TypeOf((*MockLoyaltyStore)(nil).AdjustLoyalty), ctx, entry)
+}
+
+// GetLoyaltyAccount mocks base method.
+func (m *MockLoyaltyStore) GetLoyaltyAccount(ctx context.Context, customerID string) (*model.LoyaltyAccount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoyaltyAccount", ctx, customerID)
+	ret0, _ := ret[0].(*model.LoyaltyAccount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoyaltyAccount indicates an expected call of GetLoyaltyAccount.
+func (mr *MockLoyaltyStoreMockRecorder) GetLoyaltyAccount(ctx, customerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoyaltyAccount", reflect.TypeOf((*MockLoyaltyStore)(nil).GetLoyaltyAccount), ctx, customerID)
+}
+
+// GetLoyaltyLedger mocks base method.
+func (m *MockLoyaltyStore) GetLoyaltyLedger(ctx context.Context, customerID string) ([]model.LoyaltyEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoyaltyLedger", ctx, customerID)
+	ret0, _ := ret[0].([]model.LoyaltyEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoyaltyLedger indicates an expected call of GetLoyaltyLedger.
+func (mr *MockLoyaltyStoreMockRecorder) GetLoyaltyLedger(ctx, customerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoyaltyLedger", reflect.TypeOf((*MockLoyaltyStore)(nil).GetLoyaltyLedger), ctx, customerID)
+}
+
+// MockLimitStore is a mock of LimitStore interface.
+type MockLimitStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockLimitStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockLimitStoreMockRecorder is the mock recorder for MockLimitStore.
+type MockLimitStoreMockRecorder struct {
+	mock *MockLimitStore
+}
+
+// NewMockLimitStore creates a new mock instance.
+func NewMockLimitStore(ctrl *gomock.Controller) *MockLimitStore {
+	mock := &MockLimitStore{ctrl: ctrl}
+	mock.recorder = &MockLimitStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLimitStore) EXPECT() *MockLimitStoreMockRecorder {
+	return m.recorder
+}
+
+// DeletePurchaseLimit mocks base method.
+func (m *MockLimitStore) DeletePurchaseLimit(ctx context.Context, sku string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePurchaseLimit", ctx, sku)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePurchaseLimit indicates an expected call of DeletePurchaseLimit.
+func (mr *MockLimitStoreMockRecorder) DeletePurchaseLimit(ctx, sku any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePurchaseLimit", reflect.TypeOf((*MockLimitStore)(nil).DeletePurchaseLimit), ctx, sku)
+}
+
+// GetPurchaseLimits mocks base method.
+func (m *MockLimitStore) GetPurchaseLimits(ctx context.Context, skus []string) ([]*model.PurchaseLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPurchaseLimits", ctx, skus)
+	ret0, _ := ret[0].([]*model.PurchaseLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPurchaseLimits indicates an expected call of GetPurchaseLimits.
+func (mr *MockLimitStoreMockRecorder) GetPurchaseLimits(ctx, skus any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPurchaseLimits", reflect.TypeOf((*MockLimitStore)(nil).GetPurchaseLimits), ctx, skus)
+}
+
+// ListPurchaseLimits mocks base method.
+func (m *MockLimitStore) ListPurchaseLimits(ctx context.Context) ([]*model.PurchaseLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPurchaseLimits", ctx)
+	ret0, _ := ret[0].([]*model.PurchaseLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPurchaseLimits indicates an expected call of ListPurchaseLimits.
+func (mr *MockLimitStoreMockRecorder) ListPurchaseLimits(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPurchaseLimits", reflect.TypeOf((*MockLimitStore)(nil).ListPurchaseLimits), ctx)
+}
+
+// LockPurchaseLimits mocks base method.
+func (m *MockLimitStore) LockPurchaseLimits(ctx context.Context, customerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockPurchaseLimits", ctx, customerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockPurchaseLimits indicates an expected call of LockPurchaseLimits.
+func (mr *MockLimitStoreMockRecorder) LockPurchaseLimits(ctx, customerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockPurchaseLimits", reflect.TypeOf((*MockLimitStore)(nil).LockPurchaseLimits), ctx, customerID)
+}
+
+// SetPurchaseLimit mocks base method.
+func (m *MockLimitStore) SetPurchaseLimit(ctx context.Context, l *model.PurchaseLimit) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPurchaseLimit", ctx, l)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPurchaseLimit indicates an expected call of SetPurchaseLimit.
+func (mr *MockLimitStoreMockRecorder) SetPurchaseLimit(ctx, l any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPurchaseLimit", reflect.TypeOf((*MockLimitStore)(nil).SetPurchaseLimit), ctx, l)
+}
+
+// MockBundleStore is a mock of BundleStore interface.
+type MockBundleStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockBundleStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockBundleStoreMockRecorder is the mock recorder for MockBundleStore.
+type MockBundleStoreMockRecorder struct {
+	mock *MockBundleStore
+}
+
+// NewMockBundleStore creates a new mock instance.
+func NewMockBundleStore(ctrl *gomock.Controller) *MockBundleStore {
+	mock := &MockBundleStore{ctrl: ctrl}
+	mock.recorder = &MockBundleStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBundleStore) EXPECT() *MockBundleStoreMockRecorder {
+	return m.recorder
+}
+
+// DeleteBundle mocks base method.
+func (m *MockBundleStore) DeleteBundle(ctx context.Context, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBundle", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBundle indicates an expected call of DeleteBundle.
+func (mr *MockBundleStoreMockRecorder) DeleteBundle(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBundle", reflect.TypeOf((*MockBundleStore)(nil).DeleteBundle), ctx, code)
+}
+
+// GetBundle mocks base method.
+func (m *MockBundleStore) GetBundle(ctx context.Context, code string) (*model.Bundle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBundle", ctx, code)
+	ret0, _ := ret[0].(*model.Bundle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBundle indicates an expected call of GetBundle.
+func (mr *MockBundleStoreMockRecorder) GetBundle(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBundle", reflect.TypeOf((*MockBundleStore)(nil).GetBundle), ctx, code)
+}
+
+// ListBundles mocks base method.
+func (m *MockBundleStore) ListBundles(ctx context.Context, activeOnly bool) ([]*model.Bundle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBundles", ctx, activeOnly)
+	ret0, _ := ret[0].([]*model.Bundle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListBundles indicates an expected call of ListBundles.
+func (mr *MockBundleStoreMockRecorder) ListBundles(ctx, activeOnly any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBundles", reflect.TypeOf((*MockBundleStore)(nil).ListBundles), ctx, activeOnly)
+}
+
+// PutBundle mocks base method.
+func (m *MockBundleStore) PutBundle(ctx context.Context, b *model.Bundle) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutBundle", ctx, b)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutBundle indicates an expected call of PutBundle.
+func (mr *MockBundleStoreMockRecorder) PutBundle(ctx, b any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutBundle", reflect.TypeOf((*MockBundleStore)(nil).PutBundle), ctx, b)
+}
+
+// MockPromotionStore is a mock of PromotionStore interface.
+type MockPromotionStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockPromotionStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockPromotionStoreMockRecorder is the mock recorder for MockPromotionStore.
+type MockPromotionStoreMockRecorder struct {
+	mock *MockPromotionStore
+}
+
+// NewMockPromotionStore creates a new mock instance.
+func NewMockPromotionStore(ctrl *gomock.Controller) *MockPromotionStore {
+	mock := &MockPromotionStore{ctrl: ctrl}
+	mock.recorder = &MockPromotionStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPromotionStore) EXPECT() *MockPromotionStoreMockRecorder {
+	return m.recorder
+}
+
+// AddPromotion mocks base method.
+func (m *MockPromotionStore) AddPromotion(ctx context.Context, p *model.PromotionRule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPromotion", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPromotion indicates an expected call of AddPromotion.
+func (mr *MockPromotionStoreMockRecorder) AddPromotion(ctx, p any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPromotion", reflect.TypeOf((*MockPromotionStore)(nil).AddPromotion), ctx, p)
+}
+
+// DeletePromotion mocks base method.
+func (m *MockPromotionStore) DeletePromotion(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePromotion", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePromotion indicates an expected call of DeletePromotion.
+func (mr *MockPromotionStoreMockRecorder) DeletePromotion(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePromotion", reflect.TypeOf((*MockPromotionStore)(nil).DeletePromotion), ctx, id)
+}
+
+// GetPromotion mocks base method.
+func (m *MockPromotionStore) GetPromotion(ctx context.Context, id int) (*model.PromotionRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPromotion", ctx, id)
+	ret0, _ := ret[0].(*model.PromotionRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPromotion indicates an expected call of GetPromotion.
+func (mr *MockPromotionStoreMockRecorder) GetPromotion(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPromotion", reflect.TypeOf((*MockPromotionStore)(nil).GetPromotion), ctx, id)
+}
+
+// ListPromotions mocks base method.
+func (m *MockPromotionStore) ListPromotions(ctx context.Context, activeOnly bool) ([]*model.PromotionRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPromotions", ctx, activeOnly)
+	ret0, _ := ret[0].([]*model.PromotionRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPromotions indicates an expected call of ListPromotions.
+func (mr *MockPromotionStoreMockRecorder) ListPromotions(ctx, activeOnly any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPromotions", reflect.TypeOf((*MockPromotionStore)(nil).ListPromotions), ctx, activeOnly)
+}
+
+// RedeemPromotion mocks base method.
+func (m *MockPromotionStore) RedeemPromotion(ctx context.Context, id int, discount decimal.Decimal, units int) (*model.PromotionRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeemPromotion", ctx, id, discount, units)
+	ret0, _ := ret[0].(*model.PromotionRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RedeemPromotion indicates an expected call of RedeemPromotion.
+func (mr *MockPromotionStoreMockRecorder) RedeemPromotion(ctx, id, discount, units any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemPromotion", reflect.TypeOf((*MockPromotionStore)(nil).RedeemPromotion), ctx, id, discount, units)
+}
+
+// UpdatePromotion mocks base method.
+func (m *MockPromotionStore) UpdatePromotion(ctx context.Context, p *model.PromotionRule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePromotion", ctx, p)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePromotion indicates an expected call of UpdatePromotion.
+func (mr *MockPromotionStoreMockRecorder) UpdatePromotion(ctx, p any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePromotion", reflect.TypeOf((*MockPromotionStore)(nil).UpdatePromotion), ctx, p)
+}
+
+// MockCouponStore is a mock of CouponStore interface.
+type MockCouponStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockCouponStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockCouponStoreMockRecorder is the mock recorder for MockCouponStore.
+type MockCouponStoreMockRecorder struct {
+	mock *MockCouponStore
+}
+
+// NewMockCouponStore creates a new mock instance.
+func NewMockCouponStore(ctrl *gomock.Controller) *MockCouponStore {
+	mock := &MockCouponStore{ctrl: ctrl}
+	mock.recorder = &MockCouponStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCouponStore) EXPECT() *MockCouponStoreMockRecorder {
+	return m.recorder
+}
+
+// AddCoupon mocks base method.
+func (m *MockCouponStore) AddCoupon(ctx context.Context, c *model.Coupon) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddCoupon", ctx, c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddCoupon indicates an expected call of AddCoupon.
+func (mr *MockCouponStoreMockRecorder) AddCoupon(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCoupon", reflect.TypeOf((*MockCouponStore)(nil).AddCoupon), ctx, c)
+}
+
+// CountCouponRedemptions mocks base method.
+func (m *MockCouponStore) CountCouponRedemptions(ctx context.Context, code, customerID string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountCouponRedemptions", ctx, code, customerID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountCouponRedemptions indicates an expected call of CountCouponRedemptions.
+func (mr *MockCouponStoreMockRecorder) CountCouponRedemptions(ctx, code, customerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountCouponRedemptions", reflect.TypeOf((*MockCouponStore)(nil).CountCouponRedemptions), ctx, code, customerID)
+}
+
+// DeleteCoupon mocks base method.
+func (m *MockCouponStore) DeleteCoupon(ctx context.Context, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCoupon", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCoupon indicates an expected call of DeleteCoupon.
+func (mr *MockCouponStoreMockRecorder) DeleteCoupon(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCoupon", reflect.TypeOf((*MockCouponStore)(nil).DeleteCoupon), ctx, code)
+}
+
+// GetCoupon mocks base method.
+func (m *MockCouponStore) GetCoupon(ctx context.Context, code string) (*model.Coupon, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCoupon", ctx, code)
+	ret0, _ := ret[0].(*model.Coupon)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCoupon indicates an expected call of GetCoupon.
+func (mr *MockCouponStoreMockRecorder) GetCoupon(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoupon", reflect.TypeOf((*MockCouponStore)(nil).GetCoupon), ctx, code)
+}
+
+// ListCoupons mocks base method.
+func (m *MockCouponStore) ListCoupons(ctx context.Context) ([]*model.Coupon, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCoupons", ctx)
+	ret0, _ := ret[0].([]*model.Coupon)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCoupons indicates an expected call of ListCoupons.
+func (mr *MockCouponStoreMockRecorder) ListCoupons(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCoupons", reflect.TypeOf((*MockCouponStore)(nil).ListCoupons), ctx)
+}
+
+// RedeemCoupon mocks base method.
+func (m *MockCouponStore) RedeemCoupon(ctx context.Context, r *model.CouponRedemption) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeemCoupon", ctx, r)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RedeemCoupon indicates an expected call of RedeemCoupon.
+func (mr *MockCouponStoreMockRecorder) RedeemCoupon(ctx, r any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemCoupon", reflect.TypeOf((*MockCouponStore)(nil).RedeemCoupon), ctx, r)
+}
+
+// MockOutboxStore is a mock of OutboxStore interface.
+type MockOutboxStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockOutboxStoreMockRecorder is the mock recorder for MockOutboxStore.
+type MockOutboxStoreMockRecorder struct {
+	mock *MockOutboxStore
 }
 
 // NewMockOutboxStore creates a new mock instance.
@@ -474,6 +1905,80 @@ func (mr *MockOutboxStoreMockRecorder) AddOutboxItems(ctx, items any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOutboxItems", reflect.TypeOf((*MockOutboxStore)(nil).AddOutboxItems), ctx, items)
 }
 
+// ClaimOutboxItems mocks base method.
+func (m *MockOutboxStore) ClaimOutboxItems(ctx context.Context, owner string, lease time.Duration, limit int) ([]*model.OutboxItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimOutboxItems", ctx, owner, lease, limit)
+	ret0, _ := ret[0].([]*model.OutboxItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimOutboxItems indicates an expected call of ClaimOutboxItems.
+func (mr *MockOutboxStoreMockRecorder) ClaimOutboxItems(ctx, owner, lease, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOutboxItems", reflect.TypeOf((*MockOutboxStore)(nil).ClaimOutboxItems), ctx, owner, lease, limit)
+}
+
+// FailOutboxItem mocks base method.
+func (m *MockOutboxStore) FailOutboxItem(ctx context.Context, id int64, reason string, retryAt *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailOutboxItem", ctx, id, reason, retryAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailOutboxItem indicates an expected call of FailOutboxItem.
+func (mr *MockOutboxStoreMockRecorder) FailOutboxItem(ctx, id, reason, retryAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailOutboxItem", reflect.TypeOf((*MockOutboxStore)(nil).FailOutboxItem), ctx, id, reason, retryAt)
+}
+
+// GetOutboxAudit mocks base method.
+func (m *MockOutboxStore) GetOutboxAudit(ctx context.Context, limit int) ([]*model.OutboxAuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutboxAudit", ctx, limit)
+	ret0, _ := ret[0].([]*model.OutboxAuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutboxAudit indicates an expected call of GetOutboxAudit.
+func (mr *MockOutboxStoreMockRecorder) GetOutboxAudit(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxAudit", reflect.TypeOf((*MockOutboxStore)(nil).GetOutboxAudit), ctx, limit)
+}
+
+// GetOutboxBacklog mocks base method.
+func (m *MockOutboxStore) GetOutboxBacklog(ctx context.Context) (*model.OutboxBacklog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutboxBacklog", ctx)
+	ret0, _ := ret[0].(*model.OutboxBacklog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutboxBacklog indicates an expected call of GetOutboxBacklog.
+func (mr *MockOutboxStoreMockRecorder) GetOutboxBacklog(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxBacklog", reflect.TypeOf((*MockOutboxStore)(nil).GetOutboxBacklog), ctx)
+}
+
+// GetOutboxItem mocks base method.
+func (m *MockOutboxStore) GetOutboxItem(ctx context.Context, eventID string) (*model.OutboxItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutboxItem", ctx, eventID)
+	ret0, _ := ret[0].(*model.OutboxItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutboxItem indicates an expected call of GetOutboxItem.
+func (mr *MockOutboxStoreMockRecorder) GetOutboxItem(ctx, eventID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxItem", reflect.TypeOf((*MockOutboxStore)(nil).GetOutboxItem), ctx, eventID)
+}
+
 // GetOutboxItems mocks base method.
 func (m *MockOutboxStore) GetOutboxItems(ctx context.Context, q *database.OutboxQuery) ([]*model.OutboxItem, error) {
 	m.ctrl.T.Helper()
@@ -489,6 +1994,49 @@ func (mr *MockOutboxStoreMockRecorder) GetOutboxItems(ctx, q any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutboxItems", reflect.TypeOf((*MockOutboxStore)(nil).GetOutboxItems), ctx, q)
 }
 
+// PurgeOutboxItems mocks base method.
+func (m *MockOutboxStore) PurgeOutboxItems(ctx context.Context, before time.Time, limit int, archive bool) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeOutboxItems", ctx, before, limit, archive)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeOutboxItems indicates an expected call of PurgeOutboxItems.
+func (mr *MockOutboxStoreMockRecorder) PurgeOutboxItems(ctx, before, limit, archive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeOutboxItems", reflect.TypeOf((*MockOutboxStore)(nil).PurgeOutboxItems), ctx, before, limit, archive)
+}
+
+// ReleaseOutboxItems mocks base method.
+func (m *MockOutboxStore) ReleaseOutboxItems(ctx context.Context, owner string, ids []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseOutboxItems", ctx, owner, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseOutboxItems indicates an expected call of ReleaseOutboxItems.
+func (mr *MockOutboxStoreMockRecorder) ReleaseOutboxItems(ctx, owner, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseOutboxItems", reflect.TypeOf((*MockOutboxStore)(nil).ReleaseOutboxItems), ctx, owner, ids)
+}
+
+// ResetOutboxItems mocks base method.
+func (m *MockOutboxStore) ResetOutboxItems(ctx context.Context, entry *model.OutboxAuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetOutboxItems", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetOutboxItems indicates an expected call of ResetOutboxItems.
+func (mr *MockOutboxStoreMockRecorder) ResetOutboxItems(ctx, entry any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetOutboxItems", reflect.TypeOf((*MockOutboxStore)(nil).ResetOutboxItems), ctx, entry)
+}
+
 // SetDeliveredAt mocks base method.
 func (m *MockOutboxStore) SetDeliveredAt(ctx context.Context, id int64, t time.Time) error {
 	m.ctrl.T.Helper()
@@ -503,6 +2051,20 @@ func (mr *MockOutboxStoreMockRecorder) SetDeliveredAt(ctx, id, t any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeliveredAt", reflect.TypeOf((*MockOutboxStore)(nil).SetDeliveredAt), ctx, id, t)
 }
 
+// SetDeliveredAtBulk mocks base method.
+func (m *MockOutboxStore) SetDeliveredAtBulk(ctx context.Context, ids []int64, t time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDeliveredAtBulk", ctx, ids, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDeliveredAtBulk indicates an expected call of SetDeliveredAtBulk.
+func (mr *MockOutboxStoreMockRecorder) SetDeliveredAtBulk(ctx, ids, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeliveredAtBulk", reflect.TypeOf((*MockOutboxStore)(nil).SetDeliveredAtBulk), ctx, ids, t)
+}
+
 // SetDeliveredByEventID mocks base method.
 func (m *MockOutboxStore) SetDeliveredByEventID(ctx context.Context, eventID string, t time.Time) error {
 	m.ctrl.T.Helper()
@@ -530,3 +2092,32 @@ func (mr *MockOutboxStoreMockRecorder) SetPublishedAt(ctx, id, t any) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPublishedAt", reflect.TypeOf((*MockOutboxStore)(nil).SetPublishedAt), ctx, id, t)
 }
+
+// SetPublishedAtBulk mocks base method.
+func (m *MockOutboxStore) SetPublishedAtBulk(ctx context.Context, ids []int64, t time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPublishedAtBulk", ctx, ids, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPublishedAtBulk indicates an expected call of SetPublishedAtBulk.
+func (mr *MockOutboxStoreMockRecorder) SetPublishedAtBulk(ctx, ids, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPublishedAtBulk", reflect.TypeOf((*MockOutboxStore)(nil).SetPublishedAtBulk), ctx, ids, t)
+}
+
+// WatchOutbox mocks base method.
+func (m *MockOutboxStore) WatchOutbox(ctx context.Context) (<-chan struct{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchOutbox", ctx)
+	ret0, _ := ret[0].(<-chan struct{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchOutbox indicates an expected call of WatchOutbox.
+func (mr *MockOutboxStoreMockRecorder) WatchOutbox(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchOutbox", reflect.TypeOf((*MockOutboxStore)(nil).WatchOutbox), ctx)
+}
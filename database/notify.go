@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// outboxChannel is the Postgres notification channel AddOutboxItems notifies
+// and WatchOutbox listens on.
+const outboxChannel = "outbox"
+
+// wakeup broadcasts "rows were added to the outbox" to the watchers in this
+// process. It stands in for LISTEN/NOTIFY on databases without it (SQLite),
+// where every writer and relay share the process anyway.
+type wakeup struct {
+	mu       sync.Mutex
+	watchers map[chan struct{}]struct{}
+}
+
+func newWakeup() *wakeup {
+	return &wakeup{watchers: make(map[chan struct{}]struct{})}
+}
+
+// watch returns a channel signalled on every notify until ctx is done, when
+// it is closed.
+func (w *wakeup) watch(ctx context.Context) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+	w.mu.Lock()
+	w.watchers[ch] = struct{}{}
+	w.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		w.mu.Lock()
+		delete(w.watchers, ch)
+		w.mu.Unlock()
+		close(ch)
+	}()
+	return ch
+}
+
+// notify signals every watcher without blocking. A watcher already signalled
+// has a wake-up pending, so signals coalesce.
+func (w *wakeup) notify() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// listen holds a dedicated Postgres connection LISTENing on outboxChannel and
+// signals the returned channel on each notification. The channel is closed
+// when ctx is done or the connection fails.
+func listen(ctx context.Context, db *sql.DB) (<-chan struct{}, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listen for outbox notifications: %w", err)
+	}
+	if err := conn.Raw(func(dc any) error {
+		_, err := dc.(*stdlib.Conn).Conn().Exec(ctx, "LISTEN "+outboxChannel)
+		return err
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("listen for outbox notifications: %w", err)
+	}
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+		defer conn.Close()
+		var err error
+		_ = conn.Raw(func(dc any) error {
+			c := dc.(*stdlib.Conn).Conn()
+			for {
+				if _, err = c.WaitForNotification(ctx); err != nil {
+					// The connection is still LISTENing: have the pool
+					// discard it rather than hand it to another query.
+					return driver.ErrBadConn
+				}
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		})
+		if ctx.Err() == nil {
+			slog.Warn("outbox notification listener stopped", "error", err)
+		}
+	}()
+	return ch, nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
 )
 
 type InventoryStore interface {
@@ -13,11 +14,138 @@ type InventoryStore interface {
 	GetItemByName(ctx context.Context, name string) (*model.Item, error)
 	GetItemBySKU(ctx context.Context, sku string) (*model.Item, error)
 	GetItemsBySKU(ctx context.Context, sku []string) ([]*model.Item, error)
+	// AdjustInventory atomically adds delta (which may be negative) to an
+	// item's stock. It errors with ErrItemNotFound if no item has that SKU.
+	AdjustInventory(ctx context.Context, sku string, delta int) error
 }
 
 type OrderStore interface {
 	AddOrder(ctx context.Context, o *model.Order) error
 	GetOrders(ctx context.Context, userID string) ([]*model.Order, error)
+	// GetOrdersSince returns the customer's orders placed at or after since.
+	GetOrdersSince(ctx context.Context, userID string, since time.Time) ([]*model.Order, error)
+	// GetOrderSummary summarizes the customer's order history.
+	GetOrderSummary(ctx context.Context, userID string) (*model.OrderSummary, error)
+	// GetOrderByReference returns the order with the given reference, or
+	// ErrOrderNotFound.
+	GetOrderByReference(ctx context.Context, reference string) (*model.Order, error)
+	// GetOrderForUpdate is GetOrderByReference that, inside a transaction,
+	// also locks the order row until commit so concurrent writers against the
+	// same order (e.g. two refunds) serialize.
+	GetOrderForUpdate(ctx context.Context, reference string) (*model.Order, error)
+	// SetPaymentStatus strictly updates an order's payment status: it errors
+	// with ErrOrderNotFound if no order has that reference.
+	SetPaymentStatus(ctx context.Context, reference string, status model.PaymentStatus) error
+}
+
+// RefundStore persists refunds recorded against orders.
+type RefundStore interface {
+	AddRefund(ctx context.Context, r *model.Refund) error
+	// GetRefunds returns an order's refunds, oldest first.
+	GetRefunds(ctx context.Context, orderReference string) ([]*model.Refund, error)
+	// SetRefundStatus strictly updates a refund's status: it errors with
+	// ErrRefundNotFound if no refund has that reference.
+	SetRefundStatus(ctx context.Context, reference string, status model.RefundStatus) error
+}
+
+// LimitStore persists per-SKU purchase limits.
+type LimitStore interface {
+	// SetPurchaseLimit creates or replaces the limit for l.SKU.
+	SetPurchaseLimit(ctx context.Context, l *model.PurchaseLimit) error
+	ListPurchaseLimits(ctx context.Context) ([]*model.PurchaseLimit, error)
+	// GetPurchaseLimits returns the limits set for any of skus.
+	GetPurchaseLimits(ctx context.Context, skus []string) ([]*model.PurchaseLimit, error)
+	// DeletePurchaseLimit errors with ErrLimitNotFound if sku has no limit.
+	DeletePurchaseLimit(ctx context.Context, sku string) error
+	// LockPurchaseLimits locks the customer's limit row until the surrounding
+	// transaction ends, serializing window-limit checks for that customer.
+	LockPurchaseLimits(ctx context.Context, customerID string) error
+}
+
+// BundleStore persists fixed-price bundles.
+type BundleStore interface {
+	// PutBundle creates or replaces the bundle with b.Code.
+	PutBundle(ctx context.Context, b *model.Bundle) error
+	// GetBundle errors with ErrBundleNotFound if no bundle has that code.
+	GetBundle(ctx context.Context, code string) (*model.Bundle, error)
+	// ListBundles returns bundles in code order, only active ones if
+	// activeOnly is set.
+	ListBundles(ctx context.Context, activeOnly bool) ([]*model.Bundle, error)
+	// DeleteBundle errors with ErrBundleNotFound if no bundle has that code.
+	DeleteBundle(ctx context.Context, code string) error
+}
+
+// PromotionStore persists promotions defined as data.
+type PromotionStore interface {
+	AddPromotion(ctx context.Context, p *model.PromotionRule) error
+	// UpdatePromotion replaces every field of the promotion with p.ID. It
+	// errors with ErrPromotionNotFound if there is none.
+	UpdatePromotion(ctx context.Context, p *model.PromotionRule) error
+	GetPromotion(ctx context.Context, id int) (*model.PromotionRule, error)
+	// ListPromotions returns promotions in ID order, only active ones if
+	// activeOnly is set.
+	ListPromotions(ctx context.Context, activeOnly bool) ([]*model.PromotionRule, error)
+	DeletePromotion(ctx context.Context, id int) error
+	// RedeemPromotion atomically adds one redemption of discount (in the
+	// catalog currency) over units to the promotion's usage, deactivating it
+	// if that exhausts its budget, and returns the updated promotion. It
+	// errors with ErrPromotionInactive, recording nothing, if the promotion is
+	// no longer active.
+	RedeemPromotion(ctx context.Context, id int, discount decimal.Decimal, units int) (*model.PromotionRule, error)
+}
+
+// CouponStore persists coupon codes and their redemptions.
+type CouponStore interface {
+	AddCoupon(ctx context.Context, c *model.Coupon) error
+	// GetCoupon errors with ErrCouponNotFound if no coupon has that code.
+	GetCoupon(ctx context.Context, code string) (*model.Coupon, error)
+	ListCoupons(ctx context.Context) ([]*model.Coupon, error)
+	DeleteCoupon(ctx context.Context, code string) error
+	// CountCouponRedemptions returns how many times the customer has redeemed
+	// the coupon.
+	CountCouponRedemptions(ctx context.Context, code, customerID string) (int, error)
+	// RedeemCoupon atomically records r against the coupon's limits. It errors
+	// with ErrCouponExpired past the coupon's expiry, ErrCouponExhausted when
+	// it has no redemptions left and ErrCouponCustomerLimit when r.CustomerID
+	// has used it up, leaving nothing recorded.
+	RedeemCoupon(ctx context.Context, r *model.CouponRedemption) error
+}
+
+// GiftCardStore persists gift cards and their balance ledgers.
+type GiftCardStore interface {
+	// IssueGiftCard creates a card with its opening balance and records the
+	// matching issue entry on its ledger.
+	IssueGiftCard(ctx context.Context, card *model.GiftCard) error
+	// GetGiftCard errors with ErrGiftCardNotFound if no card has that code.
+	GetGiftCard(ctx context.Context, code string) (*model.GiftCard, error)
+	// GetGiftCardLedger returns a card's ledger entries, oldest first.
+	GetGiftCardLedger(ctx context.Context, code string) ([]model.GiftCardEntry, error)
+	// AdjustGiftCard atomically applies entry.Amount to the card's balance and
+	// appends entry to its ledger. A redemption is refused with
+	// ErrGiftCardExpired past the card's expiry and with
+	// ErrInsufficientBalance if it would take the balance below zero; the
+	// check and the debit are a single conditional UPDATE, so concurrent
+	// redemptions cannot overdraw the card.
+	AdjustGiftCard(ctx context.Context, entry *model.GiftCardEntry) error
+	// ExpireGiftCard forfeits a card's remaining balance, recording an expiry
+	// entry, and sets its expiry to at if that is sooner.
+	ExpireGiftCard(ctx context.Context, code string, at time.Time) error
+}
+
+// LoyaltyStore persists customers' loyalty points balances and ledgers.
+type LoyaltyStore interface {
+	// GetLoyaltyAccount returns the customer's balance, zero if they have
+	// never earned points.
+	GetLoyaltyAccount(ctx context.Context, customerID string) (*model.LoyaltyAccount, error)
+	// GetLoyaltyLedger returns a customer's ledger entries, oldest first.
+	GetLoyaltyLedger(ctx context.Context, customerID string) ([]model.LoyaltyEntry, error)
+	// AdjustLoyalty atomically applies entry.Points to the customer's balance
+	// and appends entry to their ledger. An entry repeating the reason of one
+	// already recorded for its order is ignored, so an earning can be
+	// replayed safely. A redemption is refused with ErrInsufficientPoints if
+	// it would take the balance below zero; the check and the debit are a
+	// single conditional UPDATE, as for gift cards.
+	AdjustLoyalty(ctx context.Context, entry *model.LoyaltyEntry) error
 }
 
 // OutboxStore persists and drains transactional outbox rows.
@@ -26,15 +154,80 @@ type OutboxStore interface {
 	// transaction as the business write it accompanies.
 	AddOutboxItems(ctx context.Context, items []*model.OutboxItem) error
 
+	// WatchOutbox returns a channel signalled soon after rows are added to
+	// the outbox, by any writer sharing the database on Postgres (LISTEN/
+	// NOTIFY) or by this process elsewhere. Signals coalesce: one may stand
+	// for several additions. The channel is closed when ctx is done or the
+	// watch fails, after which the caller may watch again.
+	WatchOutbox(ctx context.Context) (<-chan struct{}, error)
+
 	// GetOutboxItems reads items, optionally filtered to those not yet
 	// published or delivered. Results are ordered by ID ascending (enqueue
 	// order).
 	GetOutboxItems(ctx context.Context, q *OutboxQuery) ([]*model.OutboxItem, error)
 
+	// GetOutboxBacklog summarises the unpublished and dead items.
+	GetOutboxBacklog(ctx context.Context) (*model.OutboxBacklog, error)
+
+	// GetOutboxItem reads the item carrying the event with the given ID. It
+	// errors with ErrOutboxItemNotFound if there is none.
+	GetOutboxItem(ctx context.Context, eventID string) (*model.OutboxItem, error)
+
+	// ClaimOutboxItems leases up to limit unpublished rows to owner for lease
+	// and returns them in ID order. Rows under another unexpired lease are
+	// skipped, so relays sharing the table each publish a different batch, as
+	// are dead rows and rows backing off until their next attempt. A row with
+	// a partition key is also skipped while an earlier row with its topic and
+	// key is unpublished, not dead and not in the batch, so rows sharing a key
+	// are published in order. owner must be unique to the relay.
+	ClaimOutboxItems(ctx context.Context, owner string, lease time.Duration, limit int) ([]*model.OutboxItem, error)
+
+	// FailOutboxItem records a failed attempt to publish one item: it counts
+	// the attempt, keeps reason as its last error and releases its claim. The
+	// item is claimable again from retryAt or, when retryAt is nil, marked
+	// dead and never claimed again. It errors with ErrOutboxItemNotFound if no
+	// row has that ID.
+	FailOutboxItem(ctx context.Context, id int64, reason string, retryAt *time.Time) error
+
+	// ReleaseOutboxItems gives up owner's claim on the items with the given
+	// IDs without counting an attempt, so they are claimable again at once.
+	// Items no longer claimed by owner, their lease having run out, are left
+	// alone.
+	ReleaseOutboxItems(ctx context.Context, owner string, ids []int64) error
+
+	// PurgeOutboxItems removes up to limit rows created before before that
+	// have been both published and delivered, oldest first, and returns how
+	// many it removed. With archive set the rows are copied to the
+	// outbox_archive table (model.ArchivedOutboxItem) in the same transaction.
+	PurgeOutboxItems(ctx context.Context, before time.Time, limit int, archive bool) (int, error)
+
+	// ResetOutboxItems clears published_at, delivered_at or both, as entry
+	// says, on the items carrying entry's event IDs, and records entry, with
+	// the number of items changed in Affected, in the outbox audit log. Both
+	// happen in one transaction. Clearing published_at also clears the items'
+	// attempts, dead-letter state and claims, so the relay publishes them
+	// again.
+	ResetOutboxItems(ctx context.Context, entry *model.OutboxAuditEntry) error
+
+	// GetOutboxAudit reads the latest limit entries of the outbox audit log,
+	// newest first; limit <= 0 reads them all.
+	GetOutboxAudit(ctx context.Context, limit int) ([]*model.OutboxAuditEntry, error)
+
 	// SetPublishedAt strictly marks one item published: it errors with
 	// ErrOutboxItemNotFound if no row has that ID.
 	SetPublishedAt(ctx context.Context, id int64, t time.Time) error
 
+	// SetPublishedAtBulk marks the items with the given IDs published in one
+	// statement. It errors with ErrOutboxItemNotFound if any ID matches no
+	// row, having marked the rest.
+	SetPublishedAtBulk(ctx context.Context, ids []int64, t time.Time) error
+
+	// SetDeliveredAtBulk marks the items with the given IDs both published
+	// and delivered in one statement, for rows consumed in-process that no
+	// downstream consumer acknowledges. Same not-found semantics as
+	// SetPublishedAtBulk.
+	SetDeliveredAtBulk(ctx context.Context, ids []int64, t time.Time) error
+
 	// SetDeliveredAt strictly marks one item delivered, with the same
 	// not-found semantics as SetPublishedAt.
 	SetDeliveredAt(ctx context.Context, id int64, t time.Time) error
@@ -48,11 +241,26 @@ type OutboxStore interface {
 // OutboxQuery filters an outbox read. The zero value selects everything.
 type OutboxQuery struct {
 	// OnlyUnpublished restricts to rows not yet sent to the broker
-	// (published_at IS NULL). This is the relay's claim filter.
+	// (published_at IS NULL).
 	OnlyUnpublished bool
+	// OnlyPublished restricts to rows sent to the broker.
+	OnlyPublished bool
 	// OnlyUndelivered restricts to rows not yet marked delivered
-	// (delivered_at IS NULL).
+	// (delivered_at IS NULL); OnlyDelivered to the others.
 	OnlyUndelivered bool
+	OnlyDelivered   bool
+	// OnlyDead restricts to rows the relay gave up on (dead_at IS NOT NULL);
+	// OnlyLive to the others.
+	OnlyDead bool
+	OnlyLive bool
+	// Topics restricts to rows on the given topics; empty means all topics.
+	Topics []string
+	// EventIDs restricts to rows carrying the given events; empty means all.
+	EventIDs []string
+	// CreatedAfter and CreatedBefore restrict to rows enqueued at or after,
+	// and before, the given times. Zero times leave the range open.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
 	// Limit caps the batch size; <= 0 means no limit.
 	Limit int
 }
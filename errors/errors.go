@@ -18,4 +18,13 @@ var (
 	// ErrNotFound signals a requested resource does not exist or is
 	// unavailable (maps to 404).
 	ErrNotFound = errors.New("not found")
+	// ErrPaymentDeclined signals the payment processor refused to authorize
+	// the charge (maps to 402).
+	ErrPaymentDeclined = errors.New("payment declined")
+	// ErrConflict signals the request is valid but conflicts with the current
+	// state of the resource (maps to 409).
+	ErrConflict = errors.New("conflict")
+	// ErrUnprocessable signals a well-formed request that breaks a business
+	// rule, such as a purchase limit (maps to 422).
+	ErrUnprocessable = errors.New("unprocessable")
 )
@@ -2,3 +2,15 @@ package event
 
 // TopicOrderCreated carries an event per completed purchase order.
 const TopicOrderCreated = "orders.created"
+
+// TopicOrderRefunded carries an event per refund recorded against an order.
+const TopicOrderRefunded = "orders.refunded"
+
+// TopicPaymentCapture carries a request to capture an order's payment
+// authorization. It is handled in-process by the orders relay rather than
+// published to the broker.
+const TopicPaymentCapture = "payments.capture"
+
+// TopicPaymentRefund carries a request to return money against an order's
+// payment authorization. Like TopicPaymentCapture it is handled in-process.
+const TopicPaymentRefund = "payments.refund"
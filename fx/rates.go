@@ -0,0 +1,105 @@
+// Package fx converts prices between currencies. The orders service prices a
+// basket in the catalog's currency and, when the customer asks for another, asks
+// a Provider for the exchange rate to apply. Provider is the seam for a live rate
+// source; StaticProvider, backed by a table file, is the default.
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// RatePrecision is the number of decimal places a rate is rounded to. It matches
+// the fx_rate column on the orders table, so the rate recorded on an order is
+// exactly the rate that was applied.
+const RatePrecision = 8
+
+// ErrUnsupportedCurrency is returned when a Provider has no rate for a currency.
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// Provider quotes exchange rates.
+type Provider interface {
+	// Rate returns the number of units of currency `to` bought by one unit of
+	// currency `from`. Rate(ctx, c, c) is always 1.
+	Rate(ctx context.Context, from, to string) (decimal.Decimal, error)
+}
+
+// StaticProvider serves rates from a fixed table quoted against a single base
+// currency. A cross rate between two non-base currencies is derived through the
+// base.
+type StaticProvider struct {
+	base  string
+	rates map[string]decimal.Decimal // units of currency per one unit of base
+}
+
+// NewStaticProvider builds a provider from rates quoted against base. base need
+// not appear in rates. A nil map yields a provider that only converts a
+// currency to itself.
+func NewStaticProvider(base string, rates map[string]decimal.Decimal) (*StaticProvider, error) {
+	base = strings.ToUpper(base)
+	table := map[string]decimal.Decimal{base: decimal.NewFromInt(1)}
+	for c, r := range rates {
+		if !r.IsPositive() {
+			return nil, fmt.Errorf("rate for %s must be positive, got %s", c, r)
+		}
+		table[strings.ToUpper(c)] = r
+	}
+	return &StaticProvider{base: base, rates: table}, nil
+}
+
+// rateTable is the on-disk format read by LoadStaticProvider:
+//
+//	{ "base": "USD", "rates": { "EUR": "0.92", "GBP": "0.79" } }
+type rateTable struct {
+	Base  string                     `json:"base"`
+	Rates map[string]decimal.Decimal `json:"rates"`
+}
+
+// LoadStaticProvider reads a rate table file (see rateTable).
+func LoadStaticProvider(path string) (*StaticProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fx rate table: %w", err)
+	}
+	var t rateTable
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("parse fx rate table %s: %w", path, err)
+	}
+	if t.Base == "" {
+		return nil, fmt.Errorf("fx rate table %s: missing base currency", path)
+	}
+	return NewStaticProvider(t.Base, t.Rates)
+}
+
+// Base returns the currency the table is quoted against.
+func (p *StaticProvider) Base() string {
+	return p.base
+}
+
+// Rate implements Provider.
+func (p *StaticProvider) Rate(_ context.Context, from, to string) (decimal.Decimal, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	fromRate, ok := p.rates[from]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, from)
+	}
+	toRate, ok := p.rates[to]
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, to)
+	}
+	return toRate.Div(fromRate).Round(RatePrecision), nil
+}
+
+// Convert applies rate to amount and rounds to minor units (cents).
+func Convert(amount, rate decimal.Decimal) decimal.Decimal {
+	return amount.Mul(rate).Round(2)
+}
@@ -0,0 +1,66 @@
+//go:build !integration
+
+package fx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticProvider_Rate(t *testing.T) {
+	p, err := NewStaticProvider("USD", map[string]decimal.Decimal{
+		"EUR": decimal.RequireFromString("0.9"),
+		"GBP": decimal.RequireFromString("0.75"),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+		wantErr  bool
+	}{
+		{"identity", "JPY", "JPY", "1", false},
+		{"base to quoted", "USD", "EUR", "0.9", false},
+		{"quoted to base", "GBP", "USD", "1.33333333", false},
+		{"cross rate", "EUR", "GBP", "0.83333333", false},
+		{"lower case", "usd", "eur", "0.9", false},
+		{"unsupported", "USD", "CHF", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := p.Rate(context.Background(), tc.from, tc.to)
+			if tc.wantErr {
+				require.ErrorIs(t, err, ErrUnsupportedCurrency)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, decimal.RequireFromString(tc.want).Equal(got), "rate = %s, want %s", got, tc.want)
+		})
+	}
+}
+
+func TestLoadStaticProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"base":"USD","rates":{"EUR":"0.92"}}`), 0o600))
+
+	p, err := LoadStaticProvider(path)
+	require.NoError(t, err)
+	require.Equal(t, "USD", p.Base())
+
+	rate, err := p.Rate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, "46.00", Convert(decimal.NewFromInt(50), rate).StringFixed(2))
+
+	t.Run("non-positive rate", func(t *testing.T) {
+		bad := filepath.Join(t.TempDir(), "bad.json")
+		require.NoError(t, os.WriteFile(bad, []byte(`{"base":"USD","rates":{"EUR":"0"}}`), 0o600))
+		_, err := LoadStaticProvider(bad)
+		require.Error(t, err)
+	})
+}
@@ -134,6 +134,12 @@ func statusFor(err error) int {
 		return http.StatusBadRequest
 	case errors.Is(err, srverrors.ErrNotFound):
 		return http.StatusNotFound
+	case errors.Is(err, srverrors.ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, srverrors.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, srverrors.ErrUnprocessable):
+		return http.StatusUnprocessableEntity
 	default:
 		return http.StatusInternalServerError
 	}
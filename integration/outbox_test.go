@@ -0,0 +1,166 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/event"
+	"github.com/ATMackay/checkout/integration/stack"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/services/orders"
+)
+
+// recordingPublisher counts what each relay publishes, by event ID.
+type recordingPublisher struct {
+	relay string
+	mu    *sync.Mutex
+	seen  map[string][]string // event ID -> relays that published it
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, ev *event.Event) error {
+	// Slow enough that the relays' scans overlap.
+	time.Sleep(time.Millisecond)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seen[ev.ID] = append(p.seen[ev.ID], p.relay)
+	return nil
+}
+
+func (p *recordingPublisher) PublishBatch(ctx context.Context, evs []*event.Event) []error {
+	errs := make([]error, len(evs))
+	for i, ev := range evs {
+		errs[i] = p.Publish(ctx, ev)
+	}
+	return errs
+}
+
+func (p *recordingPublisher) Ping(context.Context) error { return nil }
+func (p *recordingPublisher) Close() error               { return nil }
+
+// Test_OutboxRelaysShareRows runs several relays, as several orders replicas
+// would, against one Postgres outbox: every row is published exactly once.
+func Test_OutboxRelaysShareRows(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	net := stack.CreateNetwork(t, ctx)
+	pg := stack.StartPostgres(t, ctx, net.Name, false)
+
+	const rows, relays = 500, 4
+	var items []*model.OutboxItem
+	for i := range rows {
+		ev := event.New(event.TopicOrderCreated, fmt.Sprintf("ref-%d", i), map[string]int{"n": i})
+		data, err := ev.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, &model.OutboxItem{EventID: ev.ID, Topic: ev.Topic, PartitionKey: ev.Key, Data: data, OccurredAt: ev.OccurredAt})
+	}
+	if err := pg.Open(t, ctx).AddOutboxItems(ctx, items); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string][]string)
+	var started []*orders.OutboxRelayer
+	for i := range relays {
+		name := fmt.Sprintf("relay-%d", i)
+		// A connection pool per relay, as per replica.
+		r := orders.NewOutboxRelayer(pg.Open(t, ctx), &recordingPublisher{relay: name, mu: &mu, seen: seen},
+			orders.WithOwner(name),
+			orders.WithPollInterval(10*time.Millisecond),
+			orders.WithBatchSize(20),
+		)
+		if err := r.Start(ctx); err != nil {
+			t.Fatalf("start %s: %v", name, err)
+		}
+		started = append(started, r)
+	}
+	defer func() {
+		for _, r := range started {
+			_ = r.Stop()
+		}
+	}()
+
+	deadline := time.Now().Add(60 * time.Second)
+	for {
+		mu.Lock()
+		n := len(seen)
+		mu.Unlock()
+		if n == rows {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("published %d of %d events", n, rows)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	// Give a straggling double publish time to show up.
+	time.Sleep(500 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	perRelay := make(map[string]int)
+	for id, by := range seen {
+		if len(by) != 1 {
+			t.Errorf("event %s published %d times, by %v", id, len(by), by)
+		}
+		perRelay[by[0]]++
+	}
+	if len(perRelay) < 2 {
+		t.Errorf("only %v published; want the rows shared between relays", perRelay)
+	}
+	t.Logf("rows published per relay: %v", perRelay)
+}
+
+// Test_OutboxNotify checks that rows committed through one connection wake a
+// watcher on another (LISTEN/NOTIFY), and only once committed.
+func Test_OutboxNotify(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	net := stack.CreateNetwork(t, ctx)
+	pg := stack.StartPostgres(t, ctx, net.Name, false)
+	writer, watcher := pg.Open(t, ctx), pg.Open(t, ctx)
+
+	watchCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	wake, err := watcher.WatchOutbox(watchCtx)
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	signalled := func(within time.Duration) bool {
+		select {
+		case <-wake:
+			return true
+		case <-time.After(within):
+			return false
+		}
+	}
+
+	err = writer.Transaction(ctx, func(tx database.Database) error {
+		if err := tx.AddOutboxItems(ctx, []*model.OutboxItem{{EventID: "notify-1", Topic: event.TopicOrderCreated}}); err != nil {
+			return err
+		}
+		if signalled(500 * time.Millisecond) {
+			t.Error("watcher woken before the rows were committed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if !signalled(10 * time.Second) {
+		t.Fatal("watcher not woken by the committed rows")
+	}
+
+	stop()
+	for range wake {
+	}
+}
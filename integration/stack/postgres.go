@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ATMackay/checkout/database"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/log"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -70,3 +71,21 @@ func StartPostgres(t *testing.T,
 		db:      TestDbName,
 	}
 }
+
+// Open connects to the database from the test process, through the container's
+// mapped port.
+func (p *PGContainer) Open(t *testing.T, ctx context.Context) *database.GormDB {
+	host, err := p.ctr.Host(ctx)
+	if err != nil {
+		t.Fatalf("resolve postgres host: %v", err)
+	}
+	mp, err := p.ctr.MappedPort(ctx, TestPostgresPort)
+	if err != nil {
+		t.Fatalf("resolve postgres mapped port: %v", err)
+	}
+	db, err := database.NewPostgresDB(host, p.user, p.pass, int(mp.Num()))
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	return db
+}
@@ -3,6 +3,7 @@ package stack
 import (
 	"context"
 	"fmt"
+	"maps"
 	"math/rand/v2"
 	"os"
 	"strings"
@@ -70,7 +71,10 @@ func MakeStack(t *testing.T, ctx context.Context, opts *Opts) *Stack {
 		t.Logf("Kafka created: broker=%s", kafkaCtr.InternalBroker())
 	}
 
-	ordersApp := createServiceContainer(t, ctx, net, pg, "orders", []string{"run", "orders"}, brokerEnv, opts.AppLogs, opts.Debug)
+	// No real payment processor is integrated: orders charges the fake.
+	ordersEnv := map[string]string{"CHECKOUT_FAKE_PAYMENTS": "true"}
+	maps.Copy(ordersEnv, brokerEnv)
+	ordersApp := createServiceContainer(t, ctx, net, pg, "orders", []string{"run", "orders"}, ordersEnv, opts.AppLogs, opts.Debug)
 	t.Logf("orders listening on: %s", ordersApp.url())
 
 	var notifierApp *appContainer
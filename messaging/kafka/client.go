@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	"github.com/ATMackay/checkout/event"
 	"github.com/ATMackay/checkout/messaging"
@@ -116,6 +117,76 @@ func (c *Client) Publish(ctx context.Context, ev *event.Event) error {
 	return res.FirstErr()
 }
 
+// PublishBatch writes the events asynchronously, flushes them, and returns each
+// one's outcome once the brokers have acknowledged or rejected it, so a batch
+// costs one round trip rather than one per event.
+//
+// Records for a partition are produced in order, and when one fails the client
+// fails every record buffered after it on that partition, so events sharing a
+// key keep their order: a failed event is never overtaken by a later one. Only
+// the first failure on a partition is the event's own; the later ones are
+// reported as ErrHeldBack, wrapping the client's error. An event that cannot be
+// encoded never reaches the client, so the later events with its key are held
+// back here instead.
+func (c *Client) PublishBatch(ctx context.Context, events []*event.Event) []error {
+	errs := make([]error, len(events))
+	type key struct{ topic, key string }
+	unencoded := make(map[key]bool)
+	produced := make([]bool, len(events))
+	partitions := make([]int32, len(events))
+	var wg sync.WaitGroup
+	for i, ev := range events {
+		if ev != nil && ev.Key != "" && unencoded[key{ev.Topic, ev.Key}] {
+			errs[i] = messaging.ErrHeldBack
+			continue
+		}
+		value, err := ev.Encode()
+		if err != nil {
+			errs[i] = err
+			if ev != nil && ev.Key != "" {
+				unencoded[key{ev.Topic, ev.Key}] = true
+			}
+			continue
+		}
+		wg.Add(1)
+		produced[i] = true
+		c.client.Produce(ctx, &kgo.Record{
+			Topic: ev.Topic,
+			Key:   []byte(ev.Key),
+			Value: value,
+		}, func(r *kgo.Record, err error) {
+			// Promises run serially, and wg.Wait orders these writes before
+			// errs and partitions are read.
+			errs[i] = err
+			partitions[i] = r.Partition
+			wg.Done()
+		})
+	}
+	// A cancelled flush leaves the records to fail with ctx; every promise is
+	// still called, so waiting on them is enough.
+	if err := c.client.Flush(ctx); err != nil {
+		slog.Debug("kafka flush interrupted", "error", err)
+	}
+	wg.Wait()
+	type partition struct {
+		topic string
+		id    int32
+	}
+	failed := make(map[partition]bool)
+	for i, err := range errs {
+		if !produced[i] || err == nil {
+			continue
+		}
+		p := partition{events[i].Topic, partitions[i]}
+		if failed[p] {
+			errs[i] = fmt.Errorf("%w: %w", messaging.ErrHeldBack, err)
+			continue
+		}
+		failed[p] = true
+	}
+	return errs
+}
+
 func (c *Client) Close() error {
 	// Close client
 	slog.Debug("closing kafka client")
@@ -11,6 +11,7 @@ import (
 
 	"github.com/ATMackay/checkout/event"
 	"github.com/ATMackay/checkout/integration/stack"
+	"github.com/ATMackay/checkout/messaging"
 )
 
 // Test Client-Kafka integration with testcontainers.
@@ -195,3 +196,79 @@ func TestRoundTrip(t *testing.T) {
 		t.Fatalf("commit: %v", err)
 	}
 }
+
+// TestPublishBatch publishes interleaved keys in one batch and asserts each
+// event's outcome is reported in place and every key is consumed in order.
+func TestPublishBatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 60*time.Second)
+	defer cancel()
+
+	kafkaCtr := startBroker(t, ctx)
+
+	producer, err := NewClient(kafkaCtr.Brokers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = producer.Close() })
+
+	const (
+		keyCount = 3
+		perKey   = 4
+	)
+	var batch []*event.Event
+	for seq := range perKey {
+		for i := range keyCount {
+			key := fmt.Sprintf("ref-%d", i)
+			batch = append(batch, event.New(testTopic, key, testPayload{Reference: key, Seq: seq}))
+		}
+	}
+	// A malformed event fails alone; the rest of the batch is unaffected.
+	batch = append(batch, &event.Event{Topic: testTopic, ID: "no-key"})
+	// An event that cannot be encoded holds back the later ones with its key.
+	batch = append(batch,
+		event.New(testTopic, "held", make(chan int)),
+		event.New(testTopic, "held", testPayload{Reference: "held"}))
+	good := len(batch) - 3
+
+	errs := producer.PublishBatch(ctx, batch)
+	if len(errs) != len(batch) {
+		t.Fatalf("got %d outcomes for %d events", len(errs), len(batch))
+	}
+	for i, err := range errs[:good] {
+		if err != nil {
+			t.Fatalf("event %d: %v", i, err)
+		}
+	}
+	if err := errs[good]; !errors.Is(err, event.ErrMalformedEvent) {
+		t.Errorf("malformed event: err = %v, want %v", err, event.ErrMalformedEvent)
+	}
+	if err := errs[good+1]; err == nil {
+		t.Error("unencodable event: err = nil, want an encoding error")
+	}
+	if err := errs[good+2]; !errors.Is(err, messaging.ErrHeldBack) {
+		t.Errorf("event behind it: err = %v, want %v", err, messaging.ErrHeldBack)
+	}
+
+	consumer, err := NewClient(kafkaCtr.Brokers(), WithConsumerGroup("test-batch-group", testTopic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = consumer.Close() })
+
+	seqs := make(map[string][]int, keyCount)
+	for _, ev := range pollN(t, ctx, consumer, keyCount*perKey) {
+		var payload testPayload
+		if err := ev.DecodeData(&payload); err != nil {
+			t.Fatalf("decode data: %v", err)
+		}
+		seqs[ev.Key] = append(seqs[ev.Key], payload.Seq)
+	}
+	for key, got := range seqs {
+		for i, seq := range got {
+			if seq != i {
+				t.Errorf("key %s: consumed seqs %v, want 0..%d in order", key, got, perKey-1)
+				break
+			}
+		}
+	}
+}
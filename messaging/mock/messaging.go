@@ -83,6 +83,20 @@ func (mr *MockPublisherMockRecorder) Publish(ctx, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockPublisher)(nil).Publish), ctx, arg1)
 }
 
+// PublishBatch mocks base method.
+func (m *MockPublisher) PublishBatch(ctx context.Context, events []*event.Event) []error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishBatch", ctx, events)
+	ret0, _ := ret[0].([]error)
+	return ret0
+}
+
+// PublishBatch indicates an expected call of PublishBatch.
+func (mr *MockPublisherMockRecorder) PublishBatch(ctx, events any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishBatch", reflect.TypeOf((*MockPublisher)(nil).PublishBatch), ctx, events)
+}
+
 // MockConsumer is a mock of Consumer interface.
 type MockConsumer struct {
 	ctrl     *gomock.Controller
@@ -20,6 +20,10 @@ func (c *Client) Publish(context.Context, *event.Event) error {
 	return nil
 }
 
+func (c *Client) PublishBatch(_ context.Context, events []*event.Event) []error {
+	return make([]error, len(events))
+}
+
 func (c *Client) Close() error {
 	return nil
 }
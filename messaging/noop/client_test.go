@@ -5,6 +5,8 @@ package noop
 import (
 	"context"
 	"testing"
+
+	"github.com/ATMackay/checkout/event"
 )
 
 func Test_Client(t *testing.T) {
@@ -19,6 +21,12 @@ func Test_Client(t *testing.T) {
 	if err := cl.Publish(context.TODO(), nil); err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	// Nor should any event in a batch
+	for _, err := range cl.PublishBatch(context.TODO(), make([]*event.Event, 2)) {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
 	// Close should never error
 	if err := cl.Close(); err != nil {
 		t.Errorf("unexpected error: %v", err)
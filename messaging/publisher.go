@@ -2,16 +2,29 @@ package messaging
 
 import (
 	"context"
+	"errors"
 	"io"
 
 	"github.com/ATMackay/checkout/event"
 )
 
+// ErrHeldBack fails an event in a batch that was not sent, or not accepted,
+// only because an earlier event with its topic and key, or on its partition,
+// failed. The event itself is not at fault.
+var ErrHeldBack = errors.New("held back behind a failed event with the same key")
+
 //go:generate mockgen -destination mock/messaging.go -package mock github.com/ATMackay/checkout/messaging Publisher,Consumer
 
 // Producer is an event producer
 type Publisher interface {
 	io.Closer
 	Publish(ctx context.Context, event *event.Event) error
+	// PublishBatch publishes events together and returns once every one has
+	// been acknowledged or has failed: errs[i] is the outcome of events[i].
+	// Events sharing a topic and key are published in slice order: once one
+	// fails, the later ones fail too rather than overtake it. An event failed
+	// only because of an earlier one, with its key or on its partition, fails
+	// with ErrHeldBack.
+	PublishBatch(ctx context.Context, events []*event.Event) (errs []error)
 	Ping(ctx context.Context) error
 }
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var bundleCodeRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Bundle is a set of items sold together at a fixed price. A basket holding
+// all of a bundle's units pays Price for them instead of their unit prices.
+type Bundle struct {
+	Code string `json:"code" gorm:"primaryKey;type:text"`
+	Name string `json:"name" gorm:"column:name;type:text"`
+	// SKUs lists the bundle's items, one entry per unit.
+	SKUs  []string        `json:"skus" gorm:"column:skus;type:text;serializer:json"`
+	Price decimal.Decimal `json:"price" gorm:"column:price;type:numeric(12,2)"`
+	// Currency is the ISO 4217 code Price is expressed in. The bundle only
+	// applies to items in the same currency.
+	Currency  string    `json:"currency" gorm:"column:currency;type:string;default:USD"`
+	Active    bool      `json:"active" gorm:"column:active;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (b *Bundle) TableName() string {
+	return "bundles"
+}
+
+// Validate checks the bundle is well formed.
+func (b *Bundle) Validate() error {
+	if !bundleCodeRegex.MatchString(b.Code) {
+		return fmt.Errorf("bundle code must be 1 to 32 letters, digits, '-' or '_'")
+	}
+	if b.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(b.SKUs) < 2 {
+		return fmt.Errorf("a bundle needs at least 2 units")
+	}
+	for _, sku := range b.SKUs {
+		if !IsSKU(sku) {
+			return fmt.Errorf("invalid sku '%s'", sku)
+		}
+	}
+	if b.Price.IsNegative() {
+		return fmt.Errorf("invalid price less than 0")
+	}
+	if b.Currency != "" && !IsCurrency(b.Currency) {
+		return fmt.Errorf("bundle currency must be an ISO 4217 code, got %q", b.Currency)
+	}
+	return nil
+}
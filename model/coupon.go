@@ -0,0 +1,77 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var couponCodeRegex = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// Coupon is a code a customer enters to unlock a coupon-only promotion (see
+// PromotionRule.CouponOnly). Redemptions counts the orders that have used it.
+type Coupon struct {
+	ID          int    `json:"id,omitempty" gorm:"primaryKey;type:integer"`
+	Code        string `json:"code" gorm:"column:code;type:string;uniqueIndex"`
+	PromotionID int    `json:"promotion_id" gorm:"column:promotion_id;index"`
+	// MaxRedemptions caps redemptions across all customers; zero is unlimited.
+	MaxRedemptions int `json:"max_redemptions,omitempty" gorm:"column:max_redemptions"`
+	// MaxPerCustomer caps redemptions by any one customer; zero is unlimited.
+	MaxPerCustomer int `json:"max_per_customer,omitempty" gorm:"column:max_per_customer"`
+	Redemptions    int `json:"redemptions" gorm:"column:redemptions;default:0"`
+	// ExpiresAt, when set, is the time after which the code is rejected.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"column:expires_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (c *Coupon) TableName() string {
+	return "coupons"
+}
+
+// Expired reports whether the coupon had expired at t.
+func (c *Coupon) Expired(t time.Time) bool {
+	return c.ExpiresAt != nil && !t.Before(*c.ExpiresAt)
+}
+
+// Exhausted reports whether the coupon has no redemptions left.
+func (c *Coupon) Exhausted() bool {
+	return c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions
+}
+
+// Validate checks the coupon's code and limits.
+func (c *Coupon) Validate() error {
+	if !IsCouponCode(c.Code) {
+		return fmt.Errorf("invalid coupon code '%s': want 3-32 of A-Z, 0-9, '-' and '_'", c.Code)
+	}
+	if c.PromotionID < 1 {
+		return fmt.Errorf("promotion_id is required")
+	}
+	if c.MaxRedemptions < 0 || c.MaxPerCustomer < 0 {
+		return fmt.Errorf("redemption limits must not be negative")
+	}
+	return nil
+}
+
+// NormalizeCouponCode returns code as stored: codes are case-insensitive.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// IsCouponCode checks if the input string is a normalized coupon code.
+func IsCouponCode(input string) bool {
+	return couponCodeRegex.MatchString(input)
+}
+
+// CouponRedemption records one order's use of a coupon.
+type CouponRedemption struct {
+	ID             int       `json:"id,omitempty" gorm:"primaryKey;type:integer"`
+	Code           string    `json:"code" gorm:"column:code;type:string;index:idx_coupon_redemptions_customer,priority:1"`
+	CustomerID     string    `json:"customer_id" gorm:"column:customer_id;type:text;index:idx_coupon_redemptions_customer,priority:2"`
+	OrderReference string    `json:"order_reference" gorm:"column:order_reference;type:text"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (r *CouponRedemption) TableName() string {
+	return "coupon_redemptions"
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// GiftCard is stored value (a gift card or store credit) a customer can tender
+// against a purchase. Balance is the running total of the card's ledger; every
+// change to it is recorded as a GiftCardEntry.
+type GiftCard struct {
+	ID       int             `json:"id,omitempty" gorm:"primaryKey;type:integer"`
+	Code     string          `json:"code" gorm:"column:code;type:string;uniqueIndex"`
+	Balance  decimal.Decimal `json:"balance" gorm:"column:balance;type:numeric(12,2)"`
+	Currency string          `json:"currency" gorm:"column:currency;type:string;default:USD"`
+	// CustomerID is set for store credit issued to a customer; a gift card is
+	// a bearer instrument and leaves it empty.
+	CustomerID string `json:"customer_id,omitempty" gorm:"column:customer_id;type:text"`
+	// ExpiresAt, when set, is the time after which the card cannot be redeemed.
+	ExpiresAt *time.Time      `json:"expires_at,omitempty" gorm:"column:expires_at"`
+	CreatedAt time.Time       `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	Ledger    []GiftCardEntry `json:"ledger,omitempty" gorm:"-"`
+}
+
+func (g *GiftCard) TableName() string {
+	return "gift_cards"
+}
+
+// Expired reports whether the card had expired at t.
+func (g *GiftCard) Expired(t time.Time) bool {
+	return g.ExpiresAt != nil && !t.Before(*g.ExpiresAt)
+}
+
+// HeldBy reports whether customerID may see and spend the card: anyone holding
+// a gift card's code, but only the customer store credit was issued to.
+func (g *GiftCard) HeldBy(customerID string) bool {
+	return g.CustomerID == "" || g.CustomerID == customerID
+}
+
+// GiftCardReason says why a gift card balance changed.
+type GiftCardReason string
+
+const (
+	GiftCardIssued   GiftCardReason = "issued"
+	GiftCardRedeemed GiftCardReason = "redeemed"
+	GiftCardRefunded GiftCardReason = "refunded"
+	GiftCardExpired  GiftCardReason = "expired"
+)
+
+// GiftCardEntry is one row of a gift card's append-only balance ledger. Amount
+// is signed: credits are positive and debits negative.
+type GiftCardEntry struct {
+	ID             int             `json:"id,omitempty" gorm:"primaryKey;type:integer"`
+	Code           string          `json:"code" gorm:"column:code;type:string;index"`
+	Amount         decimal.Decimal `json:"amount" gorm:"column:amount;type:numeric(12,2)"`
+	Reason         GiftCardReason  `json:"reason" gorm:"column:reason;type:text"`
+	OrderReference string          `json:"order_reference,omitempty" gorm:"column:order_reference;type:text"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (e *GiftCardEntry) TableName() string {
+	return "gift_card_ledger"
+}
+
+// IssueGiftCardRequest issues a new card with an opening balance.
+type IssueGiftCardRequest struct {
+	Amount     decimal.Decimal `json:"amount"`
+	Currency   string          `json:"currency"`
+	CustomerID string          `json:"customer_id,omitempty"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+}
+
+// giftCardAlphabet omits characters easily confused when read aloud or typed
+// (0/O, 1/I).
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateGiftCardCode generates a random 16-character redemption code.
+func GenerateGiftCardCode() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b) // never returns an error
+	for i := range b {
+		b[i] = giftCardAlphabet[int(b[i])%len(giftCardAlphabet)]
+	}
+	return string(b)
+}
@@ -9,13 +9,51 @@ import (
 
 var skuRegex = regexp.MustCompile(`^[a-zA-Z0-9]{6}$`)
 
+var currencyRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// DefaultCurrency is the currency an item is priced in when none is given.
+const DefaultCurrency = "USD"
+
 type Item struct {
 	ID    int             `json:"id,omitempty" gorm:"primaryKey;type:integer"`
 	SKU   string          `json:"sku" gorm:"column:sku;type:string;unique"`
 	Name  string          `json:"name" gorm:"column:name;type:string;unique"`
 	Price decimal.Decimal `json:"price" gorm:"column:price;type:numeric(12,2)"`
+	// Currency is the ISO 4217 code Price is expressed in.
+	Currency string `json:"currency" gorm:"column:currency;type:string;default:USD"`
+	// Category optionally groups items for promotion rules, e.g. "accessories".
+	Category string `json:"category,omitempty" gorm:"column:category;type:string;index"`
 	// InventoryQuantity adds a non-zero check at the DB level
 	InventoryQuantity int `json:"inventory_quantity" gorm:"column:inventory_quantity;type:integer;check:chk_inventory_non_negative,inventory_quantity >= 0"`
+	// PriceTiers lower the unit price of larger quantities, in ascending order
+	// of MinQuantity.
+	PriceTiers []PriceTier `json:"price_tiers,omitempty" gorm:"column:price_tiers;type:text;serializer:json"`
+}
+
+// PriceTier is a quantity band: a basket holding at least MinQuantity units of
+// the item pays UnitPrice for every one of them.
+type PriceTier struct {
+	MinQuantity int             `json:"min_quantity"`
+	UnitPrice   decimal.Decimal `json:"unit_price"`
+}
+
+// TierFor returns the tier n units of the item fall in, nil below the first.
+func (i *Item) TierFor(n int) *PriceTier {
+	var tier *PriceTier
+	for j := range i.PriceTiers {
+		if i.PriceTiers[j].MinQuantity <= n {
+			tier = &i.PriceTiers[j]
+		}
+	}
+	return tier
+}
+
+// UnitPriceFor returns the unit price of the item when n units are bought.
+func (i *Item) UnitPriceFor(n int) decimal.Decimal {
+	if tier := i.TierFor(n); tier != nil {
+		return tier.UnitPrice
+	}
+	return i.Price
 }
 
 func (i *Item) TableName() string {
@@ -36,9 +74,23 @@ func (i *Item) Validate() error {
 	if i.Price.LessThan(decimal.Zero) {
 		return fmt.Errorf("invalid price less than 0")
 	}
+	if i.Currency != "" && !IsCurrency(i.Currency) {
+		return fmt.Errorf("item currency must be an ISO 4217 code, got %q", i.Currency)
+	}
 	if i.InventoryQuantity < 1 {
 		return fmt.Errorf("invalid inventory_quantity less than 1")
 	}
+	for j, t := range i.PriceTiers {
+		if t.MinQuantity < 2 {
+			return fmt.Errorf("price tier %d: min_quantity must be at least 2", j)
+		}
+		if j > 0 && t.MinQuantity <= i.PriceTiers[j-1].MinQuantity {
+			return fmt.Errorf("price tier %d: min_quantity must be greater than the previous tier's", j)
+		}
+		if t.UnitPrice.IsNegative() || t.UnitPrice.GreaterThan(i.Price) {
+			return fmt.Errorf("price tier %d: unit_price must be between 0 and the item price", j)
+		}
+	}
 	return nil
 }
 
@@ -47,11 +99,29 @@ func IsSKU(input string) bool {
 	return skuRegex.MatchString(input)
 }
 
+// IsCurrency checks if the input string is an upper-case ISO 4217 currency code
+func IsCurrency(input string) bool {
+	return currencyRegex.MatchString(input)
+}
+
 type PurchaseItemsRequest struct {
 	SKUs []string `json:"skus"`
+	// GiftCard is an optional gift card code to pay with. Its balance is
+	// applied first and the payment provider is charged any remainder.
+	GiftCard string `json:"gift_card,omitempty"`
+	// CouponCodes unlock coupon-only promotions. Each must be valid for the
+	// customer or the purchase is refused.
+	CouponCodes []string `json:"coupon_codes,omitempty"`
+	// LoyaltyPoints is an optional number of the customer's loyalty points to
+	// redeem as a discount, applied after promotions.
+	LoyaltyPoints int64 `json:"loyalty_points,omitempty"`
 }
 
 type PurchaseItemsResponse struct {
 	OrderReference string  `json:"order_reference"`
 	Cost           float64 `json:"cost"`
+	Currency       string  `json:"currency"`
+	GiftCardAmount float64 `json:"gift_card_amount,omitempty"`
+	// LoyaltyDiscount is what the redeemed loyalty points took off Cost.
+	LoyaltyDiscount float64 `json:"loyalty_discount,omitempty"`
 }
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// PurchaseLimit caps how many units of one SKU a single customer may buy. A
+// zero limit is not enforced. Both limits count the units the customer asks
+// for, the SKUs of a purchase request; units a promotion adds free do not
+// count.
+type PurchaseLimit struct {
+	ID  int    `json:"id,omitempty" gorm:"primaryKey;type:integer"`
+	SKU string `json:"sku" gorm:"column:sku;type:string;uniqueIndex"`
+	// MaxPerOrder caps the units of the SKU in any one order.
+	MaxPerOrder int `json:"max_per_order" gorm:"column:max_per_order"`
+	// MaxPerWindow caps the units of the SKU across all of a customer's orders
+	// placed within the trailing WindowSeconds.
+	MaxPerWindow  int   `json:"max_per_window" gorm:"column:max_per_window"`
+	WindowSeconds int64 `json:"window_seconds" gorm:"column:window_seconds"`
+}
+
+func (l *PurchaseLimit) TableName() string {
+	return "purchase_limits"
+}
+
+// Window returns the rolling window MaxPerWindow applies over.
+func (l *PurchaseLimit) Window() time.Duration {
+	return time.Duration(l.WindowSeconds) * time.Second
+}
+
+// SetPurchaseLimitRequest sets the limits for one SKU, replacing any previous.
+type SetPurchaseLimitRequest struct {
+	MaxPerOrder   int   `json:"max_per_order"`
+	MaxPerWindow  int   `json:"max_per_window"`
+	WindowSeconds int64 `json:"window_seconds"`
+}
+
+// PurchaseLimitLock is the row a purchase locks before checking the customer's
+// rolling-window limits, so concurrent purchases by one customer check in turn.
+type PurchaseLimitLock struct {
+	CustomerID string `json:"customer_id" gorm:"column:customer_id;type:string;primaryKey"`
+	// Purchases counts the locked checks; the update is what takes the lock.
+	Purchases int64 `json:"purchases" gorm:"column:purchases"`
+}
+
+func (l *PurchaseLimitLock) TableName() string {
+	return "purchase_limit_locks"
+}
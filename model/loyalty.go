@@ -0,0 +1,93 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LoyaltyAccount is a customer's loyalty points balance. Balance is the running
+// total of the account's ledger; every change to it is recorded as a
+// LoyaltyEntry.
+type LoyaltyAccount struct {
+	CustomerID string         `json:"customer_id" gorm:"primaryKey;type:text"`
+	Balance    int64          `json:"balance" gorm:"column:balance"`
+	UpdatedAt  time.Time      `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+	Ledger     []LoyaltyEntry `json:"ledger,omitempty" gorm:"-"`
+}
+
+func (a *LoyaltyAccount) TableName() string {
+	return "loyalty_accounts"
+}
+
+// LoyaltyReason says why a points balance changed.
+type LoyaltyReason string
+
+const (
+	LoyaltyEarned   LoyaltyReason = "earned"
+	LoyaltyRedeemed LoyaltyReason = "redeemed"
+)
+
+// LoyaltyEntry is one row of a customer's append-only points ledger. Points is
+// signed: earnings are positive and redemptions negative. Every entry belongs
+// to an order, and an order has at most one entry per reason, so replaying the
+// event that earned an order's points does not credit them twice.
+type LoyaltyEntry struct {
+	ID             int           `json:"id,omitempty" gorm:"primaryKey;type:integer"`
+	CustomerID     string        `json:"customer_id" gorm:"column:customer_id;type:text;index"`
+	Points         int64         `json:"points" gorm:"column:points"`
+	Reason         LoyaltyReason `json:"reason" gorm:"column:reason;type:text;uniqueIndex:idx_loyalty_ledger_order_reason"`
+	OrderReference string        `json:"order_reference" gorm:"column:order_reference;type:text;uniqueIndex:idx_loyalty_ledger_order_reason"`
+	CreatedAt      time.Time     `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (e *LoyaltyEntry) TableName() string {
+	return "loyalty_ledger"
+}
+
+// LoyaltyProgram sets how points are earned and what they are worth, both in
+// the catalog currency. The zero value earns nothing and refuses redemptions.
+type LoyaltyProgram struct {
+	// EarnRate is the points earned per unit of currency paid.
+	EarnRate decimal.Decimal
+	// PointValue is the discount one point buys.
+	PointValue decimal.Decimal
+}
+
+// Redeemable reports whether points can be spent.
+func (p LoyaltyProgram) Redeemable() bool {
+	return p.PointValue.IsPositive()
+}
+
+// PointsFor returns the points an order earns: EarnRate per unit of catalog
+// currency paid for its lines, after discounts. Units a promotion added free
+// earn nothing. Fractions of a point are dropped.
+func (p LoyaltyProgram) PointsFor(o *Order) (int64, error) {
+	if !p.EarnRate.IsPositive() {
+		return 0, nil
+	}
+	lines, err := o.GetLines()
+	if err != nil {
+		return 0, err
+	}
+	paid := decimal.Zero
+	for _, l := range lines {
+		if l.Promotional {
+			continue
+		}
+		paid = paid.Add(l.UnitPrice.Mul(decimal.NewFromInt(int64(l.Quantity))))
+	}
+	paid = paid.Sub(o.Discount)
+	if !paid.IsPositive() {
+		return 0, nil
+	}
+	if o.FXRate.IsPositive() {
+		paid = paid.Div(o.FXRate)
+	}
+	return paid.Mul(p.EarnRate).IntPart(), nil
+}
+
+// Value returns what points are worth.
+func (p LoyaltyProgram) Value(points int64) decimal.Decimal {
+	return p.PointValue.Mul(decimal.NewFromInt(points))
+}
@@ -4,7 +4,10 @@ import "time"
 
 // Notification is a rendered order event ready to be delivered to a client.
 type Notification struct {
-	EventID    string    `json:"event_id"`
+	EventID string `json:"event_id"`
+	// Topic says what happened (e.g. orders.created, orders.refunded);
+	// Reference is the order it happened to.
+	Topic      string    `json:"topic"`
 	Reference  string    `json:"reference"`
 	CustomerID string    `json:"customer_id"`
 	OccurredAt time.Time `json:"occurred_at"`
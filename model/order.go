@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
@@ -11,9 +12,56 @@ import (
 type Order struct {
 	ID         int             `json:"id,omitempty" gorm:"primaryKey;type:integer"`
 	Reference  string          `json:"reference" gorm:"column:reference;type:string;uniqueIndex"` // Unique random reference
-	CustomerID string          `json:"customer_id" gorm:"column:customer_id;type:text"`
+	CustomerID string          `json:"customer_id" gorm:"column:customer_id;type:text;index"`
 	SKUList    string          `json:"sku_list" gorm:"column:sku_list;type:text"`
 	Price      decimal.Decimal `json:"price" gorm:"column:price;type:numeric(12,2)"`
+	// Currency is the currency Price was charged in. BaseCurrency is the
+	// currency the items were priced in, and FXRate the rate applied to convert
+	// from one to the other, so Price / FXRate recovers the catalog amount.
+	Currency     string          `json:"currency" gorm:"column:currency;type:string;default:USD"`
+	BaseCurrency string          `json:"base_currency" gorm:"column:base_currency;type:string;default:USD"`
+	FXRate       decimal.Decimal `json:"fx_rate" gorm:"column:fx_rate;type:numeric(18,8);default:1"`
+	// PaymentID is the processor's authorization ID and PaymentStatus where the
+	// charge is in its lifecycle.
+	PaymentID     string        `json:"payment_id,omitempty" gorm:"column:payment_id;type:text"`
+	PaymentStatus PaymentStatus `json:"payment_status,omitempty" gorm:"column:payment_status;type:text;not null;default:captured"`
+	// Lines is the JSON-encoded []OrderLine priced at purchase time, and
+	// Discount the promotion deduction taken off their total. Refunds are
+	// computed from these rather than current catalog prices.
+	Lines    string          `json:"lines,omitempty" gorm:"column:lines;type:text"`
+	Discount decimal.Decimal `json:"discount" gorm:"column:discount;type:numeric(12,2);default:0"`
+	// Adjustments is the JSON-encoded []Adjustment the promotions made,
+	// attributing Discount to the lines it was taken off.
+	Adjustments string `json:"adjustments,omitempty" gorm:"column:adjustments;type:text"`
+	// GiftCardCode is the gift card tendered, if any, and GiftCardAmount the
+	// part of Price it paid. The payment provider is charged the remainder.
+	GiftCardCode   string          `json:"gift_card_code,omitempty" gorm:"column:gift_card_code;type:text"`
+	GiftCardAmount decimal.Decimal `json:"gift_card_amount" gorm:"column:gift_card_amount;type:numeric(12,2);default:0"`
+	// LoyaltyPoints is the number of loyalty points redeemed against the order.
+	// What they took off is part of Discount, recorded as the loyalty
+	// adjustment.
+	LoyaltyPoints int64     `json:"loyalty_points,omitempty" gorm:"column:loyalty_points;default:0"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime;index"`
+}
+
+// OrderSummary summarizes a customer's order history.
+type OrderSummary struct {
+	// Orders is the number of orders the customer has placed.
+	Orders int `json:"orders"`
+	// FirstOrderAt and LastOrderAt are when the customer placed their first
+	// and latest orders; nil if they have none.
+	FirstOrderAt *time.Time `json:"first_order_at,omitempty"`
+	LastOrderAt  *time.Time `json:"last_order_at,omitempty"`
+}
+
+// OrderLine is a quantity of one SKU on an order at the price charged for it.
+type OrderLine struct {
+	SKU       string          `json:"sku"`
+	Name      string          `json:"name"`
+	Quantity  int             `json:"quantity"`
+	UnitPrice decimal.Decimal `json:"unit_price"`
+	// Promotional marks units added free by a promotion.
+	Promotional bool `json:"promotional,omitempty"`
 }
 
 func (o *Order) TableName() string {
@@ -41,6 +89,50 @@ func (o *Order) SetSKUList(skuList []string) error {
 	return nil
 }
 
+// GetLines returns the order lines as a slice
+func (o *Order) GetLines() ([]OrderLine, error) {
+	if o.Lines == "" {
+		return nil, nil
+	}
+	var lines []OrderLine
+	if err := json.Unmarshal([]byte(o.Lines), &lines); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal order lines: %w", err)
+	}
+	return lines, nil
+}
+
+// SetLines sets the order lines from a slice
+func (o *Order) SetLines(lines []OrderLine) error {
+	b, err := json.Marshal(lines)
+	if err != nil {
+		return fmt.Errorf("failed to marshal order lines: %w", err)
+	}
+	o.Lines = string(b)
+	return nil
+}
+
+// GetAdjustments returns the promotion adjustments as a slice
+func (o *Order) GetAdjustments() ([]Adjustment, error) {
+	if o.Adjustments == "" {
+		return nil, nil
+	}
+	var adjustments []Adjustment
+	if err := json.Unmarshal([]byte(o.Adjustments), &adjustments); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal order adjustments: %w", err)
+	}
+	return adjustments, nil
+}
+
+// SetAdjustments sets the promotion adjustments from a slice
+func (o *Order) SetAdjustments(adjustments []Adjustment) error {
+	b, err := json.Marshal(adjustments)
+	if err != nil {
+		return fmt.Errorf("failed to marshal order adjustments: %w", err)
+	}
+	o.Adjustments = string(b)
+	return nil
+}
+
 // GenerateReference generates a random reference using UUID
 func GenerateReference() string {
 	return uuid.New().String()
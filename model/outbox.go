@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // OutboxItem is one row of the transactional outbox. It is written in the same
 // database transaction as the business change it describes, then drained to the
@@ -20,17 +23,17 @@ type OutboxItem struct {
 	// Topic and PartitionKey are the broker routing metadata. They are columns
 	// rather than fields inside Data so the relay can route without decoding the
 	// payload.
-	Topic        string `json:"topic" gorm:"column:topic"`
-	PartitionKey string `json:"partition_key" gorm:"column:partition_key"`
+	Topic        string `json:"topic" gorm:"column:topic;index:,composite:topic_key"`
+	PartitionKey string `json:"partition_key" gorm:"column:partition_key;index:,composite:topic_key"`
 
 	// Data is the encoded event value, shipped to the broker as-is.
 	Data []byte `json:"data" gorm:"column:data"`
 
 	// OccurredAt is when the event happened; CreatedAt is when the row was
 	// enqueued. The relay does not read these, but they support retention and
-	// debugging.
+	// debugging: the retention purge ages rows by CreatedAt.
 	OccurredAt time.Time `json:"occurred_at" gorm:"column:occurred_at"`
-	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime;index"`
 
 	// PublishedAt and DeliveredAt are nil until the respective milestone is
 	// reached. They MUST be pointers: a zero time.Time is a real timestamp, not
@@ -38,6 +41,22 @@ type OutboxItem struct {
 	// and the relay's "WHERE published_at IS NULL" scan would match nothing.
 	PublishedAt *time.Time `json:"published_at,omitempty" gorm:"column:published_at;index"`
 	DeliveredAt *time.Time `json:"delivered_at,omitempty" gorm:"column:delivered_at"`
+
+	// ClaimedBy names the relay that last claimed the row for publishing, and
+	// ClaimedUntil is when its lease runs out. Until then no other relay
+	// claims the row; a relay that dies mid-batch so only delays its rows by
+	// the lease.
+	ClaimedBy    string     `json:"claimed_by,omitempty" gorm:"column:claimed_by"`
+	ClaimedUntil *time.Time `json:"claimed_until,omitempty" gorm:"column:claimed_until;index"`
+
+	// Attempts counts the failed attempts to publish the row and LastError
+	// holds the latest failure. The relay backs off exponentially: the row is
+	// not claimed again before NextAttemptAt. DeadAt is set when the relay
+	// gives up on the row, which is then never claimed again.
+	Attempts      int        `json:"attempts,omitempty" gorm:"column:attempts;default:0"`
+	LastError     string     `json:"last_error,omitempty" gorm:"column:last_error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" gorm:"column:next_attempt_at"`
+	DeadAt        *time.Time `json:"dead_at,omitempty" gorm:"column:dead_at;index"`
 }
 
 func (o *OutboxItem) TableName() string {
@@ -45,3 +64,74 @@ func (o *OutboxItem) TableName() string {
 }
 
 type OutboxItems []OutboxItem
+
+// OutboxBacklog summarises the rows waiting for the relay: Rows is how many are
+// unpublished and not dead, and Oldest when the oldest of them was enqueued
+// (nil when there are none). Dead is how many rows the relay gave up on.
+type OutboxBacklog struct {
+	Rows   int64      `json:"rows"`
+	Oldest *time.Time `json:"oldest,omitempty"`
+	Dead   int64      `json:"dead"`
+}
+
+// ArchivedOutboxItem is an outbox row the retention purge moved out of the
+// outbox, kept as it was when purged.
+type ArchivedOutboxItem struct {
+	OutboxItem `gorm:"embedded"`
+	ArchivedAt time.Time `json:"archived_at" gorm:"column:archived_at"`
+}
+
+func (a *ArchivedOutboxItem) TableName() string {
+	return "outbox_archive"
+}
+
+// MaxOutboxResetEvents caps how many events one reset may select.
+const MaxOutboxResetEvents = 1000
+
+// OutboxResetRequest selects outbox rows by event ID and the milestones to
+// clear on them. Clearing published_at republishes a row: its failed attempts
+// and dead-letter state are cleared with it. Clearing delivered_at marks it
+// undelivered again.
+type OutboxResetRequest struct {
+	EventIDs  []string `json:"event_ids"`
+	Published bool     `json:"published"`
+	Delivered bool     `json:"delivered"`
+	// Reason says why, for the audit log.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Validate checks the request selects events and something to reset on them.
+func (r *OutboxResetRequest) Validate() error {
+	if len(r.EventIDs) == 0 {
+		return fmt.Errorf("event_ids is required")
+	}
+	if len(r.EventIDs) > MaxOutboxResetEvents {
+		return fmt.Errorf("at most %d event_ids per reset, got %d", MaxOutboxResetEvents, len(r.EventIDs))
+	}
+	for _, id := range r.EventIDs {
+		if id == "" {
+			return fmt.Errorf("event_ids must not be empty")
+		}
+	}
+	if !r.Published && !r.Delivered {
+		return fmt.Errorf("set published, delivered or both to reset")
+	}
+	return nil
+}
+
+// OutboxAuditEntry records one administrative change to the outbox: who made
+// it, what it selected and how many rows it changed.
+type OutboxAuditEntry struct {
+	ID        int64     `json:"id,omitempty" gorm:"primaryKey;autoIncrement"`
+	Actor     string    `json:"actor" gorm:"column:actor;type:text"`
+	EventIDs  []string  `json:"event_ids" gorm:"column:event_ids;type:text;serializer:json"`
+	Published bool      `json:"published" gorm:"column:published"`
+	Delivered bool      `json:"delivered" gorm:"column:delivered"`
+	Reason    string    `json:"reason,omitempty" gorm:"column:reason;type:text"`
+	Affected  int       `json:"affected" gorm:"column:affected"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;autoCreateTime;index"`
+}
+
+func (a *OutboxAuditEntry) TableName() string {
+	return "outbox_audit"
+}
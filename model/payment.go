@@ -0,0 +1,26 @@
+package model
+
+import "github.com/shopspring/decimal"
+
+// PaymentStatus tracks an order's charge through the payment processor.
+type PaymentStatus string
+
+const (
+	// PaymentAuthorized: the amount is reserved; capture is queued in the
+	// outbox alongside the order.
+	PaymentAuthorized PaymentStatus = "authorized"
+	// PaymentCaptured: the charge has settled.
+	PaymentCaptured PaymentStatus = "captured"
+	// PaymentFailed: the capture was dead-lettered, so the charge never
+	// settled. Redelivering the capture from the outbox can still settle it.
+	PaymentFailed PaymentStatus = "failed"
+)
+
+// PaymentCapture is the payload of a payments.capture event: a request to
+// settle an order's authorization, enqueued in the order transaction.
+type PaymentCapture struct {
+	OrderReference  string          `json:"order_reference"`
+	AuthorizationID string          `json:"authorization_id"`
+	Amount          decimal.Decimal `json:"amount"`
+	Currency        string          `json:"currency"`
+}
@@ -1,17 +1,82 @@
 package model
 
+import "github.com/shopspring/decimal"
+
 type ItemsPriceRequest struct {
 	SKUs []string `json:"skus"`
+	// CouponCodes unlock coupon-only promotions, as on a purchase.
+	CouponCodes []string `json:"coupon_codes,omitempty"`
 }
 
 type PriceResponse struct {
-	Items             []*Item     `json:"items"`
-	Promotions        *Promotions `json:"promotions,omitempty"`
-	TotalGross        float64     `json:"total_gross"`
-	TotalWithDiscount float64     `json:"total_with_discount"`
+	Items []*Item `json:"items"`
+	// Lines price the basket per SKU before promotions, with any tier and
+	// bundle applied.
+	Lines []PriceLine `json:"lines,omitempty"`
+	// Bundles lists the bundles the basket was sold as, and what each saved.
+	Bundles    []Adjustment `json:"bundles,omitempty"`
+	Promotions *Promotions  `json:"promotions,omitempty"`
+	// TotalGross is the basket's price before promotions, after tiers and
+	// bundles.
+	TotalGross        float64 `json:"total_gross"`
+	TotalWithDiscount float64 `json:"total_with_discount"`
+	// Currency is the currency every amount in the response is quoted in.
+	Currency string `json:"currency"`
+}
+
+// PriceLine is how the units of one SKU in a basket are priced before
+// promotions.
+type PriceLine struct {
+	SKU      string `json:"sku"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	// ListPrice is the item's unit price; UnitPrice is what each unit costs
+	// at this quantity, lower if TierMinQuantity names the tier reached.
+	ListPrice       decimal.Decimal `json:"list_price"`
+	UnitPrice       decimal.Decimal `json:"unit_price"`
+	TierMinQuantity int             `json:"tier_min_quantity,omitempty"`
+	// Bundled units were sold in a bundle, which took BundleDiscount off
+	// them.
+	Bundled        int             `json:"bundled,omitempty"`
+	BundleDiscount decimal.Decimal `json:"bundle_discount"`
+	// Total is Quantity at UnitPrice less BundleDiscount.
+	Total decimal.Decimal `json:"total"`
 }
 
 type Promotions struct {
 	Deduction  float64 `json:"deduction"`
 	AddedItems []*Item `json:"added_items"`
+	// Adjustments break Deduction and AddedItems down by the promotion that
+	// gave them and the lines they apply to.
+	Adjustments []Adjustment `json:"adjustments,omitempty"`
+	// Consumed lists the basket units a promotion used, as passed to it, so
+	// the engine can keep them from lower-priority promotions.
+	Consumed []*Item `json:"-"`
+}
+
+// Adjustment is what one promotion did to a basket: an amount taken off some
+// of its units, and any items added free.
+type Adjustment struct {
+	// PromotionID identifies the promotion: "builtin:<name>" for a built-in
+	// deal, "promotion:<id>" for a stored one and "file:<name>" for a rule in
+	// the promotions file. A bundle is recorded as "bundle:<code>".
+	PromotionID string  `json:"promotion_id"`
+	Reason      string  `json:"reason"`
+	Amount      float64 `json:"amount"`
+	// Lines are the units the promotion applied to, by SKU, with each line's
+	// share of Amount. The shares sum to Amount.
+	Lines []AdjustmentLine `json:"lines"`
+	// AddedSKUs lists the items added free, one entry per unit.
+	AddedSKUs []string `json:"added_skus,omitempty"`
+	// Units counts the units the promotion made free or discounted, added
+	// ones included. Paid units that only qualified for it, such as the
+	// bought units of a buy N get M, are on Lines but not counted.
+	Units int `json:"units,omitempty"`
+}
+
+// AdjustmentLine is an Adjustment's share of the units of one SKU.
+type AdjustmentLine struct {
+	SKU      string  `json:"sku"`
+	Quantity int     `json:"quantity"`
+	Amount   float64 `json:"amount"`
 }
@@ -0,0 +1,214 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PromotionType selects how a PromotionRule is applied and which of its fields
+// it reads.
+type PromotionType string
+
+const (
+	// PromotionBuyNGetMFree makes FreeQuantity units of SKU free for every
+	// BuyQuantity paid: "buy 2 get 1 free" is 3 for the price of 2.
+	PromotionBuyNGetMFree PromotionType = "buy_n_get_m_free"
+	// PromotionPercentOffOverQuantity takes PercentOff off every unit of SKU
+	// once the basket holds at least MinQuantity of them.
+	PromotionPercentOffOverQuantity PromotionType = "percent_off_over_quantity"
+	// PromotionFreeGift adds one GiftSKU free for every unit of SKU bought.
+	PromotionFreeGift PromotionType = "free_gift_with_purchase"
+)
+
+// PromotionRule is a promotion defined as data rather than code, so deals can
+// be changed through the admin API without a deploy.
+type PromotionRule struct {
+	ID     int           `json:"id,omitempty" gorm:"primaryKey;type:integer"`
+	Name   string        `json:"name" gorm:"column:name;type:text"`
+	Type   PromotionType `json:"type" gorm:"column:type;type:text"`
+	Active bool          `json:"active" gorm:"column:active;index"`
+	// SKU is the item the rule is triggered by.
+	SKU          string          `json:"sku" gorm:"column:sku;type:string"`
+	BuyQuantity  int             `json:"buy_quantity,omitempty" gorm:"column:buy_quantity"`
+	FreeQuantity int             `json:"free_quantity,omitempty" gorm:"column:free_quantity"`
+	MinQuantity  int             `json:"min_quantity,omitempty" gorm:"column:min_quantity"`
+	PercentOff   decimal.Decimal `json:"percent_off,omitempty" gorm:"column:percent_off;type:numeric(5,2);default:0"`
+	GiftSKU      string          `json:"gift_sku,omitempty" gorm:"column:gift_sku;type:string"`
+	// CouponOnly rules apply only to checkouts that present one of their
+	// coupon codes, never automatically.
+	CouponOnly bool `json:"coupon_only,omitempty" gorm:"column:coupon_only;default:false"`
+	// Priority, ExclusiveGroup and Stackable control how the rule combines
+	// with other promotions (see promotions.Policy).
+	Priority       int    `json:"priority,omitempty" gorm:"column:priority;default:0"`
+	ExclusiveGroup string `json:"exclusive_group,omitempty" gorm:"column:exclusive_group;type:text"`
+	Stackable      bool   `json:"stackable,omitempty" gorm:"column:stackable;default:false"`
+	// Schedule limits when an active rule applies, and CustomerCondition to
+	// whom.
+	Schedule          `gorm:"embedded"`
+	CustomerCondition `gorm:"embedded"`
+	// PromotionBudget caps what the rule gives away and PromotionUsage is what
+	// it has given so far. Usage is kept by purchases: it is ignored on create
+	// and update.
+	PromotionBudget `gorm:"embedded"`
+	PromotionUsage  `gorm:"embedded"`
+	// ScheduleStatus and AppliesNow report the schedule at the time of the
+	// response; they are not stored.
+	ScheduleStatus ScheduleStatus `json:"status,omitempty" gorm:"-"`
+	AppliesNow     bool           `json:"applies_now" gorm:"-"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (p *PromotionRule) TableName() string {
+	return "promotions"
+}
+
+// Validate checks that the fields the rule's type reads are set and sane.
+func (p *PromotionRule) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !IsSKU(p.SKU) {
+		return fmt.Errorf("invalid sku '%s'", p.SKU)
+	}
+	if err := p.Schedule.Validate(); err != nil {
+		return err
+	}
+	if err := p.CustomerCondition.Validate(); err != nil {
+		return err
+	}
+	if err := p.PromotionBudget.Validate(); err != nil {
+		return err
+	}
+	switch p.Type {
+	case PromotionBuyNGetMFree:
+		if p.BuyQuantity < 1 || p.FreeQuantity < 1 {
+			return fmt.Errorf("%s requires buy_quantity and free_quantity of at least 1", p.Type)
+		}
+	case PromotionPercentOffOverQuantity:
+		if p.MinQuantity < 1 {
+			return fmt.Errorf("%s requires min_quantity of at least 1", p.Type)
+		}
+		if !p.PercentOff.IsPositive() || p.PercentOff.GreaterThan(decimal.NewFromInt(100)) {
+			return fmt.Errorf("%s requires percent_off in (0, 100]", p.Type)
+		}
+	case PromotionFreeGift:
+		if !IsSKU(p.GiftSKU) {
+			return fmt.Errorf("%s requires a valid gift_sku, got '%s'", p.Type, p.GiftSKU)
+		}
+	default:
+		return fmt.Errorf("unknown promotion type '%s'", p.Type)
+	}
+	return nil
+}
+
+// CustomerCondition limits a promotion to customers with some order history.
+// The zero CustomerCondition applies to everyone, including anonymous price
+// quotes; any other applies only to a known customer.
+type CustomerCondition struct {
+	// FirstOrder limits the promotion to a customer's first order.
+	FirstOrder bool `json:"first_order,omitempty" yaml:"first_order" gorm:"column:first_order;default:false"`
+	// MinOrders limits the promotion to customers who have already placed at
+	// least that many orders.
+	MinOrders int `json:"min_orders,omitempty" yaml:"min_orders" gorm:"column:min_orders;default:0"`
+}
+
+// Validate checks the condition is satisfiable.
+func (c CustomerCondition) Validate() error {
+	if c.MinOrders < 0 {
+		return fmt.Errorf("min_orders must not be negative")
+	}
+	if c.FirstOrder && c.MinOrders > 0 {
+		return fmt.Errorf("first_order and min_orders cannot both be set")
+	}
+	return nil
+}
+
+// Matches reports whether a customer with order history h qualifies. A nil h
+// is an anonymous customer.
+func (c CustomerCondition) Matches(h *OrderSummary) bool {
+	if c == (CustomerCondition{}) {
+		return true
+	}
+	if h == nil {
+		return false
+	}
+	if c.FirstOrder {
+		return h.Orders == 0
+	}
+	return h.Orders >= c.MinOrders
+}
+
+// PromotionBudget caps what a promotion may give away. A purchase that reaches
+// either cap disables the promotion; that purchase gets the whole discount, so
+// a budget can be overrun by at most one order. Zero caps are unlimited.
+type PromotionBudget struct {
+	// MaxDiscount caps the total discount granted, in the catalog currency.
+	MaxDiscount decimal.Decimal `json:"max_discount,omitempty" gorm:"column:max_discount;type:numeric(18,2);default:0"`
+	// MaxUnits caps the units discounted or given free.
+	MaxUnits int `json:"max_units,omitempty" gorm:"column:max_units;default:0"`
+}
+
+// Validate checks the caps are not negative.
+func (b PromotionBudget) Validate() error {
+	if b.MaxDiscount.IsNegative() {
+		return fmt.Errorf("max_discount must not be negative")
+	}
+	if b.MaxUnits < 0 {
+		return fmt.Errorf("max_units must not be negative")
+	}
+	return nil
+}
+
+// Exhausted reports whether u has reached either cap.
+func (b PromotionBudget) Exhausted(u PromotionUsage) bool {
+	return (b.MaxDiscount.IsPositive() && u.DiscountGranted.GreaterThanOrEqual(b.MaxDiscount)) ||
+		(b.MaxUnits > 0 && u.UnitsGranted >= b.MaxUnits)
+}
+
+// PromotionUsage is what a promotion has given away in committed purchases.
+// Refunds do not give it back.
+type PromotionUsage struct {
+	// Redemptions counts the orders the promotion applied to.
+	Redemptions int `json:"redemptions" gorm:"column:redemptions;default:0"`
+	// DiscountGranted is the total discount, in the catalog currency.
+	DiscountGranted decimal.Decimal `json:"discount_granted" gorm:"column:discount_granted;type:numeric(18,2);default:0"`
+	// UnitsGranted counts the units discounted or given free.
+	UnitsGranted int `json:"units_granted" gorm:"column:units_granted;default:0"`
+}
+
+// PromotionStats reports a stored promotion's usage against its budget.
+type PromotionStats struct {
+	PromotionID int  `json:"promotion_id"`
+	Active      bool `json:"active"`
+	PromotionBudget
+	PromotionUsage
+	// Exhausted is set once either cap has been reached.
+	Exhausted bool `json:"exhausted"`
+	// RemainingDiscount and RemainingUnits are what is left under each cap,
+	// absent when the cap is unlimited.
+	RemainingDiscount *decimal.Decimal `json:"remaining_discount,omitempty"`
+	RemainingUnits    *int             `json:"remaining_units,omitempty"`
+}
+
+// Stats returns p's usage against its budget.
+func (p *PromotionRule) Stats() *PromotionStats {
+	s := &PromotionStats{
+		PromotionID:     p.ID,
+		Active:          p.Active,
+		PromotionBudget: p.PromotionBudget,
+		PromotionUsage:  p.PromotionUsage,
+		Exhausted:       p.Exhausted(p.PromotionUsage),
+	}
+	if p.MaxDiscount.IsPositive() {
+		left := decimal.Max(p.MaxDiscount.Sub(p.DiscountGranted), decimal.Zero)
+		s.RemainingDiscount = &left
+	}
+	if p.MaxUnits > 0 {
+		left := max(p.MaxUnits-p.UnitsGranted, 0)
+		s.RemainingUnits = &left
+	}
+	return s
+}
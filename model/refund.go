@@ -0,0 +1,84 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RefundStatus tracks a refund's payment through the processor.
+type RefundStatus string
+
+const (
+	// RefundPending: the refund is recorded and its payment is queued in the
+	// outbox.
+	RefundPending RefundStatus = "pending"
+	// RefundCompleted: the processor has returned the money.
+	RefundCompleted RefundStatus = "completed"
+)
+
+// Refund records goods returned against an order and the amount given back.
+type Refund struct {
+	ID             int             `json:"id,omitempty" gorm:"primaryKey;type:integer"`
+	Reference      string          `json:"reference" gorm:"column:reference;type:string;uniqueIndex"`
+	OrderReference string          `json:"order_reference" gorm:"column:order_reference;type:string;index"`
+	CustomerID     string          `json:"customer_id" gorm:"column:customer_id;type:text"`
+	Lines          string          `json:"lines" gorm:"column:lines;type:text"` // JSON-encoded []RefundLine
+	Amount         decimal.Decimal `json:"amount" gorm:"column:amount;type:numeric(12,2)"`
+	// GiftCardAmount is the part of Amount credited back to the order's gift
+	// card; the rest is refunded through the payment provider.
+	GiftCardAmount decimal.Decimal `json:"gift_card_amount" gorm:"column:gift_card_amount;type:numeric(12,2);default:0"`
+	Currency       string          `json:"currency" gorm:"column:currency;type:string"`
+	Restocked      bool            `json:"restocked" gorm:"column:restocked"`
+	Status         RefundStatus    `json:"status" gorm:"column:status;type:text"`
+	CreatedAt      time.Time       `json:"created_at" gorm:"column:created_at;autoCreateTime"`
+}
+
+func (r *Refund) TableName() string {
+	return "refunds"
+}
+
+// RefundLine is a quantity of one SKU returned, and the amount refunded for it.
+type RefundLine struct {
+	SKU      string          `json:"sku"`
+	Quantity int             `json:"quantity"`
+	Amount   decimal.Decimal `json:"amount,omitempty"`
+}
+
+// GetLines returns the refunded lines
+func (r *Refund) GetLines() ([]RefundLine, error) {
+	var lines []RefundLine
+	if err := json.Unmarshal([]byte(r.Lines), &lines); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal refund lines: %w", err)
+	}
+	return lines, nil
+}
+
+// SetLines sets the refunded lines
+func (r *Refund) SetLines(lines []RefundLine) error {
+	b, err := json.Marshal(lines)
+	if err != nil {
+		return fmt.Errorf("failed to marshal refund lines: %w", err)
+	}
+	r.Lines = string(b)
+	return nil
+}
+
+// RefundRequest selects the lines of an order to return.
+type RefundRequest struct {
+	Lines []RefundLine `json:"lines"`
+	// Restock returns the refunded units to inventory.
+	Restock bool `json:"restock"`
+}
+
+// PaymentRefund is the payload of a payments.refund event: a request to return
+// money against an order's authorization, enqueued in the refund transaction.
+type PaymentRefund struct {
+	OrderReference  string          `json:"order_reference"`
+	RefundReference string          `json:"refund_reference"`
+	AuthorizationID string          `json:"authorization_id"`
+	Amount          decimal.Decimal `json:"amount"`
+	Currency        string          `json:"currency"`
+}
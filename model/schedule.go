@@ -0,0 +1,162 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule limits when a promotion applies: between StartsAt and EndsAt, and
+// optionally only on some days of the week and between two times of day. The
+// zero Schedule always applies.
+type Schedule struct {
+	// StartsAt, when set, is the time the promotion starts applying.
+	StartsAt *time.Time `json:"starts_at,omitempty" yaml:"starts_at" gorm:"column:starts_at"`
+	// EndsAt, when set, is the time the promotion stops applying.
+	EndsAt *time.Time `json:"ends_at,omitempty" yaml:"ends_at" gorm:"column:ends_at"`
+	// Days limits the promotion to days of the week, e.g. "sat,sun". Empty is
+	// every day.
+	Days string `json:"days,omitempty" yaml:"days" gorm:"column:days;type:text"`
+	// From and To limit the promotion to a daily window, as "HH:MM" in
+	// TimeZone. A To before From wraps past midnight ("22:00" to "02:00"),
+	// and the hours after midnight count as the day the window opened. From
+	// and To must differ.
+	From string `json:"from,omitempty" yaml:"from" gorm:"column:time_from;type:text"`
+	To   string `json:"to,omitempty" yaml:"to" gorm:"column:time_to;type:text"`
+	// TimeZone is the IANA zone Days, From and To are read in; default UTC.
+	TimeZone string `json:"time_zone,omitempty" yaml:"time_zone" gorm:"column:time_zone;type:text"`
+}
+
+// ScheduleStatus places a schedule's date range relative to a time.
+type ScheduleStatus string
+
+const (
+	ScheduleUpcoming ScheduleStatus = "upcoming"
+	ScheduleActive   ScheduleStatus = "active"
+	ScheduleExpired  ScheduleStatus = "expired"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Validate checks the schedule's fields parse and its range is not empty.
+func (s *Schedule) Validate() error {
+	if s.StartsAt != nil && s.EndsAt != nil && !s.EndsAt.After(*s.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	if _, err := s.days(); err != nil {
+		return err
+	}
+	if (s.From == "") != (s.To == "") {
+		return fmt.Errorf("from and to must be set together")
+	}
+	if s.From != "" {
+		from, err := minuteOfDay(s.From)
+		if err != nil {
+			return err
+		}
+		to, err := minuteOfDay(s.To)
+		if err != nil {
+			return err
+		}
+		// AppliesAt reads from == to as an empty window, never as all day.
+		if from == to {
+			return fmt.Errorf("from and to must differ")
+		}
+	}
+	if _, err := s.location(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Status reports whether t is before, within or after the schedule's date
+// range. Day and time-of-day windows do not affect it; see AppliesAt.
+func (s *Schedule) Status(t time.Time) ScheduleStatus {
+	switch {
+	case s.StartsAt != nil && t.Before(*s.StartsAt):
+		return ScheduleUpcoming
+	case s.EndsAt != nil && !t.Before(*s.EndsAt):
+		return ScheduleExpired
+	default:
+		return ScheduleActive
+	}
+}
+
+// AppliesAt reports whether a promotion on this schedule applies at t. An
+// invalid schedule never applies.
+func (s *Schedule) AppliesAt(t time.Time) bool {
+	if s.Status(t) != ScheduleActive {
+		return false
+	}
+	loc, err := s.location()
+	if err != nil {
+		return false
+	}
+	t = t.In(loc)
+	days, err := s.days()
+	if err != nil {
+		return false
+	}
+	day := t.Weekday()
+	if s.From != "" {
+		from, err := minuteOfDay(s.From)
+		if err != nil {
+			return false
+		}
+		to, err := minuteOfDay(s.To)
+		if err != nil {
+			return false
+		}
+		m := t.Hour()*60 + t.Minute()
+		switch {
+		case from <= to:
+			if m < from || m >= to {
+				return false
+			}
+		case m < to:
+			// The early hours of a window that wraps past midnight belong to
+			// the day it opened on.
+			day = t.AddDate(0, 0, -1).Weekday()
+		case m < from:
+			return false
+		}
+	}
+	return len(days) == 0 || days[day]
+}
+
+func (s *Schedule) days() (map[time.Weekday]bool, error) {
+	if s.Days == "" {
+		return nil, nil
+	}
+	days := make(map[time.Weekday]bool)
+	for _, d := range strings.Split(s.Days, ",") {
+		wd, ok := weekdays[strings.ToLower(strings.TrimSpace(d))]
+		if !ok {
+			return nil, fmt.Errorf("invalid day '%s': want sun, mon, tue, wed, thu, fri or sat", d)
+		}
+		days[wd] = true
+	}
+	return days, nil
+}
+
+func (s *Schedule) location() (*time.Location, error) {
+	if s.TimeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time_zone '%s'", s.TimeZone)
+	}
+	return loc, nil
+}
+
+func minuteOfDay(hm string) (int, error) {
+	t, err := time.Parse("15:04", hm)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day '%s': want HH:MM", hm)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
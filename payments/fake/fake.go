@@ -0,0 +1,159 @@
+// Package fake implements an in-memory payments.Provider. It approves every
+// operation unless told otherwise, and its outcomes can be scripted so tests can
+// drive the purchase flow through declines and processor outages. It keeps its
+// authorizations in memory only, so it is for tests and local development
+// (run orders --fake-payments), never a deployment.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ATMackay/checkout/payments"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Operation names a Provider method for scripting and call recording.
+type Operation string
+
+const (
+	Authorize Operation = "authorize"
+	Capture   Operation = "capture"
+	Void      Operation = "void"
+	Refund    Operation = "refund"
+)
+
+// Call records one invocation of the provider.
+type Call struct {
+	Op              Operation
+	AuthorizationID string
+	Amount          decimal.Decimal
+	Err             error
+}
+
+type authorization struct {
+	amount   decimal.Decimal
+	captured decimal.Decimal
+	refunded decimal.Decimal
+	refunds  map[string]bool // refund references already paid
+	voided   bool
+}
+
+var _ payments.Provider = (*Provider)(nil)
+
+// Provider is a scriptable in-memory payment processor. It keeps enough state to
+// enforce the rules a real processor would — no capture beyond the authorized
+// amount, no refund beyond the captured amount, no capture after a void — and
+// treats repeated captures and voids as no-ops.
+type Provider struct {
+	mu     sync.Mutex
+	script map[Operation][]error
+	auths  map[string]*authorization
+	calls  []Call
+}
+
+// NewProvider returns a Provider that approves everything.
+func NewProvider() *Provider {
+	return &Provider{
+		script: make(map[Operation][]error),
+		auths:  make(map[string]*authorization),
+	}
+}
+
+// Script queues outcomes for the next calls of op, consumed in order. A nil
+// outcome is a success; once the queue is empty op succeeds again.
+func (p *Provider) Script(op Operation, outcomes ...error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.script[op] = append(p.script[op], outcomes...)
+}
+
+// Calls returns every call made so far, in order.
+func (p *Provider) Calls() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Call(nil), p.calls...)
+}
+
+// next pops the scripted outcome for op and records the call. The caller holds
+// p.mu.
+func (p *Provider) next(op Operation, id string, amount decimal.Decimal) error {
+	var err error
+	if q := p.script[op]; len(q) > 0 {
+		err, p.script[op] = q[0], q[1:]
+	}
+	p.calls = append(p.calls, Call{Op: op, AuthorizationID: id, Amount: amount, Err: err})
+	return err
+}
+
+func (p *Provider) Authorize(_ context.Context, req *payments.AuthorizeRequest) (*payments.Authorization, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := "auth_" + uuid.New().String()
+	if err := p.next(Authorize, id, req.Amount); err != nil {
+		return nil, err
+	}
+	p.auths[id] = &authorization{amount: req.Amount, refunds: make(map[string]bool)}
+	return &payments.Authorization{ID: id, Amount: req.Amount, Currency: req.Currency}, nil
+}
+
+func (p *Provider) Capture(_ context.Context, id string, amount decimal.Decimal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.next(Capture, id, amount); err != nil {
+		return err
+	}
+	a, ok := p.auths[id]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %s", payments.ErrUnknownAuthorization, id)
+	case a.voided:
+		return fmt.Errorf("%w: authorization %s was voided", payments.ErrDeclined, id)
+	case a.captured.IsPositive():
+		return nil // already captured
+	case amount.GreaterThan(a.amount):
+		return fmt.Errorf("%w: capture %s exceeds authorized %s", payments.ErrDeclined, amount, a.amount)
+	}
+	a.captured = amount
+	return nil
+}
+
+func (p *Provider) Void(_ context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.next(Void, id, decimal.Zero); err != nil {
+		return err
+	}
+	a, ok := p.auths[id]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %s", payments.ErrUnknownAuthorization, id)
+	case a.captured.IsPositive():
+		return fmt.Errorf("%w: authorization %s already captured", payments.ErrDeclined, id)
+	}
+	a.voided = true
+	return nil
+}
+
+func (p *Provider) Refund(_ context.Context, id, reference string, amount decimal.Decimal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.next(Refund, id, amount); err != nil {
+		return err
+	}
+	a, ok := p.auths[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", payments.ErrUnknownAuthorization, id)
+	}
+	if a.refunds[reference] {
+		return nil // already refunded
+	}
+	if a.refunded.Add(amount).GreaterThan(a.captured) {
+		return fmt.Errorf("%w: refund %s exceeds captured %s less refunded %s", payments.ErrDeclined, amount, a.captured, a.refunded)
+	}
+	a.refunded = a.refunded.Add(amount)
+	a.refunds[reference] = true
+	return nil
+}
@@ -0,0 +1,57 @@
+//go:build !integration
+
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ATMackay/checkout/payments"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvider()
+	amount := decimal.NewFromInt(100)
+
+	auth, err := p.Authorize(ctx, &payments.AuthorizeRequest{Reference: "ref", Amount: amount, Currency: "USD"})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, p.Capture(ctx, auth.ID, decimal.NewFromInt(101)), payments.ErrDeclined)
+	require.NoError(t, p.Capture(ctx, auth.ID, amount))
+	require.NoError(t, p.Capture(ctx, auth.ID, amount), "a repeated capture is a no-op")
+	require.ErrorIs(t, p.Void(ctx, auth.ID), payments.ErrDeclined, "cannot void after capture")
+
+	require.NoError(t, p.Refund(ctx, auth.ID, "r1", decimal.NewFromInt(60)))
+	require.NoError(t, p.Refund(ctx, auth.ID, "r1", decimal.NewFromInt(60)), "a repeated refund is a no-op")
+	require.ErrorIs(t, p.Refund(ctx, auth.ID, "r2", decimal.NewFromInt(41)), payments.ErrDeclined)
+	require.NoError(t, p.Refund(ctx, auth.ID, "r2", decimal.NewFromInt(40)))
+
+	require.ErrorIs(t, p.Capture(ctx, "auth_unknown", amount), payments.ErrUnknownAuthorization)
+}
+
+func TestProvider_Script(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvider()
+	outage := errors.New("processor unavailable")
+	p.Script(Authorize, payments.ErrDeclined, outage)
+
+	req := &payments.AuthorizeRequest{Amount: decimal.NewFromInt(1)}
+	_, err := p.Authorize(ctx, req)
+	require.ErrorIs(t, err, payments.ErrDeclined)
+	_, err = p.Authorize(ctx, req)
+	require.ErrorIs(t, err, outage)
+	auth, err := p.Authorize(ctx, req)
+	require.NoError(t, err, "the script is exhausted, so the provider approves again")
+
+	require.NoError(t, p.Void(ctx, auth.ID))
+	require.ErrorIs(t, p.Capture(ctx, auth.ID, decimal.NewFromInt(1)), payments.ErrDeclined, "cannot capture after void")
+
+	calls := p.Calls()
+	require.Len(t, calls, 5)
+	require.Equal(t, Authorize, calls[0].Op)
+	require.ErrorIs(t, calls[0].Err, payments.ErrDeclined)
+}
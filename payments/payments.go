@@ -0,0 +1,58 @@
+// Package payments defines the contract between checkout and a payment
+// processor. A purchase authorizes the charge before the order commits, then
+// captures it asynchronously once the order is durable; an authorization whose
+// order fails to commit is voided. The processor is behind Provider so a real
+// gateway and the local fake (package fake) are interchangeable.
+package payments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrDeclined is returned when the processor refuses an operation, e.g. an
+// authorization for insufficient funds.
+var ErrDeclined = errors.New("payment declined")
+
+// ErrUnknownAuthorization is returned for an operation on an authorization the
+// processor has no record of.
+var ErrUnknownAuthorization = errors.New("unknown authorization")
+
+// Provider is a payment processor.
+//
+// Capture, Void and Refund may be retried (capture runs from the outbox, which
+// is at-least-once), so implementations must treat a repeat of a completed
+// operation as a no-op rather than charging twice.
+type Provider interface {
+	// Authorize reserves the amount against the customer's payment method
+	// without moving money.
+	Authorize(ctx context.Context, req *AuthorizeRequest) (*Authorization, error)
+	// Capture settles amount (at most the authorized amount) of an
+	// authorization.
+	Capture(ctx context.Context, authorizationID string, amount decimal.Decimal) error
+	// Void releases an authorization that has not been captured.
+	Void(ctx context.Context, authorizationID string) error
+	// Refund returns amount of a captured authorization to the customer. An
+	// authorization may be refunded in several parts; reference identifies
+	// each part so that a retried refund is not paid twice.
+	Refund(ctx context.Context, authorizationID, reference string, amount decimal.Decimal) error
+}
+
+// AuthorizeRequest describes a charge to reserve.
+type AuthorizeRequest struct {
+	// Reference is the order reference, passed to the processor so the charge
+	// can be reconciled against the order.
+	Reference  string
+	CustomerID string
+	Amount     decimal.Decimal
+	Currency   string
+}
+
+// Authorization is a successful reservation.
+type Authorization struct {
+	ID       string
+	Amount   decimal.Decimal
+	Currency string
+}
@@ -0,0 +1,60 @@
+package promotions
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ATMackay/checkout/model"
+)
+
+// Basket is what a promotion applies to: the units being bought, one entry
+// per unit, and who is buying them.
+type Basket struct {
+	Items []*model.Item
+	// Customer is nil for an anonymous basket, such as a price quote.
+	Customer *Customer
+}
+
+// NewBasket returns an anonymous basket of items.
+func NewBasket(items []*model.Item) *Basket {
+	return &Basket{Items: items}
+}
+
+// History returns the order history of the basket's customer, nil if the
+// basket is anonymous.
+func (b *Basket) History(ctx context.Context) (*model.OrderSummary, error) {
+	if b.Customer == nil {
+		return nil, nil
+	}
+	return b.Customer.History(ctx)
+}
+
+// with returns a basket of items for the same customer.
+func (b *Basket) with(items []*model.Item) *Basket {
+	return &Basket{Items: items, Customer: b.Customer}
+}
+
+// Customer is the shopper a basket belongs to. Their order history is loaded
+// on first use, so a basket no customer-aware promotion looks at costs no
+// lookup.
+type Customer struct {
+	ID string
+
+	load    func(ctx context.Context) (*model.OrderSummary, error)
+	once    sync.Once
+	history *model.OrderSummary
+	err     error
+}
+
+// NewCustomer returns the customer id, whose order history history loads.
+func NewCustomer(id string, history func(ctx context.Context) (*model.OrderSummary, error)) *Customer {
+	return &Customer{ID: id, load: history}
+}
+
+// History returns the customer's order history.
+func (c *Customer) History(ctx context.Context) (*model.OrderSummary, error) {
+	c.once.Do(func() {
+		c.history, c.err = c.load(ctx)
+	})
+	return c.history, c.err
+}
@@ -2,34 +2,405 @@ package promotions
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
 
+	"github.com/ATMackay/checkout/database"
 	"github.com/ATMackay/checkout/model"
 )
 
 // PromotionsEngine applies all registered promotions.
+//
+// By default promotions are applied highest Policy.Priority first
+// (registration order breaks ties), each to the basket units no earlier
+// promotion has consumed. A promotion consumes the units it reports in
+// Promotions.Consumed unless its policy is Stackable, and at most one
+// promotion of an exclusivity group applies. So two deals never discount the
+// same unit unless one of them is declared to stack. With an Optimizer set,
+// the same rules hold but units go to whichever deals save the customer the
+// most, priority only breaking ties.
 type PromotionsEngine struct {
 	promotions []Promotion
+	now        func() time.Time
+	optimizer  *Optimizer
 }
 
 // NewPromotionsEngine creates a new PromotionsEngine with the given promotions.
 func NewPromotionsEngine(promotions ...Promotion) *PromotionsEngine {
 	return &PromotionsEngine{
 		promotions: promotions,
+		now:        time.Now,
 	}
 }
 
-// ApplyPromotions applies all registered promotions to the items.
-func (e *PromotionsEngine) ApplyPromotions(ctx context.Context, items []*model.Item) (*model.Promotions, error) {
-	result := &model.Promotions{}
+// SetClock sets the clock promotion schedules are checked against.
+func (e *PromotionsEngine) SetClock(now func() time.Time) {
+	e.now = now
+}
+
+// SetOptimizer makes the engine allocate units to promotions with o. A nil o
+// restores priority order.
+func (e *PromotionsEngine) SetOptimizer(o *Optimizer) {
+	e.optimizer = o
+}
+
+// ApplyPromotions applies all registered promotions, and any extra ones for
+// this basket only (e.g. unlocked by a coupon), to the basket. Scheduled
+// promotions that do not apply now are skipped.
+func (e *PromotionsEngine) ApplyPromotions(ctx context.Context, basket *Basket, extra ...Promotion) (*model.Promotions, error) {
+	candidates, _, err := e.candidates(ctx, extra)
+	if err != nil {
+		return nil, err
+	}
+	allocation, err := e.allocate(ctx, candidates, basket)
+	if err != nil {
+		return nil, err
+	}
+	return allocation.Promotions(), nil
+}
+
+// Decision is what became of one promotion when a basket was priced.
+type Decision struct {
+	PromotionID string
+	// Result is what the promotion gave, nil if it did not apply.
+	Result *model.Promotions
+	// Reason says why the promotion did not apply.
+	Reason string
+}
+
+// Explain is ApplyPromotions that also returns a Decision for every
+// promotion, in the order they were considered (those outside their schedule
+// last), to diagnose pricing. It applies promotions that lost out once more
+// on their own to tell why, so it is slower.
+func (e *PromotionsEngine) Explain(ctx context.Context, basket *Basket, extra ...Promotion) (*model.Promotions, []Decision, error) {
+	candidates, unscheduled, err := e.candidates(ctx, extra)
+	if err != nil {
+		return nil, nil, err
+	}
+	allocation, err := e.allocate(ctx, candidates, basket)
+	if err != nil {
+		return nil, nil, err
+	}
+	results := make(map[int]*model.Promotions)
+	groups := make(map[string]string) // exclusivity group to the promotion that took it
+	for _, a := range allocation {
+		results[a.index] = a.Result
+		if group := PolicyOf(a.Promotion).Group; group != "" {
+			groups[group] = IDOf(a.Promotion)
+		}
+	}
+	history, err := basket.History(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decisions := make([]Decision, 0, len(candidates)+len(unscheduled))
+	for i, p := range candidates {
+		d := Decision{PromotionID: IDOf(p), Result: results[i]}
+		if d.Result == nil {
+			alone, err := p.Apply(ctx, basket)
+			if err != nil {
+				return nil, nil, err
+			}
+			group := PolicyOf(p).Group
+			cond := CustomerConditionOf(p)
+			switch {
+			case !cond.Matches(history):
+				d.Reason = "customer does not qualify (" + describe(cond) + ")"
+			case empty(alone):
+				d.Reason = "its conditions are not met by this basket"
+			case group != "" && groups[group] != "":
+				d.Reason = fmt.Sprintf("exclusive group %q went to %s", group, groups[group])
+			case e.optimizer != nil:
+				d.Reason = "the units it needs went to promotions that save more"
+			default:
+				d.Reason = "the units it needs went to higher-priority promotions"
+			}
+		}
+		decisions = append(decisions, d)
+	}
+	for _, p := range unscheduled {
+		decisions = append(decisions, Decision{PromotionID: IDOf(p), Reason: "outside its schedule at " + e.now().Format(time.RFC3339)})
+	}
+	return allocation.Promotions(), decisions, nil
+}
+
+// allocate allocates the basket's units to candidates, which are in priority
+// order.
+func (e *PromotionsEngine) allocate(ctx context.Context, candidates []Promotion, basket *Basket) (Allocation, error) {
+	if e.optimizer != nil {
+		return e.optimizer.Optimize(ctx, candidates, basket)
+	}
+	ev := newEvaluator(ctx, candidates, basket)
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	st, err := ev.inOrder(ev.start(), order)
+	if err != nil {
+		return nil, err
+	}
+	return st.steps, nil
+}
+
+// MissingItems is a promotion that names SKUs the catalog does not hold.
+type MissingItems struct {
+	PromotionID string
+	SKUs        []string
+}
 
-	for _, promotion := range e.promotions {
-		p, err := promotion.Apply(ctx, items)
+// CheckReferences returns the registered promotions, including set members,
+// that name SKUs inventory does not hold. Such a promotion cannot apply (or
+// cannot give its gift) until the items are added.
+func (e *PromotionsEngine) CheckReferences(ctx context.Context, inventory database.InventoryStore) ([]MissingItems, error) {
+	promotions, err := expand(ctx, e.promotions)
+	if err != nil {
+		return nil, err
+	}
+	var skus []string
+	for _, p := range promotions {
+		for _, sku := range ReferencesOf(p) {
+			if !slices.Contains(skus, sku) {
+				skus = append(skus, sku)
+			}
+		}
+	}
+	if len(skus) == 0 {
+		return nil, nil
+	}
+	items, err := inventory.GetItemsBySKU(ctx, skus)
+	if err != nil {
+		return nil, fmt.Errorf("could not get referenced items: %w", err)
+	}
+	held := make(map[string]bool, len(items))
+	for _, it := range items {
+		held[it.SKU] = true
+	}
+
+	var missing []MissingItems
+	for _, p := range promotions {
+		m := MissingItems{PromotionID: IDOf(p)}
+		for _, sku := range ReferencesOf(p) {
+			if !held[sku] && !slices.Contains(m.SKUs, sku) {
+				m.SKUs = append(m.SKUs, sku)
+			}
+		}
+		if len(m.SKUs) > 0 {
+			missing = append(missing, m)
+		}
+	}
+	return missing, nil
+}
+
+// candidates returns the promotions that apply now, expanding sets, in the
+// order they are applied, and those outside their schedule.
+func (e *PromotionsEngine) candidates(ctx context.Context, extra []Promotion) ([]Promotion, []Promotion, error) {
+	now := e.now()
+	members, err := expand(ctx, append(slices.Clone(e.promotions), extra...))
+	if err != nil {
+		return nil, nil, err
+	}
+	var candidates, unscheduled []Promotion
+	for _, m := range members {
+		if s, ok := m.(Scheduled); ok && !s.AppliesAt(now) {
+			unscheduled = append(unscheduled, m)
+			continue
+		}
+		candidates = append(candidates, m)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return PolicyOf(candidates[i]).Priority > PolicyOf(candidates[j]).Priority
+	})
+	return candidates, unscheduled, nil
+}
+
+// expand replaces each Set in promotions with its members.
+func expand(ctx context.Context, promotions []Promotion) ([]Promotion, error) {
+	var expanded []Promotion
+	for _, promotion := range promotions {
+		set, ok := promotion.(Set)
+		if !ok {
+			expanded = append(expanded, promotion)
+			continue
+		}
+		members, err := set.Members(ctx)
 		if err != nil {
 			return nil, err
 		}
-		result.Deduction += p.Deduction
-		result.AddedItems = append(result.AddedItems, p.AddedItems...)
+		expanded = append(expanded, members...)
 	}
+	return expanded, nil
+}
 
-	return result, nil
+// Set is implemented by a promotion made of others, such as StoreRules. The
+// engine applies its members individually so each competes on its own policy.
+type Set interface {
+	Members(ctx context.Context) ([]Promotion, error)
+}
+
+// Scheduled is implemented by promotions that only apply at some times.
+type Scheduled interface {
+	AppliesAt(t time.Time) bool
+}
+
+// Identified is implemented by promotions with an ID, as reported in their
+// adjustments.
+type Identified interface {
+	PromotionID() string
+}
+
+// IDOf returns p's ID, or its type if it has none.
+func IDOf(p Promotion) string {
+	if id, ok := p.(Identified); ok && id.PromotionID() != "" {
+		return id.PromotionID()
+	}
+	return fmt.Sprintf("%T", p)
+}
+
+// CustomerConditioned is implemented by promotions limited to some customers.
+type CustomerConditioned interface {
+	CustomerCondition() model.CustomerCondition
+}
+
+// CustomerConditionOf returns p's customer condition, the zero (everyone) if
+// it has none.
+func CustomerConditionOf(p Promotion) model.CustomerCondition {
+	if c, ok := p.(CustomerConditioned); ok {
+		return c.CustomerCondition()
+	}
+	return model.CustomerCondition{}
+}
+
+// describe says whom c admits.
+func describe(c model.CustomerCondition) string {
+	switch {
+	case c.FirstOrder:
+		return "first order only"
+	case c.MinOrders > 0:
+		return fmt.Sprintf("needs %d previous orders", c.MinOrders)
+	default:
+		return "everyone"
+	}
+}
+
+// Referencing is implemented by promotions that name catalog items, by SKU.
+type Referencing interface {
+	References() []string
+}
+
+// ReferencesOf returns the SKUs p names, if any.
+func ReferencesOf(p Promotion) []string {
+	if r, ok := p.(Referencing); ok {
+		return r.References()
+	}
+	return nil
+}
+
+// Policy controls how a promotion combines with others.
+type Policy struct {
+	// Priority orders promotions: higher applies first and gets first claim
+	// on the basket's units.
+	Priority int
+	// Group names an exclusivity group: at most one promotion in it applies,
+	// the first that gives anything.
+	Group string
+	// Stackable promotions apply to every unit, including those consumed by
+	// other promotions, and consume none themselves.
+	Stackable bool
+}
+
+// Prioritized is implemented by promotions with a non-default Policy.
+type Prioritized interface {
+	Policy() Policy
+}
+
+// PolicyOf returns p's Policy, the zero Policy if it has none.
+func PolicyOf(p Promotion) Policy {
+	if pp, ok := p.(Prioritized); ok {
+		return pp.Policy()
+	}
+	return Policy{}
+}
+
+// WithSchedule restricts p to the times s applies. The zero Schedule leaves p
+// unrestricted.
+func WithSchedule(p Promotion, s model.Schedule) Promotion {
+	if s == (model.Schedule{}) {
+		return p
+	}
+	return &decorated{Promotion: p, schedule: &s}
+}
+
+// WithPolicy sets how p combines with other promotions. The zero Policy
+// leaves p as it is.
+func WithPolicy(p Promotion, policy Policy) Promotion {
+	if policy == (Policy{}) {
+		return p
+	}
+	return &decorated{Promotion: p, policy: &policy}
+}
+
+// ForCustomers limits p to customers c matches. The zero CustomerCondition
+// leaves p unrestricted.
+func ForCustomers(p Promotion, c model.CustomerCondition) Promotion {
+	if c == (model.CustomerCondition{}) {
+		return p
+	}
+	return &decorated{Promotion: p, customers: &c}
+}
+
+// decorated adds a schedule, policy or customer condition to a promotion,
+// deferring to the promotion's own for whichever it does not set.
+type decorated struct {
+	Promotion
+	schedule  *model.Schedule
+	policy    *Policy
+	customers *model.CustomerCondition
+}
+
+func (d *decorated) Apply(ctx context.Context, basket *Basket) (*model.Promotions, error) {
+	if d.customers != nil {
+		history, err := basket.History(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !d.customers.Matches(history) {
+			return &model.Promotions{}, nil
+		}
+	}
+	return d.Promotion.Apply(ctx, basket)
+}
+
+func (d *decorated) AppliesAt(t time.Time) bool {
+	if d.schedule != nil && !d.schedule.AppliesAt(t) {
+		return false
+	}
+	if s, ok := d.Promotion.(Scheduled); ok {
+		return s.AppliesAt(t)
+	}
+	return true
+}
+
+func (d *decorated) Policy() Policy {
+	if d.policy != nil {
+		return *d.policy
+	}
+	return PolicyOf(d.Promotion)
+}
+
+func (d *decorated) PromotionID() string {
+	return IDOf(d.Promotion)
+}
+
+func (d *decorated) References() []string {
+	return ReferencesOf(d.Promotion)
+}
+
+func (d *decorated) CustomerCondition() model.CustomerCondition {
+	if d.customers != nil {
+		return *d.customers
+	}
+	return CustomerConditionOf(d.Promotion)
 }
@@ -0,0 +1,136 @@
+//go:build !integration
+
+package promotions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// Overlapping deals never discount the same unit twice unless one stacks.
+func TestOverlappingPromotions(t *testing.T) {
+	ctx := context.Background()
+	threeForTwo := &BuyNGetMFree{SKU: "120P90", Buy: 2, Free: 1}
+	tenOff := &PercentOffOverQuantity{SKU: "120P90", MinQuantity: 3, PercentOff: decimal.NewFromInt(10)}
+
+	for _, tc := range []struct {
+		name       string
+		promotions []Promotion
+		units      int
+		deduction  float64
+	}{
+		// 3 for 2 consumes all three units, leaving nothing for 10% off.
+		{"consumed", []Promotion{threeForTwo, tenOff}, 3, 10},
+		// Registration order breaks ties.
+		{"registration-order", []Promotion{tenOff, threeForTwo}, 3, 3},
+		{"priority", []Promotion{threeForTwo, WithPolicy(tenOff, Policy{Priority: 1})}, 3, 3},
+		// The seventh unit is left over after two 3 for 2 groups, but 10%
+		// off needs three.
+		{"leftover", []Promotion{threeForTwo, tenOff}, 7, 20},
+		{"stackable", []Promotion{threeForTwo, WithPolicy(tenOff, Policy{Stackable: true})}, 3, 13},
+		{"group", []Promotion{
+			WithPolicy(threeForTwo, Policy{Group: "120P90", Stackable: true}),
+			WithPolicy(tenOff, Policy{Group: "120P90", Stackable: true}),
+		}, 3, 10},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewPromotionsEngine(tc.promotions...).ApplyPromotions(ctx, NewBasket(units("120P90", 10, tc.units)))
+			require.NoError(t, err)
+			require.InDelta(t, tc.deduction, got.Deduction, 1e-9)
+		})
+	}
+
+	t.Run("extra", func(t *testing.T) {
+		// A coupon's promotion competes under the same rules.
+		coupon := WithPolicy(&PercentOffOverQuantity{SKU: "120P90", MinQuantity: 1, PercentOff: decimal.NewFromInt(50)}, Policy{Priority: 1})
+		got, err := NewPromotionsEngine(threeForTwo).ApplyPromotions(ctx, NewBasket(units("120P90", 10, 3)), coupon)
+		require.NoError(t, err)
+		require.Equal(t, 15.0, got.Deduction)
+	})
+
+	t.Run("set", func(t *testing.T) {
+		// Members of a set compete individually, on their own policies.
+		set := set{threeForTwo, WithPolicy(tenOff, Policy{Priority: 1})}
+		got, err := NewPromotionsEngine(set).ApplyPromotions(ctx, NewBasket(units("120P90", 10, 3)))
+		require.NoError(t, err)
+		require.InDelta(t, 3.0, got.Deduction, 1e-9)
+	})
+}
+
+// The built-in deals count each unit once, however the basket repeats items.
+func TestBuiltinPromotionsOnce(t *testing.T) {
+	ctx := context.Background()
+	tv := &model.Item{Name: "Google TV", SKU: "120P90", Price: decimal.NewFromFloat(49.99)}
+	speaker := &model.Item{Name: "Alexa Speaker", SKU: "A304SD", Price: decimal.NewFromFloat(109.50)}
+	e := NewPromotionsEngine(&GoogleTVPromotion{}, &AlexaSpeakerPromotion{})
+
+	got, err := e.ApplyPromotions(ctx, NewBasket([]*model.Item{tv, tv, tv, tv, tv, tv, tv}))
+	require.NoError(t, err)
+	require.Equal(t, 99.98, got.Deduction)
+
+	got, err = e.ApplyPromotions(ctx, NewBasket([]*model.Item{speaker, speaker, speaker, speaker}))
+	require.NoError(t, err)
+	require.Equal(t, 43.8, got.Deduction)
+}
+
+type set []Promotion
+
+func (s set) Members(context.Context) ([]Promotion, error) { return s, nil }
+
+func (s set) Apply(ctx context.Context, basket *Basket) (*model.Promotions, error) {
+	return NewPromotionsEngine(s...).ApplyPromotions(ctx, basket)
+}
+
+// Each promotion reports what it took off which lines.
+func TestAdjustments(t *testing.T) {
+	ctx := context.Background()
+	e := NewPromotionsEngine(
+		&BuyNGetMFree{Label: Label{ID: "promotion:1"}, SKU: "120P90", Buy: 2, Free: 1},
+		&PercentOffOverQuantity{Label: Label{ID: "promotion:2", Reason: "Accessories sale"}, SKU: "A304SD", MinQuantity: 1, PercentOff: decimal.NewFromInt(10)},
+	)
+	got, err := e.ApplyPromotions(ctx, NewBasket(append(units("120P90", 10, 4), units("A304SD", 5, 1)...)))
+	require.NoError(t, err)
+	require.Equal(t, []model.Adjustment{
+		{PromotionID: "promotion:1", Reason: "Buy 2 get 1 free on 120P90", Amount: 10, Lines: []model.AdjustmentLine{{SKU: "120P90", Quantity: 3, Amount: 10}}, Units: 1},
+		{PromotionID: "promotion:2", Reason: "Accessories sale", Amount: 0.5, Lines: []model.AdjustmentLine{{SKU: "A304SD", Quantity: 1, Amount: 0.5}}, Units: 1},
+	}, got.Adjustments)
+}
+
+// Explain says why each promotion did or did not apply.
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+	e := NewPromotionsEngine(
+		&BuyNGetMFree{Label: Label{ID: "3for2"}, SKU: "120P90", Buy: 2, Free: 1},
+		&PercentOffOverQuantity{Label: Label{ID: "10off"}, SKU: "120P90", MinQuantity: 3, PercentOff: decimal.NewFromInt(10)},
+		&PercentOffOverQuantity{Label: Label{ID: "speaker"}, SKU: "A304SD", MinQuantity: 1, PercentOff: decimal.NewFromInt(10)},
+		ForCustomers(&PercentOffOverQuantity{Label: Label{ID: "welcome"}, SKU: "120P90", MinQuantity: 1, PercentOff: decimal.NewFromInt(5)}, model.CustomerCondition{FirstOrder: true}),
+		WithSchedule(&BuyNGetMFree{Label: Label{ID: "weekend"}, SKU: "120P90", Buy: 1, Free: 1}, model.Schedule{Days: "sat"}),
+	)
+	e.SetClock(func() time.Time { return time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC) }) // a Monday
+
+	got, decisions, err := e.Explain(ctx, NewBasket(units("120P90", 10, 3)))
+	require.NoError(t, err)
+	require.Equal(t, 10.0, got.Deduction)
+	require.Len(t, decisions, 5)
+	require.Equal(t, "3for2", decisions[0].PromotionID)
+	require.Equal(t, 10.0, decisions[0].Result.Deduction)
+	for i, reason := range []string{
+		"the units it needs went to higher-priority promotions",
+		"its conditions are not met by this basket",
+		"customer does not qualify (first order only)",
+		"outside its schedule at 2024-05-06T12:00:00Z",
+	} {
+		require.Nil(t, decisions[i+1].Result)
+		require.Equal(t, reason, decisions[i+1].Reason, decisions[i+1].PromotionID)
+	}
+
+	// Explaining prices the basket as ApplyPromotions does.
+	applied, err := e.ApplyPromotions(ctx, NewBasket(units("120P90", 10, 3)))
+	require.NoError(t, err)
+	require.Equal(t, applied, got)
+}
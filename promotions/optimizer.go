@@ -0,0 +1,400 @@
+package promotions
+
+import (
+	"context"
+	"slices"
+
+	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
+)
+
+// Default Optimizer limits.
+const (
+	DefaultMaxExactPromotions = 8
+	DefaultMaxSteps           = 10000
+)
+
+// Optimizer finds the allocation of basket units to competing promotions
+// that saves the customer the most.
+//
+// Promotions whose units do not overlap (and that share no exclusivity group)
+// do not compete and are applied once each. Each group of competing
+// promotions is searched separately: every order in which they could claim
+// units is tried, pruned by an optimistic bound, for groups of up to
+// MaxExactPromotions. Larger groups, or a search that runs out of MaxSteps,
+// keep the best of the priority-order and greedy allocations and whatever the
+// search found so far, so the result is never worse than either.
+//
+// Savings are compared by deduction first, then by the value of added items.
+// Equal allocations are resolved in favour of the priority order.
+type Optimizer struct {
+	// MaxExactPromotions is the largest group of competing promotions
+	// searched exhaustively.
+	MaxExactPromotions int
+	// MaxSteps bounds the steps (one promotion claiming units) one group's
+	// search may try.
+	MaxSteps int
+}
+
+// NewOptimizer returns an Optimizer with the default limits.
+func NewOptimizer() *Optimizer {
+	return &Optimizer{MaxExactPromotions: DefaultMaxExactPromotions, MaxSteps: DefaultMaxSteps}
+}
+
+// Allocation is the promotions applied to a basket, in the order they claimed
+// its units, with what each gave.
+type Allocation []Applied
+
+// Applied is one promotion of an Allocation.
+type Applied struct {
+	Promotion Promotion
+	Result    *model.Promotions
+	// index is the promotion's position among those allocated.
+	index int
+}
+
+// Promotions merges the allocation's results.
+func (a Allocation) Promotions() *model.Promotions {
+	result := &model.Promotions{}
+	deduction := decimal.Zero
+	for _, applied := range a {
+		deduction = deduction.Add(decimal.NewFromFloat(applied.Result.Deduction))
+		result.AddedItems = append(result.AddedItems, applied.Result.AddedItems...)
+		result.Consumed = append(result.Consumed, applied.Result.Consumed...)
+		result.Adjustments = append(result.Adjustments, applied.Result.Adjustments...)
+	}
+	result.Deduction = deduction.InexactFloat64()
+	return result
+}
+
+// Optimize returns the best allocation of the basket's units to promotions,
+// which are in priority order.
+func (o *Optimizer) Optimize(ctx context.Context, promotions []Promotion, basket *Basket) (Allocation, error) {
+	ev := newEvaluator(ctx, promotions, basket)
+
+	// Each promotion's result on the whole basket finds who competes, and
+	// bounds what it can give on fewer units.
+	full := make([]*model.Promotions, len(promotions))
+	var candidates []int
+	for i := range promotions {
+		p, err := ev.apply(i, ev.units)
+		if err != nil {
+			return nil, err
+		}
+		if !empty(p) {
+			full[i] = p
+			candidates = append(candidates, i)
+		}
+	}
+
+	var allocation Allocation
+	for _, members := range competing(promotions, full, candidates) {
+		if len(members) == 1 {
+			i := members[0]
+			allocation = append(allocation, Applied{Promotion: promotions[i], Result: full[i], index: i})
+			continue
+		}
+		best, err := ev.inOrder(ev.start(), members)
+		if err != nil {
+			return nil, err
+		}
+		greedy, err := ev.greedy(members)
+		if err != nil {
+			return nil, err
+		}
+		if greedy.saving.better(best.saving) {
+			best = greedy
+		}
+		if len(members) <= o.MaxExactPromotions {
+			s := &search{evaluator: ev, members: members, full: full, best: best, budget: ev.steps + o.MaxSteps, seen: make(map[string]bool)}
+			if err := s.run(ev.start()); err != nil {
+				return nil, err
+			}
+			best = s.best
+		}
+		allocation = append(allocation, best.steps...)
+	}
+	return allocation, nil
+}
+
+// competing partitions candidates into groups that compete for units: two
+// promotions compete if both consume units and their whole-basket results
+// overlap, or if they share an exclusivity group.
+func competing(promotions []Promotion, full []*model.Promotions, candidates []int) [][]int {
+	parent := make(map[int]int, len(candidates))
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for _, i := range candidates {
+		parent[i] = i
+	}
+	for x, i := range candidates {
+		for _, j := range candidates[x+1:] {
+			pi, pj := PolicyOf(promotions[i]), PolicyOf(promotions[j])
+			sameGroup := pi.Group != "" && pi.Group == pj.Group
+			overlap := !pi.Stackable && !pj.Stackable && slices.ContainsFunc(full[i].Consumed, func(u *model.Item) bool {
+				return slices.Contains(full[j].Consumed, u)
+			})
+			if sameGroup || overlap {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+	var groups [][]int
+	index := make(map[int]int)
+	for _, i := range candidates {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+// search is a branch-and-bound search over the orders in which a group of
+// competing promotions claims units.
+type search struct {
+	*evaluator
+	members []int
+	full    []*model.Promotions
+	best    state
+	budget  int
+	seen    map[string]bool
+}
+
+func (s *search) run(st state) error {
+	if st.saving.better(s.best.saving) {
+		s.best = st
+	}
+	key := st.key(s.evaluator)
+	if s.seen[key] || s.steps >= s.budget {
+		return nil
+	}
+	s.seen[key] = true
+
+	// Assume a promotion gives no more on fewer units: if even every
+	// remaining promotion's whole-basket result cannot beat the best, stop.
+	bound := st.saving
+	for _, m := range s.members {
+		if st.allowed(s.promotions[m], m) {
+			bound = bound.add(s.full[m])
+		}
+	}
+	if !bound.better(s.best.saving) {
+		return nil
+	}
+
+	for _, m := range s.members {
+		next, ok, err := s.step(st, m)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := s.run(next); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// evaluator applies promotions to a basket's units, remembering each result
+// so no promotion is applied to the same units twice, and counts the steps
+// taken.
+type evaluator struct {
+	ctx        context.Context
+	promotions []Promotion
+	basket     *Basket
+	units      []*model.Item
+	index      map[*model.Item]int
+	memo       map[evaluation]*model.Promotions
+	steps      int
+}
+
+type evaluation struct {
+	promotion int
+	units     string
+}
+
+func newEvaluator(ctx context.Context, promotions []Promotion, basket *Basket) *evaluator {
+	// Copy each unit so every one has its own identity, even when the caller
+	// repeats an *Item for a repeated SKU.
+	units := make([]*model.Item, len(basket.Items))
+	index := make(map[*model.Item]int, len(basket.Items))
+	for i, it := range basket.Items {
+		u := *it
+		units[i] = &u
+		index[&u] = i
+	}
+	return &evaluator{
+		ctx:        ctx,
+		promotions: promotions,
+		basket:     basket,
+		units:      units,
+		index:      index,
+		memo:       make(map[evaluation]*model.Promotions),
+	}
+}
+
+// apply applies promotion i to available units, or to every unit if it is
+// stackable.
+func (ev *evaluator) apply(i int, available []*model.Item) (*model.Promotions, error) {
+	if PolicyOf(ev.promotions[i]).Stackable {
+		available = ev.units
+	}
+	key := evaluation{promotion: i, units: ev.set(available)}
+	if p, ok := ev.memo[key]; ok {
+		return p, nil
+	}
+	p, err := ev.promotions[i].Apply(ev.ctx, ev.basket.with(available))
+	if err != nil {
+		return nil, err
+	}
+	ev.memo[key] = p
+	return p, nil
+}
+
+// set returns a key for a set of units.
+func (ev *evaluator) set(units []*model.Item) string {
+	bits := make([]byte, (len(ev.units)+7)/8)
+	for _, u := range units {
+		i := ev.index[u]
+		bits[i/8] |= 1 << (i % 8)
+	}
+	return string(bits)
+}
+
+// start returns the state before any promotion applies.
+func (ev *evaluator) start() state {
+	return state{available: ev.units, used: make([]bool, len(ev.promotions))}
+}
+
+// step applies promotion i in st, reporting false if it may not apply or
+// gives nothing.
+func (ev *evaluator) step(st state, i int) (state, bool, error) {
+	promotion := ev.promotions[i]
+	if !st.allowed(promotion, i) {
+		return st, false, nil
+	}
+	ev.steps++
+	p, err := ev.apply(i, st.available)
+	if err != nil {
+		return st, false, err
+	}
+	if empty(p) {
+		return st, false, nil
+	}
+	policy := PolicyOf(promotion)
+	next := state{
+		available: st.available,
+		groups:    st.groups,
+		used:      slices.Clone(st.used),
+		steps:     append(slices.Clone(st.steps), Applied{Promotion: promotion, Result: p, index: i}),
+		saving:    st.saving.add(p),
+	}
+	next.used[i] = true
+	if policy.Group != "" {
+		next.groups = append(slices.Clone(st.groups), policy.Group)
+	}
+	if !policy.Stackable {
+		next.available = slices.DeleteFunc(slices.Clone(st.available), func(u *model.Item) bool {
+			return slices.Contains(p.Consumed, u)
+		})
+	}
+	return next, true, nil
+}
+
+// inOrder applies members in order, each if it may.
+func (ev *evaluator) inOrder(st state, members []int) (state, error) {
+	for _, m := range members {
+		next, ok, err := ev.step(st, m)
+		if err != nil {
+			return st, err
+		}
+		if ok {
+			st = next
+		}
+	}
+	return st, nil
+}
+
+// greedy repeatedly applies whichever member saves the most on the units
+// left.
+func (ev *evaluator) greedy(members []int) (state, error) {
+	st := ev.start()
+	for {
+		var best *state
+		for _, m := range members {
+			next, ok, err := ev.step(st, m)
+			if err != nil {
+				return st, err
+			}
+			if ok && (best == nil || next.saving.better(best.saving)) {
+				best = &next
+			}
+		}
+		if best == nil {
+			return st, nil
+		}
+		st = *best
+	}
+}
+
+// state is a partial allocation.
+type state struct {
+	available []*model.Item
+	groups    []string
+	used      []bool
+	steps     Allocation
+	saving    saving
+}
+
+// allowed reports whether promotion i may still apply.
+func (st state) allowed(promotion Promotion, i int) bool {
+	group := PolicyOf(promotion).Group
+	return !st.used[i] && (group == "" || !slices.Contains(st.groups, group))
+}
+
+// key identifies the state's remaining choices.
+func (st state) key(ev *evaluator) string {
+	used := make([]byte, len(st.used))
+	for i, u := range st.used {
+		if u {
+			used[i] = 1
+		}
+	}
+	return string(used) + ev.set(st.available)
+}
+
+// saving is what an allocation saves the customer.
+type saving struct {
+	deduction decimal.Decimal
+	added     decimal.Decimal
+}
+
+func (s saving) add(p *model.Promotions) saving {
+	s.deduction = s.deduction.Add(decimal.NewFromFloat(p.Deduction))
+	for _, it := range p.AddedItems {
+		s.added = s.added.Add(it.Price)
+	}
+	return s
+}
+
+func (s saving) better(o saving) bool {
+	if c := s.deduction.Cmp(o.deduction); c != 0 {
+		return c > 0
+	}
+	return s.added.GreaterThan(o.added)
+}
+
+func empty(p *model.Promotions) bool {
+	return p.Deduction == 0 && len(p.AddedItems) == 0
+}
@@ -0,0 +1,116 @@
+//go:build !integration
+
+package promotions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// bundle takes a fixed amount off one unit of each of its SKUs, once.
+type bundle struct {
+	skus []string
+	off  float64
+}
+
+func (b *bundle) Apply(_ context.Context, basket *Basket) (*model.Promotions, error) {
+	var picked []*model.Item
+	for _, sku := range b.skus {
+		for _, it := range basket.Items {
+			if it.SKU == sku && !containsItem(picked, it) {
+				picked = append(picked, it)
+				break
+			}
+		}
+	}
+	if len(picked) < len(b.skus) {
+		return &model.Promotions{}, nil
+	}
+	return &model.Promotions{Deduction: b.off, Consumed: picked}, nil
+}
+
+func containsItem(items []*model.Item, it *model.Item) bool {
+	for _, i := range items {
+		if i == it {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOptimizer(t *testing.T) {
+	ctx := context.Background()
+	basket := append(units("A", 10, 2), units("B", 10, 1)...)
+	// Greedy takes the A+B bundle, leaving a lone A: 15. Pairing the As and
+	// taking B alone gives 17.
+	deals := []Promotion{
+		&bundle{skus: []string{"A", "B"}, off: 15},
+		&bundle{skus: []string{"A", "A"}, off: 12},
+		&bundle{skus: []string{"B"}, off: 5},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		optimizer *Optimizer
+		deduction float64
+	}{
+		{"exact", NewOptimizer(), 17},
+		{"greedy-fallback", &Optimizer{MaxExactPromotions: 2, MaxSteps: DefaultMaxSteps}, 15},
+		// Out of budget, the search keeps the best allocation found so far.
+		{"bounded", &Optimizer{MaxExactPromotions: DefaultMaxExactPromotions, MaxSteps: 1}, 15},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			allocation, err := tc.optimizer.Optimize(ctx, deals, NewBasket(basket))
+			require.NoError(t, err)
+			require.Equal(t, tc.deduction, allocation.Promotions().Deduction)
+		})
+	}
+
+	t.Run("engine", func(t *testing.T) {
+		// Priority order gives 3 for 2 the units; the optimizer gives them to
+		// 50% off instead.
+		threeForTwo := &BuyNGetMFree{SKU: "120P90", Buy: 2, Free: 1}
+		half := &PercentOffOverQuantity{SKU: "120P90", MinQuantity: 3, PercentOff: decimal.NewFromInt(50)}
+		e := NewPromotionsEngine(threeForTwo, half)
+		got, err := e.ApplyPromotions(ctx, NewBasket(units("120P90", 10, 3)))
+		require.NoError(t, err)
+		require.Equal(t, 10.0, got.Deduction)
+
+		e.SetOptimizer(NewOptimizer())
+		got, err = e.ApplyPromotions(ctx, NewBasket(units("120P90", 10, 3)))
+		require.NoError(t, err)
+		require.Equal(t, 15.0, got.Deduction)
+	})
+
+	t.Run("ties-keep-priority", func(t *testing.T) {
+		first := &bundle{skus: []string{"A"}, off: 5}
+		second := &bundle{skus: []string{"A"}, off: 5}
+		allocation, err := NewOptimizer().Optimize(ctx, []Promotion{first, second}, NewBasket(units("A", 10, 1)))
+		require.NoError(t, err)
+		require.Len(t, allocation, 1)
+		require.Same(t, first, allocation[0].Promotion)
+	})
+
+	t.Run("independent", func(t *testing.T) {
+		// Promotions that do not compete are applied once, on the whole basket.
+		ctrl := gomock.NewController(t)
+		db := mock.NewMockDatabase(ctrl)
+		pi := &model.Item{Name: "Raspberry Pi B", SKU: "234234", Price: decimal.NewFromFloat(30.0), InventoryQuantity: 2}
+		db.EXPECT().GetItemBySKU(ctx, "234234").Return(pi, nil).Times(1)
+		mac := &model.Item{Name: "MacBook Pro", SKU: "43N23P", Price: decimal.NewFromFloat(5399.99)}
+		tv := &model.Item{Name: "Google TV", SKU: "120P90", Price: decimal.NewFromFloat(49.99)}
+		allocation, err := NewOptimizer().Optimize(ctx,
+			[]Promotion{NewMacBookProPromotion(db), &GoogleTVPromotion{}, &bundle{skus: []string{"120P90"}, off: 1}},
+			NewBasket([]*model.Item{mac, tv, tv, tv}))
+		require.NoError(t, err)
+		got := allocation.Promotions()
+		require.Equal(t, 49.99, got.Deduction)
+		require.Equal(t, []*model.Item{pi}, got.AddedItems)
+	})
+}
@@ -10,7 +10,21 @@ import (
 
 // Promotion defines the interface for a promotion strategy.
 type Promotion interface {
-	Apply(ctx context.Context, items []*model.Item) (*model.Promotions, error)
+	Apply(ctx context.Context, basket *Basket) (*model.Promotions, error)
+}
+
+// SKUs of the catalog items the built-in promotions are keyed on. Promotions
+// match items by SKU, never by name, so renaming an item cannot break a deal.
+const (
+	MacBookProSKU   = "43N23P"
+	RaspberryPiBSKU = "234234"
+	GoogleTVSKU     = "120P90"
+	AlexaSpeakerSKU = "A304SD"
+)
+
+// Builtin returns the built-in promotions, looking gifts up in inventory.
+func Builtin(inventory database.InventoryStore) []Promotion {
+	return []Promotion{NewMacBookProPromotion(inventory), &GoogleTVPromotion{}, &AlexaSpeakerPromotion{}}
 }
 
 // MacBookProPromotion adds a free Raspberry Pi B for each MacBook Pro.
@@ -22,12 +36,12 @@ func NewMacBookProPromotion(db database.InventoryStore) *MacBookProPromotion {
 	return &MacBookProPromotion{db: db}
 }
 
-func (p *MacBookProPromotion) Apply(ctx context.Context, items []*model.Item) (*model.Promotions, error) {
+func (p *MacBookProPromotion) Apply(ctx context.Context, basket *Basket) (*model.Promotions, error) {
 	promotions := &model.Promotions{}
-	itemCounts := countItemsByName(items)
+	macs := itemsBySKU(basket.Items)[MacBookProSKU]
 
-	if n, ok := itemCounts["MacBook Pro"]; ok {
-		it, err := p.db.GetItemByName(ctx, "Raspberry Pi B")
+	if n := len(macs); n > 0 {
+		it, err := p.db.GetItemBySKU(ctx, RaspberryPiBSKU)
 		if err != nil {
 			return nil, err
 		}
@@ -37,50 +51,98 @@ func (p *MacBookProPromotion) Apply(ctx context.Context, items []*model.Item) (*
 		for range n {
 			promotions.AddedItems = append(promotions.AddedItems, it)
 		}
+		promotions.Consumed = macs
+		adjust(promotions, p.PromotionID(), "Free Raspberry Pi B with every MacBook Pro", macs, 0)
 	}
 
 	return promotions, nil
 }
 
+func (p *MacBookProPromotion) PromotionID() string { return "builtin:macbook-pro" }
+
+func (p *MacBookProPromotion) References() []string {
+	return []string{MacBookProSKU, RaspberryPiBSKU}
+}
+
 // GoogleTVPromotion applies a "Buy 3 for the price of 2" discount.
 type GoogleTVPromotion struct{}
 
-func (p *GoogleTVPromotion) Apply(ctx context.Context, items []*model.Item) (*model.Promotions, error) {
+func (p *GoogleTVPromotion) Apply(ctx context.Context, basket *Basket) (*model.Promotions, error) {
 	promotions := &model.Promotions{}
-	itemCounts := countItemsByName(items)
+	tvs := itemsBySKU(basket.Items)[GoogleTVSKU]
 
-	for _, item := range items {
-		if item.Name == "Google TV" && itemCounts["Google TV"] >= 3 {
-			discount := item.Price.Mul(decimal.NewFromInt(int64(itemCounts["Google TV"] / 3)))
-			promotions.Deduction += discount.InexactFloat64()
-		}
+	if groups := len(tvs) / 3; groups > 0 {
+		promotions.Deduction = tvs[0].Price.Mul(decimal.NewFromInt(int64(groups))).InexactFloat64()
+		promotions.Consumed = tvs[:groups*3]
+		adjust(promotions, p.PromotionID(), "3 Google TVs for the price of 2", promotions.Consumed, groups)
 	}
 
 	return promotions, nil
 }
 
+func (p *GoogleTVPromotion) PromotionID() string { return "builtin:google-tv" }
+
+func (p *GoogleTVPromotion) References() []string { return []string{GoogleTVSKU} }
+
 // AlexaSpeakerPromotion applies a 10% discount if more than 3 are bought.
 type AlexaSpeakerPromotion struct{}
 
-func (p *AlexaSpeakerPromotion) Apply(ctx context.Context, items []*model.Item) (*model.Promotions, error) {
+func (p *AlexaSpeakerPromotion) Apply(ctx context.Context, basket *Basket) (*model.Promotions, error) {
 	promotions := &model.Promotions{}
-	itemCounts := countItemsByName(items)
+	speakers := itemsBySKU(basket.Items)[AlexaSpeakerSKU]
 
-	for _, item := range items {
-		if item.Name == "Alexa Speaker" && itemCounts["Alexa Speaker"] > 3 {
-			discount := item.Price.Mul(decimal.NewFromInt(int64(itemCounts["Alexa Speaker"]))).Mul(decimal.NewFromFloat(0.1))
-			promotions.Deduction += discount.InexactFloat64()
+	if len(speakers) > 3 {
+		total := decimal.Zero
+		for _, it := range speakers {
+			total = total.Add(it.Price)
 		}
+		promotions.Deduction = total.Mul(decimal.NewFromFloat(0.1)).InexactFloat64()
+		promotions.Consumed = speakers
+		adjust(promotions, p.PromotionID(), "10% off more than 3 Alexa Speakers", speakers, len(speakers))
 	}
 
 	return promotions, nil
 }
 
-// countItemsByName counts the occurrences of each item by name.
-func countItemsByName(items []*model.Item) map[string]int {
-	itemCounts := make(map[string]int)
-	for _, item := range items {
-		itemCounts[item.Name]++
+func (p *AlexaSpeakerPromotion) PromotionID() string { return "builtin:alexa-speaker" }
+
+func (p *AlexaSpeakerPromotion) References() []string { return []string{AlexaSpeakerSKU} }
+
+// adjust records on promotions the Adjustment made by the promotion id:
+// Deduction taken off units, shared between them by price, and AddedItems.
+// discounted is how many of units the promotion made free or cheaper, which
+// with the added items are the units it granted. Call it once the rest of the
+// result is set.
+func adjust(promotions *model.Promotions, id, reason string, units []*model.Item, discounted int) {
+	a := model.Adjustment{PromotionID: id, Reason: reason, Amount: promotions.Deduction,
+		Units: discounted + len(promotions.AddedItems)}
+	for _, it := range promotions.AddedItems {
+		a.AddedSKUs = append(a.AddedSKUs, it.SKU)
+	}
+
+	gross := decimal.Zero
+	bySKU := make(map[string]decimal.Decimal)
+	var skus []string
+	quantity := make(map[string]int)
+	for _, it := range units {
+		if _, ok := bySKU[it.SKU]; !ok {
+			skus = append(skus, it.SKU)
+		}
+		bySKU[it.SKU] = bySKU[it.SKU].Add(it.Price)
+		quantity[it.SKU]++
+		gross = gross.Add(it.Price)
+	}
+	// Round each share to the cent, leaving the remainder to the last line so
+	// the shares sum to Amount.
+	amount := decimal.NewFromFloat(promotions.Deduction)
+	left := amount
+	for i, sku := range skus {
+		share := left
+		if i < len(skus)-1 && gross.IsPositive() {
+			share = amount.Mul(bySKU[sku]).Div(gross).Round(2)
+		}
+		left = left.Sub(share)
+		a.Lines = append(a.Lines, model.AdjustmentLine{SKU: sku, Quantity: quantity[sku], Amount: share.InexactFloat64()})
 	}
-	return itemCounts
+	promotions.Adjustments = append(promotions.Adjustments, a)
 }
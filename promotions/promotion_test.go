@@ -5,6 +5,7 @@ package promotions
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/ATMackay/checkout/database/mock"
 	"github.com/ATMackay/checkout/model"
@@ -26,15 +27,88 @@ func TestPromotions(t *testing.T) {
 	e := NewPromotionsEngine(NewMacBookProPromotion(db), &GoogleTVPromotion{}, &AlexaSpeakerPromotion{})
 	t.Run("macbook-pro", func(t *testing.T) {
 		it := &model.Item{Name: "Raspberry Pi B", SKU: "234234", Price: decimal.NewFromFloat(30.0), InventoryQuantity: 2}
-		db.EXPECT().GetItemByName(context.Background(), "Raspberry Pi B").Return(it, nil)
+		db.EXPECT().GetItemBySKU(context.Background(), "234234").Return(it, nil)
 		items := []*model.Item{
-			{Name: "MacBook Pro", SKU: "MacBookPro", Price: decimal.NewFromFloat(5399.99)},
-			{Name: "Google TV", SKU: "GoogleTV", Price: decimal.NewFromFloat(49.99)},
-			{Name: "Alexa Speaker", SKU: "AlexaSpeaker", Price: decimal.NewFromFloat(109.50)},
+			{Name: "MacBook Pro", SKU: "43N23P", Price: decimal.NewFromFloat(5399.99)},
+			{Name: "Google TV", SKU: "120P90", Price: decimal.NewFromFloat(49.99)},
+			{Name: "Alexa Speaker", SKU: "A304SD", Price: decimal.NewFromFloat(109.50)},
 		}
-		promotions, err := e.ApplyPromotions(context.Background(), items)
+		promotions, err := e.ApplyPromotions(context.Background(), NewBasket(items))
 		require.NoError(t, err)
 		require.NotNil(t, promotions)
 		require.Equal(t, []*model.Item{it}, promotions.AddedItems)
 	})
+	t.Run("renamed", func(t *testing.T) {
+		// Deals are keyed on SKU, so a renamed item still qualifies.
+		tvs := units("120P90", 49.99, 3)
+		for _, tv := range tvs {
+			tv.Name = "Google TV (2nd gen)"
+		}
+		promotions, err := e.ApplyPromotions(context.Background(), NewBasket(tvs))
+		require.NoError(t, err)
+		require.Equal(t, 49.99, promotions.Deduction)
+	})
+}
+
+// Promotions naming SKUs the catalog lacks are reported, with set members
+// checked individually.
+func TestCheckReferences(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	ctx := context.Background()
+
+	gift := &model.PromotionRule{ID: 3, Name: "gift", Type: model.PromotionFreeGift, Active: true, SKU: "120P90", GiftSKU: "HDMI01"}
+	db.EXPECT().ListPromotions(ctx, true).Return([]*model.PromotionRule{gift}, nil)
+	db.EXPECT().GetItemsBySKU(ctx, []string{"43N23P", "234234", "120P90", "HDMI01"}).Return([]*model.Item{
+		{SKU: "43N23P"}, {SKU: "120P90"},
+	}, nil)
+
+	e := NewPromotionsEngine(NewMacBookProPromotion(db), &GoogleTVPromotion{}, NewStoreRules(db, db))
+	missing, err := e.CheckReferences(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []MissingItems{
+		{PromotionID: "builtin:macbook-pro", SKUs: []string{"234234"}},
+		{PromotionID: "promotion:3", SKUs: []string{"HDMI01"}},
+	}, missing)
+}
+
+// Scheduled promotions apply only within their date range and daily window,
+// read against the engine's clock.
+func TestScheduledPromotions(t *testing.T) {
+	ctx := context.Background()
+	at := func(s string) time.Time {
+		tm, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return tm
+	}
+	start, end := at("2026-11-27T00:00:00Z"), at("2026-12-01T00:00:00Z")
+	happyHour := WithSchedule(&BuyNGetMFree{SKU: "120P90", Buy: 1, Free: 1}, model.Schedule{
+		StartsAt: &start, EndsAt: &end,
+		Days: "fri,sat", From: "22:00", To: "02:00", TimeZone: "America/New_York",
+	})
+	e := NewPromotionsEngine(happyHour)
+
+	for _, tc := range []struct {
+		now     string
+		applies bool
+	}{
+		{"2026-11-20T04:00:00Z", false}, // before starts_at
+		{"2026-11-28T04:00:00Z", true},  // Fri 23:00 New York
+		{"2026-11-28T06:30:00Z", true},  // Sat 01:30 New York, in Friday's window
+		{"2026-11-27T06:30:00Z", false}, // Fri 01:30 New York, in Thursday's window
+		{"2026-11-29T06:30:00Z", true},  // Sun 01:30 New York, in Saturday's window
+		{"2026-11-29T04:30:00Z", true},  // Sat 23:30 New York
+		{"2026-11-28T20:00:00Z", false}, // Sat 15:00 New York
+		{"2026-11-30T04:00:00Z", false}, // Sun 23:00 New York
+		{"2026-12-05T04:00:00Z", false}, // Fri, after ends_at
+	} {
+		e.SetClock(func() time.Time { return at(tc.now) })
+		got, err := e.ApplyPromotions(ctx, NewBasket(units("120P90", 10, 2)))
+		require.NoError(t, err)
+		require.Equal(t, tc.applies, got.Deduction == 10, tc.now)
+	}
+
+	require.Equal(t, &BuyNGetMFree{SKU: "120P90", Buy: 1, Free: 1}, WithSchedule(&BuyNGetMFree{SKU: "120P90", Buy: 1, Free: 1}, model.Schedule{}))
+	require.Equal(t, model.ScheduleUpcoming, (&model.Schedule{StartsAt: &start}).Status(at("2026-11-26T00:00:00Z")))
+	require.Equal(t, model.ScheduleExpired, (&model.Schedule{EndsAt: &end}).Status(end))
 }
@@ -0,0 +1,401 @@
+package promotions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// A rule file declares promotions as a condition and an action:
+//
+//	promotions:
+//	  - name: 3 Google TVs for the price of 2
+//	    when: { sku: 120P90, min_quantity: 3 }
+//	    then: { cheapest_free: 1, repeat: true }
+//	  - name: 10% off more than 3 Alexa Speakers
+//	    when: { sku: A304SD, min_quantity: 4 }
+//	    then: { percent_off: 10 }
+//	  - name: $5 off accessories over $50
+//	    when: { category: accessories, min_total: 50 }
+//	    then: { fixed_off: 5 }
+//
+// The condition selects the units a rule looks at — those with the given sku
+// and/or category, or the whole basket if neither is set — and fires when there
+// are at least min_quantity of them and the basket totals at least min_total.
+// first_order or min_orders further limit it to a customer's first order, or
+// to customers who have placed at least that many orders (see
+// model.CustomerCondition). The action then takes exactly one of:
+//
+//	percent_off    percent off every selected unit
+//	fixed_off      an amount off, at most the selected units' total
+//	cheapest_free  that many of the cheapest selected units free
+//	free_item      one unit of the given SKU added free
+//
+// With repeat, fixed_off, cheapest_free and free_item apply once per complete
+// group of min_quantity selected units rather than once per basket.
+//
+// Optional priority, group and stackable keys set how a rule combines with
+// other promotions (see Policy).
+//
+// An optional schedule limits when a rule applies (see model.Schedule):
+//
+//	schedule: { starts_at: 2026-11-27T00:00:00Z, ends_at: 2026-12-01T00:00:00Z,
+//	            days: "sat,sun", from: "09:00", to: "12:00", time_zone: Europe/London }
+type ruleFile struct {
+	Promotions []yaml.Node `yaml:"promotions"`
+}
+
+// RuleSpec is one promotion in a rule file.
+type RuleSpec struct {
+	Name     string         `yaml:"name"`
+	When     Condition      `yaml:"when"`
+	Then     Action         `yaml:"then"`
+	Schedule model.Schedule `yaml:"schedule"`
+	// Stacking is read from the rule's priority, group and stackable keys.
+	Stacking Policy `yaml:"-"`
+}
+
+// Condition decides which units a rule selects and whether it fires.
+type Condition struct {
+	SKU         string          `yaml:"sku"`
+	Category    string          `yaml:"category"`
+	MinQuantity int             `yaml:"min_quantity"`
+	MinTotal    decimal.Decimal `yaml:"min_total"`
+	// Customer limits the rule to customers with some order history, read
+	// from the first_order and min_orders keys.
+	Customer model.CustomerCondition `yaml:"-"`
+}
+
+// Action is what a rule does when it fires. Exactly one of PercentOff,
+// FixedOff, CheapestFree and FreeItem is set.
+type Action struct {
+	PercentOff   decimal.Decimal `yaml:"percent_off"`
+	FixedOff     decimal.Decimal `yaml:"fixed_off"`
+	CheapestFree int             `yaml:"cheapest_free"`
+	FreeItem     string          `yaml:"free_item"`
+	Repeat       bool            `yaml:"repeat"`
+}
+
+// RuleError is a rule file problem at a position in the file.
+type RuleError struct {
+	File         string
+	Line, Column int
+	Msg          string
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+}
+
+// LoadRuleFile reads and validates a rule file (see RuleSpec). Gifts named by
+// free_item are looked up in inventory when a rule fires.
+func LoadRuleFile(path string, inventory database.InventoryStore) ([]*RulePromotion, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read promotions file: %w", err)
+	}
+	return ParseRules(path, b, inventory)
+}
+
+// ParseRules parses and validates rule file content. name labels error
+// positions. Every invalid rule is reported, not just the first.
+func ParseRules(name string, data []byte, inventory database.InventoryStore) ([]*RulePromotion, error) {
+	var f ruleFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	var (
+		rules []*RulePromotion
+		errs  []error
+	)
+	for i := range f.Promotions {
+		node := &f.Promotions[i]
+		errAt := func(n *yaml.Node, format string, args ...any) {
+			errs = append(errs, &RuleError{File: name, Line: n.Line, Column: n.Column, Msg: fmt.Sprintf(format, args...)})
+		}
+		if node.Kind != yaml.MappingNode {
+			errAt(node, "promotion %d: expected a mapping with name, when and then", i+1)
+			continue
+		}
+		var spec RuleSpec
+		when, then := child(node, "when"), child(node, "then")
+		decodeFields(node, map[string]any{
+			"name":      &spec.Name,
+			"when":      nil,
+			"then":      nil,
+			"schedule":  nil,
+			"priority":  &spec.Stacking.Priority,
+			"group":     &spec.Stacking.Group,
+			"stackable": &spec.Stacking.Stackable,
+		}, errAt)
+		label := fmt.Sprintf("promotion %q", spec.Name)
+		if spec.Name == "" {
+			label = fmt.Sprintf("promotion %d", i+1)
+			errAt(node, "%s: name is required", label)
+		}
+		fieldErrAt := func(n *yaml.Node, format string, args ...any) {
+			errAt(n, "%s: %s", label, fmt.Sprintf(format, args...))
+		}
+		if when != nil {
+			decodeFields(when, map[string]any{
+				"sku":          &spec.When.SKU,
+				"category":     &spec.When.Category,
+				"min_quantity": &spec.When.MinQuantity,
+				"min_total":    &spec.When.MinTotal,
+				"first_order":  &spec.When.Customer.FirstOrder,
+				"min_orders":   &spec.When.Customer.MinOrders,
+			}, fieldErrAt)
+		} else {
+			when = node
+		}
+		if then != nil {
+			decodeFields(then, map[string]any{
+				"percent_off":   &spec.Then.PercentOff,
+				"fixed_off":     &spec.Then.FixedOff,
+				"cheapest_free": &spec.Then.CheapestFree,
+				"free_item":     &spec.Then.FreeItem,
+				"repeat":        &spec.Then.Repeat,
+			}, fieldErrAt)
+		} else {
+			then = node
+		}
+		if schedule := child(node, "schedule"); schedule != nil {
+			decodeFields(schedule, map[string]any{
+				"starts_at": &spec.Schedule.StartsAt,
+				"ends_at":   &spec.Schedule.EndsAt,
+				"days":      &spec.Schedule.Days,
+				"from":      &spec.Schedule.From,
+				"to":        &spec.Schedule.To,
+				"time_zone": &spec.Schedule.TimeZone,
+			}, fieldErrAt)
+			if err := spec.Schedule.Validate(); err != nil {
+				errAt(schedule, "%s: schedule: %v", label, err)
+			}
+		}
+
+		if spec.When.SKU != "" && !model.IsSKU(spec.When.SKU) {
+			errAt(childOr(when, "sku"), "%s: invalid sku '%s'", label, spec.When.SKU)
+		}
+		if spec.When.MinQuantity < 0 {
+			errAt(childOr(when, "min_quantity"), "%s: min_quantity must not be negative", label)
+		}
+		if spec.When.MinTotal.IsNegative() {
+			errAt(childOr(when, "min_total"), "%s: min_total must not be negative", label)
+		}
+		if err := spec.When.Customer.Validate(); err != nil {
+			errAt(when, "%s: %v", label, err)
+		}
+
+		actions := 0
+		if !spec.Then.PercentOff.IsZero() {
+			actions++
+			if !spec.Then.PercentOff.IsPositive() || spec.Then.PercentOff.GreaterThan(decimal.NewFromInt(100)) {
+				errAt(childOr(then, "percent_off"), "%s: percent_off must be in (0, 100]", label)
+			}
+		}
+		if !spec.Then.FixedOff.IsZero() {
+			actions++
+			if spec.Then.FixedOff.IsNegative() {
+				errAt(childOr(then, "fixed_off"), "%s: fixed_off must be positive", label)
+			}
+		}
+		if spec.Then.CheapestFree != 0 {
+			actions++
+			if spec.Then.CheapestFree < 0 || spec.Then.CheapestFree >= max(spec.When.MinQuantity, 1) {
+				errAt(childOr(then, "cheapest_free"), "%s: cheapest_free must be positive and less than min_quantity", label)
+			}
+		}
+		if spec.Then.FreeItem != "" {
+			actions++
+			if !model.IsSKU(spec.Then.FreeItem) {
+				errAt(childOr(then, "free_item"), "%s: invalid free_item sku '%s'", label, spec.Then.FreeItem)
+			}
+		}
+		if actions != 1 {
+			errAt(then, "%s: then must set exactly one of percent_off, fixed_off, cheapest_free, free_item (got %d)", label, actions)
+		}
+		rules = append(rules, &RulePromotion{RuleSpec: spec, db: inventory})
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return rules, nil
+}
+
+// decodeFields decodes each entry of mapping node n into the pointer fields
+// holds for its key, reporting unknown keys and undecodable values at their own
+// position. A nil pointer accepts the key without decoding it.
+func decodeFields(n *yaml.Node, fields map[string]any, errAt func(*yaml.Node, string, ...any)) {
+	if n.Kind != yaml.MappingNode {
+		errAt(n, "expected a mapping")
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		ptr, ok := fields[key.Value]
+		if !ok {
+			errAt(key, "unknown field '%s'", key.Value)
+			continue
+		}
+		if ptr == nil {
+			continue
+		}
+		if err := value.Decode(ptr); err != nil {
+			msg := err.Error()
+			var te *yaml.TypeError
+			if errors.As(err, &te) && len(te.Errors) > 0 {
+				// Drop the "line N:" prefix; the position is reported already.
+				msg = te.Errors[0]
+				if _, rest, ok := strings.Cut(msg, ": "); ok {
+					msg = rest
+				}
+			}
+			errAt(value, "%s: %s", key.Value, msg)
+		}
+	}
+}
+
+// childOr returns the value node under key in mapping node n, or n itself when
+// there is none, so errors point as close to the problem as the file allows.
+func childOr(n *yaml.Node, key string) *yaml.Node {
+	if c := child(n, key); c != nil {
+		return c
+	}
+	return n
+}
+
+// child returns the value node under key in mapping node n, or nil.
+func child(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				return n.Content[i+1]
+			}
+		}
+	}
+	return nil
+}
+
+// RulePromotion applies a RuleSpec.
+type RulePromotion struct {
+	RuleSpec
+	db database.InventoryStore
+}
+
+// Policy returns the rule's priority, group and stackability.
+func (p *RulePromotion) Policy() Policy {
+	return p.Stacking
+}
+
+// PromotionID identifies the rule by its name.
+func (p *RulePromotion) PromotionID() string { return "file:" + p.Name }
+
+// References returns the SKUs the rule names. Categories are not checked.
+func (p *RulePromotion) References() []string {
+	var skus []string
+	for _, sku := range []string{p.When.SKU, p.Then.FreeItem} {
+		if sku != "" {
+			skus = append(skus, sku)
+		}
+	}
+	return skus
+}
+
+// CustomerCondition returns the customers the rule is limited to.
+func (p *RulePromotion) CustomerCondition() model.CustomerCondition {
+	return p.When.Customer
+}
+
+// AppliesAt reports whether the rule's schedule applies at t.
+func (p *RulePromotion) AppliesAt(t time.Time) bool {
+	return p.Schedule.AppliesAt(t)
+}
+
+func (p *RulePromotion) Apply(ctx context.Context, basket *Basket) (*model.Promotions, error) {
+	promotions := &model.Promotions{}
+	if p.When.Customer != (model.CustomerCondition{}) {
+		history, err := basket.History(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !p.When.Customer.Matches(history) {
+			return promotions, nil
+		}
+	}
+
+	total := decimal.Zero
+	var selected []*model.Item
+	selectedTotal := decimal.Zero
+	for _, it := range basket.Items {
+		total = total.Add(it.Price)
+		if (p.When.SKU == "" || it.SKU == p.When.SKU) && (p.When.Category == "" || it.Category == p.When.Category) {
+			selected = append(selected, it)
+			selectedTotal = selectedTotal.Add(it.Price)
+		}
+	}
+	group := max(p.When.MinQuantity, 1)
+	if len(selected) < group || total.LessThan(p.When.MinTotal) {
+		return promotions, nil
+	}
+	times := 1
+	if p.Then.Repeat {
+		times = len(selected) / group
+	}
+
+	// adjusted are the units the deduction is taken off.
+	adjusted := selected
+	switch {
+	case !p.Then.PercentOff.IsZero():
+		promotions.Deduction = selectedTotal.Mul(p.Then.PercentOff).Div(decimal.NewFromInt(100)).Round(2).InexactFloat64()
+		promotions.Consumed = selected
+	case !p.Then.FixedOff.IsZero():
+		off := p.Then.FixedOff.Mul(decimal.NewFromInt(int64(times)))
+		promotions.Deduction = decimal.Min(off, selectedTotal).InexactFloat64()
+		promotions.Consumed = selected
+	case p.Then.CheapestFree > 0:
+		// Group the most expensive units together so each group's cheapest
+		// (free) units are worth as much as possible.
+		sorted := slices.Clone(selected)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Price.GreaterThan(sorted[j].Price) })
+		free := decimal.Zero
+		adjusted = nil
+		for g := range times {
+			grouped := sorted[g*group : (g+1)*group]
+			for _, it := range grouped[group-p.Then.CheapestFree:] {
+				free = free.Add(it.Price)
+				adjusted = append(adjusted, it)
+			}
+		}
+		promotions.Deduction = free.InexactFloat64()
+		promotions.Consumed = sorted[:times*group]
+	case p.Then.FreeItem != "":
+		gift, err := p.db.GetItemBySKU(ctx, p.Then.FreeItem)
+		if err != nil {
+			return nil, err
+		}
+		if gift.InventoryQuantity < times {
+			return promotions, nil
+		}
+		for range times {
+			promotions.AddedItems = append(promotions.AddedItems, gift)
+		}
+		promotions.Consumed = selected[:times*group]
+		adjust(promotions, p.PromotionID(), p.Name, promotions.Consumed, 0)
+		return promotions, nil
+	}
+	adjust(promotions, p.PromotionID(), p.Name, adjusted, len(adjusted))
+	return promotions, nil
+}
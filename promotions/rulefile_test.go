@@ -0,0 +1,182 @@
+//go:build !integration
+
+package promotions
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const exampleRules = `
+promotions:
+  - name: 3 Google TVs for the price of 2
+    when: { sku: 120P90, min_quantity: 3 }
+    then: { cheapest_free: 1, repeat: true }
+  - name: 10% off more than 3 Alexa Speakers
+    when: { sku: A304SD, min_quantity: 4 }
+    then: { percent_off: 10 }
+  - name: $5 off accessories over $50
+    when: { category: accessories, min_total: 50 }
+    then: { fixed_off: 5 }
+  - name: free cable with every 2 TVs
+    when: { sku: 120P90, min_quantity: 2 }
+    then: { free_item: HDMI01, repeat: true }
+`
+
+func TestParseRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	ctx := context.Background()
+
+	rules, err := ParseRules("rules.yaml", []byte(exampleRules), db)
+	require.NoError(t, err)
+	require.Len(t, rules, 4)
+	threeForTwo, percentOff, fixedOff, freeItem := rules[0], rules[1], rules[2], rules[3]
+
+	t.Run("cheapest-free", func(t *testing.T) {
+		got, err := threeForTwo.Apply(ctx, NewBasket(units("120P90", 50, 7)))
+		require.NoError(t, err)
+		require.Equal(t, 100.0, got.Deduction) // two complete groups of 3
+		require.Equal(t, []model.Adjustment{{
+			PromotionID: "file:3 Google TVs for the price of 2", Reason: "3 Google TVs for the price of 2", Amount: 100, Units: 2,
+			Lines: []model.AdjustmentLine{{SKU: "120P90", Quantity: 2, Amount: 100}}, // the free units
+		}}, got.Adjustments)
+		got, err = threeForTwo.Apply(ctx, NewBasket(units("120P90", 50, 2)))
+		require.NoError(t, err)
+		require.Zero(t, got.Deduction)
+	})
+
+	t.Run("percent-off", func(t *testing.T) {
+		got, err := percentOff.Apply(ctx, NewBasket(append(units("A304SD", 109.5, 4), units("120P90", 50, 1)...)))
+		require.NoError(t, err)
+		require.Equal(t, 43.8, got.Deduction)
+	})
+
+	t.Run("fixed-off-by-category", func(t *testing.T) {
+		cable := &model.Item{SKU: "HDMI01", Category: "accessories", Price: decimal.NewFromInt(3)}
+		got, err := fixedOff.Apply(ctx, NewBasket([]*model.Item{cable}))
+		require.NoError(t, err)
+		require.Zero(t, got.Deduction) // basket under min_total
+		got, err = fixedOff.Apply(ctx, NewBasket(append([]*model.Item{cable}, units("120P90", 50, 1)...)))
+		require.NoError(t, err)
+		require.Equal(t, 3.0, got.Deduction) // capped at the accessories' total
+		require.Equal(t, []model.AdjustmentLine{{SKU: "HDMI01", Quantity: 1, Amount: 3}}, got.Adjustments[0].Lines)
+	})
+
+	t.Run("free-item", func(t *testing.T) {
+		gift := &model.Item{SKU: "HDMI01", InventoryQuantity: 1}
+		db.EXPECT().GetItemBySKU(ctx, "HDMI01").Return(gift, nil).Times(2)
+		got, err := freeItem.Apply(ctx, NewBasket(units("120P90", 50, 3)))
+		require.NoError(t, err)
+		require.Equal(t, []*model.Item{gift}, got.AddedItems)
+		got, err = freeItem.Apply(ctx, NewBasket(units("120P90", 50, 4)))
+		require.NoError(t, err)
+		require.Empty(t, got.AddedItems) // not enough stock for two
+	})
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	const bad = `promotions:
+  - name: no action
+    when: { sku: 120P90 }
+    then: {}
+  - name: two actions
+    when: { sku: bad }
+    then: { percent_off: 150, fixed_off: 5 }
+  - when: { sku: 120P90, min_quantity: 2 }
+    then: { cheapest_free: 1, extra: true }
+  - name: not a number
+    when: { min_quantity: lots }
+    then: { percent_off: 5 }
+  - name: bad schedule
+    then: { percent_off: 5 }
+    schedule: { days: "mon,funday" }
+  - name: empty window
+    then: { percent_off: 5 }
+    schedule: { from: "09:00", to: "9:00" }
+`
+	_, err := ParseRules("rules.yaml", []byte(bad), nil)
+	require.Error(t, err)
+
+	var got []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var re *RuleError
+		require.True(t, errors.As(e, &re))
+		got = append(got, re.Error())
+	}
+	require.Equal(t, []string{
+		`rules.yaml:4:11: promotion "no action": then must set exactly one of percent_off, fixed_off, cheapest_free, free_item (got 0)`,
+		`rules.yaml:6:18: promotion "two actions": invalid sku 'bad'`,
+		`rules.yaml:7:26: promotion "two actions": percent_off must be in (0, 100]`,
+		`rules.yaml:7:11: promotion "two actions": then must set exactly one of percent_off, fixed_off, cheapest_free, free_item (got 2)`,
+		`rules.yaml:8:5: promotion 3: name is required`,
+		`rules.yaml:9:31: promotion 3: unknown field 'extra'`,
+		"rules.yaml:11:27: promotion \"not a number\": min_quantity: cannot unmarshal !!str `lots` into int",
+		`rules.yaml:15:15: promotion "bad schedule": schedule: invalid day 'funday': want sun, mon, tue, wed, thu, fri or sat`,
+		`rules.yaml:18:15: promotion "empty window": schedule: from and to must differ`,
+	}, got)
+}
+
+func TestParseRulesSchedule(t *testing.T) {
+	const scheduled = `promotions:
+  - name: weekend sale
+    then: { percent_off: 10 }
+    schedule: { starts_at: 2026-11-27T00:00:00Z, days: "sat,sun" }
+`
+	rules, err := ParseRules("rules.yaml", []byte(scheduled), nil)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.False(t, rules[0].AppliesAt(time.Date(2026, 11, 22, 12, 0, 0, 0, time.UTC))) // Sunday, before starts_at
+	require.False(t, rules[0].AppliesAt(time.Date(2026, 11, 27, 12, 0, 0, 0, time.UTC))) // Friday
+	require.True(t, rules[0].AppliesAt(time.Date(2026, 11, 28, 12, 0, 0, 0, time.UTC)))  // Saturday
+}
+
+func TestParseRulesCustomer(t *testing.T) {
+	const loyalty = `promotions:
+  - name: 10% off your first order
+    when: { first_order: true }
+    then: { percent_off: 10 }
+  - name: both
+    when: { first_order: true, min_orders: 5 }
+    then: { percent_off: 10 }
+`
+	_, err := ParseRules("rules.yaml", []byte(loyalty), nil)
+	require.EqualError(t, err, `rules.yaml:6:11: promotion "both": first_order and min_orders cannot both be set`)
+
+	rules, err := ParseRules("rules.yaml", []byte(strings.SplitAfter(loyalty, "percent_off: 10 }\n")[0]), nil)
+	require.NoError(t, err)
+	ctx := context.Background()
+	customer := func(orders int) *Customer {
+		return NewCustomer("customer", func(context.Context) (*model.OrderSummary, error) {
+			return &model.OrderSummary{Orders: orders}, nil
+		})
+	}
+	got, err := rules[0].Apply(ctx, &Basket{Items: units("120P90", 50, 1), Customer: customer(0)})
+	require.NoError(t, err)
+	require.Equal(t, 5.0, got.Deduction)
+	got, err = rules[0].Apply(ctx, &Basket{Items: units("120P90", 50, 1), Customer: customer(1)})
+	require.NoError(t, err)
+	require.Zero(t, got.Deduction)
+}
+
+func TestLoadRuleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(exampleRules), 0o600))
+	rules, err := LoadRuleFile(path, nil)
+	require.NoError(t, err)
+	require.Len(t, rules, 4)
+
+	_, err = LoadRuleFile(filepath.Join(t.TempDir(), "missing.yaml"), nil)
+	require.Error(t, err)
+}
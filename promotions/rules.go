@@ -0,0 +1,245 @@
+package promotions
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
+)
+
+// FromRule builds the Promotion a stored rule describes. Gift lookups go to
+// inventory.
+func FromRule(rule *model.PromotionRule, inventory database.InventoryStore) (Promotion, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, fmt.Errorf("promotion %d (%s): %w", rule.ID, rule.Name, err)
+	}
+	label := Label{ID: RuleID(rule.ID), Reason: rule.Name}
+	var p Promotion
+	switch rule.Type {
+	case model.PromotionBuyNGetMFree:
+		p = &BuyNGetMFree{Label: label, SKU: rule.SKU, Buy: rule.BuyQuantity, Free: rule.FreeQuantity}
+	case model.PromotionPercentOffOverQuantity:
+		p = &PercentOffOverQuantity{Label: label, SKU: rule.SKU, MinQuantity: rule.MinQuantity, PercentOff: rule.PercentOff}
+	default: // model.PromotionFreeGift; Validate rejects anything else
+		p = &FreeGift{Label: label, SKU: rule.SKU, GiftSKU: rule.GiftSKU, db: inventory}
+	}
+	p = ForCustomers(p, rule.CustomerCondition)
+	p = WithPolicy(p, Policy{Priority: rule.Priority, Group: rule.ExclusiveGroup, Stackable: rule.Stackable})
+	return WithSchedule(p, rule.Schedule), nil
+}
+
+// storedPrefix starts the IDs of promotions built from stored rules.
+const storedPrefix = "promotion:"
+
+// RuleID returns the ID the promotion built from stored rule id reports.
+func RuleID(id int) string {
+	return storedPrefix + strconv.Itoa(id)
+}
+
+// StoredRuleID returns the stored rule a promotion ID belongs to, if any.
+func StoredRuleID(promotionID string) (int, bool) {
+	s, ok := strings.CutPrefix(promotionID, storedPrefix)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(s)
+	return id, err == nil
+}
+
+// Label identifies a rule promotion in the adjustments it makes. An empty
+// Reason is described from the rule.
+type Label struct {
+	ID, Reason string
+}
+
+// PromotionID returns l.ID.
+func (l Label) PromotionID() string { return l.ID }
+
+func (l Label) reason(format string, args ...any) string {
+	if l.Reason != "" {
+		return l.Reason
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// BuyNGetMFree makes Free units of SKU free for every Buy paid.
+type BuyNGetMFree struct {
+	Label
+	SKU       string
+	Buy, Free int
+}
+
+func (p *BuyNGetMFree) References() []string { return []string{p.SKU} }
+
+func (p *BuyNGetMFree) Apply(_ context.Context, basket *Basket) (*model.Promotions, error) {
+	promotions := &model.Promotions{}
+	units := itemsBySKU(basket.Items)[p.SKU]
+	if len(units) == 0 {
+		return promotions, nil
+	}
+	groups := len(units) / (p.Buy + p.Free)
+	promotions.Deduction = units[0].Price.Mul(decimal.NewFromInt(int64(groups * p.Free))).InexactFloat64()
+	promotions.Consumed = units[:groups*(p.Buy+p.Free)]
+	if groups > 0 {
+		adjust(promotions, p.PromotionID(), p.reason("Buy %d get %d free on %s", p.Buy, p.Free, p.SKU), promotions.Consumed, groups*p.Free)
+	}
+	return promotions, nil
+}
+
+// PercentOffOverQuantity takes PercentOff off every unit of SKU once the basket
+// holds at least MinQuantity of them.
+type PercentOffOverQuantity struct {
+	Label
+	SKU         string
+	MinQuantity int
+	PercentOff  decimal.Decimal
+}
+
+func (p *PercentOffOverQuantity) References() []string { return []string{p.SKU} }
+
+func (p *PercentOffOverQuantity) Apply(_ context.Context, basket *Basket) (*model.Promotions, error) {
+	promotions := &model.Promotions{}
+	units := itemsBySKU(basket.Items)[p.SKU]
+	if len(units) == 0 || len(units) < p.MinQuantity {
+		return promotions, nil
+	}
+	total := units[0].Price.Mul(decimal.NewFromInt(int64(len(units))))
+	promotions.Deduction = total.Mul(p.PercentOff).Div(decimal.NewFromInt(100)).InexactFloat64()
+	promotions.Consumed = units
+	adjust(promotions, p.PromotionID(), p.reason("%s%% off %d or more %s", p.PercentOff, p.MinQuantity, p.SKU), units, len(units))
+	return promotions, nil
+}
+
+// FreeGift adds one GiftSKU free for every unit of SKU bought, if inventory
+// holds enough gifts for all of them.
+type FreeGift struct {
+	Label
+	SKU, GiftSKU string
+	db           database.InventoryStore
+}
+
+func (p *FreeGift) References() []string { return []string{p.SKU, p.GiftSKU} }
+
+func (p *FreeGift) Apply(ctx context.Context, basket *Basket) (*model.Promotions, error) {
+	promotions := &model.Promotions{}
+	bought := itemsBySKU(basket.Items)[p.SKU]
+	n := len(bought)
+	if n == 0 {
+		return promotions, nil
+	}
+	gift, err := p.db.GetItemBySKU(ctx, p.GiftSKU)
+	if err != nil {
+		return nil, err
+	}
+	if gift.InventoryQuantity < n {
+		return promotions, nil
+	}
+	for range n {
+		promotions.AddedItems = append(promotions.AddedItems, gift)
+	}
+	promotions.Consumed = bought
+	adjust(promotions, p.PromotionID(), p.reason("Free %s with every %s", p.GiftSKU, p.SKU), bought, 0)
+	return promotions, nil
+}
+
+// itemsBySKU groups the basket's units by SKU.
+func itemsBySKU(items []*model.Item) map[string][]*model.Item {
+	bySKU := make(map[string][]*model.Item)
+	for _, item := range items {
+		bySKU[item.SKU] = append(bySKU[item.SKU], item)
+	}
+	return bySKU
+}
+
+// DefaultRefreshInterval bounds how stale StoreRules' cache can be when a rule
+// is changed through another replica.
+const DefaultRefreshInterval = 30 * time.Second
+
+// StoreRules is a Promotion that applies every active rule in a
+// PromotionStore, except coupon-only ones. Rules are cached: the cache is rebuilt when Invalidate is
+// called (this replica's admin API does so on every change) or, to pick up
+// changes made through other replicas, once it is older than the refresh
+// interval.
+type StoreRules struct {
+	store     database.PromotionStore
+	inventory database.InventoryStore
+	refresh   time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	cached   []Promotion
+	loadedAt time.Time
+	valid    bool
+}
+
+// NewStoreRules returns StoreRules over store, looking gifts up in inventory.
+func NewStoreRules(store database.PromotionStore, inventory database.InventoryStore) *StoreRules {
+	return &StoreRules{store: store, inventory: inventory, refresh: DefaultRefreshInterval, now: time.Now}
+}
+
+// SetClock sets the clock used to age the cache and check rule schedules.
+func (s *StoreRules) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	s.now = now
+	s.mu.Unlock()
+}
+
+// Invalidate drops the cache so the next Apply reloads rules from the store.
+func (s *StoreRules) Invalidate() {
+	s.mu.Lock()
+	s.valid = false
+	s.mu.Unlock()
+}
+
+// Members returns the active stored rules, so an engine applies each on its
+// own policy.
+func (s *StoreRules) Members(ctx context.Context) ([]Promotion, error) {
+	rules, _, err := s.rules(ctx)
+	return rules, err
+}
+
+// Apply applies every active stored rule to items.
+func (s *StoreRules) Apply(ctx context.Context, basket *Basket) (*model.Promotions, error) {
+	rules, now, err := s.rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e := NewPromotionsEngine(rules...)
+	e.SetClock(now)
+	return e.ApplyPromotions(ctx, basket)
+}
+
+func (s *StoreRules) rules(ctx context.Context) ([]Promotion, func() time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.valid && s.now().Sub(s.loadedAt) < s.refresh {
+		return s.cached, s.now, nil
+	}
+	stored, err := s.store.ListPromotions(ctx, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load promotions: %w", err)
+	}
+	rules := make([]Promotion, 0, len(stored))
+	for _, r := range stored {
+		if r.CouponOnly {
+			continue // applied by the checkout that presents its coupon
+		}
+		p, err := FromRule(r, s.inventory)
+		if err != nil {
+			// The admin API validates rules on write, so this is a row edited
+			// by hand. Skip it rather than failing every checkout.
+			slog.Error("skipping invalid promotion", "promotion_id", r.ID, "error", err)
+			continue
+		}
+		rules = append(rules, p)
+	}
+	s.cached, s.loadedAt, s.valid = rules, s.now(), true
+	return rules, s.now, nil
+}
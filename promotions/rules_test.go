@@ -0,0 +1,148 @@
+//go:build !integration
+
+package promotions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func units(sku string, price float64, n int) []*model.Item {
+	items := make([]*model.Item, n)
+	for i := range items {
+		items[i] = &model.Item{SKU: sku, Price: decimal.NewFromFloat(price)}
+	}
+	return items
+}
+
+func TestFromRule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	ctx := context.Background()
+
+	t.Run("buy-n-get-m-free", func(t *testing.T) {
+		p, err := FromRule(&model.PromotionRule{Name: "3 for 2", Type: model.PromotionBuyNGetMFree, SKU: "120P90", BuyQuantity: 2, FreeQuantity: 1}, db)
+		require.NoError(t, err)
+		got, err := p.Apply(ctx, NewBasket(units("120P90", 10, 7)))
+		require.NoError(t, err)
+		require.Equal(t, 20.0, got.Deduction)
+	})
+
+	t.Run("percent-off-over-quantity", func(t *testing.T) {
+		p, err := FromRule(&model.PromotionRule{Name: "10% off 4+", Type: model.PromotionPercentOffOverQuantity, SKU: "A304SD", MinQuantity: 4, PercentOff: decimal.NewFromInt(10)}, db)
+		require.NoError(t, err)
+		got, err := p.Apply(ctx, NewBasket(units("A304SD", 100, 3)))
+		require.NoError(t, err)
+		require.Zero(t, got.Deduction)
+		got, err = p.Apply(ctx, NewBasket(units("A304SD", 100, 4)))
+		require.NoError(t, err)
+		require.Equal(t, 40.0, got.Deduction)
+	})
+
+	t.Run("free-gift", func(t *testing.T) {
+		gift := &model.Item{SKU: "234234", InventoryQuantity: 2}
+		db.EXPECT().GetItemBySKU(ctx, "234234").Return(gift, nil)
+		p, err := FromRule(&model.PromotionRule{Name: "gift", Type: model.PromotionFreeGift, SKU: "43N23P", GiftSKU: "234234"}, db)
+		require.NoError(t, err)
+		got, err := p.Apply(ctx, NewBasket(units("43N23P", 100, 2)))
+		require.NoError(t, err)
+		require.Equal(t, []*model.Item{gift, gift}, got.AddedItems)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := FromRule(&model.PromotionRule{Name: "bad", Type: model.PromotionBuyNGetMFree, SKU: "120P90"}, db)
+		require.Error(t, err)
+	})
+}
+
+// Stored rules are cached until invalidated or stale.
+func TestStoreRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	ctx := context.Background()
+	now := time.Now()
+	s := NewStoreRules(db, db)
+	s.now = func() time.Time { return now }
+
+	rule := &model.PromotionRule{Name: "3 for 2", Type: model.PromotionBuyNGetMFree, Active: true, SKU: "120P90", BuyQuantity: 2, FreeQuantity: 1}
+	// Coupon-only rules are left to the checkout that presents the coupon.
+	coupon := &model.PromotionRule{Name: "half off", Type: model.PromotionPercentOffOverQuantity, Active: true, CouponOnly: true, SKU: "120P90", MinQuantity: 1, PercentOff: decimal.NewFromInt(50)}
+	db.EXPECT().ListPromotions(ctx, true).Return([]*model.PromotionRule{rule, coupon}, nil).Times(1)
+	for range 2 {
+		got, err := s.Apply(ctx, NewBasket(units("120P90", 10, 3)))
+		require.NoError(t, err)
+		require.Equal(t, 10.0, got.Deduction)
+	}
+
+	s.Invalidate()
+	db.EXPECT().ListPromotions(ctx, true).Return(nil, nil).Times(1)
+	got, err := s.Apply(ctx, NewBasket(units("120P90", 10, 3)))
+	require.NoError(t, err)
+	require.Zero(t, got.Deduction)
+
+	now = now.Add(DefaultRefreshInterval)
+	db.EXPECT().ListPromotions(ctx, true).Return([]*model.PromotionRule{rule}, nil).Times(1)
+	got, err = s.Apply(ctx, NewBasket(units("120P90", 10, 3)))
+	require.NoError(t, err)
+	require.Equal(t, 10.0, got.Deduction)
+}
+
+// Customer conditions read the basket's order history, loaded once and only
+// when a promotion needs it.
+func TestCustomerPromotions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	ctx := context.Background()
+
+	firstOrder, err := FromRule(&model.PromotionRule{ID: 1, Name: "10% off your first order", Type: model.PromotionPercentOffOverQuantity,
+		SKU: "120P90", MinQuantity: 1, PercentOff: decimal.NewFromInt(10), CustomerCondition: model.CustomerCondition{FirstOrder: true}}, db)
+	require.NoError(t, err)
+	loyal, err := FromRule(&model.PromotionRule{ID: 2, Name: "free gift after 5 orders", Type: model.PromotionFreeGift,
+		SKU: "120P90", GiftSKU: "234234", CustomerCondition: model.CustomerCondition{MinOrders: 5}}, db)
+	require.NoError(t, err)
+	e := NewPromotionsEngine(firstOrder, loyal)
+
+	customer := func(orders int, loads *int) *Customer {
+		return NewCustomer("customer", func(context.Context) (*model.OrderSummary, error) {
+			*loads++
+			return &model.OrderSummary{Orders: orders}, nil
+		})
+	}
+
+	var loads int
+	got, err := e.ApplyPromotions(ctx, &Basket{Items: units("120P90", 50, 1), Customer: customer(0, &loads)})
+	require.NoError(t, err)
+	require.Equal(t, 5.0, got.Deduction)
+	require.Empty(t, got.AddedItems)
+	require.Equal(t, 1, loads)
+
+	gift := &model.Item{SKU: "234234", InventoryQuantity: 1}
+	db.EXPECT().GetItemBySKU(ctx, "234234").Return(gift, nil)
+	got, err = e.ApplyPromotions(ctx, &Basket{Items: units("120P90", 50, 1), Customer: customer(5, &loads)})
+	require.NoError(t, err)
+	require.Zero(t, got.Deduction)
+	require.Equal(t, []*model.Item{gift}, got.AddedItems)
+
+	// Anonymous baskets match neither.
+	got, err = e.ApplyPromotions(ctx, NewBasket(units("120P90", 50, 1)))
+	require.NoError(t, err)
+	require.Zero(t, got.Deduction)
+	require.Empty(t, got.AddedItems)
+
+	// Nothing loads the history of a basket no promotion asks about.
+	loads = 0
+	_, err = NewPromotionsEngine(&GoogleTVPromotion{}).ApplyPromotions(ctx, &Basket{Items: units("120P90", 50, 3), Customer: customer(0, &loads)})
+	require.NoError(t, err)
+	require.Zero(t, loads)
+
+	_, err = FromRule(&model.PromotionRule{Name: "both", Type: model.PromotionBuyNGetMFree, SKU: "120P90", BuyQuantity: 1, FreeQuantity: 1,
+		CustomerCondition: model.CustomerCondition{FirstOrder: true, MinOrders: 2}}, db)
+	require.Error(t, err)
+}
@@ -25,7 +25,12 @@ func (h *Service) Notifications() httprouter.Handle {
 	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
 		undelivered := r.URL.Query().Get("undelivered") == "true"
 
-		items, err := h.store.GetOutboxItems(r.Context(), &database.OutboxQuery{OnlyUndelivered: undelivered})
+		// The outbox also carries events the notifier does not announce (e.g.
+		// payment captures), so only read the topics it renders.
+		items, err := h.store.GetOutboxItems(r.Context(), &database.OutboxQuery{
+			OnlyUndelivered: undelivered,
+			Topics:          Topics,
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -51,6 +51,24 @@ func Test_Dispatch(t *testing.T) {
 	require.True(t, sink.got[0].Delivered)
 }
 
+// A refund event is announced against the order it refunds.
+func Test_DispatchRefund(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockDatabase(ctrl)
+	sink := &recordingSink{}
+	s := NewService(nil, store, msgmock.NewMockConsumer(ctrl), sink)
+
+	ev := event.New(event.TopicOrderRefunded, "ref-1", &model.Refund{Reference: "rf-1", OrderReference: "ref-1", CustomerID: "c-ref-1"})
+	store.EXPECT().SetDeliveredByEventID(gomock.Any(), ev.ID, gomock.Any()).Return(nil)
+
+	s.dispatch(context.Background(), ev)
+
+	require.Len(t, sink.got, 1)
+	require.Equal(t, event.TopicOrderRefunded, sink.got[0].Topic)
+	require.Equal(t, "ref-1", sink.got[0].Reference)
+	require.Equal(t, "c-ref-1", sink.got[0].CustomerID)
+}
+
 // The /v1/notifications endpoint maps outbox rows to notifications and passes
 // the undelivered filter through to the store query.
 func Test_NotificationsEndpoint(t *testing.T) {
@@ -85,13 +103,13 @@ func Test_NotificationsEndpoint(t *testing.T) {
 	}
 
 	t.Run("all", func(t *testing.T) {
-		store.EXPECT().GetOutboxItems(gomock.Any(), &database.OutboxQuery{OnlyUndelivered: false}).
+		store.EXPECT().GetOutboxItems(gomock.Any(), &database.OutboxQuery{OnlyUndelivered: false, Topics: Topics}).
 			Return([]*model.OutboxItem{mkItem("a", true), mkItem("b", false)}, nil)
 		require.Len(t, get(t, NotificationsEndPnt), 2)
 	})
 
 	t.Run("undelivered only", func(t *testing.T) {
-		store.EXPECT().GetOutboxItems(gomock.Any(), &database.OutboxQuery{OnlyUndelivered: true}).
+		store.EXPECT().GetOutboxItems(gomock.Any(), &database.OutboxQuery{OnlyUndelivered: true, Topics: Topics}).
 			Return([]*model.OutboxItem{mkItem("b", false)}, nil)
 		got := get(t, NotificationsEndPnt+"?undelivered=true")
 		require.Len(t, got, 1)
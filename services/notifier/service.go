@@ -18,6 +18,9 @@ const ServiceName = "notifier service"
 // replicas share it, so the broker splits partitions among them.
 const ConsumerGroup = "notifier"
 
+// Topics are the event topics the notifier consumes and renders.
+var Topics = []string{event.TopicOrderCreated, event.TopicOrderRefunded}
+
 // store is the notifier's view of the database: only the outbox (to read
 // notifications and mark them delivered) and a health probe. This narrow
 // interface is where splitting the stores pays off — unlike orders, the notifier
@@ -14,17 +14,27 @@ import (
 
 // notificationFromEvent builds a Notification from an order event.
 func notificationFromEvent(ev *event.Event, delivered bool) (*model.Notification, error) {
-	var order model.Order
-	if err := ev.DecodeData(&order); err != nil {
-		return nil, fmt.Errorf("decode order event: %w", err)
-	}
-	return &model.Notification{
+	n := &model.Notification{
 		EventID:    ev.ID,
-		Reference:  order.Reference,
-		CustomerID: order.CustomerID,
+		Topic:      ev.Topic,
 		OccurredAt: ev.OccurredAt,
 		Delivered:  delivered,
-	}, nil
+	}
+	switch ev.Topic {
+	case event.TopicOrderRefunded:
+		var refund model.Refund
+		if err := ev.DecodeData(&refund); err != nil {
+			return nil, fmt.Errorf("decode refund event: %w", err)
+		}
+		n.Reference, n.CustomerID = refund.OrderReference, refund.CustomerID
+	default:
+		var order model.Order
+		if err := ev.DecodeData(&order); err != nil {
+			return nil, fmt.Errorf("decode order event: %w", err)
+		}
+		n.Reference, n.CustomerID = order.Reference, order.CustomerID
+	}
+	return n, nil
 }
 
 // Sink writes notifications to an output.
@@ -50,7 +60,7 @@ func NewSink(filePath string) (Sink, error) {
 type terminalSink struct{}
 
 func (terminalSink) Write(_ context.Context, n *model.Notification) error {
-	slog.Info("notification", "event_id", n.EventID, "reference", n.Reference, "customer_id", n.CustomerID)
+	slog.Info("notification", "event_id", n.EventID, "topic", n.Topic, "reference", n.Reference, "customer_id", n.CustomerID)
 	return nil
 }
 
@@ -20,9 +20,32 @@ var (
 	ItemPriceEndPnt    = "/v1/inventory/item/price"
 	ItemsPriceEndPnt   = "/v1/inventory/items/price"
 	ItemPurchaseEndPnt = "/v1/inventory/items/purchase"
+	BundlesEndPnt      = "/v1/inventory/bundles"
 	KeyParam           = "/:key"
 
-	OrdersEndPnt = "/v1/orders"
+	OrdersEndPnt   = "/v1/orders"
+	ReferenceParam = "/:reference"
+	RefundsEndPnt  = "/refunds"
+
+	LoyaltyEndPnt = "/v1/loyalty"
+
+	GiftCardsEndPnt = "/v1/giftcards"
+	CodeParam       = "/:code"
+	ExpireEndPnt    = "/expire"
+
+	LimitsEndPnt = "/v1/limits"
+	SKUParam     = "/:sku"
+
+	PromotionsEndPnt = "/v1/promotions"
+	IDParam          = "/:id"
+	StatsEndPnt      = "/stats"
+
+	CouponsEndPnt = "/v1/coupons"
+
+	OutboxEndPnt       = "/v1/outbox"
+	OutboxEventsEndPnt = "/events"
+	ResetEndPnt        = "/reset"
+	AuditEndPnt        = "/audit"
 )
 
 func (h *Service) RegisterHandlers() *httprouter.Router {
@@ -40,6 +63,7 @@ func (h *Service) RegisterHandlers() *httprouter.Router {
 			Handler: httpserver.HealthHandler(ServiceName, constants.Version,
 				httpserver.Check{Name: "database", Probe: h.store.Ping},
 				httpserver.Check{Name: "broker", Probe: h.relay.Ping},
+				httpserver.Check{Name: "outbox", Probe: h.relay.CheckBacklog},
 			),
 		},
 		//
@@ -60,7 +84,8 @@ func (h *Service) RegisterHandlers() *httprouter.Router {
 			MethodType: http.MethodPost,
 			Handler:    h.ItemsPrice(),
 		},
-		// Authenticated requests
+		// Authenticated requests. Routes that mint value or set the rules of a
+		// sale are for administrators only.
 		{
 			Path:       ItemPurchaseEndPnt, // Execute purchase order (records the buyer)
 			MethodType: http.MethodPost,
@@ -71,10 +96,140 @@ func (h *Service) RegisterHandlers() *httprouter.Router {
 			MethodType: http.MethodGet,
 			Handler:    middleware.Auth(h.authn)(h.Orders()),
 		},
+		{
+			Path:       OrdersEndPnt + ReferenceParam + RefundsEndPnt, // Return lines of one of the customer's orders
+			MethodType: http.MethodPost,
+			Handler:    middleware.Auth(h.authn)(h.RefundOrder()),
+		},
+		{
+			Path:       LoyaltyEndPnt, // The authenticated customer's loyalty points
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.authn)(h.Loyalty()),
+		},
+		{
+			Path:       GiftCardsEndPnt, // Issue a gift card or store credit
+			MethodType: http.MethodPost,
+			Handler:    middleware.Auth(h.admin)(h.IssueGiftCard()),
+		},
+		{
+			Path:       GiftCardsEndPnt + CodeParam, // Gift card balance and ledger
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.authn)(h.GiftCard()),
+		},
+		{
+			Path:       GiftCardsEndPnt + CodeParam + ExpireEndPnt, // Forfeit a gift card's balance
+			MethodType: http.MethodPost,
+			Handler:    middleware.Auth(h.admin)(h.ExpireGiftCard()),
+		},
+		{
+			Path:       LimitsEndPnt, // List per-customer purchase limits
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.authn)(h.PurchaseLimits()),
+		},
+		{
+			Path:       LimitsEndPnt + SKUParam, // Set the purchase limit on a SKU
+			MethodType: http.MethodPut,
+			Handler:    middleware.Auth(h.admin)(h.SetPurchaseLimit()),
+		},
+		{
+			Path:       LimitsEndPnt + SKUParam, // Remove the purchase limit on a SKU
+			MethodType: http.MethodDelete,
+			Handler:    middleware.Auth(h.admin)(h.DeletePurchaseLimit()),
+		},
+		{
+			Path:       PromotionsEndPnt, // List promotions managed as data
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.admin)(h.ListPromotions()),
+		},
+		{
+			Path:       PromotionsEndPnt, // Create a promotion
+			MethodType: http.MethodPost,
+			Handler:    middleware.Auth(h.admin)(h.CreatePromotion()),
+		},
+		{
+			Path:       PromotionsEndPnt + IDParam, // Get a promotion
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.admin)(h.GetPromotion()),
+		},
+		{
+			Path:       PromotionsEndPnt + IDParam, // Replace a promotion
+			MethodType: http.MethodPut,
+			Handler:    middleware.Auth(h.admin)(h.UpdatePromotion()),
+		},
+		{
+			Path:       PromotionsEndPnt + IDParam, // Delete a promotion
+			MethodType: http.MethodDelete,
+			Handler:    middleware.Auth(h.admin)(h.DeletePromotion()),
+		},
+		{
+			Path:       PromotionsEndPnt + IDParam + StatsEndPnt, // A promotion's usage against its budget
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.admin)(h.PromotionStats()),
+		},
+		{
+			Path:       CouponsEndPnt, // List coupons
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.admin)(h.ListCoupons()),
+		},
+		{
+			Path:       CouponsEndPnt, // Create a coupon for a coupon-only promotion
+			MethodType: http.MethodPost,
+			Handler:    middleware.Auth(h.admin)(h.CreateCoupon()),
+		},
+		{
+			Path:       CouponsEndPnt + CodeParam, // Get a coupon and its redemption count
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.admin)(h.GetCoupon()),
+		},
+		{
+			Path:       CouponsEndPnt + CodeParam, // Delete a coupon
+			MethodType: http.MethodDelete,
+			Handler:    middleware.Auth(h.admin)(h.DeleteCoupon()),
+		},
+		{
+			Path:       OutboxEndPnt, // List outbox rows by state, topic and time
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.admin)(h.Outbox()),
+		},
+		{
+			Path:       OutboxEndPnt + OutboxEventsEndPnt + IDParam, // Inspect an outbox row and its decoded event
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.admin)(h.OutboxEventByID()),
+		},
+		{
+			Path:       OutboxEndPnt + ResetEndPnt, // Republish or redeliver outbox events
+			MethodType: http.MethodPost,
+			Handler:    middleware.Auth(h.admin)(h.ResetOutbox()),
+		},
+		{
+			Path:       OutboxEndPnt + AuditEndPnt, // Outbox audit log
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.admin)(h.OutboxAudit()),
+		},
 		{
 			Path:       ItemsEndPnt, // Add items to the inventory item table
 			MethodType: http.MethodPost,
 			Handler:    middleware.Auth(h.authn)(h.AddItems()),
 		},
+		{
+			Path:       BundlesEndPnt, // List fixed-price bundles
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.admin)(h.ListBundles()),
+		},
+		{
+			Path:       BundlesEndPnt + CodeParam, // Get a bundle
+			MethodType: http.MethodGet,
+			Handler:    middleware.Auth(h.admin)(h.GetBundle()),
+		},
+		{
+			Path:       BundlesEndPnt + CodeParam, // Create or replace a bundle
+			MethodType: http.MethodPut,
+			Handler:    middleware.Auth(h.admin)(h.PutBundle()),
+		},
+		{
+			Path:       BundlesEndPnt + CodeParam, // Delete a bundle
+			MethodType: http.MethodDelete,
+			Handler:    middleware.Auth(h.admin)(h.DeleteBundle()),
+		},
 	}).Routes()
 }
@@ -0,0 +1,167 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+
+	"github.com/ATMackay/checkout/fx"
+	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
+)
+
+// BundleIDPrefix starts the ID of the adjustment a bundle is recorded as.
+const BundleIDPrefix = "bundle:"
+
+// pricedBasket is a basket priced before promotions: each unit at its tier
+// price, less what bundles take off.
+type pricedBasket struct {
+	// units holds one item per unit requested, in request order, priced at
+	// its tier in the quote currency.
+	units []*model.Item
+	// promotable are the units not sold in a bundle. Only they are offered
+	// to promotions, so a bundle is never discounted again.
+	promotable []*model.Item
+	lines      []model.PriceLine
+	bundles    []model.Adjustment
+	// gross totals units; bundleDiscount is what bundles took off it.
+	gross, bundleDiscount decimal.Decimal
+}
+
+// total is the basket's price before promotions.
+func (b *pricedBasket) total() decimal.Decimal {
+	return b.gross.Sub(b.bundleDiscount)
+}
+
+// priceBasket prices one unit per entry of skus, which must all be in items
+// (catalog items by SKU), in x's quote currency. Each SKU's units are priced
+// at the tier their quantity reaches; then active bundles are applied, the
+// one saving most per set first, each as many times as the remaining units
+// allow.
+func (h *Service) priceBasket(ctx context.Context, skus []string, items map[string]*model.Item, x *exchange) (*pricedBasket, error) {
+	count := make(map[string]int)
+	var distinct []string
+	for _, sku := range skus {
+		if count[sku] == 0 {
+			distinct = append(distinct, sku)
+		}
+		count[sku]++
+	}
+
+	b := &pricedBasket{gross: decimal.Zero, bundleDiscount: decimal.Zero}
+	unit := make(map[string]*model.Item, len(distinct))
+	line := make(map[string]int, len(distinct)) // index into b.lines
+	for _, sku := range distinct {
+		it := items[sku]
+		quoted := x.convert([]*model.Item{it})[0]
+		quoted.Price = fx.Convert(it.UnitPriceFor(count[sku]), x.rate)
+		unit[sku] = quoted
+		l := model.PriceLine{
+			SKU:            sku,
+			Name:           it.Name,
+			Quantity:       count[sku],
+			ListPrice:      fx.Convert(it.Price, x.rate),
+			UnitPrice:      quoted.Price,
+			BundleDiscount: decimal.Zero,
+		}
+		if tier := it.TierFor(count[sku]); tier != nil {
+			l.TierMinQuantity = tier.MinQuantity
+		}
+		line[sku] = len(b.lines)
+		b.lines = append(b.lines, l)
+	}
+	for _, sku := range skus {
+		b.units = append(b.units, unit[sku])
+		b.gross = b.gross.Add(unit[sku].Price)
+	}
+
+	bundles, err := h.store.ListBundles(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not get bundles: %w", err)
+	}
+	type offer struct {
+		bundle *model.Bundle
+		need   map[string]int
+		saving decimal.Decimal
+	}
+	var offers []offer
+	for _, bundle := range bundles {
+		if bundle.Currency != "" && bundle.Currency != x.base {
+			continue
+		}
+		o := offer{bundle: bundle, need: make(map[string]int)}
+		value := decimal.Zero
+		for _, sku := range bundle.SKUs {
+			it, ok := unit[sku]
+			if !ok {
+				value = decimal.Zero
+				break
+			}
+			o.need[sku]++
+			value = value.Add(it.Price)
+		}
+		o.saving = value.Sub(fx.Convert(bundle.Price, x.rate))
+		if o.saving.IsPositive() {
+			offers = append(offers, o)
+		}
+	}
+	sort.SliceStable(offers, func(i, j int) bool { return offers[i].saving.GreaterThan(offers[j].saving) })
+
+	remaining := maps.Clone(count)
+	bundled := make(map[string]int)
+	for _, o := range offers {
+		sets := -1
+		for sku, q := range o.need {
+			if n := remaining[sku] / q; sets < 0 || n < sets {
+				sets = n
+			}
+		}
+		if sets <= 0 {
+			continue
+		}
+		saving := o.saving.Mul(decimal.NewFromInt(int64(sets)))
+		a := model.Adjustment{PromotionID: BundleIDPrefix + o.bundle.Code, Reason: o.bundle.Name, Amount: saving.InexactFloat64()}
+		// Each SKU's share of the saving is prorated by its value in the
+		// set; the last takes the rounding remainder.
+		var order []string
+		setValue := decimal.Zero
+		for _, sku := range o.bundle.SKUs {
+			if o.need[sku] > 0 && !slices.Contains(order, sku) {
+				order = append(order, sku)
+			}
+			setValue = setValue.Add(unit[sku].Price)
+		}
+		left := saving
+		for i, sku := range order {
+			q := o.need[sku] * sets
+			share := left
+			if i < len(order)-1 {
+				share = saving.Mul(unit[sku].Price.Mul(decimal.NewFromInt(int64(o.need[sku])))).Div(setValue).Round(2)
+			}
+			left = left.Sub(share)
+			remaining[sku] -= q
+			bundled[sku] += q
+			l := &b.lines[line[sku]]
+			l.Bundled += q
+			l.BundleDiscount = l.BundleDiscount.Add(share)
+			a.Lines = append(a.Lines, model.AdjustmentLine{SKU: sku, Quantity: q, Amount: share.InexactFloat64()})
+		}
+		b.bundles = append(b.bundles, a)
+		b.bundleDiscount = b.bundleDiscount.Add(saving)
+	}
+
+	for _, sku := range skus {
+		if bundled[sku] > 0 {
+			bundled[sku]--
+			continue
+		}
+		b.promotable = append(b.promotable, unit[sku])
+	}
+	for i := range b.lines {
+		l := &b.lines[i]
+		l.Total = l.UnitPrice.Mul(decimal.NewFromInt(int64(l.Quantity))).Sub(l.BundleDiscount)
+	}
+	return b, nil
+}
@@ -0,0 +1,91 @@
+package orders
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/errors"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/promotions"
+	"github.com/shopspring/decimal"
+)
+
+// redemption is what one promotion gave one order.
+type redemption struct {
+	promotionID string
+	// discount is in the catalog currency.
+	discount decimal.Decimal
+	units    int
+}
+
+// redemptionsOf totals an order's adjustments per promotion, in the order the
+// promotions were applied. Discounts are converted back to the catalog currency
+// from the order's, which is rate times it.
+func redemptionsOf(adjustments []model.Adjustment, rate decimal.Decimal) []redemption {
+	var rs []redemption
+	index := make(map[string]int)
+	for _, a := range adjustments {
+		i, ok := index[a.PromotionID]
+		if !ok {
+			i = len(rs)
+			index[a.PromotionID] = i
+			rs = append(rs, redemption{promotionID: a.PromotionID})
+		}
+		rs[i].discount = rs[i].discount.Add(decimal.NewFromFloat(a.Amount))
+		rs[i].units += a.Units
+	}
+	if !rate.IsZero() {
+		for i := range rs {
+			rs[i].discount = rs[i].discount.Div(rate).Round(2)
+		}
+	}
+	return rs
+}
+
+// redeemPromotions records the redemptions of stored promotions against their
+// budgets in tx, returning those the order exhausted. A promotion deactivated
+// since the order was priced fails the purchase, which the customer can retry
+// at the new price.
+func (h *Service) redeemPromotions(ctx context.Context, tx database.Database, rs []redemption) ([]*model.PromotionRule, error) {
+	var exhausted []*model.PromotionRule
+	for _, r := range rs {
+		id, ok := promotions.StoredRuleID(r.promotionID)
+		if !ok {
+			continue
+		}
+		p, err := tx.RedeemPromotion(ctx, id, r.discount, r.units)
+		if err != nil {
+			if stderrors.Is(err, database.ErrPromotionInactive) || stderrors.Is(err, database.ErrPromotionNotFound) {
+				h.rules.Invalidate() // so the retry is priced without it
+				return nil, fmt.Errorf("%w: %v; the price has changed, please retry", errors.ErrConflict, err)
+			}
+			return nil, fmt.Errorf("could not redeem promotion: %w", err)
+		}
+		if !p.Active {
+			exhausted = append(exhausted, p)
+		}
+	}
+	return exhausted, nil
+}
+
+// recordRedemptions updates the promotion metrics for a committed order, and
+// stops offering the promotions it exhausted.
+func (h *Service) recordRedemptions(rs []redemption, exhausted []*model.PromotionRule) {
+	for _, r := range rs {
+		PromotionRedemptions.WithLabelValues(r.promotionID).Inc()
+		PromotionDiscountGranted.WithLabelValues(r.promotionID).Add(r.discount.InexactFloat64())
+		PromotionUnitsGranted.WithLabelValues(r.promotionID).Add(float64(r.units))
+	}
+	for _, p := range exhausted {
+		id := promotions.RuleID(p.ID)
+		PromotionBudgetExhausted.WithLabelValues(id).Inc()
+		slog.Info("promotion budget exhausted, deactivated", "promotion_id", id,
+			"discount_granted", p.DiscountGranted, "units_granted", p.UnitsGranted)
+	}
+	if len(exhausted) > 0 {
+		h.rules.Invalidate()
+	}
+}
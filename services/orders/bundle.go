@@ -0,0 +1,115 @@
+package orders
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/errors"
+	"github.com/ATMackay/checkout/httpserver"
+	"github.com/ATMackay/checkout/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListBundles godoc
+// @Summary List bundles
+// @Description List the fixed-price bundles, active or not.
+// @Tags inventory
+// @Produce json
+// @Success 200 {array}  model.Bundle
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/inventory/bundles [get]
+func (h *Service) ListBundles() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		bs, err := h.store.ListBundles(r.Context(), false)
+		if err != nil {
+			return nil, fmt.Errorf("could not get bundles: %w", err)
+		}
+		return bs, nil
+	})
+}
+
+// GetBundle godoc
+// @Summary Get a bundle
+// @Tags inventory
+// @Produce json
+// @Param   code  path    string  true  "Bundle code"
+// @Success 200 {object} model.Bundle
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/inventory/bundles/{code} [get]
+func (h *Service) GetBundle() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		b, err := h.store.GetBundle(r.Context(), p.ByName("code"))
+		if err != nil {
+			return nil, bundleError(err)
+		}
+		return b, nil
+	})
+}
+
+// PutBundle godoc
+// @Summary Create or replace a bundle
+// @Description Sell a set of units together at a fixed price. An active bundle applies to quotes and purchases before promotions.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Param   code     path    string        true  "Bundle code"
+// @Param   request  body    model.Bundle  true  "Bundle"
+// @Success 200 {object} model.Bundle
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/inventory/bundles/{code} [put]
+func (h *Service) PutBundle() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		var b model.Bundle
+		if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+		}
+		b.Code = p.ByName("code")
+		if b.Currency == "" {
+			b.Currency = model.DefaultCurrency
+		}
+		if err := b.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+		}
+		if err := h.store.PutBundle(r.Context(), &b); err != nil {
+			return nil, fmt.Errorf("could not save bundle: %w", err)
+		}
+		return &b, nil
+	})
+}
+
+// DeleteBundle godoc
+// @Summary Delete a bundle
+// @Tags inventory
+// @Param   code  path  string  true  "Bundle code"
+// @Success 200
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/inventory/bundles/{code} [delete]
+func (h *Service) DeleteBundle() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		if err := h.store.DeleteBundle(r.Context(), p.ByName("code")); err != nil {
+			return nil, bundleError(err)
+		}
+		return nil, nil
+	})
+}
+
+func bundleError(err error) error {
+	if stderrors.Is(err, database.ErrBundleNotFound) {
+		return fmt.Errorf("%w: %v", errors.ErrNotFound, err)
+	}
+	return fmt.Errorf("could not access bundle: %w", err)
+}
@@ -0,0 +1,161 @@
+//go:build !integration
+
+package orders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments/fake"
+	"github.com/ATMackay/checkout/services/auth"
+	ordersmock "github.com/ATMackay/checkout/services/orders/mock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// deskInventory is a cable priced 8.00 from three units and a dock, sold
+// together as the desk bundle.
+func deskInventory() []*model.Item {
+	return []*model.Item{
+		{Name: "USB Cable", SKU: "CABLE1", Price: decimal.NewFromInt(10), Currency: "USD", InventoryQuantity: 10,
+			PriceTiers: []model.PriceTier{{MinQuantity: 3, UnitPrice: decimal.NewFromInt(8)}}},
+		{Name: "Dock", SKU: "DOCK01", Price: decimal.NewFromInt(100), Currency: "USD", InventoryQuantity: 10},
+	}
+}
+
+func deskBundle() *model.Bundle {
+	return &model.Bundle{Code: "desk", Name: "Desk set", SKUs: []string{"DOCK01", "CABLE1"},
+		Price: decimal.NewFromInt(100), Currency: "USD", Active: true}
+}
+
+var deskBasket = []string{"CABLE1", "DOCK01", "CABLE1", "CABLE1"}
+
+// newBundleService is newPurchaseService with the desk bundle active.
+func newBundleService(ctrl *gomock.Controller, db *mock.MockDatabase) *Service {
+	noStoredPromotions(db)
+	db.EXPECT().ListBundles(gomock.Any(), true).Return([]*model.Bundle{deskBundle()}, nil).AnyTimes()
+	authn := auth.NewPasswordAuthenticator(map[string]string{testPassword: "customer"})
+	return NewService(db, ordersmock.NewMockRelayer(ctrl), authn, WithPaymentProvider(fake.NewProvider()), withTestAdmin())
+}
+
+// Three cables reach their tier, and one of them goes into the desk bundle
+// with the dock: the 8.00 saving is split across both lines by value.
+func Test_ItemsPriceTiersAndBundles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(deskInventory(), nil)
+
+	body, err := json.Marshal(&model.ItemsPriceRequest{SKUs: deskBasket})
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+	newBundleService(ctrl, db).RegisterHandlers().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, ItemPriceEndPnt, bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var resp model.PriceResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 116.0, resp.TotalGross)
+	assert.Equal(t, 116.0, resp.TotalWithDiscount)
+	require.Len(t, resp.Lines, 2)
+
+	cable, dock := resp.Lines[0], resp.Lines[1]
+	assert.Equal(t, "CABLE1", cable.SKU)
+	assert.Equal(t, 3, cable.Quantity)
+	assert.Equal(t, "10", cable.ListPrice.String())
+	assert.Equal(t, "8", cable.UnitPrice.String())
+	assert.Equal(t, 3, cable.TierMinQuantity)
+	assert.Equal(t, 1, cable.Bundled)
+	assert.Equal(t, "0.59", cable.BundleDiscount.String())
+	assert.Equal(t, "23.41", cable.Total.String())
+
+	assert.Equal(t, "DOCK01", dock.SKU)
+	assert.Equal(t, 0, dock.TierMinQuantity)
+	assert.Equal(t, 1, dock.Bundled)
+	assert.Equal(t, "7.41", dock.BundleDiscount.String())
+	assert.Equal(t, "92.59", dock.Total.String())
+
+	assert.Equal(t, []model.Adjustment{{PromotionID: "bundle:desk", Reason: "Desk set", Amount: 8,
+		Lines: []model.AdjustmentLine{{SKU: "DOCK01", Quantity: 1, Amount: 7.41}, {SKU: "CABLE1", Quantity: 1, Amount: 0.59}}}}, resp.Bundles)
+}
+
+// A purchase is charged the bundled price and records the bundle as an
+// adjustment on the order, so refunds net it out.
+func Test_PurchaseBundle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(deskInventory(), nil)
+	db.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(database.Database) error) error { return fn(db) })
+	db.EXPECT().UpsertItems(gomock.Any(), gomock.Any()).Return(nil, nil)
+	var order *model.Order
+	db.EXPECT().AddOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, o *model.Order) error {
+		order = o
+		return nil
+	})
+	db.EXPECT().AddOutboxItems(gomock.Any(), gomock.Any()).Return(nil)
+
+	rr := purchase(t, newBundleService(ctrl, db), deskBasket...)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	assert.Equal(t, "116", order.Price.String())
+	adjustments, err := order.GetAdjustments()
+	require.NoError(t, err)
+	require.Len(t, adjustments, 1)
+	assert.Equal(t, "bundle:desk", adjustments[0].PromotionID)
+	assert.Equal(t, 8.0, adjustments[0].Amount)
+}
+
+// Bundles are managed through the inventory admin API.
+func Test_BundleAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	router := newPurchaseService(ctrl, db, fake.NewProvider()).RegisterHandlers()
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(auth.XAuthHeaderKey, testAdminPassword)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("put", func(t *testing.T) {
+		var saved *model.Bundle
+		db.EXPECT().PutBundle(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, b *model.Bundle) error {
+			saved = b
+			return nil
+		})
+		rr := do(http.MethodPut, BundlesEndPnt+"/desk", `{"name":"Desk set","skus":["DOCK01","CABLE1"],"price":"100","active":true}`)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, "desk", saved.Code)
+		assert.Equal(t, model.DefaultCurrency, saved.Currency)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		rr := do(http.MethodPut, BundlesEndPnt+"/desk", `{"name":"Desk set","skus":["DOCK01"],"price":"100"}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db.EXPECT().GetBundle(gomock.Any(), "nope").Return(nil, database.ErrBundleNotFound)
+		require.Equal(t, http.StatusNotFound, do(http.MethodGet, BundlesEndPnt+"/nope", "").Code)
+		db.EXPECT().DeleteBundle(gomock.Any(), "nope").Return(database.ErrBundleNotFound)
+		require.Equal(t, http.StatusNotFound, do(http.MethodDelete, BundlesEndPnt+"/nope", "").Code)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, BundlesEndPnt, nil))
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
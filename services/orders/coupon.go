@@ -0,0 +1,268 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/errors"
+	"github.com/ATMackay/checkout/httpserver"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/promotions"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListCoupons godoc
+// @Summary List coupons
+// @Tags coupons
+// @Produce json
+// @Success 200 {array}  model.Coupon
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/coupons [get]
+func (h *Service) ListCoupons() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		cs, err := h.store.ListCoupons(r.Context())
+		if err != nil {
+			return nil, fmt.Errorf("could not get coupons: %w", err)
+		}
+		return cs, nil
+	})
+}
+
+// GetCoupon godoc
+// @Summary Get a coupon
+// @Tags coupons
+// @Produce json
+// @Param   code  path    string  true  "Coupon code"
+// @Success 200 {object} model.Coupon
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/coupons/{code} [get]
+func (h *Service) GetCoupon() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		c, err := h.store.GetCoupon(r.Context(), model.NormalizeCouponCode(p.ByName("code")))
+		if err != nil {
+			if stderrors.Is(err, database.ErrCouponNotFound) {
+				return nil, fmt.Errorf("%w: %v", errors.ErrNotFound, err)
+			}
+			return nil, fmt.Errorf("could not get coupon: %w", err)
+		}
+		return c, nil
+	})
+}
+
+// CreateCoupon godoc
+// @Summary Create a coupon
+// @Description Create a code that unlocks a coupon-only promotion, with optional global and per-customer redemption limits and expiry.
+// @Tags coupons
+// @Accept json
+// @Produce json
+// @Param   request  body    model.Coupon  true  "Coupon"
+// @Success 200 {object} model.Coupon
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 409 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/coupons [post]
+func (h *Service) CreateCoupon() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		ctx := r.Context()
+		var c model.Coupon
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+		}
+		c.ID, c.Redemptions = 0, 0
+		c.Code = model.NormalizeCouponCode(c.Code)
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+		}
+		if c.ExpiresAt != nil && !c.ExpiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: expires_at must be in the future", errors.ErrInvalidInput)
+		}
+		promo, err := h.store.GetPromotion(ctx, c.PromotionID)
+		if err != nil {
+			if stderrors.Is(err, database.ErrPromotionNotFound) {
+				return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+			}
+			return nil, fmt.Errorf("could not get promotion: %w", err)
+		}
+		if !promo.CouponOnly {
+			return nil, fmt.Errorf("%w: promotion %d applies automatically; set coupon_only to attach coupons", errors.ErrInvalidInput, promo.ID)
+		}
+		if _, err := h.store.GetCoupon(ctx, c.Code); err == nil {
+			return nil, fmt.Errorf("%w: coupon %s already exists", errors.ErrConflict, c.Code)
+		} else if !stderrors.Is(err, database.ErrCouponNotFound) {
+			return nil, fmt.Errorf("could not get coupon: %w", err)
+		}
+		if err := h.store.AddCoupon(ctx, &c); err != nil {
+			return nil, fmt.Errorf("could not create coupon: %w", err)
+		}
+		return &c, nil
+	})
+}
+
+// DeleteCoupon godoc
+// @Summary Delete a coupon
+// @Tags coupons
+// @Param   code  path  string  true  "Coupon code"
+// @Success 200
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/coupons/{code} [delete]
+func (h *Service) DeleteCoupon() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		if err := h.store.DeleteCoupon(r.Context(), model.NormalizeCouponCode(p.ByName("code"))); err != nil {
+			if stderrors.Is(err, database.ErrCouponNotFound) {
+				return nil, fmt.Errorf("%w: %v", errors.ErrNotFound, err)
+			}
+			return nil, fmt.Errorf("could not delete coupon: %w", err)
+		}
+		return nil, nil
+	})
+}
+
+// errCouponInapplicable rejects a coupon whose promotion gives nothing on the
+// basket, so the customer learns the code did nothing rather than being
+// silently charged full price.
+var errCouponInapplicable = stderrors.New("its promotion does not apply to these items")
+
+// errCouponOutOfSchedule rejects a coupon whose promotion's schedule does not
+// apply at the time of the checkout.
+var errCouponOutOfSchedule = stderrors.New("its promotion does not apply at this time")
+
+// errCouponUnavailable rejects a coupon whose promotion was deactivated or
+// deleted.
+var errCouponUnavailable = stderrors.New("its promotion is no longer available")
+
+// errCouponNotApplied rejects a coupon whose promotion lost its units to
+// another when the basket was priced, so the code is not used up for nothing.
+var errCouponNotApplied = stderrors.New("not applied: a better promotion was chosen")
+
+// checkedCoupon is a code that passed couponPromotions, normalized, with the
+// ID its promotion reports in adjustments.
+type checkedCoupon struct {
+	code        string
+	promotionID string
+}
+
+// couponPromotions checks each of codes and returns the promotions they
+// unlock for basket, to be applied alongside the automatic ones under the same
+// priority rules, and the checked codes, for appliedCoupons. The basket may be
+// anonymous (a price quote), which skips per-customer limits.
+//
+// These checks are advisory: they read the coupon outside the order
+// transaction. RedeemCoupon re-checks every limit atomically at purchase.
+func (h *Service) couponPromotions(ctx context.Context, codes []string, basket *promotions.Basket, now time.Time) ([]promotions.Promotion, []checkedCoupon, error) {
+	var unlocked []promotions.Promotion
+	seen := make(map[string]bool)
+	var checked []checkedCoupon
+	for _, raw := range codes {
+		code := model.NormalizeCouponCode(raw)
+		if !model.IsCouponCode(code) {
+			return nil, nil, fmt.Errorf("%w: invalid coupon code '%s'", errors.ErrInvalidInput, raw)
+		}
+		if seen[code] {
+			return nil, nil, fmt.Errorf("%w: coupon %s given more than once", errors.ErrInvalidInput, code)
+		}
+		seen[code] = true
+
+		c, err := h.store.GetCoupon(ctx, code)
+		if err != nil {
+			return nil, nil, couponError(code, err)
+		}
+		switch {
+		case c.Expired(now):
+			return nil, nil, couponError(code, database.ErrCouponExpired)
+		case c.Exhausted():
+			return nil, nil, couponError(code, database.ErrCouponExhausted)
+		}
+		if basket.Customer != nil && c.MaxPerCustomer > 0 {
+			used, err := h.store.CountCouponRedemptions(ctx, code, basket.Customer.ID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not count coupon redemptions: %w", err)
+			}
+			if used >= c.MaxPerCustomer {
+				return nil, nil, couponError(code, database.ErrCouponCustomerLimit)
+			}
+		}
+
+		rule, err := h.store.GetPromotion(ctx, c.PromotionID)
+		if err != nil && !stderrors.Is(err, database.ErrPromotionNotFound) {
+			return nil, nil, fmt.Errorf("could not get promotion: %w", err)
+		}
+		if err != nil || !rule.Active {
+			return nil, nil, couponError(code, errCouponUnavailable)
+		}
+		if !rule.Schedule.AppliesAt(now) {
+			return nil, nil, couponError(code, errCouponOutOfSchedule)
+		}
+		promo, err := promotions.FromRule(rule, h.store)
+		if err != nil {
+			return nil, nil, couponError(code, errCouponUnavailable)
+		}
+		// Reject a code that gives nothing on this basket even on its own.
+		p, err := promo.Apply(ctx, basket)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not apply coupon %s: %w", code, err)
+		}
+		if p.Deduction == 0 && len(p.AddedItems) == 0 {
+			return nil, nil, couponError(code, errCouponInapplicable)
+		}
+		unlocked = append(unlocked, promo)
+		checked = append(checked, checkedCoupon{code: code, promotionID: promotions.RuleID(rule.ID)})
+	}
+	return unlocked, checked, nil
+}
+
+// appliedCoupons returns the codes of coupons, for redemption, rejecting the
+// first whose promotion gave nothing in adjustments.
+func appliedCoupons(coupons []checkedCoupon, adjustments []model.Adjustment) ([]string, error) {
+	gave := make(map[string]bool)
+	for _, a := range adjustments {
+		if a.Amount > 0 || len(a.AddedSKUs) > 0 {
+			gave[a.PromotionID] = true
+		}
+	}
+	codes := make([]string, 0, len(coupons))
+	for _, c := range coupons {
+		if !gave[c.promotionID] {
+			return nil, couponError(c.code, errCouponNotApplied)
+		}
+		codes = append(codes, c.code)
+	}
+	return codes, nil
+}
+
+// couponError maps a coupon rejection to a response explaining it.
+func couponError(code string, err error) error {
+	var reason string
+	switch {
+	case stderrors.Is(err, database.ErrCouponNotFound):
+		reason = "unknown code"
+	case stderrors.Is(err, database.ErrCouponExpired):
+		reason = "expired"
+	case stderrors.Is(err, database.ErrCouponExhausted):
+		reason = "redemption limit reached"
+	case stderrors.Is(err, database.ErrCouponCustomerLimit):
+		reason = "already used the maximum number of times by this customer"
+	case stderrors.Is(err, errCouponInapplicable),
+		stderrors.Is(err, errCouponNotApplied),
+		stderrors.Is(err, errCouponOutOfSchedule),
+		stderrors.Is(err, errCouponUnavailable):
+		reason = err.Error()
+	default:
+		return fmt.Errorf("could not redeem coupon %s: %w", code, err)
+	}
+	return fmt.Errorf("%w: coupon %s rejected: %s", errors.ErrUnprocessable, code, reason)
+}
@@ -0,0 +1,175 @@
+//go:build !integration
+
+package orders
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments/fake"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func couponInventory() []*model.Item {
+	return []*model.Item{
+		{Name: "Google TV", SKU: "120P90", Price: decimal.NewFromInt(50), Currency: "USD", InventoryQuantity: 10},
+	}
+}
+
+// tenPercentOff is a coupon-only 10% off Google TVs.
+func tenPercentOff() *model.PromotionRule {
+	return &model.PromotionRule{ID: 7, Name: "10% off", Type: model.PromotionPercentOffOverQuantity, Active: true,
+		CouponOnly: true, SKU: "120P90", MinQuantity: 1, PercentOff: decimal.NewFromInt(10)}
+}
+
+// A valid coupon discounts the basket and is redeemed inside the order
+// transaction against the order it paid for.
+func Test_PurchaseWithCoupon(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(couponInventory(), nil)
+	db.EXPECT().GetCoupon(gomock.Any(), "SAVE10").Return(&model.Coupon{Code: "SAVE10", PromotionID: 7, MaxPerCustomer: 1}, nil)
+	db.EXPECT().CountCouponRedemptions(gomock.Any(), "SAVE10", "customer").Return(0, nil)
+	db.EXPECT().GetPromotion(gomock.Any(), 7).Return(tenPercentOff(), nil)
+	db.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(database.Database) error) error { return fn(db) })
+	db.EXPECT().UpsertItems(gomock.Any(), gomock.Any()).Return(nil, nil)
+	var order *model.Order
+	db.EXPECT().AddOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, o *model.Order) error {
+		order = o
+		return nil
+	})
+	var redemption *model.CouponRedemption
+	db.EXPECT().RedeemCoupon(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r *model.CouponRedemption) error {
+		redemption = r
+		return nil
+	})
+	db.EXPECT().RedeemPromotion(gomock.Any(), 7, amount("5"), 1).Return(tenPercentOff(), nil)
+	db.EXPECT().AddOutboxItems(gomock.Any(), gomock.Any()).Return(nil)
+
+	rr := purchaseWith(t, newPurchaseService(ctrl, db, payer), &model.PurchaseItemsRequest{SKUs: []string{"120P90"}, CouponCodes: []string{"save10"}})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	assert.Equal(t, "45", order.Price.String())
+	adjustments, err := order.GetAdjustments()
+	require.NoError(t, err)
+	assert.Equal(t, []model.Adjustment{{PromotionID: "promotion:7", Reason: "10% off", Amount: 5,
+		Lines: []model.AdjustmentLine{{SKU: "120P90", Quantity: 1, Amount: 5}}, Units: 1}}, adjustments)
+	assert.Equal(t, &model.CouponRedemption{Code: "SAVE10", CustomerID: "customer", OrderReference: order.Reference}, redemption)
+	calls := payer.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "45", calls[0].Amount.String())
+}
+
+// A coupon that fails any check is refused with 422 and a reason, before
+// anything is charged or written.
+func Test_PurchaseCouponRejected(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	otherSKU := tenPercentOff()
+	otherSKU.SKU = "A304SD"
+	inactive := tenPercentOff()
+	inactive.Active = false
+
+	for _, tc := range []struct {
+		name   string
+		setup  func(db *mock.MockDatabase)
+		reason string
+	}{
+		{"unknown", func(db *mock.MockDatabase) {
+			db.EXPECT().GetCoupon(gomock.Any(), "SAVE10").Return(nil, database.ErrCouponNotFound)
+		}, "unknown code"},
+		{"expired", func(db *mock.MockDatabase) {
+			db.EXPECT().GetCoupon(gomock.Any(), "SAVE10").Return(&model.Coupon{Code: "SAVE10", PromotionID: 7, ExpiresAt: &past}, nil)
+		}, "expired"},
+		{"exhausted", func(db *mock.MockDatabase) {
+			db.EXPECT().GetCoupon(gomock.Any(), "SAVE10").Return(&model.Coupon{Code: "SAVE10", PromotionID: 7, MaxRedemptions: 5, Redemptions: 5}, nil)
+		}, "redemption limit reached"},
+		{"per-customer", func(db *mock.MockDatabase) {
+			db.EXPECT().GetCoupon(gomock.Any(), "SAVE10").Return(&model.Coupon{Code: "SAVE10", PromotionID: 7, MaxPerCustomer: 1}, nil)
+			db.EXPECT().CountCouponRedemptions(gomock.Any(), "SAVE10", "customer").Return(1, nil)
+		}, "already used the maximum number of times by this customer"},
+		{"inactive-promotion", func(db *mock.MockDatabase) {
+			db.EXPECT().GetCoupon(gomock.Any(), "SAVE10").Return(&model.Coupon{Code: "SAVE10", PromotionID: 7}, nil)
+			db.EXPECT().GetPromotion(gomock.Any(), 7).Return(inactive, nil)
+		}, "its promotion is no longer available"},
+		{"inapplicable", func(db *mock.MockDatabase) {
+			db.EXPECT().GetCoupon(gomock.Any(), "SAVE10").Return(&model.Coupon{Code: "SAVE10", PromotionID: 7}, nil)
+			db.EXPECT().GetPromotion(gomock.Any(), 7).Return(otherSKU, nil)
+		}, "its promotion does not apply to these items"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			db := mock.NewMockDatabase(ctrl)
+			payer := fake.NewProvider()
+			db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+			db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(couponInventory(), nil)
+			tc.setup(db)
+			// No Transaction expectation: gomock fails the test if the order is written.
+
+			rr := purchaseWith(t, newPurchaseService(ctrl, db, payer), &model.PurchaseItemsRequest{SKUs: []string{"120P90"}, CouponCodes: []string{"SAVE10"}})
+			require.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), "coupon SAVE10 rejected: "+tc.reason)
+			assert.Empty(t, payer.Calls())
+		})
+	}
+}
+
+// A coupon used up by a concurrent purchase after the advisory check fails
+// this purchase at redemption, and the authorization is voided.
+func Test_PurchaseCouponRedemptionRace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(couponInventory(), nil)
+	db.EXPECT().GetCoupon(gomock.Any(), "SAVE10").Return(&model.Coupon{Code: "SAVE10", PromotionID: 7, MaxRedemptions: 1}, nil)
+	db.EXPECT().GetPromotion(gomock.Any(), 7).Return(tenPercentOff(), nil)
+	db.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(database.Database) error) error { return fn(db) })
+	db.EXPECT().UpsertItems(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().AddOrder(gomock.Any(), gomock.Any()).Return(nil)
+	db.EXPECT().RedeemCoupon(gomock.Any(), gomock.Any()).Return(database.ErrCouponExhausted)
+
+	rr := purchaseWith(t, newPurchaseService(ctrl, db, payer), &model.PurchaseItemsRequest{SKUs: []string{"120P90"}, CouponCodes: []string{"SAVE10"}})
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "redemption limit reached")
+
+	calls := payer.Calls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, fake.Void, calls[1].Op)
+}
+
+// Of two coupons competing for the same unit, the one whose promotion lost is
+// refused rather than redeemed for nothing, before anything is charged.
+func Test_PurchaseCompetingCoupons(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	twentyPercentOff := &model.PromotionRule{ID: 8, Name: "20% off", Type: model.PromotionPercentOffOverQuantity, Active: true,
+		CouponOnly: true, SKU: "120P90", MinQuantity: 1, PercentOff: decimal.NewFromInt(20), Priority: 1}
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(couponInventory(), nil)
+	db.EXPECT().GetCoupon(gomock.Any(), "SAVE10").Return(&model.Coupon{Code: "SAVE10", PromotionID: 7}, nil)
+	db.EXPECT().GetPromotion(gomock.Any(), 7).Return(tenPercentOff(), nil)
+	db.EXPECT().GetCoupon(gomock.Any(), "SAVE20").Return(&model.Coupon{Code: "SAVE20", PromotionID: 8}, nil)
+	db.EXPECT().GetPromotion(gomock.Any(), 8).Return(twentyPercentOff, nil)
+	// No Transaction expectation: gomock fails the test if either code is redeemed.
+
+	rr := purchaseWith(t, newPurchaseService(ctrl, db, payer), &model.PurchaseItemsRequest{SKUs: []string{"120P90"}, CouponCodes: []string{"SAVE10", "SAVE20"}})
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "coupon SAVE10 rejected: not applied: a better promotion was chosen")
+	assert.Empty(t, payer.Calls())
+}
@@ -0,0 +1,150 @@
+package orders
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/errors"
+	"github.com/ATMackay/checkout/httpserver"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/services/auth"
+	"github.com/julienschmidt/httprouter"
+)
+
+// IssueGiftCard godoc
+// @Summary Issue a gift card.
+// @Description Issue a gift card (or, with customer_id, store credit) with an opening balance.
+// @Tags giftcards
+// @Accept json
+// @Produce json
+// @Param   request  body    model.IssueGiftCardRequest  true  "Opening balance"
+// @Success 200 {object} model.GiftCard
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/giftcards [post]
+func (h *Service) IssueGiftCard() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		var req model.IssueGiftCardRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+		}
+		if !req.Amount.IsPositive() {
+			return nil, fmt.Errorf("%w: amount must be positive", errors.ErrInvalidInput)
+		}
+		if req.Currency == "" {
+			req.Currency = model.DefaultCurrency
+		}
+		req.Currency = strings.ToUpper(req.Currency)
+		if !model.IsCurrency(req.Currency) {
+			return nil, fmt.Errorf("%w: invalid currency '%s'", errors.ErrInvalidInput, req.Currency)
+		}
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: expires_at must be in the future", errors.ErrInvalidInput)
+		}
+		card := &model.GiftCard{
+			Code:       model.GenerateGiftCardCode(),
+			Balance:    req.Amount.Round(2),
+			Currency:   req.Currency,
+			CustomerID: req.CustomerID,
+			ExpiresAt:  req.ExpiresAt,
+		}
+		if err := h.store.IssueGiftCard(r.Context(), card); err != nil {
+			return nil, fmt.Errorf("could not issue gift card: %w", err)
+		}
+		return card, nil
+	})
+}
+
+// GiftCard godoc
+// @Summary Get a gift card.
+// @Description Get a gift card's balance and its ledger of balance changes. Store credit is visible only to the customer it was issued to.
+// @Tags giftcards
+// @Produce json
+// @Param   code  path    string  true  "Gift card code"
+// @Success 200 {object} model.GiftCard
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/giftcards/{code} [get]
+func (h *Service) GiftCard() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		ctx := r.Context()
+		customerID, ok := auth.UserID(ctx)
+		if !ok {
+			return nil, fmt.Errorf("%w", errors.ErrInvalidInput)
+		}
+		card, err := h.giftCard(r, p)
+		if err != nil {
+			return nil, err
+		}
+		// Another customer's store credit is indistinguishable from a missing
+		// card.
+		if !card.HeldBy(customerID) {
+			return nil, fmt.Errorf("%w: gift card %s", errors.ErrNotFound, card.Code)
+		}
+		ledger, err := h.store.GetGiftCardLedger(ctx, card.Code)
+		if err != nil {
+			return nil, fmt.Errorf("could not get gift card ledger: %w", err)
+		}
+		card.Ledger = ledger
+		return card, nil
+	})
+}
+
+// ExpireGiftCard godoc
+// @Summary Expire a gift card.
+// @Description Forfeit a gift card's remaining balance so it can no longer be redeemed.
+// @Tags giftcards
+// @Produce json
+// @Param   code  path    string  true  "Gift card code"
+// @Success 200 {object} model.GiftCard
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/giftcards/{code}/expire [post]
+func (h *Service) ExpireGiftCard() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		code := p.ByName("code")
+		if err := h.store.ExpireGiftCard(r.Context(), code, time.Now().UTC()); err != nil {
+			if stderrors.Is(err, database.ErrGiftCardNotFound) {
+				return nil, fmt.Errorf("%w: %v", errors.ErrNotFound, err)
+			}
+			return nil, fmt.Errorf("could not expire gift card: %w", err)
+		}
+		return h.giftCard(r, p)
+	})
+}
+
+func (h *Service) giftCard(r *http.Request, p httprouter.Params) (*model.GiftCard, error) {
+	card, err := h.store.GetGiftCard(r.Context(), p.ByName("code"))
+	if err != nil {
+		if stderrors.Is(err, database.ErrGiftCardNotFound) {
+			return nil, fmt.Errorf("%w: %v", errors.ErrNotFound, err)
+		}
+		return nil, fmt.Errorf("could not get gift card: %w", err)
+	}
+	return card, nil
+}
+
+// giftCardError maps a store error from redeeming a gift card to the error the
+// purchase returns.
+func giftCardError(err error) error {
+	switch {
+	case stderrors.Is(err, database.ErrGiftCardNotFound):
+		return fmt.Errorf("%w: %v", errors.ErrNotFound, err)
+	case stderrors.Is(err, database.ErrGiftCardExpired), stderrors.Is(err, database.ErrInsufficientBalance):
+		return fmt.Errorf("%w: %v", errors.ErrPaymentDeclined, err)
+	default:
+		return fmt.Errorf("could not redeem gift card: %w", err)
+	}
+}
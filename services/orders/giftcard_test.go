@@ -0,0 +1,42 @@
+//go:build !integration
+
+package orders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments/fake"
+	"github.com/ATMackay/checkout/services/auth"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// A customer reads a bearer gift card and their own store credit, but another
+// customer's store credit answers 404, like a missing card.
+func Test_GiftCardLookup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	cards := map[string]*model.GiftCard{
+		"GIFT":   {Code: "GIFT", Balance: decimal.NewFromInt(10), Currency: "USD"},
+		"MINE":   {Code: "MINE", Balance: decimal.NewFromInt(10), Currency: "USD", CustomerID: "customer"},
+		"THEIRS": {Code: "THEIRS", Balance: decimal.NewFromInt(10), Currency: "USD", CustomerID: "someone-else"},
+	}
+	db.EXPECT().GetGiftCard(gomock.Any(), gomock.Any()).DoAndReturn(func(_ any, code string) (*model.GiftCard, error) {
+		return cards[code], nil
+	}).Times(3)
+	db.EXPECT().GetGiftCardLedger(gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+	router := newPurchaseService(ctrl, db, fake.NewProvider()).RegisterHandlers()
+
+	for code, want := range map[string]int{"GIFT": http.StatusOK, "MINE": http.StatusOK, "THEIRS": http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodGet, GiftCardsEndPnt+"/"+code, nil)
+		req.Header.Set(auth.XAuthHeaderKey, testPassword)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		require.Equal(t, want, rr.Code, "%s: %s", code, rr.Body.String())
+	}
+}
@@ -8,8 +8,8 @@ import (
 	"github.com/ATMackay/checkout/errors"
 	"github.com/ATMackay/checkout/httpserver"
 	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/promotions"
 	"github.com/julienschmidt/httprouter"
-	"github.com/shopspring/decimal"
 )
 
 // ListItems godoc
@@ -57,6 +57,9 @@ func (h *Service) AddItems() httprouter.Handle {
 			if err := it.Validate(); err != nil {
 				return nil, fmt.Errorf("%w: item at index %d was invalid: %v", errors.ErrInvalidInput, i, err)
 			}
+			if it.Currency == "" {
+				it.Currency = model.DefaultCurrency
+			}
 		}
 
 		return h.store.UpsertItems(r.Context(), iReq.Items)
@@ -69,6 +72,7 @@ func (h *Service) AddItems() httprouter.Handle {
 // @Tags         inventory
 // @Produce      json
 // @Param        key   path      string                true  "Item SKU or Name"
+// @Param        currency  query  string               false "ISO 4217 currency to quote in (default: the item's currency)"
 // @Success      200   {object}  model.PriceResponse
 // @Failure      400   {object}  errors.JSONError
 // @Failure      404   {object}  errors.JSONError
@@ -78,8 +82,11 @@ func (h *Service) ItemPrice() httprouter.Handle {
 	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
 		ctx := r.Context()
 		nameOrSku := p.ByName("key")
+		currency, err := requestedCurrency(r)
+		if err != nil {
+			return nil, err
+		}
 		var dbItem *model.Item
-		var err error
 
 		if model.IsSKU(nameOrSku) {
 			dbItem, err = h.store.GetItemBySKU(ctx, nameOrSku)
@@ -93,10 +100,17 @@ func (h *Service) ItemPrice() httprouter.Handle {
 			return nil, fmt.Errorf("%w: item %s empty", errors.ErrNotFound, dbItem.SKU)
 		}
 
+		x, err := h.exchangeFor(ctx, []*model.Item{dbItem}, currency)
+		if err != nil {
+			return nil, err
+		}
+		price := x.convert([]*model.Item{dbItem})[0].Price
+
 		return &model.PriceResponse{
-			Items:             []*model.Item{{Name: dbItem.Name, SKU: dbItem.SKU, Price: dbItem.Price}},
-			TotalGross:        dbItem.Price.InexactFloat64(),
-			TotalWithDiscount: dbItem.Price.InexactFloat64(),
+			Items:             []*model.Item{{Name: dbItem.Name, SKU: dbItem.SKU, Price: price, Currency: x.quote}},
+			TotalGross:        price.InexactFloat64(),
+			TotalWithDiscount: price.InexactFloat64(),
+			Currency:          x.quote,
 		}, nil
 	})
 }
@@ -108,15 +122,22 @@ func (h *Service) ItemPrice() httprouter.Handle {
 // @Accept       json
 // @Produce      json
 // @Param        request  body     model.ItemsPriceRequest  true  "List of SKUs"
+// @Param        currency query    string                   false "ISO 4217 currency to quote in (default: the items' currency)"
 // @Success      200      {object} model.PriceResponse
 // @Failure      400      {object} errors.JSONError
 // @Failure      404      {object} errors.JSONError
+// @Failure      422      {object} errors.JSONError
 // @Failure      500      {object} errors.JSONError
 // @Router       /v1/inventory/items/price [post]
 func (h *Service) ItemsPrice() httprouter.Handle {
 	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
 		ctx := r.Context()
 
+		currency, err := requestedCurrency(r)
+		if err != nil {
+			return nil, err
+		}
+
 		var pReq model.ItemsPriceRequest
 		if err := json.NewDecoder(r.Body).Decode(&pReq); err != nil {
 			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
@@ -134,24 +155,59 @@ func (h *Service) ItemsPrice() httprouter.Handle {
 			return nil, fmt.Errorf("could not get items: %w", err)
 		}
 
-		resp := &model.PriceResponse{}
-		total := decimal.Zero
 		for _, it := range dbItems {
 			if it.InventoryQuantity < 1 {
 				return nil, fmt.Errorf("%w: item %s empty", errors.ErrNotFound, it.SKU)
 			}
-			resp.Items = append(resp.Items, it)
-			total = total.Add(it.Price)
 		}
 
-		promotions, err := h.promotionsEngine.ApplyPromotions(ctx, resp.Items)
+		x, err := h.exchangeFor(ctx, dbItems, currency)
 		if err != nil {
-			return nil, fmt.Errorf("could not apply promotion/deals: %w", err)
+			return nil, err
 		}
 
-		resp.Promotions = promotions
-		resp.TotalGross = total.InexactFloat64()
-		resp.TotalWithDiscount = total.InexactFloat64() - promotions.Deduction
+		// Price one unit per requested SKU, so repeats count towards tiers,
+		// bundles and quantity-based promotions just as they do at purchase.
+		bySKU := make(map[string]*model.Item, len(dbItems))
+		for _, it := range dbItems {
+			bySKU[it.SKU] = it
+		}
+		for _, sku := range pReq.SKUs {
+			if _, ok := bySKU[sku]; !ok {
+				return nil, fmt.Errorf("%w: item %s", errors.ErrNotFound, sku)
+			}
+		}
+		priced, err := h.priceBasket(ctx, pReq.SKUs, bySKU, x)
+		if err != nil {
+			return nil, err
+		}
+
+		// Quotes are anonymous, so per-customer coupon limits and promotions
+		// for first or repeat orders only apply at purchase.
+		basket := promotions.NewBasket(priced.promotable)
+		coupons, checked, err := h.couponPromotions(ctx, pReq.CouponCodes, basket, h.now())
+		if err != nil {
+			return nil, err
+		}
+		applied, err := h.promotionsEngine.ApplyPromotions(ctx, basket, coupons...)
+		if err != nil {
+			return nil, fmt.Errorf("could not apply promotion/deals: %w", err)
+		}
+		if _, err := appliedCoupons(checked, applied.Adjustments); err != nil {
+			return nil, err
+		}
+		applied.AddedItems = x.convert(applied.AddedItems)
+
+		total := priced.total()
+		resp := &model.PriceResponse{
+			Items:             priced.units,
+			Lines:             priced.lines,
+			Bundles:           priced.bundles,
+			Promotions:        applied,
+			TotalGross:        total.InexactFloat64(),
+			TotalWithDiscount: total.InexactFloat64() - applied.Deduction,
+			Currency:          x.quote,
+		}
 
 		return resp, nil
 	})
@@ -0,0 +1,203 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/errors"
+	"github.com/ATMackay/checkout/httpserver"
+	"github.com/ATMackay/checkout/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PurchaseLimits godoc
+// @Summary List purchase limits
+// @Description List the per-customer purchase limits set on SKUs.
+// @Tags limits
+// @Produce json
+// @Success 200 {array}  model.PurchaseLimit
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/limits [get]
+func (h *Service) PurchaseLimits() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		ls, err := h.store.ListPurchaseLimits(r.Context())
+		if err != nil {
+			return nil, fmt.Errorf("could not get purchase limits: %w", err)
+		}
+		return ls, nil
+	})
+}
+
+// SetPurchaseLimit godoc
+// @Summary Set a purchase limit
+// @Description Set how many units of a SKU one customer may buy per order and per rolling window. Zero disables a limit.
+// @Tags limits
+// @Accept json
+// @Produce json
+// @Param   sku      path    string                         true  "Item SKU"
+// @Param   request  body    model.SetPurchaseLimitRequest  true  "Limits"
+// @Success 200 {object} model.PurchaseLimit
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/limits/{sku} [put]
+func (h *Service) SetPurchaseLimit() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		sku := p.ByName("sku")
+		if !model.IsSKU(sku) {
+			return nil, fmt.Errorf("%w: invalid sku input '%s'", errors.ErrInvalidInput, sku)
+		}
+		var req model.SetPurchaseLimitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+		}
+		if req.MaxPerOrder < 0 || req.MaxPerWindow < 0 || req.WindowSeconds < 0 {
+			return nil, fmt.Errorf("%w: limits must not be negative", errors.ErrInvalidInput)
+		}
+		if (req.MaxPerWindow > 0) != (req.WindowSeconds > 0) {
+			return nil, fmt.Errorf("%w: max_per_window and window_seconds must be set together", errors.ErrInvalidInput)
+		}
+		l := &model.PurchaseLimit{
+			SKU:           sku,
+			MaxPerOrder:   req.MaxPerOrder,
+			MaxPerWindow:  req.MaxPerWindow,
+			WindowSeconds: req.WindowSeconds,
+		}
+		if err := h.store.SetPurchaseLimit(r.Context(), l); err != nil {
+			return nil, fmt.Errorf("could not set purchase limit: %w", err)
+		}
+		return l, nil
+	})
+}
+
+// DeletePurchaseLimit godoc
+// @Summary Remove a purchase limit
+// @Tags limits
+// @Param   sku  path  string  true  "Item SKU"
+// @Success 200
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/limits/{sku} [delete]
+func (h *Service) DeletePurchaseLimit() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		if err := h.store.DeletePurchaseLimit(r.Context(), p.ByName("sku")); err != nil {
+			if stderrors.Is(err, database.ErrLimitNotFound) {
+				return nil, fmt.Errorf("%w: %v", errors.ErrNotFound, err)
+			}
+			return nil, fmt.Errorf("could not delete purchase limit: %w", err)
+		}
+		return nil, nil
+	})
+}
+
+// checkOrderLimits rejects a purchase of skus that would take the customer
+// past any per-order limit on those SKUs. It returns the limits found, for
+// checkWindowLimits to check in the order transaction.
+func (h *Service) checkOrderLimits(ctx context.Context, skus []string) ([]*model.PurchaseLimit, error) {
+	limits, err := h.store.GetPurchaseLimits(ctx, skus)
+	if err != nil {
+		return nil, fmt.Errorf("could not get purchase limits: %w", err)
+	}
+	requested := requestedUnits(skus)
+	for _, l := range limits {
+		if l.MaxPerOrder > 0 && requested[l.SKU] > l.MaxPerOrder {
+			return nil, fmt.Errorf("%w: purchase limit exceeded: at most %d of item %s per order", errors.ErrUnprocessable, l.MaxPerOrder, l.SKU)
+		}
+	}
+	return limits, nil
+}
+
+// checkWindowLimits rejects a purchase of skus that would take the customer
+// past any rolling-window limit in limits. Like per-order limits, window
+// limits count the units the customer asked for on their orders in the
+// window, not units a promotion added free.
+//
+// It runs in the order transaction tx and first locks the customer's limit
+// row, so a concurrent purchase by the same customer waits for this order to
+// commit or roll back and then counts it: two racing purchases cannot both
+// pass.
+func checkWindowLimits(ctx context.Context, tx database.Database, customerID string, limits []*model.PurchaseLimit, skus []string, now time.Time) error {
+	// One history query covers every window: fetch from the start of the
+	// longest and filter per limit.
+	var longest time.Duration
+	for _, l := range limits {
+		if l.MaxPerWindow > 0 && l.Window() > longest {
+			longest = l.Window()
+		}
+	}
+	if longest == 0 {
+		return nil
+	}
+	if err := tx.LockPurchaseLimits(ctx, customerID); err != nil {
+		return fmt.Errorf("could not lock purchase limits: %w", err)
+	}
+	history, err := tx.GetOrdersSince(ctx, customerID, now.Add(-longest))
+	if err != nil {
+		return fmt.Errorf("could not get order history: %w", err)
+	}
+	requested := requestedUnits(skus)
+	for _, l := range limits {
+		if l.MaxPerWindow == 0 {
+			continue
+		}
+		since := now.Add(-l.Window())
+		bought := 0
+		for _, o := range history {
+			if o.CreatedAt.Before(since) {
+				continue
+			}
+			units, err := orderedUnits(o)
+			if err != nil {
+				return err
+			}
+			bought += units[l.SKU]
+		}
+		if bought+requested[l.SKU] > l.MaxPerWindow {
+			return fmt.Errorf("%w: purchase limit exceeded: at most %d of item %s per %s, %d already bought",
+				errors.ErrUnprocessable, l.MaxPerWindow, l.SKU, l.Window(), bought)
+		}
+	}
+	return nil
+}
+
+func requestedUnits(skus []string) map[string]int {
+	requested := make(map[string]int)
+	for _, sku := range skus {
+		requested[sku]++
+	}
+	return requested
+}
+
+// orderedUnits counts the units of each SKU the customer asked for on o: its
+// lines less the ones a promotion added. An order recorded before lines falls
+// back to its SKU list.
+func orderedUnits(o *model.Order) (map[string]int, error) {
+	lines, err := o.GetLines()
+	if err != nil {
+		return nil, err
+	}
+	if lines == nil {
+		skus, err := o.GetSKUList()
+		if err != nil {
+			return nil, err
+		}
+		return requestedUnits(skus), nil
+	}
+	units := make(map[string]int)
+	for _, l := range lines {
+		if !l.Promotional {
+			units[l.SKU] += l.Quantity
+		}
+	}
+	return units, nil
+}
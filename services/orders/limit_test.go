@@ -0,0 +1,118 @@
+//go:build !integration
+
+package orders
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments/fake"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func orderAt(t *testing.T, at time.Time, skus ...string) *model.Order {
+	t.Helper()
+	o := &model.Order{CreatedAt: at}
+	require.NoError(t, o.SetSKUList(skus))
+	return o
+}
+
+// A purchase over the per-order limit is refused with 422 before anything is
+// priced or charged.
+func Test_PurchaseLimitPerOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return([]*model.PurchaseLimit{{SKU: "120P90", MaxPerOrder: 1}}, nil)
+
+	rr := purchase(t, newPurchaseService(ctrl, db, payer), "120P90", "120P90")
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), "at most 1 of item 120P90 per order")
+	require.Empty(t, payer.Calls())
+}
+
+// Window limits count the customer's units bought within the window only,
+// read after taking the customer's limit lock.
+func Test_CheckWindowLimits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	now := time.Now().UTC()
+
+	limits := []*model.PurchaseLimit{{SKU: "120P90", MaxPerWindow: 3, WindowSeconds: 3600}}
+	history := []*model.Order{
+		orderAt(t, now.Add(-10*time.Minute), "120P90", "A304SD"),
+		orderAt(t, now.Add(-30*time.Minute), "120P90"),
+	}
+	gomock.InOrder(
+		db.EXPECT().LockPurchaseLimits(gomock.Any(), "customer").Return(nil),
+		db.EXPECT().GetOrdersSince(gomock.Any(), "customer", now.Add(-time.Hour)).Return(history, nil),
+	)
+	require.NoError(t, checkWindowLimits(context.Background(), db, "customer", limits, []string{"120P90"}, now))
+
+	gomock.InOrder(
+		db.EXPECT().LockPurchaseLimits(gomock.Any(), "customer").Return(nil),
+		db.EXPECT().GetOrdersSince(gomock.Any(), "customer", now.Add(-time.Hour)).Return(history, nil),
+	)
+	require.Error(t, checkWindowLimits(context.Background(), db, "customer", limits, []string{"120P90", "120P90"}, now))
+
+	// Per-order limits alone need neither the lock nor the history.
+	perOrder := []*model.PurchaseLimit{{SKU: "120P90", MaxPerOrder: 1}}
+	require.NoError(t, checkWindowLimits(context.Background(), db, "customer", perOrder, []string{"120P90"}, now))
+}
+
+// Window limits count the units the customer asked for, as per-order limits
+// do: a unit a promotion added free is on the order but does not count.
+func Test_CheckWindowLimitsSkipsPromotionalUnits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	now := time.Now().UTC()
+
+	limits := []*model.PurchaseLimit{{SKU: "120P90", MaxPerWindow: 2, WindowSeconds: 3600}}
+	o := orderAt(t, now.Add(-10*time.Minute), "120P90", "120P90")
+	require.NoError(t, o.SetLines([]model.OrderLine{
+		{SKU: "120P90", Quantity: 1, UnitPrice: decimal.NewFromInt(50)},
+		{SKU: "120P90", Quantity: 1, UnitPrice: decimal.Zero, Promotional: true},
+	}))
+	db.EXPECT().LockPurchaseLimits(gomock.Any(), "customer").Return(nil).Times(2)
+	db.EXPECT().GetOrdersSince(gomock.Any(), "customer", now.Add(-time.Hour)).Return([]*model.Order{o}, nil).Times(2)
+
+	require.NoError(t, checkWindowLimits(context.Background(), db, "customer", limits, []string{"120P90"}, now))
+	require.Error(t, checkWindowLimits(context.Background(), db, "customer", limits, []string{"120P90", "120P90"}, now))
+}
+
+// The window check runs in the order transaction after the customer's limit
+// lock, so a purchase that raced another and lost sees the winner's order,
+// is refused with 422 and its authorization is voided.
+func Test_PurchaseLimitWindowConcurrent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	limits := []*model.PurchaseLimit{{SKU: "120P90", MaxPerWindow: 1, WindowSeconds: 3600}}
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(limits, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(testInventory(), nil)
+	db.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(database.Database) error) error { return fn(db) })
+	gomock.InOrder(
+		db.EXPECT().LockPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil),
+		// The concurrent purchase committed while this one waited on the lock.
+		db.EXPECT().GetOrdersSince(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return([]*model.Order{orderAt(t, time.Now().UTC().Add(-time.Second), "120P90")}, nil),
+	)
+
+	rr := purchase(t, newPurchaseService(ctrl, db, payer), "120P90")
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), "at most 1 of item 120P90 per 1h0m0s, 1 already bought")
+
+	calls := payer.Calls()
+	require.Len(t, calls, 2)
+	require.Equal(t, fake.Void, calls[1].Op)
+}
@@ -0,0 +1,151 @@
+package orders
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/errors"
+	"github.com/ATMackay/checkout/event"
+	"github.com/ATMackay/checkout/fx"
+	"github.com/ATMackay/checkout/httpserver"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/services/auth"
+	"github.com/julienschmidt/httprouter"
+	"github.com/shopspring/decimal"
+)
+
+// LoyaltyAdjustmentID is the ID of the adjustment redeemed loyalty points are
+// recorded as.
+const LoyaltyAdjustmentID = "loyalty"
+
+// Loyalty godoc
+// @Summary Get loyalty points
+// @Description The authenticated customer's loyalty points balance and ledger
+// @Tags orders
+// @Produce json
+// @Success 200 {object} model.LoyaltyAccount
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/loyalty [get]
+func (h *Service) Loyalty() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		ctx := r.Context()
+		customerID, ok := auth.UserID(ctx)
+		if !ok {
+			return nil, fmt.Errorf("%w", errors.ErrInvalidInput)
+		}
+		a, err := h.store.GetLoyaltyAccount(ctx, customerID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get loyalty account: %w", err)
+		}
+		if a.Ledger, err = h.store.GetLoyaltyLedger(ctx, customerID); err != nil {
+			return nil, fmt.Errorf("could not get loyalty ledger: %w", err)
+		}
+		return a, nil
+	})
+}
+
+// NewLoyaltyHandler returns the relay hook for orders.created events, crediting
+// the customer with the points program says the order earned. The store
+// ignores a second earning for the same order, so a redelivered event is
+// harmless.
+func NewLoyaltyHandler(store database.LoyaltyStore, program model.LoyaltyProgram) Handler {
+	return func(ctx context.Context, ev *event.Event) error {
+		var o model.Order
+		if err := ev.DecodeData(&o); err != nil {
+			return err
+		}
+		points, err := program.PointsFor(&o)
+		if err != nil {
+			return err
+		}
+		if points < 1 || o.CustomerID == "" {
+			return nil
+		}
+		if err := store.AdjustLoyalty(ctx, &model.LoyaltyEntry{
+			CustomerID:     o.CustomerID,
+			Points:         points,
+			Reason:         model.LoyaltyEarned,
+			OrderReference: o.Reference,
+		}); err != nil {
+			return fmt.Errorf("credit loyalty points for order %s: %w", o.Reference, err)
+		}
+		slog.Debug("credited loyalty points", "order_reference", o.Reference, "customer_id", o.CustomerID, "points", points)
+		LoyaltyPointsEarned.Add(float64(points))
+		return nil
+	}
+}
+
+// loyaltyAdjustment checks the customer can redeem points against an order of
+// price, priced with lines and discounted by adjustments, and returns the
+// discount the points buy in x's quote currency. It is shared out over the
+// paid lines in proportion to what is left to pay on each. The balance is only
+// read here; the debit is made in the order transaction.
+func (h *Service) loyaltyAdjustment(ctx context.Context, customerID string, points int64, lines []model.OrderLine, adjustments []model.Adjustment, price decimal.Decimal, x *exchange) (*model.Adjustment, error) {
+	if points < 0 {
+		return nil, fmt.Errorf("%w: loyalty_points must not be negative", errors.ErrInvalidInput)
+	}
+	if !h.loyalty.Redeemable() {
+		return nil, fmt.Errorf("%w: loyalty points cannot be redeemed", errors.ErrInvalidInput)
+	}
+	a, err := h.store.GetLoyaltyAccount(ctx, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get loyalty account: %w", err)
+	}
+	if a.Balance < points {
+		return nil, loyaltyError(fmt.Errorf("%w: %d requested, %d held", database.ErrInsufficientPoints, points, a.Balance))
+	}
+	discount := fx.Convert(h.loyalty.Value(points), x.rate)
+	// Points too few to be worth a cent would be debited for nothing.
+	if !discount.IsPositive() {
+		return nil, fmt.Errorf("%w: %d loyalty points are worth nothing", errors.ErrUnprocessable, points)
+	}
+	if discount.GreaterThan(price) {
+		return nil, fmt.Errorf("%w: %d loyalty points are worth %s, more than the order's %s", errors.ErrUnprocessable, points, discount.StringFixed(2), price.StringFixed(2))
+	}
+
+	// Paid lines are merged per SKU, so each SKU has one.
+	var paid []model.OrderLine
+	due := make(map[string]decimal.Decimal)
+	for _, l := range lines {
+		if l.Promotional {
+			continue
+		}
+		paid = append(paid, l)
+		due[l.SKU] = l.UnitPrice.Mul(decimal.NewFromInt(int64(l.Quantity)))
+	}
+	for _, adj := range adjustments {
+		for _, l := range adj.Lines {
+			due[l.SKU] = due[l.SKU].Sub(decimal.NewFromFloat(l.Amount))
+		}
+	}
+	adj := &model.Adjustment{
+		PromotionID: LoyaltyAdjustmentID,
+		Reason:      fmt.Sprintf("%d loyalty points", points),
+		Amount:      discount.InexactFloat64(),
+	}
+	left := discount
+	for i, l := range paid {
+		share := left
+		// A free order has no price to prorate over; the last line takes it all.
+		if i < len(paid)-1 && !price.IsZero() {
+			share = discount.Mul(due[l.SKU]).Div(price).Round(2)
+		}
+		left = left.Sub(share)
+		adj.Lines = append(adj.Lines, model.AdjustmentLine{SKU: l.SKU, Quantity: l.Quantity, Amount: share.InexactFloat64()})
+	}
+	return adj, nil
+}
+
+func loyaltyError(err error) error {
+	if stderrors.Is(err, database.ErrInsufficientPoints) {
+		return fmt.Errorf("%w: %v", errors.ErrUnprocessable, err)
+	}
+	return fmt.Errorf("could not redeem loyalty points: %w", err)
+}
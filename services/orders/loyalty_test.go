@@ -0,0 +1,170 @@
+//go:build !integration
+
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/event"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments/fake"
+	"github.com/ATMackay/checkout/services/auth"
+	ordersmock "github.com/ATMackay/checkout/services/orders/mock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// testLoyalty earns a point per dollar and takes a cent off per point.
+func testLoyalty() model.LoyaltyProgram {
+	return model.LoyaltyProgram{EarnRate: decimal.NewFromInt(1), PointValue: decimal.RequireFromString("0.01")}
+}
+
+func newLoyaltyService(ctrl *gomock.Controller, db *mock.MockDatabase, payer *fake.Provider, program model.LoyaltyProgram) *Service {
+	noStoredPromotions(db)
+	noBundles(db)
+	authn := auth.NewPasswordAuthenticator(map[string]string{testPassword: "customer"})
+	return NewService(db, ordersmock.NewMockRelayer(ctrl), authn, WithPaymentProvider(payer), WithLoyalty(program))
+}
+
+// Redeemed points come off the order as the loyalty adjustment and are
+// debited in the order transaction.
+func Test_PurchaseRedeemsLoyaltyPoints(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(couponInventory(), nil)
+	db.EXPECT().GetLoyaltyAccount(gomock.Any(), "customer").Return(&model.LoyaltyAccount{CustomerID: "customer", Balance: 800}, nil)
+	db.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(database.Database) error) error { return fn(db) })
+	db.EXPECT().UpsertItems(gomock.Any(), gomock.Any()).Return(nil, nil)
+	var order *model.Order
+	db.EXPECT().AddOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, o *model.Order) error {
+		order = o
+		return nil
+	})
+	var debit *model.LoyaltyEntry
+	db.EXPECT().AdjustLoyalty(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, e *model.LoyaltyEntry) error {
+		debit = e
+		return nil
+	})
+	db.EXPECT().AddOutboxItems(gomock.Any(), gomock.Any()).Return(nil)
+
+	rr := purchaseWith(t, newLoyaltyService(ctrl, db, payer, testLoyalty()), &model.PurchaseItemsRequest{SKUs: []string{"120P90"}, LoyaltyPoints: 500})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var resp model.PurchaseItemsResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 5.0, resp.LoyaltyDiscount)
+	assert.Equal(t, "45", order.Price.String())
+	assert.Equal(t, "5", order.Discount.String())
+	assert.EqualValues(t, 500, order.LoyaltyPoints)
+	adjustments, err := order.GetAdjustments()
+	require.NoError(t, err)
+	assert.Equal(t, []model.Adjustment{{PromotionID: LoyaltyAdjustmentID, Reason: "500 loyalty points", Amount: 5,
+		Lines: []model.AdjustmentLine{{SKU: "120P90", Quantity: 1, Amount: 5}}}}, adjustments)
+	assert.Equal(t, &model.LoyaltyEntry{CustomerID: "customer", Points: -500, Reason: model.LoyaltyRedeemed, OrderReference: order.Reference}, debit)
+	calls := payer.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "45", calls[0].Amount.String())
+}
+
+// A redemption the program, balance or order cannot cover is refused before
+// anything is charged or written.
+func Test_PurchaseLoyaltyRejected(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		program model.LoyaltyProgram
+		points  int64
+		balance int64
+		code    int
+		reason  string
+	}{
+		{"negative", testLoyalty(), -1, 0, http.StatusBadRequest, "must not be negative"},
+		{"disabled", model.LoyaltyProgram{}, 100, 0, http.StatusBadRequest, "cannot be redeemed"},
+		{"insufficient", testLoyalty(), 500, 499, http.StatusUnprocessableEntity, "insufficient loyalty points"},
+		{"worth more than the order", testLoyalty(), 6000, 6000, http.StatusUnprocessableEntity, "more than the order's 50.00"},
+		{"worth nothing", model.LoyaltyProgram{PointValue: decimal.RequireFromString("0.001")}, 1, 1, http.StatusUnprocessableEntity, "worth nothing"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			db := mock.NewMockDatabase(ctrl)
+			payer := fake.NewProvider()
+			db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+			db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(couponInventory(), nil)
+			db.EXPECT().GetLoyaltyAccount(gomock.Any(), "customer").Return(&model.LoyaltyAccount{CustomerID: "customer", Balance: tc.balance}, nil).MaxTimes(1)
+
+			rr := purchaseWith(t, newLoyaltyService(ctrl, db, payer, tc.program), &model.PurchaseItemsRequest{SKUs: []string{"120P90"}, LoyaltyPoints: tc.points})
+			require.Equal(t, tc.code, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), tc.reason)
+			assert.Empty(t, payer.Calls())
+		})
+	}
+}
+
+// Points worth nothing against a free order are refused, rather than prorated
+// over a zero price.
+func Test_PurchaseLoyaltyFreeOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+	free := []*model.Item{{Name: "Sticker", SKU: "STICK1", Price: decimal.Zero, Currency: "USD", InventoryQuantity: 10}}
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(free, nil)
+	db.EXPECT().GetLoyaltyAccount(gomock.Any(), "customer").Return(&model.LoyaltyAccount{CustomerID: "customer", Balance: 1}, nil)
+
+	program := model.LoyaltyProgram{PointValue: decimal.RequireFromString("0.001")}
+	rr := purchaseWith(t, newLoyaltyService(ctrl, db, payer, program), &model.PurchaseItemsRequest{SKUs: []string{"STICK1"}, LoyaltyPoints: 1})
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	assert.Empty(t, payer.Calls())
+}
+
+// An order earns points on what was paid for its lines in the catalog
+// currency, not on units a promotion added free.
+func Test_LoyaltyHandlerCreditsOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockLoyaltyStore(ctrl)
+
+	// 2 x 25.00 and a free unit, less 5.00 off, charged in EUR at 0.5: 90.00
+	// in the catalog currency.
+	o := &model.Order{Reference: "ref", CustomerID: "customer", Discount: decimal.NewFromInt(5), FXRate: decimal.RequireFromString("0.5")}
+	require.NoError(t, o.SetLines([]model.OrderLine{
+		{SKU: "120P90", Quantity: 2, UnitPrice: decimal.NewFromInt(25)},
+		{SKU: "234234", Quantity: 1, UnitPrice: decimal.Zero, Promotional: true},
+	}))
+	store.EXPECT().AdjustLoyalty(gomock.Any(), &model.LoyaltyEntry{CustomerID: "customer", Points: 90, Reason: model.LoyaltyEarned, OrderReference: "ref"}).Return(nil)
+
+	require.NoError(t, NewLoyaltyHandler(store, testLoyalty())(context.Background(), event.New(event.TopicOrderCreated, o.Reference, o)))
+
+	// Without an earn rate nothing is credited.
+	require.NoError(t, NewLoyaltyHandler(store, model.LoyaltyProgram{})(context.Background(), event.New(event.TopicOrderCreated, o.Reference, o)))
+}
+
+// The loyalty endpoint shows the caller's balance and ledger.
+func Test_Loyalty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	db.EXPECT().GetLoyaltyAccount(gomock.Any(), "customer").Return(&model.LoyaltyAccount{CustomerID: "customer", Balance: 90}, nil)
+	db.EXPECT().GetLoyaltyLedger(gomock.Any(), "customer").Return([]model.LoyaltyEntry{{CustomerID: "customer", Points: 90, Reason: model.LoyaltyEarned, OrderReference: "ref"}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, LoyaltyEndPnt, nil)
+	req.Header.Set(auth.XAuthHeaderKey, testPassword)
+	rr := httptest.NewRecorder()
+	newLoyaltyService(ctrl, db, fake.NewProvider(), testLoyalty()).RegisterHandlers().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var a model.LoyaltyAccount
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &a))
+	assert.EqualValues(t, 90, a.Balance)
+	require.Len(t, a.Ledger, 1)
+	assert.Equal(t, "ref", a.Ledger[0].OrderReference)
+}
@@ -0,0 +1,140 @@
+package orders
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// PromotionMissingItems is the number of SKUs each promotion names that the
+	// catalog does not hold, as checked at startup. Such a promotion cannot
+	// apply until the items are added.
+	PromotionMissingItems = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "promotion_missing_items",
+			Help: "Number of SKUs a promotion references that are missing from inventory",
+		},
+		[]string{"promotion_id"},
+	)
+)
+
+var (
+	// PromotionRedemptions, PromotionDiscountGranted and PromotionUnitsGranted
+	// count what each promotion gave in committed purchases. Discounts are in
+	// the catalog currency.
+	PromotionRedemptions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "promotion_redemptions_total",
+			Help: "Number of orders a promotion applied to",
+		},
+		[]string{"promotion_id"},
+	)
+	PromotionDiscountGranted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "promotion_discount_granted_total",
+			Help: "Total discount a promotion granted, in the catalog currency",
+		},
+		[]string{"promotion_id"},
+	)
+	PromotionUnitsGranted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "promotion_units_granted_total",
+			Help: "Number of units a promotion discounted or gave free",
+		},
+		[]string{"promotion_id"},
+	)
+	// PromotionBudgetExhausted counts the promotions deactivated for reaching
+	// their budget.
+	PromotionBudgetExhausted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "promotion_budget_exhausted_total",
+			Help: "Number of times a promotion was deactivated for exhausting its budget",
+		},
+		[]string{"promotion_id"},
+	)
+)
+
+var (
+	// LoyaltyPointsEarned and LoyaltyPointsRedeemed count the loyalty points
+	// credited for orders and spent on them.
+	LoyaltyPointsEarned = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "loyalty_points_earned_total",
+			Help: "Number of loyalty points credited for orders",
+		},
+	)
+	LoyaltyPointsRedeemed = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "loyalty_points_redeemed_total",
+			Help: "Number of loyalty points redeemed against orders",
+		},
+	)
+)
+
+var (
+	// OutboxPublishFailures counts failed attempts to publish outbox rows, and
+	// OutboxDeadLettered the rows given up on, per topic.
+	OutboxPublishFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_publish_failures_total",
+			Help: "Number of failed attempts to publish an outbox row",
+		},
+		[]string{"topic"},
+	)
+	OutboxDeadLettered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_dead_lettered_total",
+			Help: "Number of outbox rows dead-lettered after exhausting their attempts",
+		},
+		[]string{"topic"},
+	)
+	// OutboxPublished counts the outbox rows published (or handled
+	// in-process) per topic, and OutboxPublishLatency how long each waited in
+	// the outbox, from being enqueued to being published.
+	OutboxPublished = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_published_total",
+			Help: "Number of outbox rows published",
+		},
+		[]string{"topic"},
+	)
+	OutboxPublishLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "outbox_publish_latency_seconds",
+			Help:    "Time from an outbox row being enqueued to it being published",
+			Buckets: prometheus.ExponentialBuckets(0.005, 4, 10), // 5ms to ~22m
+		},
+		[]string{"topic"},
+	)
+	// OutboxBacklogRows, OutboxOldestUnpublishedAge and OutboxDeadRows describe
+	// the outbox as of the relay's last drain: the rows waiting to be
+	// published, how long the oldest of them has waited, and the rows
+	// dead-lettered.
+	OutboxBacklogRows = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_backlog_rows",
+			Help: "Number of outbox rows waiting to be published",
+		},
+	)
+	OutboxOldestUnpublishedAge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_oldest_unpublished_age_seconds",
+			Help: "Age of the oldest outbox row waiting to be published, or 0 if none are",
+		},
+	)
+	OutboxDeadRows = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_dead_rows",
+			Help: "Number of dead-lettered outbox rows",
+		},
+	)
+	// OutboxRowsPurged counts outbox rows removed by the retention purge,
+	// labelled by whether they were deleted or archived.
+	OutboxRowsPurged = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_rows_purged_total",
+			Help: "Number of published and delivered outbox rows removed after the retention period",
+		},
+		[]string{"action"},
+	)
+)
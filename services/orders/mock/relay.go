@@ -40,6 +40,20 @@ func (m *MockRelayer) EXPECT() *MockRelayerMockRecorder {
 	return m.recorder
 }
 
+// CheckBacklog mocks base method.
+func (m *MockRelayer) CheckBacklog(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckBacklog", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckBacklog indicates an expected call of CheckBacklog.
+func (mr *MockRelayerMockRecorder) CheckBacklog(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckBacklog", reflect.TypeOf((*MockRelayer)(nil).CheckBacklog), ctx)
+}
+
 // Ping mocks base method.
 func (m *MockRelayer) Ping(ctx context.Context) error {
 	m.ctrl.T.Helper()
@@ -1,10 +1,36 @@
 package orders
 
 import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/errors"
 	"github.com/ATMackay/checkout/event"
+	"github.com/ATMackay/checkout/httpserver"
 	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/services/auth"
+	"github.com/julienschmidt/httprouter"
 )
 
+// defaultOutboxListLimit caps an outbox listing, or audit log read, that sets
+// no limit.
+const defaultOutboxListLimit = 100
+
+// OutboxEvent is an outbox row with the event it carries decoded. A row that
+// cannot be decoded has no Event, and DecodeError says why.
+type OutboxEvent struct {
+	Item        *model.OutboxItem `json:"item"`
+	Event       *event.Event      `json:"event,omitempty"`
+	DecodeError string            `json:"decode_error,omitempty"`
+}
+
 // newOutboxItem maps an event onto an outbox row. It lives here, in the domain
 // service, rather than in model or event so that neither of those packages has
 // to know about the other: model stays free of an event import, and event stays
@@ -27,3 +53,193 @@ func newOutboxItem(ev *event.Event) (*model.OutboxItem, error) {
 		OccurredAt:   ev.OccurredAt,
 	}, nil
 }
+
+// Outbox godoc
+// @Summary List outbox rows
+// @Description List outbox rows, oldest first, with their publish attempts. state selects rows pending publication, published but undelivered, delivered, or dead (given up on by the relay).
+// @Tags outbox
+// @Produce json
+// @Param   state  query   string  false  "Only rows that are pending, published, delivered or dead"
+// @Param   topic  query   string  false  "Only rows on this topic; repeat for several"
+// @Param   since  query   string  false  "Only rows enqueued at or after this time, RFC 3339"
+// @Param   until  query   string  false  "Only rows enqueued before this time, RFC 3339"
+// @Param   limit  query   int     false  "Maximum rows returned (default 100)"
+// @Success 200 {array}  model.OutboxItem
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/outbox [get]
+func (h *Service) Outbox() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		v := r.URL.Query()
+		var since, until time.Time
+		for _, t := range []struct {
+			name string
+			v    *time.Time
+		}{{"since", &since}, {"until", &until}} {
+			if s := v.Get(t.name); s != "" {
+				var err error
+				if *t.v, err = time.Parse(time.RFC3339, s); err != nil {
+					return nil, fmt.Errorf("%w: invalid %s '%s': want RFC 3339", errors.ErrInvalidInput, t.name, s)
+				}
+			}
+		}
+		limit := defaultOutboxListLimit
+		if s := v.Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: invalid limit '%s'", errors.ErrInvalidInput, s)
+			}
+			limit = n
+		}
+		q, err := NewOutboxQuery(v.Get("state"), v["topic"], since, until, limit)
+		if err != nil {
+			return nil, err
+		}
+		items, err := h.store.GetOutboxItems(r.Context(), q)
+		if err != nil {
+			return nil, fmt.Errorf("could not get outbox rows: %w", err)
+		}
+		return items, nil
+	})
+}
+
+// NewOutboxQuery builds the query listing at most limit outbox rows in state
+// ("" for any, pending, published, delivered or dead), on topics (any if
+// empty), enqueued from since and before until (open if zero).
+func NewOutboxQuery(state string, topics []string, since, until time.Time, limit int) (*database.OutboxQuery, error) {
+	q := &database.OutboxQuery{Topics: topics, CreatedAfter: since, CreatedBefore: until, Limit: limit}
+	switch state {
+	case "":
+	case "pending":
+		q.OnlyUnpublished, q.OnlyLive = true, true
+	case "published":
+		q.OnlyPublished, q.OnlyUndelivered = true, true
+	case "delivered":
+		q.OnlyDelivered = true
+	case "dead":
+		q.OnlyDead = true
+	default:
+		return nil, fmt.Errorf("%w: invalid state '%s': want pending, published, delivered or dead", errors.ErrInvalidInput, state)
+	}
+	return q, nil
+}
+
+// OutboxEventByID godoc
+// @Summary Inspect an outbox event
+// @Description An outbox row with the event it carries decoded.
+// @Tags outbox
+// @Produce json
+// @Param   id  path    string  true  "Event ID"
+// @Success 200 {object} OutboxEvent
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/outbox/events/{id} [get]
+func (h *Service) OutboxEventByID() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		return InspectOutboxEvent(r.Context(), h.store, p.ByName("id"))
+	})
+}
+
+// InspectOutboxEvent reads the outbox row carrying the event eventID and
+// decodes the event.
+func InspectOutboxEvent(ctx context.Context, store database.OutboxStore, eventID string) (*OutboxEvent, error) {
+	item, err := store.GetOutboxItem(ctx, eventID)
+	if err != nil {
+		return nil, outboxError(err)
+	}
+	e := &OutboxEvent{Item: item}
+	if e.Event, err = event.Decode(item.Topic, item.PartitionKey, item.Data); err != nil {
+		e.DecodeError = err.Error()
+	}
+	return e, nil
+}
+
+// ResetOutbox godoc
+// @Summary Reset outbox events
+// @Description Clear published_at (republish) or delivered_at on the outbox rows carrying the given events. Republishing also clears their failed attempts and dead-letter state. The reset is recorded in the outbox audit log.
+// @Tags outbox
+// @Accept json
+// @Produce json
+// @Param   request  body    model.OutboxResetRequest  true  "Events and milestones to reset"
+// @Success 200 {object} model.OutboxAuditEntry
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/outbox/reset [post]
+func (h *Service) ResetOutbox() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		ctx := r.Context()
+		actor, ok := auth.UserID(ctx)
+		if !ok {
+			return nil, fmt.Errorf("%w", errors.ErrInvalidInput)
+		}
+		var req model.OutboxResetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+		}
+		return ResetOutboxEvents(ctx, h.store, actor, &req)
+	})
+}
+
+// ResetOutboxEvents makes the reset req asks for on behalf of actor and
+// returns its audit log entry.
+func ResetOutboxEvents(ctx context.Context, store database.OutboxStore, actor string, req *model.OutboxResetRequest) (*model.OutboxAuditEntry, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+	}
+	entry := &model.OutboxAuditEntry{
+		Actor:     actor,
+		EventIDs:  req.EventIDs,
+		Published: req.Published,
+		Delivered: req.Delivered,
+		Reason:    req.Reason,
+	}
+	if err := store.ResetOutboxItems(ctx, entry); err != nil {
+		return nil, fmt.Errorf("could not reset outbox events: %w", err)
+	}
+	slog.Info("reset outbox events", "actor", actor, "events", len(entry.EventIDs), "affected", entry.Affected,
+		"published", entry.Published, "delivered", entry.Delivered, "reason", entry.Reason)
+	return entry, nil
+}
+
+// OutboxAudit godoc
+// @Summary Outbox audit log
+// @Description The latest outbox resets, newest first.
+// @Tags outbox
+// @Produce json
+// @Param   limit  query   int  false  "Maximum entries returned (default 100)"
+// @Success 200 {array}  model.OutboxAuditEntry
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/outbox/audit [get]
+func (h *Service) OutboxAudit() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		limit := defaultOutboxListLimit
+		if s := r.URL.Query().Get("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: invalid limit '%s'", errors.ErrInvalidInput, s)
+			}
+			limit = n
+		}
+		entries, err := h.store.GetOutboxAudit(r.Context(), limit)
+		if err != nil {
+			return nil, fmt.Errorf("could not get outbox audit log: %w", err)
+		}
+		return entries, nil
+	})
+}
+
+func outboxError(err error) error {
+	if stderrors.Is(err, database.ErrOutboxItemNotFound) {
+		return fmt.Errorf("%w: %v", errors.ErrNotFound, err)
+	}
+	return fmt.Errorf("could not get outbox row: %w", err)
+}
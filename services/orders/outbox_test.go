@@ -0,0 +1,130 @@
+//go:build !integration
+
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments/fake"
+	"github.com/ATMackay/checkout/services/auth"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// The outbox listing maps its state, topic and time filters onto the store
+// query.
+func Test_OutboxAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	router := newPurchaseService(ctrl, db, fake.NewProvider()).RegisterHandlers()
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, OutboxEndPnt+query, nil)
+		req.Header.Set(auth.XAuthHeaderKey, testAdminPassword)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	since := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	for query, want := range map[string]*database.OutboxQuery{
+		"":                    {Limit: defaultOutboxListLimit},
+		"?state=pending":      {OnlyUnpublished: true, OnlyLive: true, Limit: defaultOutboxListLimit},
+		"?state=dead&limit=5": {OnlyDead: true, Limit: 5},
+		"?state=published&topic=orders.created&topic=payments.capture&since=2026-05-01T00:00:00Z": {
+			OnlyPublished: true, OnlyUndelivered: true, Topics: []string{"orders.created", "payments.capture"},
+			CreatedAfter: since, Limit: defaultOutboxListLimit,
+		},
+		"?state=delivered&until=2026-05-01T00:00:00Z": {OnlyDelivered: true, CreatedBefore: since, Limit: defaultOutboxListLimit},
+	} {
+		db.EXPECT().GetOutboxItems(gomock.Any(), want).Return([]*model.OutboxItem{{ID: 1, Attempts: 12, LastError: "broker down"}}, nil)
+		rr := get(query)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), `"last_error":"broker down"`)
+	}
+
+	require.Equal(t, http.StatusBadRequest, get("?state=lost").Code)
+	require.Equal(t, http.StatusBadRequest, get("?limit=0").Code)
+	require.Equal(t, http.StatusBadRequest, get("?since=yesterday").Code)
+}
+
+// An outbox event is shown with its payload decoded, or why it cannot be.
+func Test_OutboxInspect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	router := newPurchaseService(ctrl, db, fake.NewProvider()).RegisterHandlers()
+	get := func(id string) (*httptest.ResponseRecorder, OutboxEvent) {
+		req := httptest.NewRequest(http.MethodGet, OutboxEndPnt+OutboxEventsEndPnt+"/"+id, nil)
+		req.Header.Set(auth.XAuthHeaderKey, testAdminPassword)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var e OutboxEvent
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &e))
+		}
+		return rr, e
+	}
+
+	item := testItem(t, 1, "ref-1")
+	poison := testItem(t, 2, "ref-2")
+	poison.Data = []byte("not an envelope")
+	db.EXPECT().GetOutboxItem(gomock.Any(), item.EventID).Return(item, nil)
+	db.EXPECT().GetOutboxItem(gomock.Any(), poison.EventID).Return(poison, nil)
+	db.EXPECT().GetOutboxItem(gomock.Any(), "nope").Return(nil, database.ErrOutboxItemNotFound)
+
+	rr, e := get(item.EventID)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, item.EventID, e.Event.ID)
+	require.Equal(t, map[string]any{"ref": "ref-1"}, e.Event.Data)
+
+	rr, e = get(poison.EventID)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Nil(t, e.Event)
+	require.Contains(t, e.DecodeError, "unmarshal event envelope")
+
+	rr, _ = get("nope")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+// A reset is made for the authenticated user and returned as its audit entry.
+func Test_OutboxReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	router := newPurchaseService(ctrl, db, fake.NewProvider()).RegisterHandlers()
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(auth.XAuthHeaderKey, testAdminPassword)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	db.EXPECT().ResetOutboxItems(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, e *model.OutboxAuditEntry) error {
+		require.Equal(t, "admin", e.Actor)
+		require.Equal(t, []string{"e1", "e2"}, e.EventIDs)
+		require.True(t, e.Published)
+		require.False(t, e.Delivered)
+		e.Affected = 2
+		return nil
+	})
+	rr := do(http.MethodPost, OutboxEndPnt+ResetEndPnt, `{"event_ids":["e1","e2"],"published":true,"reason":"consumer bug"}`)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), `"affected":2`)
+
+	for _, body := range []string{`{"event_ids":[],"published":true}`, `{"event_ids":["e1"]}`, `{`} {
+		require.Equal(t, http.StatusBadRequest, do(http.MethodPost, OutboxEndPnt+ResetEndPnt, body).Code, body)
+	}
+
+	db.EXPECT().GetOutboxAudit(gomock.Any(), 10).Return([]*model.OutboxAuditEntry{{ID: 1, Actor: "admin", Affected: 2}}, nil)
+	rr = do(http.MethodGet, OutboxEndPnt+AuditEndPnt+"?limit=10", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), `"actor":"admin"`)
+}
@@ -0,0 +1,60 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/event"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments"
+)
+
+// NewCaptureHandler returns the relay Handler for payments.capture events. The
+// purchase handler enqueues the event in the order transaction, so a capture is
+// attempted only for an order that committed, and is retried by the relay until
+// it succeeds or is dead-lettered (see NewCaptureFailedHandler). Both steps are idempotent — the provider ignores a repeat
+// capture and the status update is a plain overwrite — so a redelivered event
+// is harmless.
+func NewCaptureHandler(store database.OrderStore, provider payments.Provider) Handler {
+	return func(ctx context.Context, ev *event.Event) error {
+		var c model.PaymentCapture
+		if err := ev.DecodeData(&c); err != nil {
+			return err
+		}
+		if err := provider.Capture(ctx, c.AuthorizationID, c.Amount); err != nil {
+			return fmt.Errorf("capture payment for order %s: %w", c.OrderReference, err)
+		}
+		return store.SetPaymentStatus(ctx, c.OrderReference, model.PaymentCaptured)
+	}
+}
+
+// NewCaptureFailedHandler returns the relay dead-letter Handler for
+// payments.capture events (WithDeadLetter). A capture the relay gave up on
+// leaves the order unpaid, so its payment status moves from authorized to
+// failed rather than staying authorized forever.
+func NewCaptureFailedHandler(store database.OrderStore) Handler {
+	return func(ctx context.Context, ev *event.Event) error {
+		var c model.PaymentCapture
+		if err := ev.DecodeData(&c); err != nil {
+			return err
+		}
+		return store.SetPaymentStatus(ctx, c.OrderReference, model.PaymentFailed)
+	}
+}
+
+// NewRefundHandler returns the relay Handler for payments.refund events. The
+// provider deduplicates on the refund reference, so a redelivered event does
+// not pay twice; the status update is a plain overwrite.
+func NewRefundHandler(store database.RefundStore, provider payments.Provider) Handler {
+	return func(ctx context.Context, ev *event.Event) error {
+		var rf model.PaymentRefund
+		if err := ev.DecodeData(&rf); err != nil {
+			return err
+		}
+		if err := provider.Refund(ctx, rf.AuthorizationID, rf.RefundReference, rf.Amount); err != nil {
+			return fmt.Errorf("refund payment for order %s: %w", rf.OrderReference, err)
+		}
+		return store.SetRefundStatus(ctx, rf.RefundReference, model.RefundCompleted)
+	}
+}
@@ -0,0 +1,88 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	srverrors "github.com/ATMackay/checkout/errors"
+	"github.com/ATMackay/checkout/fx"
+	"github.com/ATMackay/checkout/model"
+	"github.com/shopspring/decimal"
+)
+
+// CurrencyParam is the query parameter selecting the currency a price or
+// purchase is quoted in. Omitting it quotes in the catalog currency.
+const CurrencyParam = "currency"
+
+// exchange describes how a basket priced in the catalog currency (base) is
+// quoted in the customer's currency (quote).
+type exchange struct {
+	base  string
+	quote string
+	rate  decimal.Decimal
+}
+
+// requestedCurrency returns the validated ?currency= parameter, or "" when the
+// caller did not ask for one.
+func requestedCurrency(r *http.Request) (string, error) {
+	c := strings.ToUpper(r.URL.Query().Get(CurrencyParam))
+	if c != "" && !model.IsCurrency(c) {
+		return "", fmt.Errorf("%w: invalid currency '%s'", srverrors.ErrInvalidInput, c)
+	}
+	return c, nil
+}
+
+// exchangeFor resolves the rate from the items' currency to the requested one.
+// Items in one basket must share a currency: a single recorded rate could not
+// otherwise account for the total.
+func (h *Service) exchangeFor(ctx context.Context, items []*model.Item, requested string) (*exchange, error) {
+	base := ""
+	for _, it := range items {
+		c := itemCurrency(it)
+		if base != "" && c != base {
+			return nil, fmt.Errorf("%w: basket mixes item currencies %s and %s", srverrors.ErrInvalidInput, base, c)
+		}
+		base = c
+	}
+	if base == "" {
+		base = model.DefaultCurrency
+	}
+	quote := requested
+	if quote == "" {
+		quote = base
+	}
+	rate, err := h.rates.Rate(ctx, base, quote)
+	if err != nil {
+		if errors.Is(err, fx.ErrUnsupportedCurrency) {
+			return nil, fmt.Errorf("%w: %v", srverrors.ErrInvalidInput, err)
+		}
+		return nil, fmt.Errorf("could not get exchange rate: %w", err)
+	}
+	return &exchange{base: base, quote: quote, rate: rate}, nil
+}
+
+// convert returns copies of items priced in the quote currency. The originals
+// are left untouched: in a purchase they are the inventory rows written back to
+// the database, whose prices must stay in the catalog currency.
+func (x *exchange) convert(items []*model.Item) []*model.Item {
+	out := make([]*model.Item, 0, len(items))
+	for _, it := range items {
+		c := *it
+		c.Price = fx.Convert(it.Price, x.rate)
+		c.Currency = x.quote
+		c.PriceTiers = nil // in the catalog currency; quotes apply them per line
+		out = append(out, &c)
+	}
+	return out
+}
+
+// itemCurrency returns the item's currency, treating unset as the default.
+func itemCurrency(it *model.Item) string {
+	if it.Currency == "" {
+		return model.DefaultCurrency
+	}
+	return it.Currency
+}
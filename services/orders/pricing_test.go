@@ -0,0 +1,88 @@
+//go:build !integration
+
+package orders
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/fx"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/services/auth"
+	ordersmock "github.com/ATMackay/checkout/services/orders/mock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// The batch price endpoint quotes in the ?currency= requested, converting every
+// line through the configured rate table.
+func Test_ItemsPriceCurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+
+	rates, err := fx.NewStaticProvider("USD", map[string]decimal.Decimal{"EUR": decimal.RequireFromString("0.5")})
+	require.NoError(t, err)
+	noStoredPromotions(db)
+	noBundles(db)
+	router := NewService(db, ordersmock.NewMockRelayer(ctrl), auth.NewPasswordAuthenticator(nil), WithRateProvider(rates)).RegisterHandlers()
+
+	items := func() []*model.Item {
+		return []*model.Item{
+			{Name: "Google TV", SKU: "120P90", Price: decimal.NewFromFloat(49.99), Currency: "USD", InventoryQuantity: 10},
+			{Name: "Alexa Speaker", SKU: "A304SD", Price: decimal.NewFromFloat(109.50), Currency: "USD", InventoryQuantity: 10},
+		}
+	}
+	post := func(t *testing.T, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, err := json.Marshal(&model.ItemsPriceRequest{SKUs: []string{"120P90", "A304SD"}})
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, ItemPriceEndPnt+query, bytes.NewReader(body)))
+		return rr
+	}
+
+	t.Run("catalog currency", func(t *testing.T) {
+		db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(items(), nil)
+		rr := post(t, "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		var resp model.PriceResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, "USD", resp.Currency)
+		require.Equal(t, 159.49, resp.TotalGross)
+	})
+
+	t.Run("converted", func(t *testing.T) {
+		db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(items(), nil)
+		rr := post(t, "?currency=eur")
+		require.Equal(t, http.StatusOK, rr.Code)
+		var resp model.PriceResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, "EUR", resp.Currency)
+		// 25.00 + 54.75: each line is converted and rounded to cents.
+		require.Equal(t, 79.75, resp.TotalGross)
+		for _, it := range resp.Items {
+			require.Equal(t, "EUR", it.Currency)
+		}
+	})
+
+	t.Run("unsupported currency", func(t *testing.T) {
+		db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(items(), nil)
+		require.Equal(t, http.StatusBadRequest, post(t, "?currency=CHF").Code)
+	})
+
+	t.Run("malformed currency", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, post(t, "?currency=EURO").Code)
+	})
+
+	t.Run("mixed basket", func(t *testing.T) {
+		mixed := items()
+		mixed[1].Currency = "EUR"
+		db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(mixed, nil)
+		require.Equal(t, http.StatusBadRequest, post(t, "").Code)
+	})
+}
@@ -0,0 +1,230 @@
+package orders
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/errors"
+	"github.com/ATMackay/checkout/httpserver"
+	"github.com/ATMackay/checkout/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ListPromotions godoc
+// @Summary List promotions
+// @Description List the promotions managed as data, active or not, with where each stands in its schedule.
+// @Tags promotions
+// @Produce json
+// @Param   status  query   string  false  "Only promotions whose schedule is upcoming, active or expired"
+// @Success 200 {array}  model.PromotionRule
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/promotions [get]
+func (h *Service) ListPromotions() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		status := model.ScheduleStatus(r.URL.Query().Get("status"))
+		switch status {
+		case "", model.ScheduleUpcoming, model.ScheduleActive, model.ScheduleExpired:
+		default:
+			return nil, fmt.Errorf("%w: invalid status '%s': want upcoming, active or expired", errors.ErrInvalidInput, status)
+		}
+		ps, err := h.store.ListPromotions(r.Context(), false)
+		if err != nil {
+			return nil, fmt.Errorf("could not get promotions: %w", err)
+		}
+		listed := []*model.PromotionRule{}
+		for _, p := range ps {
+			h.setScheduleStatus(p)
+			if status == "" || p.ScheduleStatus == status {
+				listed = append(listed, p)
+			}
+		}
+		return listed, nil
+	})
+}
+
+// GetPromotion godoc
+// @Summary Get a promotion
+// @Tags promotions
+// @Produce json
+// @Param   id  path    int  true  "Promotion ID"
+// @Success 200 {object} model.PromotionRule
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/promotions/{id} [get]
+func (h *Service) GetPromotion() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		id, err := promotionID(p)
+		if err != nil {
+			return nil, err
+		}
+		promo, err := h.store.GetPromotion(r.Context(), id)
+		if err != nil {
+			return nil, promotionError(err)
+		}
+		h.setScheduleStatus(promo)
+		return promo, nil
+	})
+}
+
+// CreatePromotion godoc
+// @Summary Create a promotion
+// @Description Create a promotion rule. It applies to checkouts as soon as it is active.
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param   request  body    model.PromotionRule  true  "Promotion rule"
+// @Success 200 {object} model.PromotionRule
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/promotions [post]
+func (h *Service) CreatePromotion() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, _ httprouter.Params) (any, error) {
+		promo, err := decodePromotion(r)
+		if err != nil {
+			return nil, err
+		}
+		promo.ID = 0
+		promo.PromotionUsage = model.PromotionUsage{}
+		if err := h.store.AddPromotion(r.Context(), promo); err != nil {
+			return nil, fmt.Errorf("could not create promotion: %w", err)
+		}
+		h.rules.Invalidate()
+		h.setScheduleStatus(promo)
+		return promo, nil
+	})
+}
+
+// UpdatePromotion godoc
+// @Summary Replace a promotion
+// @Tags promotions
+// @Accept json
+// @Produce json
+// @Param   id       path    int                  true  "Promotion ID"
+// @Param   request  body    model.PromotionRule  true  "Promotion rule"
+// @Success 200 {object} model.PromotionRule
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/promotions/{id} [put]
+func (h *Service) UpdatePromotion() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		id, err := promotionID(p)
+		if err != nil {
+			return nil, err
+		}
+		promo, err := decodePromotion(r)
+		if err != nil {
+			return nil, err
+		}
+		promo.ID = id
+		if err := h.store.UpdatePromotion(r.Context(), promo); err != nil {
+			return nil, promotionError(err)
+		}
+		h.rules.Invalidate()
+		promo, err = h.store.GetPromotion(r.Context(), id)
+		if err != nil {
+			return nil, promotionError(err)
+		}
+		h.setScheduleStatus(promo)
+		return promo, nil
+	})
+}
+
+// DeletePromotion godoc
+// @Summary Delete a promotion
+// @Tags promotions
+// @Param   id  path  int  true  "Promotion ID"
+// @Success 200
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/promotions/{id} [delete]
+func (h *Service) DeletePromotion() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		id, err := promotionID(p)
+		if err != nil {
+			return nil, err
+		}
+		if err := h.store.DeletePromotion(r.Context(), id); err != nil {
+			return nil, promotionError(err)
+		}
+		h.rules.Invalidate()
+		return nil, nil
+	})
+}
+
+// PromotionStats godoc
+// @Summary Get a promotion's usage
+// @Description Report what a promotion has given away in committed purchases against its budget.
+// @Tags promotions
+// @Produce json
+// @Param   id  path    int  true  "Promotion ID"
+// @Success 200 {object} model.PromotionStats
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/promotions/{id}/stats [get]
+func (h *Service) PromotionStats() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		id, err := promotionID(p)
+		if err != nil {
+			return nil, err
+		}
+		promo, err := h.store.GetPromotion(r.Context(), id)
+		if err != nil {
+			return nil, promotionError(err)
+		}
+		return promo.Stats(), nil
+	})
+}
+
+// setScheduleStatus reports where p stands in its schedule now.
+func (h *Service) setScheduleStatus(p *model.PromotionRule) {
+	now := h.now()
+	p.ScheduleStatus = p.Schedule.Status(now)
+	p.AppliesNow = p.Active && p.Schedule.AppliesAt(now)
+}
+
+func promotionID(p httprouter.Params) (int, error) {
+	id, err := strconv.Atoi(p.ByName("id"))
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid promotion id '%s'", errors.ErrInvalidInput, p.ByName("id"))
+	}
+	return id, nil
+}
+
+func decodePromotion(r *http.Request) (*model.PromotionRule, error) {
+	var promo model.PromotionRule
+	if err := json.NewDecoder(r.Body).Decode(&promo); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+	}
+	if err := promo.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+	}
+	return &promo, nil
+}
+
+func promotionError(err error) error {
+	if stderrors.Is(err, database.ErrPromotionNotFound) {
+		return fmt.Errorf("%w: %v", errors.ErrNotFound, err)
+	}
+	return fmt.Errorf("could not access promotion: %w", err)
+}
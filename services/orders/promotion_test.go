@@ -0,0 +1,240 @@
+//go:build !integration
+
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments/fake"
+	"github.com/ATMackay/checkout/services/auth"
+	ordersmock "github.com/ATMackay/checkout/services/orders/mock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// Listing promotions reports each one's schedule status against the service
+// clock and can filter on it.
+func Test_ListPromotionsByStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	now := time.Date(2026, 11, 28, 12, 0, 0, 0, time.UTC) // a Saturday
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	stored := func() []*model.PromotionRule {
+		return []*model.PromotionRule{
+			{ID: 1, Name: "ended", Active: true, Schedule: model.Schedule{EndsAt: &past}},
+			{ID: 2, Name: "weekdays", Active: true, Schedule: model.Schedule{Days: "mon,tue,wed,thu,fri"}},
+			{ID: 3, Name: "always", Active: true},
+			{ID: 4, Name: "soon", Active: true, Schedule: model.Schedule{StartsAt: &future}},
+		}
+	}
+	db.EXPECT().ListPromotions(gomock.Any(), false).DoAndReturn(func(any, bool) ([]*model.PromotionRule, error) {
+		return stored(), nil
+	}).AnyTimes()
+	s := NewService(db, ordersmock.NewMockRelayer(ctrl), auth.NewPasswordAuthenticator(nil), withTestAdmin(), WithClock(func() time.Time { return now }))
+
+	list := func(query string) (int, []*model.PromotionRule) {
+		req := httptest.NewRequest(http.MethodGet, PromotionsEndPnt+query, nil)
+		req.Header.Set(auth.XAuthHeaderKey, testAdminPassword)
+		rr := httptest.NewRecorder()
+		s.RegisterHandlers().ServeHTTP(rr, req)
+		var ps []*model.PromotionRule
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &ps))
+		}
+		return rr.Code, ps
+	}
+
+	code, ps := list("")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, ps, 4)
+	assert.Equal(t, model.ScheduleExpired, ps[0].ScheduleStatus)
+	assert.Equal(t, model.ScheduleActive, ps[1].ScheduleStatus)
+	assert.False(t, ps[1].AppliesNow) // in range, but not on a Saturday
+	assert.True(t, ps[2].AppliesNow)
+	assert.Equal(t, model.ScheduleUpcoming, ps[3].ScheduleStatus)
+
+	for status, want := range map[string][]int{"upcoming": {4}, "active": {2, 3}, "expired": {1}} {
+		code, ps := list("?status=" + status)
+		require.Equal(t, http.StatusOK, code)
+		var ids []int
+		for _, p := range ps {
+			ids = append(ids, p.ID)
+		}
+		assert.Equal(t, want, ids, status)
+	}
+
+	code, _ = list("?status=someday")
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+// At startup, promotions referencing SKUs missing from inventory are recorded
+// per promotion, and the service starts regardless.
+func Test_StartChecksPromotionReferences(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	relay := ordersmock.NewMockRelayer(ctrl)
+	noStoredPromotions(db)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return([]*model.Item{{SKU: "43N23P"}, {SKU: "120P90"}}, nil)
+	relay.EXPECT().Start(gomock.Any()).Return(nil)
+
+	s := NewService(db, relay, auth.NewPasswordAuthenticator(nil), WithPaymentProvider(fake.NewProvider()))
+	require.NoError(t, s.Start(context.Background()))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(PromotionMissingItems.WithLabelValues("builtin:macbook-pro")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(PromotionMissingItems.WithLabelValues("builtin:alexa-speaker")))
+	assert.Zero(t, testutil.ToFloat64(PromotionMissingItems.WithLabelValues("builtin:google-tv")))
+}
+
+// A first-order promotion applies only while the customer's history is empty.
+func Test_PurchaseFirstOrderPromotion(t *testing.T) {
+	for _, tc := range []struct {
+		orders int
+		price  string
+	}{
+		{0, "45"},
+		{1, "50"},
+	} {
+		ctrl := gomock.NewController(t)
+		db := mock.NewMockDatabase(ctrl)
+		firstOrder := &model.PromotionRule{ID: 9, Name: "10% off your first order", Type: model.PromotionPercentOffOverQuantity, Active: true,
+			SKU: "120P90", MinQuantity: 1, PercentOff: decimal.NewFromInt(10), CustomerCondition: model.CustomerCondition{FirstOrder: true}}
+		db.EXPECT().ListPromotions(gomock.Any(), true).Return([]*model.PromotionRule{firstOrder}, nil).AnyTimes()
+		db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+		db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(couponInventory(), nil)
+		db.EXPECT().GetOrderSummary(gomock.Any(), "customer").Return(&model.OrderSummary{Orders: tc.orders}, nil).Times(1)
+		expectTx(db)
+		db.EXPECT().UpsertItems(gomock.Any(), gomock.Any()).Return(nil, nil)
+		var order *model.Order
+		db.EXPECT().AddOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, o *model.Order) error {
+			order = o
+			return nil
+		})
+		if tc.orders == 0 {
+			db.EXPECT().RedeemPromotion(gomock.Any(), 9, amount("5"), 1).Return(firstOrder, nil)
+		}
+		db.EXPECT().AddOutboxItems(gomock.Any(), gomock.Any()).Return(nil)
+
+		authn := auth.NewPasswordAuthenticator(map[string]string{testPassword: "customer"})
+		noBundles(db)
+		s := NewService(db, ordersmock.NewMockRelayer(ctrl), authn, WithPaymentProvider(fake.NewProvider()))
+		rr := purchase(t, s, "120P90")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, tc.price, order.Price.String(), "%d previous orders", tc.orders)
+	}
+}
+
+// A purchase charges its promotions' budgets in the order transaction and
+// counts what they gave; one that exhausts a budget deactivates the promotion,
+// and one priced with a promotion deactivated meanwhile is refused.
+func Test_PurchaseChargesPromotionBudget(t *testing.T) {
+	budgeted := func() *model.PromotionRule {
+		return &model.PromotionRule{ID: 41, Name: "10% off", Type: model.PromotionPercentOffOverQuantity, Active: true,
+			SKU: "120P90", MinQuantity: 1, PercentOff: decimal.NewFromInt(10),
+			PromotionBudget: model.PromotionBudget{MaxDiscount: decimal.NewFromInt(10)}}
+	}
+	for _, tc := range []struct {
+		name      string
+		redeemed  func() (*model.PromotionRule, error)
+		code      int
+		exhausted float64
+	}{
+		{"within-budget", func() (*model.PromotionRule, error) { return budgeted(), nil }, http.StatusOK, 0},
+		{"exhausted", func() (*model.PromotionRule, error) {
+			p := budgeted()
+			p.Active = false
+			return p, nil
+		}, http.StatusOK, 1},
+		{"deactivated", func() (*model.PromotionRule, error) { return nil, database.ErrPromotionInactive }, http.StatusConflict, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			db := mock.NewMockDatabase(ctrl)
+			payer := fake.NewProvider()
+			db.EXPECT().ListPromotions(gomock.Any(), true).Return([]*model.PromotionRule{budgeted()}, nil).AnyTimes()
+			db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+			db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(couponInventory(), nil)
+			expectTx(db)
+			db.EXPECT().UpsertItems(gomock.Any(), gomock.Any()).Return(nil, nil)
+			db.EXPECT().AddOrder(gomock.Any(), gomock.Any()).Return(nil)
+			db.EXPECT().RedeemPromotion(gomock.Any(), 41, amount("5"), 1).Return(tc.redeemed())
+			if tc.code == http.StatusOK {
+				db.EXPECT().AddOutboxItems(gomock.Any(), gomock.Any()).Return(nil)
+			}
+			redemptions := testutil.ToFloat64(PromotionRedemptions.WithLabelValues("promotion:41"))
+			exhausted := testutil.ToFloat64(PromotionBudgetExhausted.WithLabelValues("promotion:41"))
+
+			authn := auth.NewPasswordAuthenticator(map[string]string{testPassword: "customer"})
+			noBundles(db)
+			s := NewService(db, ordersmock.NewMockRelayer(ctrl), authn, WithPaymentProvider(payer))
+			rr := purchase(t, s, "120P90")
+			require.Equal(t, tc.code, rr.Code, rr.Body.String())
+
+			if tc.code == http.StatusOK {
+				assert.Equal(t, redemptions+1, testutil.ToFloat64(PromotionRedemptions.WithLabelValues("promotion:41")))
+			} else {
+				assert.Contains(t, rr.Body.String(), "please retry")
+				assert.Equal(t, fake.Void, payer.Calls()[1].Op)
+			}
+			assert.Equal(t, exhausted+tc.exhausted, testutil.ToFloat64(PromotionBudgetExhausted.WithLabelValues("promotion:41")))
+		})
+	}
+}
+
+// A buy N get M promotion charges its max_units budget with the units it made
+// free, not the paid units that qualified for them.
+func Test_PurchaseChargesGrantedUnits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	// Priority 1 puts it ahead of the built-in Google TV deal, which saves as much.
+	buy2Get1 := &model.PromotionRule{ID: 42, Name: "Buy 2 get 1", Type: model.PromotionBuyNGetMFree, Active: true,
+		SKU: "120P90", BuyQuantity: 2, FreeQuantity: 1, Priority: 1, PromotionBudget: model.PromotionBudget{MaxUnits: 2}}
+	db.EXPECT().ListPromotions(gomock.Any(), true).Return([]*model.PromotionRule{buy2Get1}, nil).AnyTimes()
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(couponInventory(), nil)
+	expectTx(db)
+	db.EXPECT().UpsertItems(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().AddOrder(gomock.Any(), gomock.Any()).Return(nil)
+	db.EXPECT().RedeemPromotion(gomock.Any(), 42, amount("50"), 1).Return(buy2Get1, nil)
+	db.EXPECT().AddOutboxItems(gomock.Any(), gomock.Any()).Return(nil)
+
+	authn := auth.NewPasswordAuthenticator(map[string]string{testPassword: "customer"})
+	noBundles(db)
+	s := NewService(db, ordersmock.NewMockRelayer(ctrl), authn, WithPaymentProvider(fake.NewProvider()))
+	rr := purchase(t, s, "120P90", "120P90", "120P90")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+// The stats endpoint reports a promotion's usage against its budget.
+func Test_PromotionStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	db.EXPECT().GetPromotion(gomock.Any(), 3).Return(&model.PromotionRule{ID: 3, Active: true,
+		PromotionBudget: model.PromotionBudget{MaxDiscount: decimal.NewFromInt(100)},
+		PromotionUsage:  model.PromotionUsage{Redemptions: 4, DiscountGranted: decimal.RequireFromString("37.5"), UnitsGranted: 6}}, nil)
+	db.EXPECT().GetPromotion(gomock.Any(), 4).Return(nil, database.ErrPromotionNotFound)
+	s := NewService(db, ordersmock.NewMockRelayer(ctrl), auth.NewPasswordAuthenticator(nil), withTestAdmin())
+
+	get := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, PromotionsEndPnt+"/"+id+StatsEndPnt, nil)
+		req.Header.Set(auth.XAuthHeaderKey, testAdminPassword)
+		rr := httptest.NewRecorder()
+		s.RegisterHandlers().ServeHTTP(rr, req)
+		return rr
+	}
+	rr := get("3")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"promotion_id": 3, "active": true, "max_discount": "100", "redemptions": 4,
+		"discount_granted": "37.5", "units_granted": 6, "exhausted": false, "remaining_discount": "62.5"}`, rr.Body.String())
+	require.Equal(t, http.StatusNotFound, get("4").Code)
+}
@@ -1,15 +1,22 @@
 package orders
 
 import (
+	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/ATMackay/checkout/database"
 	"github.com/ATMackay/checkout/errors"
 	"github.com/ATMackay/checkout/event"
 	"github.com/ATMackay/checkout/httpserver"
 	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments"
+	"github.com/ATMackay/checkout/promotions"
 	"github.com/ATMackay/checkout/services/auth"
 	"github.com/julienschmidt/httprouter"
 	"github.com/shopspring/decimal"
@@ -22,9 +29,12 @@ import (
 // @Accept json
 // @Produce json
 // @Param   request  body    model.PurchaseItemsRequest  true  "List of SKUs"
+// @Param   currency query   string                      false "ISO 4217 currency to charge in (default: the items' currency)"
 // @Success 200 {object} model.PurchaseItemsResponse
 // @Failure 400 {object} errors.JSONError
+// @Failure 402 {object} errors.JSONError
 // @Failure 404 {object} errors.JSONError
+// @Failure 422 {object} errors.JSONError
 // @Failure 503 {object} errors.JSONError
 // @Security XAuthPassword
 // @Router /v1/inventory/items/purchase [post]
@@ -38,6 +48,11 @@ func (h *Service) PurchaseItems() httprouter.Handle {
 			return nil, fmt.Errorf("%w", errors.ErrInvalidInput)
 		}
 
+		currency, err := requestedCurrency(r)
+		if err != nil {
+			return nil, err
+		}
+
 		var pReq model.PurchaseItemsRequest
 		if err := json.NewDecoder(r.Body).Decode(&pReq); err != nil {
 			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
@@ -50,16 +65,24 @@ func (h *Service) PurchaseItems() httprouter.Handle {
 			}
 		}
 
+		limits, err := h.checkOrderLimits(ctx, pReq.SKUs)
+		if err != nil {
+			return nil, err
+		}
+
 		// Fetch items from DB
 		dbItems, err := h.store.GetItemsBySKU(ctx, pReq.SKUs)
 		if err != nil {
 			return nil, fmt.Errorf("could not get items: %w", err)
 		}
 
-		dbItemMap := make(map[string]*model.Item)
+		x, err := h.exchangeFor(ctx, dbItems, currency)
+		if err != nil {
+			return nil, err
+		}
 
+		dbItemMap := make(map[string]*model.Item)
 		items := []*model.Item{}
-		total := decimal.Zero
 		for _, dbIt := range dbItems {
 			dbItemMap[dbIt.SKU] = dbIt
 			items = append(items, dbIt)
@@ -68,23 +91,53 @@ func (h *Service) PurchaseItems() httprouter.Handle {
 		itemCount := make(map[string]int)
 		for _, sku := range pReq.SKUs {
 			itemCount[sku]++
-			it := dbItemMap[sku]
+			it, ok := dbItemMap[sku]
+			if !ok {
+				return nil, fmt.Errorf("%w: item %s", errors.ErrNotFound, sku)
+			}
 			if it.InventoryQuantity < itemCount[it.SKU] {
 				return nil, fmt.Errorf("%w: item %s empty", errors.ErrNotFound, it.SKU)
 			}
-			total = total.Add(it.Price)
 			// deduct inventory
 			it.InventoryQuantity--
 		}
 
+		// Prices are quoted from converted copies at their tier prices;
+		// dbItems keep catalog prices because they are written back with the
+		// new inventory totals.
+		priced, err := h.priceBasket(ctx, pReq.SKUs, dbItemMap, x)
+		if err != nil {
+			return nil, err
+		}
+		total := priced.gross
+		// lines records what was charged per SKU, in request order, so that a
+		// later refund is priced from the order rather than the current catalog.
+		var lines []model.OrderLine
+		for _, it := range priced.units {
+			lines = addOrderLine(lines, model.OrderLine{SKU: it.SKU, Name: it.Name, Quantity: 1, UnitPrice: it.Price})
+		}
+
 		skus := pReq.SKUs
 
-		promotions, err := h.promotionsEngine.ApplyPromotions(ctx, items)
+		// Promotions see one entry per unit bought outside a bundle, as in a
+		// price quote, and the customer's order history (not counting this
+		// order).
+		basket := &promotions.Basket{
+			Items: priced.promotable,
+			Customer: promotions.NewCustomer(customerID, func(ctx context.Context) (*model.OrderSummary, error) {
+				return h.store.GetOrderSummary(ctx, customerID)
+			}),
+		}
+		coupons, checked, err := h.couponPromotions(ctx, pReq.CouponCodes, basket, h.now())
+		if err != nil {
+			return nil, err
+		}
+		applied, err := h.promotionsEngine.ApplyPromotions(ctx, basket, coupons...)
 		if err != nil {
 			return nil, fmt.Errorf("could not apply promotion/deals: %w", err)
 		}
 
-		for _, it := range promotions.AddedItems {
+		for _, it := range applied.AddedItems {
 			sku := it.SKU
 			itemCount[sku]++
 			dbIt, err := h.store.GetItemBySKU(ctx, sku)
@@ -93,29 +146,126 @@ func (h *Service) PurchaseItems() httprouter.Handle {
 			}
 			if dbIt.InventoryQuantity < itemCount[sku] {
 				// Skip if we cannot add
+				applied.Adjustments = dropAdded(applied.Adjustments, sku)
 				continue
 			}
 			// Note: DB tx can fail if concurrent requests push InventoryQuantity below zero
 			dbIt.InventoryQuantity--
 
 			items = append(items, dbIt)
-			skus = append(pReq.SKUs, sku)
+			skus = append(skus, sku)
+			lines = addOrderLine(lines, model.OrderLine{SKU: sku, Name: dbIt.Name, Quantity: 1, UnitPrice: decimal.Zero, Promotional: true})
 		}
 
-		price := total.Sub(decimal.NewFromFloat(promotions.Deduction))
+		// Only codes whose promotion won units are redeemed; any other is
+		// refused before the customer is charged.
+		codes, err := appliedCoupons(checked, applied.Adjustments)
+		if err != nil {
+			return nil, err
+		}
+
+		// Bundles are recorded with the promotions' adjustments, so refunds
+		// give back each unit's share of the bundle saving too.
+		discount := priced.bundleDiscount.Add(decimal.NewFromFloat(applied.Deduction))
+		price := total.Sub(discount)
+		adjustments := append(slices.Clone(priced.bundles), applied.Adjustments...)
+
+		// Loyalty points come off what is left after bundles and promotions,
+		// and are recorded like them so refunds net them out.
+		loyaltyDiscount := decimal.Zero
+		if pReq.LoyaltyPoints != 0 {
+			adj, err := h.loyaltyAdjustment(ctx, customerID, pReq.LoyaltyPoints, lines, adjustments, price, x)
+			if err != nil {
+				return nil, err
+			}
+			loyaltyDiscount = decimal.NewFromFloat(adj.Amount)
+			discount = discount.Add(loyaltyDiscount)
+			price = price.Sub(loyaltyDiscount)
+			adjustments = append(adjustments, *adj)
+		}
 
 		// Create order
 		order := &model.Order{
-			Price:      price,
-			Reference:  model.GenerateReference(),
-			CustomerID: customerID,
+			Price:         price,
+			Reference:     model.GenerateReference(),
+			CustomerID:    customerID,
+			Currency:      x.quote,
+			BaseCurrency:  x.base,
+			FXRate:        x.rate,
+			Discount:      discount,
+			LoyaltyPoints: pReq.LoyaltyPoints,
 		}
 		if err := order.SetSKUList(skus); err != nil {
 			return nil, err
 		}
+		if err := order.SetLines(lines); err != nil {
+			return nil, err
+		}
+		if len(adjustments) > 0 {
+			if err := order.SetAdjustments(adjustments); err != nil {
+				return nil, err
+			}
+		}
+
+		// A gift card is tendered first. Its balance is only read here; the
+		// debit happens inside the transaction, conditional on the balance
+		// still covering it, so a concurrent redemption fails this purchase
+		// rather than overdrawing the card.
+		if pReq.GiftCard != "" {
+			card, err := h.store.GetGiftCard(ctx, pReq.GiftCard)
+			if err != nil {
+				return nil, giftCardError(err)
+			}
+			// Another customer's store credit is indistinguishable from a
+			// missing card.
+			if !card.HeldBy(customerID) {
+				return nil, fmt.Errorf("%w: gift card %s", errors.ErrNotFound, pReq.GiftCard)
+			}
+			if card.Currency != order.Currency {
+				return nil, fmt.Errorf("%w: gift card is in %s, order is in %s", errors.ErrInvalidInput, card.Currency, order.Currency)
+			}
+			if card.Expired(time.Now().UTC()) {
+				return nil, giftCardError(database.ErrGiftCardExpired)
+			}
+			order.GiftCardCode = card.Code
+			order.GiftCardAmount = decimal.Min(card.Balance, price)
+		}
+		charge := price.Sub(order.GiftCardAmount)
+		redemptions := redemptionsOf(applied.Adjustments, x.rate)
+
+		// Authorize before the transaction: a decline leaves nothing to undo.
+		// Capture is deferred to the outbox (below), so money only moves for an
+		// order that committed. An order paid in full by gift card has nothing
+		// to authorize.
+		var authz *payments.Authorization
+		if charge.IsPositive() {
+			if h.payments == nil {
+				return nil, ErrNoPaymentProvider
+			}
+			authz, err = h.payments.Authorize(ctx, &payments.AuthorizeRequest{
+				Reference:  order.Reference,
+				CustomerID: customerID,
+				Amount:     charge,
+				Currency:   order.Currency,
+			})
+			if err != nil {
+				if stderrors.Is(err, payments.ErrDeclined) {
+					return nil, fmt.Errorf("%w: %v", errors.ErrPaymentDeclined, err)
+				}
+				return nil, fmt.Errorf("could not authorize payment: %w", err)
+			}
+			order.PaymentID = authz.ID
+			order.PaymentStatus = model.PaymentAuthorized
+		} else {
+			order.PaymentStatus = model.PaymentCaptured
+		}
 
 		// Execute purchase in a transaction to ensure atomicity
+		var exhausted []*model.PromotionRule
 		err = h.store.Transaction(ctx, func(tx database.Database) error {
+			if err := checkWindowLimits(ctx, tx, customerID, limits, pReq.SKUs, time.Now().UTC()); err != nil {
+				return err
+			}
 			// Save updated dbItems with new inventory totals
 			if _, err := tx.UpsertItems(ctx, items); err != nil {
 				return fmt.Errorf("failed to update inventory: %w", err)
@@ -124,6 +274,43 @@ func (h *Service) PurchaseItems() httprouter.Handle {
 			if err := tx.AddOrder(ctx, order); err != nil {
 				return fmt.Errorf("failed to create order: %w", err)
 			}
+			// Redeeming in the order transaction makes the limits exact: a
+			// coupon used up by a concurrent purchase fails this one, and a
+			// purchase that fails later does not consume the coupon.
+			for _, code := range codes {
+				if err := tx.RedeemCoupon(ctx, &model.CouponRedemption{
+					Code:           code,
+					CustomerID:     customerID,
+					OrderReference: order.Reference,
+				}); err != nil {
+					return couponError(code, err)
+				}
+			}
+			// Budgets are charged here for the same reason: the order and
+			// what its promotions gave commit together.
+			if exhausted, err = h.redeemPromotions(ctx, tx, redemptions); err != nil {
+				return err
+			}
+			if order.LoyaltyPoints > 0 {
+				if err := tx.AdjustLoyalty(ctx, &model.LoyaltyEntry{
+					CustomerID:     customerID,
+					Points:         -order.LoyaltyPoints,
+					Reason:         model.LoyaltyRedeemed,
+					OrderReference: order.Reference,
+				}); err != nil {
+					return loyaltyError(err)
+				}
+			}
+			if order.GiftCardAmount.IsPositive() {
+				if err := tx.AdjustGiftCard(ctx, &model.GiftCardEntry{
+					Code:           order.GiftCardCode,
+					Amount:         order.GiftCardAmount.Neg(),
+					Reason:         model.GiftCardRedeemed,
+					OrderReference: order.Reference,
+				}); err != nil {
+					return giftCardError(err)
+				}
+			}
 			// Enqueue the event in the SAME transaction as the order. The relay
 			// publishes it to the broker asynchronously; writing it here (rather
 			// than publishing inline) is what makes the order and its event
@@ -136,15 +323,72 @@ func (h *Service) PurchaseItems() httprouter.Handle {
 			if err != nil {
 				return fmt.Errorf("failed to build outbox item: %w", err)
 			}
-			if err := tx.AddOutboxItems(ctx, []*model.OutboxItem{outboxItem}); err != nil {
+			outboxItems := []*model.OutboxItem{outboxItem}
+			if authz != nil {
+				captureItem, err := newOutboxItem(event.New(
+					event.TopicPaymentCapture,
+					order.Reference,
+					&model.PaymentCapture{
+						OrderReference:  order.Reference,
+						AuthorizationID: authz.ID,
+						Amount:          charge,
+						Currency:        order.Currency,
+					},
+				))
+				if err != nil {
+					return fmt.Errorf("failed to build outbox item: %w", err)
+				}
+				outboxItems = append(outboxItems, captureItem)
+			}
+			if err := tx.AddOutboxItems(ctx, outboxItems); err != nil {
 				return fmt.Errorf("failed to enqueue event: %w", err)
 			}
 			return nil
 		})
 		if err != nil {
+			// The order did not commit, so release the reservation. The request
+			// context may already be cancelled (that may be why the transaction
+			// failed), so the void must not inherit it.
+			if authz != nil {
+				if vErr := h.payments.Void(context.WithoutCancel(ctx), authz.ID); vErr != nil {
+					slog.Error("failed to void payment authorization", "order_reference", order.Reference, "authorization_id", authz.ID, "error", vErr)
+				}
+			}
 			return nil, err
 		}
+		h.recordRedemptions(redemptions, exhausted)
+		LoyaltyPointsRedeemed.Add(float64(order.LoyaltyPoints))
 
-		return &model.PurchaseItemsResponse{OrderReference: order.Reference, Cost: price.InexactFloat64()}, nil
+		return &model.PurchaseItemsResponse{
+			OrderReference:  order.Reference,
+			Cost:            price.InexactFloat64(),
+			Currency:        order.Currency,
+			GiftCardAmount:  order.GiftCardAmount.InexactFloat64(),
+			LoyaltyDiscount: loyaltyDiscount.InexactFloat64(),
+		}, nil
 	})
 }
+
+// addOrderLine adds l to lines, merging it into an existing line for the same
+// SKU and kind (paid or promotional).
+func addOrderLine(lines []model.OrderLine, l model.OrderLine) []model.OrderLine {
+	for i := range lines {
+		if lines[i].SKU == l.SKU && lines[i].Promotional == l.Promotional {
+			lines[i].Quantity += l.Quantity
+			return lines
+		}
+	}
+	return append(lines, l)
+}
+
+// dropAdded removes one free unit of sku, which could not be added, from the
+// adjustments that gave it.
+func dropAdded(adjustments []model.Adjustment, sku string) []model.Adjustment {
+	for i := range adjustments {
+		if j := slices.Index(adjustments[i].AddedSKUs, sku); j >= 0 {
+			adjustments[i].AddedSKUs = slices.Delete(adjustments[i].AddedSKUs, j, j+1)
+			return adjustments
+		}
+	}
+	return adjustments
+}
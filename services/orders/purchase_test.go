@@ -0,0 +1,233 @@
+//go:build !integration
+
+package orders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments"
+	"github.com/ATMackay/checkout/payments/fake"
+	"github.com/ATMackay/checkout/services/auth"
+	ordersmock "github.com/ATMackay/checkout/services/orders/mock"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const (
+	testPassword      = "pw"
+	testAdminPassword = "admin-pw"
+)
+
+// withTestAdmin opens the admin routes to testAdminPassword, as "admin".
+func withTestAdmin() ServiceOption {
+	return WithAdminAuthenticator(auth.NewPasswordAuthenticator(map[string]string{testAdminPassword: "admin"}))
+}
+
+func testInventory() []*model.Item {
+	return []*model.Item{
+		{Name: "Google TV", SKU: "120P90", Price: decimal.NewFromFloat(49.99), Currency: "USD", InventoryQuantity: 10},
+	}
+}
+
+// purchase posts a purchase of the given SKUs through the service router as an
+// authenticated caller.
+func purchase(t *testing.T, s *Service, skus ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	return purchaseWith(t, s, &model.PurchaseItemsRequest{SKUs: skus})
+}
+
+// purchaseWith posts a purchase request body through the service router as an
+// authenticated caller.
+func purchaseWith(t *testing.T, s *Service, pReq *model.PurchaseItemsRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(pReq)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, ItemPurchaseEndPnt, bytes.NewReader(body))
+	req.Header.Set(auth.XAuthHeaderKey, testPassword)
+	rr := httptest.NewRecorder()
+	s.RegisterHandlers().ServeHTTP(rr, req)
+	return rr
+}
+
+func newPurchaseService(ctrl *gomock.Controller, db *mock.MockDatabase, payer payments.Provider) *Service {
+	noStoredPromotions(db)
+	noBundles(db)
+	authn := auth.NewPasswordAuthenticator(map[string]string{testPassword: "customer"})
+	return NewService(db, ordersmock.NewMockRelayer(ctrl), authn, WithPaymentProvider(payer), withTestAdmin())
+}
+
+// noStoredPromotions stubs an empty promotions table.
+func noStoredPromotions(db *mock.MockDatabase) {
+	db.EXPECT().ListPromotions(gomock.Any(), true).Return(nil, nil).AnyTimes()
+}
+
+// noBundles stubs an empty bundles table.
+func noBundles(db *mock.MockDatabase) {
+	db.EXPECT().ListBundles(gomock.Any(), true).Return(nil, nil).AnyTimes()
+}
+
+// A successful purchase authorizes once and commits the order with the
+// authorization recorded, enqueueing the order event and the capture request.
+func Test_PurchaseAuthorizesAndEnqueuesCapture(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(testInventory(), nil)
+	db.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(database.Database) error) error { return fn(db) })
+	db.EXPECT().UpsertItems(gomock.Any(), gomock.Any()).Return(nil, nil)
+	var order *model.Order
+	db.EXPECT().AddOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, o *model.Order) error {
+		order = o
+		return nil
+	})
+	var enqueued []*model.OutboxItem
+	db.EXPECT().AddOutboxItems(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, items []*model.OutboxItem) error {
+		enqueued = items
+		return nil
+	})
+
+	rr := purchase(t, newPurchaseService(ctrl, db, payer), "120P90")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	calls := payer.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, fake.Authorize, calls[0].Op)
+	assert.Equal(t, calls[0].AuthorizationID, order.PaymentID)
+	assert.Equal(t, model.PaymentAuthorized, order.PaymentStatus)
+
+	topics := []string{}
+	for _, it := range enqueued {
+		topics = append(topics, it.Topic)
+	}
+	assert.ElementsMatch(t, []string{"orders.created", "payments.capture"}, topics)
+}
+
+// A declined authorization surfaces as 402 and nothing is written.
+func Test_PurchaseDeclined(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+	payer.Script(fake.Authorize, payments.ErrDeclined)
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(testInventory(), nil)
+	// No Transaction expectation: gomock fails the test if the order is written.
+
+	rr := purchase(t, newPurchaseService(ctrl, db, payer), "120P90")
+	require.Equal(t, http.StatusPaymentRequired, rr.Code)
+}
+
+// When the order transaction fails after authorization, the authorization is
+// voided so the customer's funds are released.
+func Test_PurchaseVoidsOnFailedCommit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(testInventory(), nil)
+	db.EXPECT().Transaction(gomock.Any(), gomock.Any()).Return(assert.AnError)
+
+	rr := purchase(t, newPurchaseService(ctrl, db, payer), "120P90")
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	calls := payer.Calls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, fake.Authorize, calls[0].Op)
+	assert.Equal(t, fake.Void, calls[1].Op)
+	assert.Equal(t, calls[0].AuthorizationID, calls[1].AuthorizationID)
+	require.NoError(t, calls[1].Err)
+}
+
+// A gift card pays what its balance covers, debited in the order transaction,
+// and only the remainder is authorized with the provider.
+func Test_PurchaseWithGiftCard(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(testInventory(), nil)
+	db.EXPECT().GetGiftCard(gomock.Any(), "CARD").Return(&model.GiftCard{Code: "CARD", Balance: decimal.NewFromInt(20), Currency: "USD"}, nil)
+	db.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(database.Database) error) error { return fn(db) })
+	db.EXPECT().UpsertItems(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().AddOrder(gomock.Any(), gomock.Any()).Return(nil)
+	var debit *model.GiftCardEntry
+	db.EXPECT().AdjustGiftCard(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, e *model.GiftCardEntry) error {
+		debit = e
+		return nil
+	})
+	db.EXPECT().AddOutboxItems(gomock.Any(), gomock.Any()).Return(nil)
+
+	rr := purchaseWith(t, newPurchaseService(ctrl, db, payer), &model.PurchaseItemsRequest{SKUs: []string{"120P90"}, GiftCard: "CARD"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	assert.Equal(t, "-20", debit.Amount.String())
+	assert.Equal(t, model.GiftCardRedeemed, debit.Reason)
+	calls := payer.Calls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, "29.99", calls[0].Amount.String())
+}
+
+// A concurrent redemption that drains the card between the balance read and
+// the debit fails the purchase with 402 and voids the authorization.
+func Test_PurchaseGiftCardOverdrawn(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(testInventory(), nil)
+	db.EXPECT().GetGiftCard(gomock.Any(), "CARD").Return(&model.GiftCard{Code: "CARD", Balance: decimal.NewFromInt(20), Currency: "USD"}, nil)
+	db.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(database.Database) error) error { return fn(db) })
+	db.EXPECT().UpsertItems(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().AddOrder(gomock.Any(), gomock.Any()).Return(nil)
+	db.EXPECT().AdjustGiftCard(gomock.Any(), gomock.Any()).Return(database.ErrInsufficientBalance)
+
+	rr := purchaseWith(t, newPurchaseService(ctrl, db, payer), &model.PurchaseItemsRequest{SKUs: []string{"120P90"}, GiftCard: "CARD"})
+	require.Equal(t, http.StatusPaymentRequired, rr.Code, rr.Body.String())
+
+	calls := payer.Calls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, fake.Void, calls[1].Op)
+}
+
+// Store credit issued to another customer cannot be tendered: the purchase
+// answers 404, as for a missing card, before anything is charged.
+func Test_PurchaseOtherCustomersStoreCredit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+	payer := fake.NewProvider()
+
+	db.EXPECT().GetPurchaseLimits(gomock.Any(), gomock.Any()).Return(nil, nil)
+	db.EXPECT().GetItemsBySKU(gomock.Any(), gomock.Any()).Return(testInventory(), nil)
+	db.EXPECT().GetGiftCard(gomock.Any(), "CREDIT").Return(&model.GiftCard{Code: "CREDIT", Balance: decimal.NewFromInt(20), Currency: "USD", CustomerID: "someone-else"}, nil)
+
+	rr := purchaseWith(t, newPurchaseService(ctrl, db, payer), &model.PurchaseItemsRequest{SKUs: []string{"120P90"}, GiftCard: "CREDIT"})
+	require.Equal(t, http.StatusNotFound, rr.Code, rr.Body.String())
+	assert.Empty(t, payer.Calls())
+}
+
+// amount matches a decimal.Decimal equal to s, whatever its exponent.
+func amount(s string) gomock.Matcher {
+	want := decimal.RequireFromString(s)
+	return gomock.Cond(func(x any) bool {
+		d, ok := x.(decimal.Decimal)
+		return ok && d.Equal(want)
+	})
+}
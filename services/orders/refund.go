@@ -0,0 +1,277 @@
+package orders
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/errors"
+	"github.com/ATMackay/checkout/event"
+	"github.com/ATMackay/checkout/httpserver"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/services/auth"
+	"github.com/julienschmidt/httprouter"
+	"github.com/shopspring/decimal"
+)
+
+// RefundOrder godoc
+// @Summary Refund lines of a purchase order.
+// @Description Return some or all of an order's paid lines. The refund is priced from the order, with the promotion discount prorated across lines, and paid back asynchronously.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param   reference path    string               true  "Order reference"
+// @Param   request   body    model.RefundRequest  true  "Lines to return"
+// @Success 200 {object} model.Refund
+// @Failure 400 {object} errors.JSONError
+// @Failure 401 {object} errors.JSONError
+// @Failure 404 {object} errors.JSONError
+// @Failure 409 {object} errors.JSONError
+// @Failure 500 {object} errors.JSONError
+// @Security XAuthPassword
+// @Router /v1/orders/{reference}/refunds [post]
+func (h *Service) RefundOrder() httprouter.Handle {
+	return httpserver.Handle(func(r *http.Request, p httprouter.Params) (any, error) {
+		ctx := r.Context()
+
+		customerID, ok := auth.UserID(ctx)
+		if !ok {
+			return nil, fmt.Errorf("%w", errors.ErrInvalidInput)
+		}
+		reference := p.ByName("reference")
+
+		var req model.RefundRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("%w: %v", errors.ErrInvalidInput, err)
+		}
+		requested, err := refundQuantities(req.Lines)
+		if err != nil {
+			return nil, err
+		}
+
+		var refund *model.Refund
+		// The order row is locked for the whole transaction, so the refundable
+		// quantities computed below cannot be raced by a concurrent refund.
+		err = h.store.Transaction(ctx, func(tx database.Database) error {
+			order, err := tx.GetOrderForUpdate(ctx, reference)
+			if err != nil {
+				if stderrors.Is(err, database.ErrOrderNotFound) {
+					return fmt.Errorf("%w: order %s", errors.ErrNotFound, reference)
+				}
+				return fmt.Errorf("could not get order: %w", err)
+			}
+			// Another customer's order is indistinguishable from a missing one.
+			if order.CustomerID != customerID {
+				return fmt.Errorf("%w: order %s", errors.ErrNotFound, reference)
+			}
+			if order.PaymentStatus != model.PaymentCaptured {
+				return fmt.Errorf("%w: order %s payment is %q, not captured", errors.ErrConflict, reference, order.PaymentStatus)
+			}
+			previous, err := tx.GetRefunds(ctx, reference)
+			if err != nil {
+				return fmt.Errorf("could not get refunds: %w", err)
+			}
+			refund, err = newRefund(order, previous, requested, req.Lines)
+			if err != nil {
+				return err
+			}
+			refund.Restocked = req.Restock
+
+			if req.Restock {
+				for _, l := range req.Lines {
+					if err := tx.AdjustInventory(ctx, l.SKU, l.Quantity); err != nil {
+						return fmt.Errorf("failed to restock: %w", err)
+					}
+				}
+			}
+			// Whatever a gift card paid is credited back to it first, in this
+			// transaction; only the remainder goes back through the provider.
+			refund.GiftCardAmount = giftCardRefund(order, previous, refund.Amount)
+			paid := refund.Amount.Sub(refund.GiftCardAmount)
+			if !paid.IsPositive() {
+				refund.Status = model.RefundCompleted
+			}
+			if refund.GiftCardAmount.IsPositive() {
+				if err := tx.AdjustGiftCard(ctx, &model.GiftCardEntry{
+					Code:           order.GiftCardCode,
+					Amount:         refund.GiftCardAmount,
+					Reason:         model.GiftCardRefunded,
+					OrderReference: order.Reference,
+				}); err != nil {
+					return fmt.Errorf("failed to credit gift card: %w", err)
+				}
+			}
+			if err := tx.AddRefund(ctx, refund); err != nil {
+				return fmt.Errorf("failed to record refund: %w", err)
+			}
+			// As with purchases, the money movement and the announcement are
+			// enqueued in the same transaction as the refund row.
+			refundedItem, err := newOutboxItem(event.New(
+				event.TopicOrderRefunded,
+				order.Reference,
+				refund,
+			))
+			if err != nil {
+				return fmt.Errorf("failed to build outbox item: %w", err)
+			}
+			outboxItems := []*model.OutboxItem{refundedItem}
+			if paid.IsPositive() {
+				paymentItem, err := newOutboxItem(event.New(
+					event.TopicPaymentRefund,
+					order.Reference,
+					&model.PaymentRefund{
+						OrderReference:  order.Reference,
+						RefundReference: refund.Reference,
+						AuthorizationID: order.PaymentID,
+						Amount:          paid,
+						Currency:        refund.Currency,
+					},
+				))
+				if err != nil {
+					return fmt.Errorf("failed to build outbox item: %w", err)
+				}
+				outboxItems = append(outboxItems, paymentItem)
+			}
+			if err := tx.AddOutboxItems(ctx, outboxItems); err != nil {
+				return fmt.Errorf("failed to enqueue event: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return refund, nil
+	})
+}
+
+// refundQuantities validates the requested lines and totals them per SKU.
+func refundQuantities(lines []model.RefundLine) (map[string]int, error) {
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("%w: no lines to refund", errors.ErrInvalidInput)
+	}
+	qty := make(map[string]int)
+	for _, l := range lines {
+		if !model.IsSKU(l.SKU) {
+			return nil, fmt.Errorf("%w: invalid sku input '%s'", errors.ErrInvalidInput, l.SKU)
+		}
+		if l.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity for %s must be positive", errors.ErrInvalidInput, l.SKU)
+		}
+		qty[l.SKU] += l.Quantity
+	}
+	return qty, nil
+}
+
+// newRefund prices a refund of the requested quantities against order, given
+// the refunds already taken against it. Only paid lines are refundable; units a
+// promotion added for free have nothing to give back.
+//
+// Each line is refunded at the unit price charged less its share of the order
+// discount. That share is what the order's adjustments took off the SKU, split
+// evenly across its units; orders placed before adjustments were recorded
+// prorate the discount by value: amount = gross - discount*gross/orderGross.
+// The total is capped at what remains of the order price, so rounding across
+// several partial refunds can never return more than was paid.
+func newRefund(order *model.Order, previous []*model.Refund, requested map[string]int, reqLines []model.RefundLine) (*model.Refund, error) {
+	orderLines, err := order.GetLines()
+	if err != nil {
+		return nil, err
+	}
+	paid := make(map[string]model.OrderLine)
+	orderGross := decimal.Zero
+	for _, l := range orderLines {
+		if l.Promotional {
+			continue
+		}
+		paid[l.SKU] = l
+		orderGross = orderGross.Add(l.UnitPrice.Mul(decimal.NewFromInt(int64(l.Quantity))))
+	}
+
+	adjustments, err := order.GetAdjustments()
+	if err != nil {
+		return nil, err
+	}
+	var discounts map[string]decimal.Decimal // by SKU
+	if adjustments != nil {
+		discounts = make(map[string]decimal.Decimal)
+		for _, a := range adjustments {
+			for _, l := range a.Lines {
+				discounts[l.SKU] = discounts[l.SKU].Add(decimal.NewFromFloat(l.Amount))
+			}
+		}
+	}
+
+	refunded := make(map[string]int)
+	refundedAmount := decimal.Zero
+	for _, r := range previous {
+		lines, err := r.GetLines()
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range lines {
+			refunded[l.SKU] += l.Quantity
+		}
+		refundedAmount = refundedAmount.Add(r.Amount)
+	}
+
+	for sku, q := range requested {
+		l, ok := paid[sku]
+		if !ok {
+			return nil, fmt.Errorf("%w: item %s is not a paid line on order %s", errors.ErrInvalidInput, sku, order.Reference)
+		}
+		if remaining := l.Quantity - refunded[sku]; q > remaining {
+			return nil, fmt.Errorf("%w: %d of item %s requested, %d refundable", errors.ErrInvalidInput, q, sku, remaining)
+		}
+	}
+
+	lines := make([]model.RefundLine, 0, len(reqLines))
+	total := decimal.Zero
+	for _, rl := range reqLines {
+		gross := paid[rl.SKU].UnitPrice.Mul(decimal.NewFromInt(int64(rl.Quantity)))
+		amount := gross
+		switch {
+		case discounts != nil:
+			l := paid[rl.SKU]
+			amount = gross.Sub(discounts[rl.SKU].Mul(decimal.NewFromInt(int64(rl.Quantity))).Div(decimal.NewFromInt(int64(l.Quantity))))
+		case orderGross.IsPositive():
+			amount = gross.Sub(order.Discount.Mul(gross).Div(orderGross))
+		}
+		amount = amount.Round(2)
+		lines = append(lines, model.RefundLine{SKU: rl.SKU, Quantity: rl.Quantity, Amount: amount})
+		total = total.Add(amount)
+	}
+	if remaining := order.Price.Sub(refundedAmount); total.GreaterThan(remaining) {
+		last := &lines[len(lines)-1]
+		last.Amount = last.Amount.Sub(total.Sub(remaining))
+		total = remaining
+	}
+
+	refund := &model.Refund{
+		Reference:      model.GenerateReference(),
+		OrderReference: order.Reference,
+		CustomerID:     order.CustomerID,
+		Amount:         total,
+		Currency:       order.Currency,
+		Status:         model.RefundPending,
+	}
+	if err := refund.SetLines(lines); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// giftCardRefund returns how much of a refund of amount goes back to the
+// order's gift card: as much as the card paid, less what earlier refunds have
+// already credited to it.
+func giftCardRefund(order *model.Order, previous []*model.Refund, amount decimal.Decimal) decimal.Decimal {
+	remaining := order.GiftCardAmount
+	for _, r := range previous {
+		remaining = remaining.Sub(r.GiftCardAmount)
+	}
+	if !remaining.IsPositive() {
+		return decimal.Zero
+	}
+	return decimal.Min(remaining, amount)
+}
@@ -0,0 +1,161 @@
+//go:build !integration
+
+package orders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/database/mock"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments/fake"
+	"github.com/ATMackay/checkout/services/auth"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// capturedOrder is a captured order for two TVs at 50.00 and one cable at
+// 20.00, less a 12.00 promotion discount, plus a free promotional cable.
+func capturedOrder(t *testing.T) *model.Order {
+	t.Helper()
+	o := &model.Order{
+		Reference:     "ref-1",
+		CustomerID:    "customer",
+		Price:         decimal.RequireFromString("108.00"),
+		Discount:      decimal.RequireFromString("12.00"),
+		Currency:      "USD",
+		PaymentID:     "auth-1",
+		PaymentStatus: model.PaymentCaptured,
+	}
+	require.NoError(t, o.SetLines([]model.OrderLine{
+		{SKU: "120P90", Quantity: 2, UnitPrice: decimal.RequireFromString("50.00")},
+		{SKU: "A304SD", Quantity: 1, UnitPrice: decimal.RequireFromString("20.00")},
+		{SKU: "A304SD", Quantity: 1, UnitPrice: decimal.Zero, Promotional: true},
+	}))
+	return o
+}
+
+func postRefund(t *testing.T, s *Service, reference string, req *model.RefundRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodPost, OrdersEndPnt+"/"+reference+RefundsEndPnt, bytes.NewReader(body))
+	r.Header.Set(auth.XAuthHeaderKey, testPassword)
+	rr := httptest.NewRecorder()
+	s.RegisterHandlers().ServeHTTP(rr, r)
+	return rr
+}
+
+func expectTx(db *mock.MockDatabase) {
+	db.EXPECT().Transaction(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(database.Database) error) error { return fn(db) })
+}
+
+// A partial refund is priced with the discount prorated by value, restocks the
+// returned units and enqueues the payment and the announcement.
+func Test_RefundProratesDiscount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mock.NewMockDatabase(ctrl)
+
+	expectTx(db)
+	db.EXPECT().GetOrderForUpdate(gomock.Any(), "ref-1").Return(capturedOrder(t), nil)
+	db.EXPECT().GetRefunds(gomock.Any(), "ref-1").Return(nil, nil)
+	db.EXPECT().AdjustInventory(gomock.Any(), "120P90", 1).Return(nil)
+	var recorded *model.Refund
+	db.EXPECT().AddRefund(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r *model.Refund) error {
+		recorded = r
+		return nil
+	})
+	var enqueued []*model.OutboxItem
+	db.EXPECT().AddOutboxItems(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, items []*model.OutboxItem) error {
+		enqueued = items
+		return nil
+	})
+
+	rr := postRefund(t, newPurchaseService(ctrl, db, fake.NewProvider()), "ref-1", &model.RefundRequest{
+		Lines:   []model.RefundLine{{SKU: "120P90", Quantity: 1}},
+		Restock: true,
+	})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// 50.00 of a 120.00 gross carries 50/120 of the 12.00 discount.
+	assert.Equal(t, "45", recorded.Amount.String())
+	assert.Equal(t, model.RefundPending, recorded.Status)
+	assert.True(t, recorded.Restocked)
+
+	topics := []string{}
+	for _, it := range enqueued {
+		topics = append(topics, it.Topic)
+	}
+	assert.ElementsMatch(t, []string{"payments.refund", "orders.refunded"}, topics)
+}
+
+// Units already refunded cannot be refunded again, and the refunds of an order
+// never add up to more than its price.
+func Test_RefundLimits(t *testing.T) {
+	previous := &model.Refund{Amount: decimal.RequireFromString("90.00")}
+	require.NoError(t, previous.SetLines([]model.RefundLine{{SKU: "120P90", Quantity: 2}}))
+
+	order := capturedOrder(t)
+	_, err := newRefund(order, []*model.Refund{previous}, map[string]int{"120P90": 1}, []model.RefundLine{{SKU: "120P90", Quantity: 1}})
+	require.Error(t, err)
+
+	_, err = newRefund(order, nil, map[string]int{"ZZZZZZ": 1}, []model.RefundLine{{SKU: "ZZZZZZ", Quantity: 1}})
+	require.Error(t, err, "only paid lines are refundable")
+
+	order.Price = decimal.RequireFromString("100.00") // e.g. an extra deduction
+	rf, err := newRefund(order, []*model.Refund{previous}, map[string]int{"A304SD": 1}, []model.RefundLine{{SKU: "A304SD", Quantity: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, "10", rf.Amount.String(), "capped at what remains of the order price")
+}
+
+// An order with adjustments is refunded less what they took off each SKU,
+// rather than a share of the discount by value.
+func Test_RefundUsesAdjustments(t *testing.T) {
+	order := capturedOrder(t)
+	require.NoError(t, order.SetAdjustments([]model.Adjustment{
+		{PromotionID: "promotion:1", Reason: "12 off TVs", Amount: 12, Lines: []model.AdjustmentLine{{SKU: "120P90", Quantity: 2, Amount: 12}}},
+	}))
+
+	rf, err := newRefund(order, nil, map[string]int{"120P90": 1}, []model.RefundLine{{SKU: "120P90", Quantity: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, "44", rf.Amount.String(), "half the TVs' 12.00")
+
+	rf, err = newRefund(order, nil, map[string]int{"A304SD": 1}, []model.RefundLine{{SKU: "A304SD", Quantity: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, "20", rf.Amount.String(), "the cable was not discounted")
+}
+
+// Refunding another customer's order is a 404, and an order whose payment was
+// not captured yet is a 409.
+func Test_RefundRejected(t *testing.T) {
+	for name, tc := range map[string]struct {
+		mutate func(*model.Order)
+		status int
+	}{
+		"other customer": {func(o *model.Order) { o.CustomerID = "someone-else" }, http.StatusNotFound},
+		"not captured":   {func(o *model.Order) { o.PaymentStatus = model.PaymentAuthorized }, http.StatusConflict},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			db := mock.NewMockDatabase(ctrl)
+			order := capturedOrder(t)
+			tc.mutate(order)
+
+			expectTx(db)
+			db.EXPECT().GetOrderForUpdate(gomock.Any(), "ref-1").Return(order, nil)
+
+			rr := postRefund(t, newPurchaseService(ctrl, db, fake.NewProvider()), "ref-1", &model.RefundRequest{
+				Lines: []model.RefundLine{{SKU: "120P90", Quantity: 1}},
+			})
+			require.Equal(t, tc.status, rr.Code, rr.Body.String())
+		})
+	}
+}
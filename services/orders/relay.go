@@ -2,7 +2,10 @@ package orders
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"os"
 	"time"
 
 	"github.com/ATMackay/checkout/database"
@@ -10,6 +13,7 @@ import (
 	"github.com/ATMackay/checkout/messaging"
 	"github.com/ATMackay/checkout/model"
 	"github.com/ATMackay/checkout/services/worker"
+	"github.com/google/uuid"
 )
 
 //go:generate mockgen -destination mock/relay.go -package mock github.com/ATMackay/checkout/services/orders Relayer
@@ -20,25 +24,60 @@ type Relayer interface {
 	Start(ctx context.Context) error
 	Stop() error
 	Ping(ctx context.Context) error
+	CheckBacklog(ctx context.Context) error
 }
 
 const (
 	// defaultPollInterval is how often the relay scans the outbox when idle.
-	defaultPollInterval = time.Second
+	// New rows wake it straight away, so this mostly bounds how late a row
+	// backing off or released by an expired lease is retried.
+	defaultPollInterval = 5 * time.Second
 	// defaultBatchSize caps how many rows are claimed per scan.
 	defaultBatchSize = 100
+	// defaultLease is how long a claimed batch is reserved for the relay that
+	// claimed it. It must comfortably exceed the time to publish a batch, or
+	// another replica may claim rows still being published.
+	defaultLease = 30 * time.Second
+	// defaultMaxAttempts is how many times a row is tried before it is
+	// dead-lettered. With the default backoff the last retry comes about half
+	// an hour after the first failure.
+	defaultMaxAttempts = 12
+	// defaultBackoffBase and defaultBackoffMax bound the wait between
+	// attempts, which doubles from the base with every failure.
+	defaultBackoffBase = time.Second
+	defaultBackoffMax  = 15 * time.Minute
 )
 
+// Handler processes an outbox event in-process instead of publishing it. It is
+// for work the orders service owes itself after a commit (e.g. capturing a
+// payment): the outbox gives it the same durability and retry as a broker
+// publish. A Handler may see an event more than once and must be idempotent.
+type Handler func(ctx context.Context, ev *event.Event) error
+
 // OutboxRelayer polls the outbox for unpublished rows and publishes them to the
 // broker, marking each published on success. It closes the loop opened by the
 // purchase handler writing an outbox row inside the order transaction:
 // durability lives in the table, so an unpublished row simply waits for the next
 // scan (or the next process start) rather than being lost.
+//
+// Each scan claims its batch under a lease (database.OutboxStore
+// ClaimOutboxItems), so replicas sharing the table publish disjoint rows. A row
+// whose publish fails is retried with exponential backoff and, after
+// maxAttempts, dead-lettered: kept, but never claimed again.
 type OutboxRelayer struct {
 	outboxStore  database.OutboxStore
 	publisher    messaging.Publisher
+	handlers     map[string]Handler
+	hooks        map[string][]Handler
+	deadLetters  map[string]Handler
 	pollInterval time.Duration
 	batchSize    int
+	owner        string
+	lease        time.Duration
+	maxAttempts  int
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+	maxAge       time.Duration
 
 	runner worker.Runner
 }
@@ -56,13 +95,73 @@ func WithBatchSize(n int) Option {
 	return func(o *OutboxRelayer) { o.batchSize = n }
 }
 
+// WithOwner sets the name the relay claims rows under, which must be unique
+// among the replicas sharing the outbox. The default is the host name with a
+// random suffix.
+func WithOwner(owner string) Option {
+	return func(o *OutboxRelayer) { o.owner = owner }
+}
+
+// WithLease overrides how long a claimed batch is reserved for this relay.
+func WithLease(d time.Duration) Option {
+	return func(o *OutboxRelayer) { o.lease = d }
+}
+
+// WithMaxAttempts overrides how many times a row is tried before it is
+// dead-lettered.
+func WithMaxAttempts(n int) Option {
+	return func(o *OutboxRelayer) { o.maxAttempts = n }
+}
+
+// WithBackoff overrides the wait after a row's first failed attempt, which
+// doubles with each further one up to ceiling.
+func WithBackoff(base, ceiling time.Duration) Option {
+	return func(o *OutboxRelayer) { o.backoffBase, o.backoffMax = base, ceiling }
+}
+
+// WithMaxBacklogAge makes CheckBacklog fail once the oldest unpublished row
+// has waited longer than d. Zero, the default, never fails it.
+func WithMaxBacklogAge(d time.Duration) Option {
+	return func(o *OutboxRelayer) { o.maxAge = d }
+}
+
+// WithHandler routes rows on topic to h instead of the broker. The row is marked
+// published and delivered once h succeeds, as no consumer will acknowledge it;
+// an error retries it after a backoff.
+func WithHandler(topic string, h Handler) Option {
+	return func(o *OutboxRelayer) { o.handlers[topic] = h }
+}
+
+// WithHook runs h on every row on topic before the row is published (or
+// handled), e.g. to act on an order event the broker's consumers also see. The
+// row is only published once every hook has succeeded, so an error retries the
+// hooks with it.
+func WithHook(topic string, h Handler) Option {
+	return func(o *OutboxRelayer) { o.hooks[topic] = append(o.hooks[topic], h) }
+}
+
+// WithDeadLetter runs h on a row on topic once it is dead-lettered, e.g. to
+// record that a payment capture gave up. The row is dead whatever h returns;
+// an error is logged only.
+func WithDeadLetter(topic string, h Handler) Option {
+	return func(o *OutboxRelayer) { o.deadLetters[topic] = h }
+}
+
 // NewOutboxRelayer builds a relayer over the given store and publisher.
 func NewOutboxRelayer(store database.OutboxStore, publisher messaging.Publisher, opts ...Option) *OutboxRelayer {
 	o := &OutboxRelayer{
 		outboxStore:  store,
 		publisher:    publisher,
+		handlers:     make(map[string]Handler),
+		hooks:        make(map[string][]Handler),
+		deadLetters:  make(map[string]Handler),
 		pollInterval: defaultPollInterval,
 		batchSize:    defaultBatchSize,
+		owner:        defaultOwner(),
+		lease:        defaultLease,
+		maxAttempts:  defaultMaxAttempts,
+		backoffBase:  defaultBackoffBase,
+		backoffMax:   defaultBackoffMax,
 	}
 	for _, opt := range opts {
 		opt(o)
@@ -77,11 +176,15 @@ func (o *OutboxRelayer) Start(startCtx context.Context) error {
 		return err
 	}
 	o.runner.Start(o.run)
-	slog.Info("outbox relayer started", "poll_interval", o.pollInterval, "batch_size", o.batchSize)
+	slog.Info("outbox relayer started", "owner", o.owner, "poll_interval", o.pollInterval, "batch_size", o.batchSize, "lease", o.lease)
 	return nil
 }
 
-// run scans the outbox on every tick until its context is cancelled by Stop.
+// run drains the outbox as soon as rows are added to it (database.OutboxStore
+// WatchOutbox), and on every tick, until its context is cancelled by Stop. The
+// ticker is the safety net: it picks up rows due for a retry or whose lease ran
+// out, and every row if the watch is down, which it re-establishes. After each
+// drain the backlog gauges are refreshed.
 // Each drain owns no request deadline, so it uses context.Background() (an honest
 // "no upstream deadline" for a background worker); shutdown just stops scheduling
 // new drains.
@@ -89,38 +192,62 @@ func (o *OutboxRelayer) run(ctx context.Context) {
 	ticker := time.NewTicker(o.pollInterval)
 	defer ticker.Stop()
 
+	wake := o.watch(ctx)
 	var counter int64
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case _, ok := <-wake:
+			if !ok {
+				// The watch ended; the next tick renews it.
+				wake = nil
+				continue
+			}
+			slog.Debug("checking outbox", "wake", counter)
+			o.drain(context.Background())
+			o.observe(context.Background())
+			counter++
 		case <-ticker.C:
+			if wake == nil {
+				wake = o.watch(ctx)
+			}
 			slog.Debug("checking outbox", "tick", counter)
 			o.drain(context.Background())
+			o.observe(context.Background())
 			counter++
 		}
 	}
 }
 
-// drain publishes unpublished rows until a scan returns less than a full batch
-// (caught up) or an error. A scan error ends this cycle; the next tick retries.
+// watch returns the channel signalling rows added to the outbox, or nil, which
+// leaves the relay polling, if the outbox cannot be watched.
+func (o *OutboxRelayer) watch(ctx context.Context) <-chan struct{} {
+	wake, err := o.outboxStore.WatchOutbox(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("could not watch outbox, polling only", "error", err)
+		}
+		return nil
+	}
+	return wake
+}
+
+// drain claims and publishes unpublished rows until a claim returns less than a
+// full batch (caught up) or an error. A claim error ends this cycle; the next
+// tick retries.
 func (o *OutboxRelayer) drain(ctx context.Context) {
 	for {
-		items, err := o.outboxStore.GetOutboxItems(ctx, &database.OutboxQuery{
-			OnlyUnpublished: true,
-			Limit:           o.batchSize,
-		})
+		items, err := o.outboxStore.ClaimOutboxItems(ctx, o.owner, o.lease, o.batchSize)
 		if err != nil {
-			slog.Error("outbox scan failed", "error", err)
+			slog.Error("outbox claim failed", "error", err)
 			return
 		}
 		if len(items) < 1 {
 			return
 		}
 		slog.Info("publishing events", "item_count", len(items))
-		for _, item := range items {
-			o.publish(ctx, item)
-		}
+		o.publish(ctx, items)
 		// A short batch means the outbox is drained; wait for the next tick.
 		if len(items) < o.batchSize {
 			return
@@ -128,24 +255,172 @@ func (o *OutboxRelayer) drain(ctx context.Context) {
 	}
 }
 
-// publish sends one row and marks it published. A publish failure leaves the row
-// unpublished for the next scan. A mark failure after a good publish is logged
-// and tolerated: the row republishes next scan and the consumer deduplicates on
-// event_id, so at-least-once holds.
-func (o *OutboxRelayer) publish(ctx context.Context, item *model.OutboxItem) {
-	ev, err := event.Decode(item.Topic, item.PartitionKey, item.Data)
-	if err != nil {
-		slog.Error("outbox item decode failed", "id", item.ID, "event_id", item.EventID, "error", err)
+// publish runs each row's hooks, then sends it to its in-process Handler if its
+// topic has one, and the rest to the broker in one batch. Rows sent to the
+// broker are marked published in one update, and handled rows published and
+// delivered in another, so retention can purge them. A hook, handler or publish failure
+// is recorded on its row, which is retried after a backoff until it runs out
+// of attempts. A mark failure after a good publish is logged and tolerated: the
+// rows republish next scan and the consumer deduplicates on event_id, so
+// at-least-once holds.
+//
+// Rows are claimed in ID order and the batch keeps that order, so events
+// sharing a topic and key reach the broker in the order they were enqueued.
+// Once a row fails, the later rows with its topic and key are held back, as
+// are the rows the publisher fails only behind an earlier failure on their
+// partition (messaging.ErrHeldBack). A held-back row is not at fault, so it is
+// released without counting an attempt; the claim then skips it until the
+// failed row with its key is published or dead.
+func (o *OutboxRelayer) publish(ctx context.Context, items []*model.OutboxItem) {
+	var published, handled, held, batch []*model.OutboxItem
+	var events []*event.Event
+	type key struct{ topic, key string }
+	failed := make(map[key]bool)
+	for _, item := range items {
+		k := key{item.Topic, item.PartitionKey}
+		if item.PartitionKey != "" && failed[k] {
+			held = append(held, item)
+			continue
+		}
+		ev, err := event.Decode(item.Topic, item.PartitionKey, item.Data)
+		if err != nil {
+			// A row that cannot be decoded never will be: retrying it is futile.
+			o.fail(ctx, item, fmt.Errorf("decode: %w", err), false)
+			failed[k] = true
+			continue
+		}
+		inProcess, err := o.handle(ctx, item.Topic, ev)
+		if err != nil {
+			o.fail(ctx, item, err, true)
+			failed[k] = true
+			continue
+		}
+		if inProcess {
+			handled = append(handled, item)
+			continue
+		}
+		batch = append(batch, item)
+		events = append(events, ev)
+	}
+	if len(events) > 0 {
+		// The publisher fails the events after a failed one with its key
+		// itself, with ErrHeldBack, so they are retried behind it.
+		errs := o.publisher.PublishBatch(ctx, events)
+		for i, item := range batch {
+			switch {
+			case errors.Is(errs[i], messaging.ErrHeldBack):
+				held = append(held, item)
+			case errs[i] != nil:
+				o.fail(ctx, item, errs[i], true)
+			default:
+				published = append(published, item)
+			}
+		}
+	}
+	now := time.Now().UTC()
+	o.mark(ctx, published, now, o.outboxStore.SetPublishedAtBulk)
+	o.mark(ctx, handled, now, o.outboxStore.SetDeliveredAtBulk)
+	o.release(ctx, held)
+}
+
+// release gives up the claim on rows held back behind a failed one, without
+// counting an attempt against them. A release failure is logged and tolerated:
+// the rows are claimed again once their lease runs out.
+func (o *OutboxRelayer) release(ctx context.Context, items []*model.OutboxItem) {
+	if len(items) == 0 {
+		return
+	}
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+		slog.Debug("outbox item held back behind a failed one", "id", item.ID, "event_id", item.EventID)
+	}
+	if err := o.outboxStore.ReleaseOutboxItems(ctx, o.owner, ids); err != nil {
+		slog.Error("outbox items release failed", "item_count", len(ids), "error", err)
+	}
+}
+
+// mark records items as published at now with set, which takes their IDs.
+func (o *OutboxRelayer) mark(ctx context.Context, items []*model.OutboxItem, now time.Time, set func(context.Context, []int64, time.Time) error) {
+	if len(items) == 0 {
+		return
+	}
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+		OutboxPublished.WithLabelValues(item.Topic).Inc()
+		OutboxPublishLatency.WithLabelValues(item.Topic).Observe(now.Sub(item.CreatedAt).Seconds())
+		slog.Debug("published event", "event_id", item.EventID, "payload_size", len(item.Data))
+	}
+	if err := set(ctx, ids, now); err != nil {
+		slog.Error("outbox items mark-published failed", "item_count", len(ids), "error", err)
+	}
+}
+
+// handle runs ev's hooks, then its Handler if its topic has one, reporting
+// whether it was handled in-process; if not, it is left for the broker.
+func (o *OutboxRelayer) handle(ctx context.Context, topic string, ev *event.Event) (bool, error) {
+	for _, hook := range o.hooks[topic] {
+		if err := hook(ctx, ev); err != nil {
+			return false, fmt.Errorf("hook: %w", err)
+		}
+	}
+	h, ok := o.handlers[topic]
+	if !ok {
+		return false, nil
+	}
+	return true, h(ctx, ev)
+}
+
+// fail records a failed attempt to publish item. A retryable failure is
+// retried after a backoff that doubles with every attempt, until the row has
+// used up its attempts; then, or straight away if the failure is not
+// retryable, the row is dead-lettered.
+func (o *OutboxRelayer) fail(ctx context.Context, item *model.OutboxItem, cause error, retryable bool) {
+	OutboxPublishFailures.WithLabelValues(item.Topic).Inc()
+	attempts := item.Attempts + 1
+	var retryAt *time.Time
+	if retryable && attempts < o.maxAttempts {
+		t := time.Now().UTC().Add(o.backoff(attempts))
+		retryAt = &t
+		slog.Error("outbox item publish failed", "id", item.ID, "event_id", item.EventID, "attempt", attempts, "retry_at", t, "error", cause)
+	} else {
+		OutboxDeadLettered.WithLabelValues(item.Topic).Inc()
+		slog.Error("outbox item dead-lettered", "id", item.ID, "event_id", item.EventID, "attempt", attempts, "error", cause)
+	}
+	if err := o.outboxStore.FailOutboxItem(ctx, item.ID, cause.Error(), retryAt); err != nil {
+		slog.Error("outbox item record-failure failed", "id", item.ID, "event_id", item.EventID, "error", err)
 		return
 	}
-	if err := o.publisher.Publish(ctx, ev); err != nil {
-		slog.Error("outbox item publish failed", "id", item.ID, "event_id", item.EventID, "error", err)
+	if retryAt == nil {
+		o.deadLetter(ctx, item)
+	}
+}
+
+// deadLetter runs the WithDeadLetter handler for a row just dead-lettered, if
+// its topic has one.
+func (o *OutboxRelayer) deadLetter(ctx context.Context, item *model.OutboxItem) {
+	h, ok := o.deadLetters[item.Topic]
+	if !ok {
 		return
 	}
-	if err := o.outboxStore.SetPublishedAt(ctx, item.ID, time.Now().UTC()); err != nil {
-		slog.Error("outbox item mark-published failed", "id", item.ID, "event_id", item.EventID, "error", err)
+	ev, err := event.Decode(item.Topic, item.PartitionKey, item.Data)
+	if err == nil {
+		err = h(ctx, ev)
+	}
+	if err != nil {
+		slog.Error("outbox item dead-letter handler failed", "id", item.ID, "event_id", item.EventID, "error", err)
+	}
+}
+
+// backoff is the wait before the attempt after the given number of failed
+// ones: the base doubled per further failure, capped at the maximum.
+func (o *OutboxRelayer) backoff(attempts int) time.Duration {
+	d := o.backoffBase
+	for i := 1; i < attempts && d < o.backoffMax; i++ {
+		d *= 2
 	}
-	slog.Debug("published event", "event_id", ev.ID, "payload_size", len(item.Data))
+	return min(d, o.backoffMax)
 }
 
 // Ping reports broker reachability, for the service health probe.
@@ -153,6 +428,52 @@ func (o *OutboxRelayer) Ping(ctx context.Context) error {
 	return o.publisher.Ping(ctx)
 }
 
+// CheckBacklog reports an error when the oldest unpublished row has waited
+// longer than the WithMaxBacklogAge limit, for the service health probe: a
+// growing backlog means the relay is stuck or the broker is not keeping up,
+// even while both still answer a ping. It refreshes the backlog gauges too.
+func (o *OutboxRelayer) CheckBacklog(ctx context.Context) error {
+	if o.maxAge <= 0 {
+		return nil
+	}
+	b, err := o.backlog(ctx)
+	if err != nil {
+		return err
+	}
+	if b.Oldest == nil {
+		return nil
+	}
+	if age := time.Since(*b.Oldest); age > o.maxAge {
+		return fmt.Errorf("oldest of %d unpublished outbox rows is %s old, over the %s limit",
+			b.Rows, age.Truncate(time.Second), o.maxAge)
+	}
+	return nil
+}
+
+// observe refreshes the backlog gauges. A failure is logged only: the gauges
+// keep their last values until the next drain.
+func (o *OutboxRelayer) observe(ctx context.Context) {
+	if _, err := o.backlog(ctx); err != nil {
+		slog.Warn("could not measure outbox backlog", "error", err)
+	}
+}
+
+// backlog reads the outbox backlog and records it in the gauges.
+func (o *OutboxRelayer) backlog(ctx context.Context) (*model.OutboxBacklog, error) {
+	b, err := o.outboxStore.GetOutboxBacklog(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("outbox backlog: %w", err)
+	}
+	var age float64
+	if b.Oldest != nil {
+		age = max(time.Since(*b.Oldest).Seconds(), 0)
+	}
+	OutboxBacklogRows.Set(float64(b.Rows))
+	OutboxOldestUnpublishedAge.Set(age)
+	OutboxDeadRows.Set(float64(b.Dead))
+	return b, nil
+}
+
 // Stop terminates the poll loop and closes the publisher. The Runner's Stop is
 // idempotent and blocks until the loop goroutine has returned.
 func (o *OutboxRelayer) Stop() error {
@@ -160,3 +481,13 @@ func (o *OutboxRelayer) Stop() error {
 	slog.Debug("stopped relayer")
 	return o.publisher.Close()
 }
+
+// defaultOwner names a relay after its host, suffixed so that two relays on one
+// host (or a restarted one) never share a name.
+func defaultOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "relay"
+	}
+	return host + "-" + uuid.New().String()[:8]
+}
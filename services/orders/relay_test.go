@@ -5,14 +5,16 @@ package orders
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
-	"github.com/ATMackay/checkout/database"
 	dbmock "github.com/ATMackay/checkout/database/mock"
 	"github.com/ATMackay/checkout/event"
+	"github.com/ATMackay/checkout/messaging"
 	msgmock "github.com/ATMackay/checkout/messaging/mock"
 	"github.com/ATMackay/checkout/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"go.uber.org/goleak"
 	"go.uber.org/mock/gomock"
 )
@@ -33,13 +35,14 @@ func testItem(t *testing.T, id int64, key string) *model.OutboxItem {
 	return item
 }
 
-// unpublishedBatch is the query the relay issues each scan.
-func unpublishedBatch() *database.OutboxQuery {
-	return &database.OutboxQuery{OnlyUnpublished: true, Limit: defaultBatchSize}
+// claimBatch expects the claim the relay makes each scan.
+func claimBatch(store *dbmock.MockOutboxStore) *gomock.Call {
+	return store.EXPECT().ClaimOutboxItems(gomock.Any(), gomock.Any(), defaultLease, defaultBatchSize)
 }
 
 // drain is exercised directly (rather than via the ticker goroutine) so the mock
-// expectations are exact: one scan of two rows publishes and marks both.
+// expectations are exact: one scan of two rows publishes both in one batch and
+// marks both in one update.
 func TestOutboxRelayer_DrainPublishesAndMarks(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	store := dbmock.NewMockOutboxStore(ctrl)
@@ -48,63 +51,326 @@ func TestOutboxRelayer_DrainPublishesAndMarks(t *testing.T) {
 	item1 := testItem(t, 1, "ref-1")
 	item2 := testItem(t, 2, "ref-2")
 
-	store.EXPECT().GetOutboxItems(gomock.Any(), unpublishedBatch()).
-		Return([]*model.OutboxItem{item1, item2}, nil)
+	claimBatch(store).Return([]*model.OutboxItem{item1, item2}, nil)
 
 	var published []*event.Event
-	pub.EXPECT().Publish(gomock.Any(), gomock.Any()).
-		DoAndReturn(func(_ context.Context, ev *event.Event) error {
-			published = append(published, ev)
-			return nil
-		}).Times(2)
+	pub.EXPECT().PublishBatch(gomock.Any(), gomock.Len(2)).
+		DoAndReturn(func(_ context.Context, evs []*event.Event) []error {
+			published = evs
+			return make([]error, len(evs))
+		})
 
-	store.EXPECT().SetPublishedAt(gomock.Any(), int64(1), gomock.Any()).Return(nil)
-	store.EXPECT().SetPublishedAt(gomock.Any(), int64(2), gomock.Any()).Return(nil)
+	store.EXPECT().SetPublishedAtBulk(gomock.Any(), []int64{1, 2}, gomock.Any()).Return(nil)
 
+	before := testutil.ToFloat64(OutboxPublished.WithLabelValues(event.TopicOrderCreated))
 	NewOutboxRelayer(store, pub).drain(context.Background())
 
 	if len(published) != 2 {
 		t.Fatalf("published %d events, want 2", len(published))
 	}
+	if got := testutil.ToFloat64(OutboxPublished.WithLabelValues(event.TopicOrderCreated)) - before; got != 2 {
+		t.Errorf("published counter rose by %v, want 2", got)
+	}
 	// Routing metadata survives the store round-trip.
 	if published[0].Topic != event.TopicOrderCreated || published[0].Key != "ref-1" {
 		t.Errorf("event 0 = {topic:%s key:%s}, want {orders.created ref-1}", published[0].Topic, published[0].Key)
 	}
 }
 
-// A publish failure must leave the row unpublished: no SetPublishedAt is
-// expected, so gomock fails the test if the relay marks it anyway.
+// A publish failure must leave the row unpublished, to be retried after a
+// backoff: no SetPublishedAtBulk is expected, so gomock fails the test if the
+// relay marks it anyway.
 func TestOutboxRelayer_DrainPublishFailureLeavesRowUnpublished(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	store := dbmock.NewMockOutboxStore(ctrl)
 	pub := msgmock.NewMockPublisher(ctrl)
 
-	store.EXPECT().GetOutboxItems(gomock.Any(), unpublishedBatch()).
-		Return([]*model.OutboxItem{testItem(t, 1, "ref-1")}, nil)
-	pub.EXPECT().Publish(gomock.Any(), gomock.Any()).Return(errors.New("broker down"))
+	claimBatch(store).Return([]*model.OutboxItem{testItem(t, 1, "ref-1")}, nil)
+	pub.EXPECT().PublishBatch(gomock.Any(), gomock.Len(1)).Return([]error{errors.New("broker down")})
+	store.EXPECT().FailOutboxItem(gomock.Any(), int64(1), "broker down", gomock.Not(gomock.Nil())).Return(nil)
+
+	NewOutboxRelayer(store, pub).drain(context.Background())
+}
+
+// The batch keeps the claim order, so rows sharing a key are published in the
+// order they were enqueued. Only the rows the broker acknowledged are marked;
+// the one it rejected is retried.
+func TestOutboxRelayer_DrainBatchPartialFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+	pub := msgmock.NewMockPublisher(ctrl)
+
+	items := []*model.OutboxItem{testItem(t, 1, "ref-1"), testItem(t, 2, "ref-2"), testItem(t, 3, "ref-1")}
+	claimBatch(store).Return(items, nil)
+
+	var published []*event.Event
+	pub.EXPECT().PublishBatch(gomock.Any(), gomock.Len(3)).
+		DoAndReturn(func(_ context.Context, evs []*event.Event) []error {
+			published = evs
+			return []error{nil, errors.New("broker down"), nil}
+		})
+	store.EXPECT().FailOutboxItem(gomock.Any(), int64(2), "broker down", gomock.Not(gomock.Nil())).Return(nil)
+	store.EXPECT().SetPublishedAtBulk(gomock.Any(), []int64{1, 3}, gomock.Any()).Return(nil)
 
 	NewOutboxRelayer(store, pub).drain(context.Background())
+
+	for i, item := range items {
+		if published[i].ID != item.EventID {
+			t.Errorf("event %d = %s, want %s (claim order broken)", i, published[i].ID, item.EventID)
+		}
+	}
+}
+
+// Once a row fails, the later rows with its key are held back rather than
+// overtake it: neither published, failed nor marked, they are released to be
+// claimed again behind it. Rows with other keys are unaffected.
+func TestOutboxRelayer_DrainHoldsBackKeyAfterFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+	pub := msgmock.NewMockPublisher(ctrl)
+
+	items := []*model.OutboxItem{testItem(t, 1, "ref-1"), testItem(t, 2, "ref-1"), testItem(t, 3, "ref-2")}
+	claimBatch(store).Return(items, nil)
+	hook := func(_ context.Context, ev *event.Event) error {
+		if ev.ID == items[0].EventID {
+			return errors.New("db down")
+		}
+		return nil
+	}
+	store.EXPECT().FailOutboxItem(gomock.Any(), int64(1), "hook: db down", gomock.Not(gomock.Nil())).Return(nil)
+	var published []*event.Event
+	pub.EXPECT().PublishBatch(gomock.Any(), gomock.Len(1)).
+		DoAndReturn(func(_ context.Context, evs []*event.Event) []error {
+			published = evs
+			return make([]error, len(evs))
+		})
+	store.EXPECT().SetPublishedAtBulk(gomock.Any(), []int64{3}, gomock.Any()).Return(nil)
+	store.EXPECT().ReleaseOutboxItems(gomock.Any(), gomock.Any(), []int64{2}).Return(nil)
+
+	NewOutboxRelayer(store, pub, WithHook(event.TopicOrderCreated, hook)).drain(context.Background())
+
+	if published[0].ID != items[2].EventID {
+		t.Errorf("published %s, want %s", published[0].ID, items[2].EventID)
+	}
+}
+
+// A row the publisher fails only behind an earlier failure on its partition
+// is released without counting an attempt; only the row that failed is
+// charged one.
+func TestOutboxRelayer_DrainReleasesPublisherHeldBack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+	pub := msgmock.NewMockPublisher(ctrl)
+
+	items := []*model.OutboxItem{testItem(t, 1, "ref-1"), testItem(t, 2, "ref-2"), testItem(t, 3, "ref-3")}
+	claimBatch(store).Return(items, nil)
+	cause := errors.New("broker down")
+	pub.EXPECT().PublishBatch(gomock.Any(), gomock.Len(3)).
+		Return([]error{cause, fmt.Errorf("%w: %w", messaging.ErrHeldBack, cause), nil})
+	store.EXPECT().FailOutboxItem(gomock.Any(), int64(1), "broker down", gomock.Not(gomock.Nil())).Return(nil)
+	store.EXPECT().ReleaseOutboxItems(gomock.Any(), "relay-a", []int64{2}).Return(nil)
+	store.EXPECT().SetPublishedAtBulk(gomock.Any(), []int64{3}, gomock.Any()).Return(nil)
+
+	NewOutboxRelayer(store, pub, WithOwner("relay-a")).drain(context.Background())
+}
+
+// A row that has used up its attempts, or cannot be decoded at all, is
+// dead-lettered: its failure is recorded with no retry time.
+func TestOutboxRelayer_DrainDeadLetters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+	pub := msgmock.NewMockPublisher(ctrl)
+
+	exhausted := testItem(t, 1, "ref-1")
+	exhausted.Attempts = 2
+	poison := testItem(t, 2, "ref-2")
+	poison.Data = []byte("not an envelope")
+
+	claimBatch(store).Return([]*model.OutboxItem{exhausted, poison}, nil)
+	pub.EXPECT().PublishBatch(gomock.Any(), gomock.Len(1)).Return([]error{errors.New("broker down")})
+	store.EXPECT().FailOutboxItem(gomock.Any(), int64(1), "broker down", nil).Return(nil)
+	store.EXPECT().FailOutboxItem(gomock.Any(), int64(2), gomock.Any(), nil).Return(nil)
+
+	NewOutboxRelayer(store, pub, WithMaxAttempts(3)).drain(context.Background())
+}
+
+// A capture the relay gives up on marks its order's payment failed, through
+// the dead-letter handler; a failure with attempts to spare does not.
+func TestOutboxRelayer_DrainDeadLetterHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+	orders := dbmock.NewMockOrderStore(ctrl)
+	pub := msgmock.NewMockPublisher(ctrl)
+
+	item, err := newOutboxItem(event.New(event.TopicPaymentCapture, "ref-1", &model.PaymentCapture{OrderReference: "ref-1"}))
+	if err != nil {
+		t.Fatalf("build outbox item: %v", err)
+	}
+	item.ID = 7
+	declined := func(context.Context, *event.Event) error { return errors.New("declined") }
+	o := NewOutboxRelayer(store, pub, WithMaxAttempts(2),
+		WithHandler(event.TopicPaymentCapture, declined),
+		WithDeadLetter(event.TopicPaymentCapture, NewCaptureFailedHandler(orders)),
+	)
+
+	// First attempt: retried, the payment stays authorized.
+	claimBatch(store).Return([]*model.OutboxItem{item}, nil)
+	store.EXPECT().FailOutboxItem(gomock.Any(), int64(7), "declined", gomock.Not(gomock.Nil())).Return(nil)
+	o.drain(context.Background())
+
+	// Last attempt: dead-lettered, the payment failed.
+	item.Attempts = 1
+	claimBatch(store).Return([]*model.OutboxItem{item}, nil)
+	store.EXPECT().FailOutboxItem(gomock.Any(), int64(7), "declined", nil).Return(nil)
+	orders.EXPECT().SetPaymentStatus(gomock.Any(), "ref-1", model.PaymentFailed).Return(nil)
+	o.drain(context.Background())
+}
+
+// The wait between attempts doubles from the base up to the ceiling.
+func TestOutboxRelayer_Backoff(t *testing.T) {
+	o := NewOutboxRelayer(nil, nil, WithBackoff(time.Second, 10*time.Second))
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 100: 10 * time.Second} {
+		if got := o.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+// A row whose topic has an in-process Handler goes to the handler, not the
+// broker: no PublishBatch is expected, so gomock fails the test if one happens.
+// No consumer acknowledges it, so it is marked delivered as well as published.
+func TestOutboxRelayer_DrainRoutesToHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+	pub := msgmock.NewMockPublisher(ctrl)
+
+	item, err := newOutboxItem(event.New(event.TopicPaymentCapture, "ref-1", &model.PaymentCapture{OrderReference: "ref-1"}))
+	if err != nil {
+		t.Fatalf("build outbox item: %v", err)
+	}
+	item.ID = 7
+
+	claimBatch(store).Return([]*model.OutboxItem{item}, nil)
+	store.EXPECT().SetDeliveredAtBulk(gomock.Any(), []int64{7}, gomock.Any()).Return(nil)
+
+	var handled []string
+	handler := func(_ context.Context, ev *event.Event) error {
+		handled = append(handled, ev.Key)
+		return nil
+	}
+	NewOutboxRelayer(store, pub, WithHandler(event.TopicPaymentCapture, handler)).drain(context.Background())
+
+	if len(handled) != 1 || handled[0] != "ref-1" {
+		t.Fatalf("handled = %v, want [ref-1]", handled)
+	}
+}
+
+// Hooks run before a row is published; a failing hook fails the row, which is
+// neither published nor marked.
+func TestOutboxRelayer_DrainRunsHooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+	pub := msgmock.NewMockPublisher(ctrl)
+
+	claimBatch(store).Return([]*model.OutboxItem{testItem(t, 1, "ref-1"), testItem(t, 2, "ref-2")}, nil)
+	pub.EXPECT().PublishBatch(gomock.Any(), gomock.Len(1)).Return([]error{nil})
+	store.EXPECT().SetPublishedAtBulk(gomock.Any(), []int64{1}, gomock.Any()).Return(nil)
+
+	var hooked []string
+	hook := func(_ context.Context, ev *event.Event) error {
+		hooked = append(hooked, ev.Key)
+		if ev.Key == "ref-2" {
+			return errors.New("db down")
+		}
+		return nil
+	}
+	store.EXPECT().FailOutboxItem(gomock.Any(), int64(2), "hook: db down", gomock.Not(gomock.Nil())).Return(nil)
+	NewOutboxRelayer(store, pub, WithHook(event.TopicOrderCreated, hook)).drain(context.Background())
+
+	if len(hooked) != 2 {
+		t.Fatalf("hooked = %v, want [ref-1 ref-2]", hooked)
+	}
 }
 
-// A scan error ends the cycle without publishing.
+// Rows are claimed under the relay's owner name and lease, which replicas
+// sharing the outbox must not share.
+func TestOutboxRelayer_DrainClaimsAsOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+	pub := msgmock.NewMockPublisher(ctrl)
+
+	store.EXPECT().ClaimOutboxItems(gomock.Any(), "relay-1", time.Minute, defaultBatchSize).Return(nil, nil)
+
+	NewOutboxRelayer(store, pub, WithOwner("relay-1"), WithLease(time.Minute)).drain(context.Background())
+
+	if a, b := defaultOwner(), defaultOwner(); a == b {
+		t.Errorf("default owners collide: %s", a)
+	}
+}
+
+// A claim error ends the cycle without publishing.
 func TestOutboxRelayer_DrainScanError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	store := dbmock.NewMockOutboxStore(ctrl)
 	pub := msgmock.NewMockPublisher(ctrl)
 
-	store.EXPECT().GetOutboxItems(gomock.Any(), unpublishedBatch()).
-		Return(nil, errors.New("db down"))
+	claimBatch(store).Return(nil, errors.New("db down"))
 
 	NewOutboxRelayer(store, pub).drain(context.Background())
 }
 
+// CheckBacklog fails once the oldest unpublished row is older than the limit,
+// and is disabled without one. Each check refreshes the backlog gauges.
+func TestOutboxRelayer_CheckBacklog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+
+	if err := NewOutboxRelayer(store, nil).CheckBacklog(context.Background()); err != nil {
+		t.Fatalf("check without a limit: %v", err)
+	}
+
+	r := NewOutboxRelayer(store, nil, WithMaxBacklogAge(time.Minute))
+	recent := time.Now().Add(-time.Second)
+	stale := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name    string
+		backlog *model.OutboxBacklog
+		err     error
+		wantErr bool
+	}{
+		{"empty", &model.OutboxBacklog{}, nil, false},
+		{"recent", &model.OutboxBacklog{Rows: 3, Oldest: &recent}, nil, false},
+		{"stale", &model.OutboxBacklog{Rows: 3, Oldest: &stale}, nil, true},
+		{"store error", nil, errors.New("db down"), true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store.EXPECT().GetOutboxBacklog(gomock.Any()).Return(tc.backlog, tc.err)
+			if err := r.CheckBacklog(context.Background()); (err != nil) != tc.wantErr {
+				t.Fatalf("check: got %v, want error %v", err, tc.wantErr)
+			}
+			if tc.backlog == nil {
+				return
+			}
+			if got := testutil.ToFloat64(OutboxBacklogRows); got != float64(tc.backlog.Rows) {
+				t.Errorf("backlog rows gauge = %v, want %d", got, tc.backlog.Rows)
+			}
+			if age := testutil.ToFloat64(OutboxOldestUnpublishedAge); (age > 0) != (tc.backlog.Oldest != nil) {
+				t.Errorf("oldest age gauge = %v with oldest %v", age, tc.backlog.Oldest)
+			}
+		})
+	}
+}
+
 // Stop is idempotent and must not panic on a second call. A long poll interval
-// keeps the ticker from firing, so the store is never touched.
+// keeps the ticker from firing and the watch is never signalled, so the store
+// is only watched.
 func TestOutboxRelayer_StopIsIdempotent(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	store := dbmock.NewMockOutboxStore(ctrl)
 	pub := msgmock.NewMockPublisher(ctrl)
 
+	store.EXPECT().WatchOutbox(gomock.Any()).Return(make(<-chan struct{}), nil).MaxTimes(1)
 	pub.EXPECT().Ping(gomock.Any()).Return(nil)
 	// Stop closes the publisher on each call; the second Stop is a no-op for the
 	// quit channel but still calls Close.
@@ -122,6 +388,75 @@ func TestOutboxRelayer_StopIsIdempotent(t *testing.T) {
 	}
 }
 
+// Rows added to the outbox wake the relay without waiting for a tick.
+func TestOutboxRelayer_WakeDrains(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+	pub := msgmock.NewMockPublisher(ctrl)
+
+	wake := make(chan struct{}, 1)
+	drained := make(chan struct{})
+	store.EXPECT().WatchOutbox(gomock.Any()).Return(wake, nil)
+	claimBatch(store).DoAndReturn(func(context.Context, string, time.Duration, int) ([]*model.OutboxItem, error) {
+		close(drained)
+		return nil, nil
+	})
+	store.EXPECT().GetOutboxBacklog(gomock.Any()).Return(&model.OutboxBacklog{}, nil).AnyTimes()
+	pub.EXPECT().Ping(gomock.Any()).Return(nil)
+	pub.EXPECT().Close().Return(nil)
+
+	r := NewOutboxRelayer(store, pub, WithPollInterval(time.Hour))
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	wake <- struct{}{}
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay did not drain on wake-up")
+	}
+	if err := r.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+}
+
+// A watch that fails or ends leaves the relay polling, and the next tick
+// watches again.
+func TestOutboxRelayer_RewatchesOnTick(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+	pub := msgmock.NewMockPublisher(ctrl)
+
+	ended := make(chan struct{})
+	close(ended)
+	rewatched := make(chan struct{})
+	gomock.InOrder(
+		store.EXPECT().WatchOutbox(gomock.Any()).Return(nil, errors.New("listen failed")),
+		store.EXPECT().WatchOutbox(gomock.Any()).Return(ended, nil),
+		store.EXPECT().WatchOutbox(gomock.Any()).DoAndReturn(func(context.Context) (<-chan struct{}, error) {
+			close(rewatched)
+			return make(chan struct{}), nil
+		}),
+	)
+	claimBatch(store).Return(nil, nil).AnyTimes()
+	store.EXPECT().GetOutboxBacklog(gomock.Any()).Return(&model.OutboxBacklog{}, nil).AnyTimes()
+	pub.EXPECT().Ping(gomock.Any()).Return(nil)
+	pub.EXPECT().Close().Return(nil)
+
+	r := NewOutboxRelayer(store, pub, WithPollInterval(10*time.Millisecond))
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	select {
+	case <-rewatched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("relay did not watch the outbox again")
+	}
+	if err := r.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+}
+
 // A failing broker ping aborts Start before any goroutine spawns.
 func TestOutboxRelayer_StartFailsWhenBrokerUnreachable(t *testing.T) {
 	ctrl := gomock.NewController(t)
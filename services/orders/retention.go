@@ -0,0 +1,130 @@
+package orders
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/services/worker"
+)
+
+const (
+	// defaultPurgeInterval is how often the purger looks for expired rows.
+	defaultPurgeInterval = time.Hour
+	// defaultPurgeBatchSize caps how many rows one purge transaction removes,
+	// so a large backlog never holds a long lock on the outbox.
+	defaultPurgeBatchSize = 500
+)
+
+// OutboxPurger enforces the outbox retention period. Rows are kept after the
+// relay publishes them and the notifier marks them delivered; once both have
+// happened and the row is older than the retention period, the purger deletes
+// it, or moves it to the outbox_archive table. Rows still pending, dead or
+// undelivered are never purged.
+//
+// Purging runs in bounded batches (database.OutboxStore PurgeOutboxItems), so
+// it is safe on several replicas at once: each batch removes rows no other
+// batch can see once it commits.
+type OutboxPurger struct {
+	outboxStore database.OutboxStore
+	retention   time.Duration
+	interval    time.Duration
+	batchSize   int
+	archive     bool
+
+	runner worker.Runner
+}
+
+// PurgerOption configures an OutboxPurger.
+type PurgerOption func(*OutboxPurger)
+
+// WithPurgeInterval overrides how often the purger runs.
+func WithPurgeInterval(d time.Duration) PurgerOption {
+	return func(p *OutboxPurger) { p.interval = d }
+}
+
+// WithPurgeBatchSize overrides how many rows each purge transaction removes.
+func WithPurgeBatchSize(n int) PurgerOption {
+	return func(p *OutboxPurger) { p.batchSize = n }
+}
+
+// WithArchive makes the purger move expired rows to the outbox_archive table
+// instead of deleting them.
+func WithArchive() PurgerOption {
+	return func(p *OutboxPurger) { p.archive = true }
+}
+
+// NewOutboxPurger returns a purger removing published and delivered rows once
+// they are older than retention.
+func NewOutboxPurger(store database.OutboxStore, retention time.Duration, opts ...PurgerOption) *OutboxPurger {
+	p := &OutboxPurger{
+		outboxStore: store,
+		retention:   retention,
+		interval:    defaultPurgeInterval,
+		batchSize:   defaultPurgeBatchSize,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Start launches the purge loop. The first purge runs one interval after
+// Start, not at boot, so a restart loop cannot hammer the database.
+func (p *OutboxPurger) Start(context.Context) error {
+	p.runner.Start(p.run)
+	slog.Info("outbox purger started", "retention", p.retention, "interval", p.interval, "batch_size", p.batchSize, "archive", p.archive)
+	return nil
+}
+
+// Stop terminates the purge loop, waiting for a purge in progress to finish
+// its current batch.
+func (p *OutboxPurger) Stop() error {
+	p.runner.Stop()
+	slog.Debug("stopped outbox purger")
+	return nil
+}
+
+func (p *OutboxPurger) run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.Purge(ctx); err != nil {
+				slog.Error("outbox purge failed", "error", err)
+			}
+		}
+	}
+}
+
+// Purge removes every expired row, a batch at a time, and returns how many it
+// removed. It stops early, with what it removed so far, when ctx is cancelled
+// between batches or a batch fails.
+func (p *OutboxPurger) Purge(ctx context.Context) (int, error) {
+	before := time.Now().UTC().Add(-p.retention)
+	action := "deleted"
+	if p.archive {
+		action = "archived"
+	}
+	total := 0
+	for ctx.Err() == nil {
+		n, err := p.outboxStore.PurgeOutboxItems(ctx, before, p.batchSize, p.archive)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		OutboxRowsPurged.WithLabelValues(action).Add(float64(n))
+		// A short batch means nothing expired is left.
+		if n == 0 || n < p.batchSize {
+			break
+		}
+	}
+	if total > 0 {
+		slog.Info("purged outbox rows", "count", total, "action", action, "before", before)
+	}
+	return total, ctx.Err()
+}
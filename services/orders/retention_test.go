@@ -0,0 +1,93 @@
+//go:build !integration
+
+package orders
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dbmock "github.com/ATMackay/checkout/database/mock"
+	"go.uber.org/mock/gomock"
+)
+
+// Purge keeps removing full batches until a short one shows nothing expired is
+// left, all against the same cutoff.
+func TestOutboxPurger_PurgeBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+
+	var cutoffs []time.Time
+	record := func(_ context.Context, before time.Time, _ int, _ bool) {
+		cutoffs = append(cutoffs, before)
+	}
+	gomock.InOrder(
+		store.EXPECT().PurgeOutboxItems(gomock.Any(), gomock.Any(), 2, true).Do(record).Return(2, nil),
+		store.EXPECT().PurgeOutboxItems(gomock.Any(), gomock.Any(), 2, true).Do(record).Return(2, nil),
+		store.EXPECT().PurgeOutboxItems(gomock.Any(), gomock.Any(), 2, true).Do(record).Return(1, nil),
+	)
+
+	start := time.Now().UTC()
+	n, err := NewOutboxPurger(store, 24*time.Hour, WithPurgeBatchSize(2), WithArchive()).Purge(context.Background())
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if n != 5 {
+		t.Fatalf("purged %d rows, want 5", n)
+	}
+	want := start.Add(-24 * time.Hour)
+	for _, c := range cutoffs {
+		if c != cutoffs[0] || c.Before(want) || c.Sub(want) > time.Minute {
+			t.Fatalf("cutoffs %v, want one near %s", cutoffs, want)
+		}
+	}
+}
+
+// A failed batch ends the purge, reporting what earlier batches removed.
+func TestOutboxPurger_PurgeError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+
+	gomock.InOrder(
+		store.EXPECT().PurgeOutboxItems(gomock.Any(), gomock.Any(), defaultPurgeBatchSize, false).Return(defaultPurgeBatchSize, nil),
+		store.EXPECT().PurgeOutboxItems(gomock.Any(), gomock.Any(), defaultPurgeBatchSize, false).Return(0, errors.New("db down")),
+	)
+
+	n, err := NewOutboxPurger(store, time.Hour).Purge(context.Background())
+	if err == nil {
+		t.Fatal("want the batch error")
+	}
+	if n != defaultPurgeBatchSize {
+		t.Fatalf("purged %d rows, want %d", n, defaultPurgeBatchSize)
+	}
+}
+
+// Start and Stop run the loop on its interval without leaking it.
+func TestOutboxPurger_StartStop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := dbmock.NewMockOutboxStore(ctrl)
+
+	ran := make(chan struct{}, 1)
+	store.EXPECT().PurgeOutboxItems(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, time.Time, int, bool) (int, error) {
+			select {
+			case ran <- struct{}{}:
+			default:
+			}
+			return 0, nil
+		}).MinTimes(1)
+
+	p := NewOutboxPurger(store, time.Hour, WithPurgeInterval(10*time.Millisecond))
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("purger never ran")
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+}
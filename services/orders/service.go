@@ -7,8 +7,14 @@ package orders
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"time"
 
 	"github.com/ATMackay/checkout/database"
+	"github.com/ATMackay/checkout/fx"
+	"github.com/ATMackay/checkout/model"
+	"github.com/ATMackay/checkout/payments"
 	"github.com/ATMackay/checkout/promotions"
 	"github.com/ATMackay/checkout/services/auth"
 )
@@ -19,10 +25,21 @@ type Service struct {
 	// Service attributed must be non-empty
 	store            store
 	promotionsEngine *promotions.PromotionsEngine
-	relay            Relayer
+	rules            *promotions.StoreRules
+	extraPromotions  []promotions.Promotion
+	// now is the clock promotion schedules are checked against.
+	now      func() time.Time
+	rates    fx.Provider
+	payments payments.Provider
+	loyalty  model.LoyaltyProgram
+	relay    Relayer
+	purger   *OutboxPurger
 	// authn resolves credentials for the service's protected routes. Injected
 	// like any other dependency; the service knows which routes need it.
 	authn auth.Authenticator
+	// admin resolves credentials for the administrative routes, which a
+	// customer credential does not open.
+	admin auth.Authenticator
 }
 
 // store is the orders service's view of the database. Orders genuinely uses
@@ -34,6 +51,12 @@ type Service struct {
 type store interface {
 	database.OrderStore
 	database.InventoryStore
+	database.GiftCardStore
+	database.LoyaltyStore
+	database.LimitStore
+	database.BundleStore
+	database.PromotionStore
+	database.CouponStore
 	database.OutboxStore
 	database.HealthChecker
 	// Transaction runs fn atomically; the callback receives a database.Database
@@ -41,33 +64,133 @@ type store interface {
 	Transaction(ctx context.Context, fn func(database.Database) error) error
 }
 
+// ServiceOption configures optional Service dependencies.
+type ServiceOption func(*Service)
+
+// WithRateProvider sets the exchange-rate source used to price baskets in a
+// currency other than the catalog's. The default converts only a currency to
+// itself, so a request for any other currency is rejected.
+func WithRateProvider(p fx.Provider) ServiceOption {
+	return func(s *Service) { s.rates = p }
+}
+
+// ErrNoPaymentProvider is returned by Start when the service was built without
+// WithPaymentProvider.
+var ErrNoPaymentProvider = errors.New("no payment provider configured")
+
+// WithPaymentProvider sets the processor purchases are charged through. It is
+// required: there is no default, so a deployment never approves payments it
+// did not mean to. Wire the same provider into the relay's capture handler
+// (NewCaptureHandler).
+func WithPaymentProvider(p payments.Provider) ServiceOption {
+	return func(s *Service) { s.payments = p }
+}
+
+// WithAdminAuthenticator sets the authenticator guarding the administrative
+// routes, such as issuing and expiring gift cards. The default admits no one,
+// so those routes are closed until it is set.
+func WithAdminAuthenticator(a auth.Authenticator) ServiceOption {
+	return func(s *Service) { s.admin = a }
+}
+
+// WithLoyalty sets the loyalty program purchases redeem points under. Wire the
+// same program into the relay's orders.created hook (NewLoyaltyHandler), which
+// credits what orders earn. The default program refuses redemptions.
+func WithLoyalty(p model.LoyaltyProgram) ServiceOption {
+	return func(s *Service) { s.loyalty = p }
+}
+
+// WithOutboxPurger runs p, enforcing the outbox retention period, alongside
+// the relay. Without it outbox rows are kept forever.
+func WithOutboxPurger(p *OutboxPurger) ServiceOption {
+	return func(s *Service) { s.purger = p }
+}
+
+// WithPromotions adds promotions to the built-in and stored ones, e.g. rules
+// loaded from a promotions file (promotions.LoadRuleFile).
+func WithPromotions(ps ...promotions.Promotion) ServiceOption {
+	return func(s *Service) { s.extraPromotions = append(s.extraPromotions, ps...) }
+}
+
+// WithClock sets the clock promotion schedules are checked against. The
+// default is time.Now.
+func WithClock(now func() time.Time) ServiceOption {
+	return func(s *Service) { s.now = now }
+}
+
 // NewService constructs the orders domain service. The listening port is not
 // its concern — the httpserver that wraps it owns that.
 func NewService(db store,
 	relayer Relayer,
 	authn auth.Authenticator,
+	opts ...ServiceOption,
 ) *Service {
+	identity, _ := fx.NewStaticProvider(model.DefaultCurrency, nil)
+	rules := promotions.NewStoreRules(db, db)
 	srv := &Service{
 		store: db,
-		promotionsEngine: promotions.NewPromotionsEngine(
-			promotions.NewMacBookProPromotion(db),
-			&promotions.GoogleTVPromotion{},
-			&promotions.AlexaSpeakerPromotion{}, // Add more deals/promotions to the engine
-		),
+		rules: rules,
+		rates: identity,
+		now:   time.Now,
 		relay: relayer, // Noop or Kafka
 		authn: authn,
+		admin: auth.NewPasswordAuthenticator(nil),
 	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	srv.promotionsEngine = promotions.NewPromotionsEngine(append(append(promotions.Builtin(db),
+		rules, // deals managed through the /v1/promotions admin API
+	), srv.extraPromotions...)...)
+	srv.promotionsEngine.SetClock(srv.now)
+	// Conflicting deals go to whichever combination is cheapest for the
+	// customer.
+	srv.promotionsEngine.SetOptimizer(promotions.NewOptimizer())
+	rules.SetClock(srv.now)
 
 	return srv
 }
 
-// Start boots the service's background processes (the outbox relay).
+// Start boots the service's background processes (the outbox relay and, if
+// set, the outbox purger), having checked that the service has a payment
+// provider and that the items promotions reference exist.
 func (h *Service) Start(ctx context.Context) error {
+	if h.payments == nil {
+		return ErrNoPaymentProvider
+	}
+	h.checkPromotions(ctx)
 	// Spawn dependent processes
-	return h.relay.Start(ctx)
+	if err := h.relay.Start(ctx); err != nil {
+		return err
+	}
+	if h.purger != nil {
+		return h.purger.Start(ctx)
+	}
+	return nil
+}
+
+// checkPromotions logs, and records in PromotionMissingItems, each promotion
+// that references SKUs missing from inventory. They are not fatal: the items
+// may be added later.
+func (h *Service) checkPromotions(ctx context.Context) {
+	missing, err := h.promotionsEngine.CheckReferences(ctx, h.store)
+	if err != nil {
+		slog.Error("could not check promotion references", "error", err)
+		return
+	}
+	PromotionMissingItems.Reset()
+	for _, m := range missing {
+		slog.Warn("promotion references missing items", "promotion_id", m.PromotionID, "skus", m.SKUs)
+		PromotionMissingItems.WithLabelValues(m.PromotionID).Set(float64(len(m.SKUs)))
+	}
 }
 
 // Stop tears down the background processes started by Start.
 func (h *Service) Stop() error {
+	if h.purger != nil {
+		if err := h.purger.Stop(); err != nil {
+			return err
+		}
+	}
 	return h.relay.Stop()
 }
@@ -3,6 +3,7 @@
 package orders
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,13 +13,15 @@ import (
 	"github.com/ATMackay/checkout/services/auth"
 	ordersmock "github.com/ATMackay/checkout/services/orders/mock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
 // Test_ServiceProbes exercises the orders service's status/health wiring through
 // its real router: /status is always 200, /health is 200 when its checks pass
-// and 503 when the database probe fails. The health mechanism itself is covered
-// in httpserver; this verifies orders supplies the right checks.
+// and 503 when the database probe or the outbox backlog check fails. The health
+// mechanism itself is covered in httpserver; this verifies orders supplies the
+// right checks.
 func Test_ServiceProbes(t *testing.T) {
 	ctrl := gomock.NewController(t)
 
@@ -31,24 +34,74 @@ func Test_ServiceProbes(t *testing.T) {
 
 	tests := []struct {
 		name     string
-		prepare  func(*mock.MockDatabase)
+		prepare  func(*mock.MockDatabase, *ordersmock.MockRelayer)
 		path     string
 		wantCode int
 	}{
-		{"status", func(*mock.MockDatabase) {}, httpserver.StatusEndPnt, http.StatusOK},
-		{"health", func(md *mock.MockDatabase) {
+		{"status", func(*mock.MockDatabase, *ordersmock.MockRelayer) {}, httpserver.StatusEndPnt, http.StatusOK},
+		{"health", func(md *mock.MockDatabase, mr *ordersmock.MockRelayer) {
 			md.EXPECT().Ping(gomock.Any()).Return(nil)
+			mr.EXPECT().CheckBacklog(gomock.Any()).Return(nil)
 		}, httpserver.HealthEndPnt, http.StatusOK},
-		{"health-db-down", func(md *mock.MockDatabase) {
+		{"health-db-down", func(md *mock.MockDatabase, mr *ordersmock.MockRelayer) {
 			md.EXPECT().Ping(gomock.Any()).Return(assert.AnError)
+			mr.EXPECT().CheckBacklog(gomock.Any()).Return(nil)
+		}, httpserver.HealthEndPnt, http.StatusServiceUnavailable},
+		{"health-outbox-stale", func(md *mock.MockDatabase, mr *ordersmock.MockRelayer) {
+			md.EXPECT().Ping(gomock.Any()).Return(nil)
+			mr.EXPECT().CheckBacklog(gomock.Any()).Return(assert.AnError)
 		}, httpserver.HealthEndPnt, http.StatusServiceUnavailable},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			tc.prepare(db)
+			tc.prepare(db, relay)
 			rr := httptest.NewRecorder()
 			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
 			assert.Equal(t, tc.wantCode, rr.Code)
 		})
 	}
 }
+
+// A service built without a payment provider refuses to start rather than
+// falling back to one.
+func Test_StartRequiresPaymentProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := NewService(mock.NewMockDatabase(ctrl), ordersmock.NewMockRelayer(ctrl), auth.NewPasswordAuthenticator(nil))
+	require.ErrorIs(t, s.Start(context.Background()), ErrNoPaymentProvider)
+}
+
+// The admin routes refuse a customer credential: a customer must not mint gift
+// cards, write promotions or coupons, change limits or bundles, or replay the
+// outbox.
+func Test_AdminRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	authn := auth.NewPasswordAuthenticator(map[string]string{testPassword: "customer"})
+	router := NewService(mock.NewMockDatabase(ctrl), ordersmock.NewMockRelayer(ctrl), authn, withTestAdmin()).RegisterHandlers()
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, GiftCardsEndPnt},
+		{http.MethodPost, GiftCardsEndPnt + "/GC1" + ExpireEndPnt},
+		{http.MethodPut, LimitsEndPnt + "/120P90"},
+		{http.MethodDelete, LimitsEndPnt + "/120P90"},
+		{http.MethodGet, PromotionsEndPnt},
+		{http.MethodPost, PromotionsEndPnt},
+		{http.MethodPut, PromotionsEndPnt + "/1"},
+		{http.MethodDelete, PromotionsEndPnt + "/1"},
+		{http.MethodGet, CouponsEndPnt},
+		{http.MethodPost, CouponsEndPnt},
+		{http.MethodDelete, CouponsEndPnt + "/HALF"},
+		{http.MethodGet, PromotionsEndPnt + "/1" + StatsEndPnt},
+		{http.MethodPut, BundlesEndPnt + "/desk"},
+		{http.MethodDelete, BundlesEndPnt + "/desk"},
+		{http.MethodGet, OutboxEndPnt},
+		{http.MethodGet, OutboxEndPnt + OutboxEventsEndPnt + "/e1"},
+		{http.MethodPost, OutboxEndPnt + ResetEndPnt},
+		{http.MethodGet, OutboxEndPnt + AuditEndPnt},
+	} {
+		req := httptest.NewRequest(route.method, route.path, nil)
+		req.Header.Set(auth.XAuthHeaderKey, testPassword)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, "%s %s", route.method, route.path)
+	}
+}